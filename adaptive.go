@@ -0,0 +1,23 @@
+package batcher
+
+// SetAdaptive turns load-awareness on or off at runtime, e.g. to fall
+// back to a fixed batch size during an incident. Turned off, the batch
+// size returns to InitialBatchSize, within the current limits, and stays
+// there, and load shedding stops, as with Config.FixedSize. Turned back
+// on, the batch size adapts again from there at the next adjustment.
+func (b *Batcher) SetAdaptive(enabled bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.adaptive.Swap(enabled) == enabled || enabled {
+		return
+	}
+	size := max(b.cfg.MinBatchSize, min(b.cfg.InitialBatchSize, b.cfg.MaxBatchSize))
+	b.setBatchSizeLocked(size)
+	b.shedding.Store(false)
+}
+
+// Adaptive reports whether load-awareness is on, see SetAdaptive
+func (b *Batcher) Adaptive() bool {
+	return b.adaptive.Load()
+}
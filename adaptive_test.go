@@ -0,0 +1,74 @@
+package batcher
+
+import (
+	"context"
+	"testing"
+)
+
+func newAdaptiveTestBatcher(t *testing.T, cfg Config) *Batcher {
+	t.Helper()
+	cfg.InitialBatchSize = 20
+	cfg.MaxBatchSize = 100
+	cfg.AdjustmentStrategy = &fixedStrategy{size: 50}
+	cfg.HandlerFunc = func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+		return &LoadFeedback{}, nil
+	}
+	b, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	t.Cleanup(func() { b.Close(context.Background()) })
+	return b
+}
+
+// adjustWithFeedback records a feedback sample and runs an adjustment
+func adjustWithFeedback(b *Batcher, fb LoadFeedback) int {
+	b.mu.Lock()
+	b.recordFeedback(fb, 20, 1)
+	b.mu.Unlock()
+	return b.AdjustNow()
+}
+
+func TestBatcher_FixedSize(t *testing.T) {
+	b := newAdaptiveTestBatcher(t, Config{
+		FixedSize:         true,
+		DropPolicy:        DropNewest,
+		ShedHighWatermark: 2,
+		ShedLoadThreshold: 0.5,
+	})
+
+	if size := adjustWithFeedback(b, LoadFeedback{CPULoad: 1, ErrorRate: 1}); size != 20 {
+		t.Errorf("Expected the batch size fixed at 20, got %d", size)
+	}
+	for i := 0; i < 5; i++ {
+		if err := b.Add(context.Background(), i); err != nil {
+			t.Errorf("Expected no shedding with a fixed size, got %v", err)
+		}
+	}
+	stats := b.GetStats()
+	if stats.Adaptive || stats.ShedItems != 0 || stats.RecentFeedbackSize == 0 {
+		t.Errorf("Expected a fixed batcher still recording feedback, got adaptive %v, %d shed, %d samples",
+			stats.Adaptive, stats.ShedItems, stats.RecentFeedbackSize)
+	}
+}
+
+func TestBatcher_SetAdaptive(t *testing.T) {
+	b := newAdaptiveTestBatcher(t, Config{})
+
+	if size := adjustWithFeedback(b, LoadFeedback{}); size != 50 || !b.Adaptive() {
+		t.Fatalf("Expected the adaptive batcher to adjust to 50, got %d", size)
+	}
+
+	b.SetAdaptive(false)
+	if size := b.GetCurrentBatchSize(); size != 20 || b.Adaptive() {
+		t.Errorf("Expected the batch size back at 20, got %d", size)
+	}
+	if size := adjustWithFeedback(b, LoadFeedback{}); size != 20 {
+		t.Errorf("Expected no adjustment while fixed, got %d", size)
+	}
+
+	b.SetAdaptive(true)
+	if size := adjustWithFeedback(b, LoadFeedback{}); size != 50 || !b.GetStats().Adaptive {
+		t.Errorf("Expected the batcher to adapt again, got %d", size)
+	}
+}
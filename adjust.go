@@ -0,0 +1,54 @@
+package batcher
+
+import "time"
+
+// AdjustEvent describes a change of the batch size by the adjuster
+type AdjustEvent struct {
+	// Previous and Current are the batch sizes before and after
+	Previous int
+	Current  int
+
+	// LoadScore is the load score the decision was based on
+	LoadScore float64
+
+	// Manual is true if the adjustment was forced with AdjustNow
+	Manual bool
+
+	// At is when the adjustment happened
+	At time.Time
+}
+
+// AdjustNow recalculates the batch size from the recent feedback right
+// away rather than at the next LoadCheckInterval tick, e.g. after a known
+// event such as a completed failover. The next automatic adjustment
+// follows a full interval later. It returns the batch size in effect.
+func (b *Batcher) AdjustNow() int {
+	b.resetAdjustTicker()
+	b.adjust(true)
+	return b.GetCurrentBatchSize()
+}
+
+// --- Internal methods ---
+
+// resetAdjustTicker starts a new interval until the next automatic
+// adjustment, unless the batcher is closed
+func (b *Batcher) resetAdjustTicker() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.closed {
+		b.adjustTicker.Reset(b.adjustInterval())
+	}
+}
+
+// adjust runs an adjustment cycle and reports a change to OnAdjust,
+// OnShadowDecision and OnDegraded
+func (b *Batcher) adjust(manual bool) {
+	event, changed := b.adjustBatchSize()
+	if changed && b.cfg.OnAdjust != nil {
+		event.Manual = manual
+		b.cfg.OnAdjust(event)
+	}
+	b.notifyShadow()
+	b.notifyDegraded()
+}
@@ -0,0 +1,189 @@
+package batcher
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBatcher_AdjustNow(t *testing.T) {
+	events := make(chan AdjustEvent, 10)
+	b, err := New(Config{
+		InitialBatchSize:  50,
+		LoadCheckInterval: time.Hour,
+		OnAdjust:          func(e AdjustEvent) { events <- e },
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			return &LoadFeedback{CPULoad: 1.0, ErrorRate: 0.5}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	// Without feedback there is nothing to act on
+	if got := b.AdjustNow(); got != 50 {
+		t.Errorf("Expected the size to hold without feedback, got %d", got)
+	}
+	if len(events) != 0 {
+		t.Errorf("Expected no event without a change, got %d", len(events))
+	}
+
+	b.Add(context.Background(), 1)
+	b.Flush(context.Background())
+	got := b.AdjustNow()
+	if got >= 50 {
+		t.Errorf("Expected the size to shrink under load, got %d", got)
+	}
+
+	select {
+	case e := <-events:
+		if !e.Manual || e.Previous != 50 || e.Current != got || e.LoadScore < 0.5 || e.At.IsZero() {
+			t.Errorf("Unexpected event: %+v", e)
+		}
+	default:
+		t.Fatal("Expected an adjust event")
+	}
+}
+
+func TestBatcher_OnAdjustAutomatic(t *testing.T) {
+	events := make(chan AdjustEvent, 100)
+	b, err := New(Config{
+		InitialBatchSize:  50,
+		LoadCheckInterval: 10 * time.Millisecond,
+		OnAdjust:          func(e AdjustEvent) { events <- e },
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			return &LoadFeedback{CPULoad: 1.0, ErrorRate: 0.5}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	b.Add(context.Background(), 1)
+	b.Flush(context.Background())
+
+	select {
+	case e := <-events:
+		if e.Manual || e.Current >= e.Previous {
+			t.Errorf("Expected an automatic shrink, got %+v", e)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected an automatic adjust event")
+	}
+}
+
+func TestBatcher_ErrorSpikeShrinks(t *testing.T) {
+	errorRate := 0.0
+	b, err := New(Config{
+		InitialBatchSize:    80,
+		LoadCheckInterval:   time.Hour,
+		AdjustmentStrategy:  holdStrategy{},
+		ErrorSpikeThreshold: 0.2,
+		ErrorSpikeFactor:    0.25,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			return &LoadFeedback{CPULoad: 0.3, ErrorRate: errorRate}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	// Errors below the threshold leave the strategy in charge
+	errorRate = 0.1
+	b.Add(context.Background(), 1)
+	b.Flush(context.Background())
+	if got := b.AdjustNow(); got != 80 {
+		t.Errorf("Expected the strategy to hold the size below the spike threshold, got %d", got)
+	}
+
+	errorRate = 0.5
+	b.Add(context.Background(), 1)
+	b.Flush(context.Background())
+	if got := b.AdjustNow(); got != 20 {
+		t.Errorf("Expected an error spike to shrink the size to 20, got %d", got)
+	}
+}
+
+func TestBatcher_ErrorSpikeShrinksOnce(t *testing.T) {
+	errorRate := 0.5
+	b, err := New(Config{
+		InitialBatchSize:    80,
+		LoadCheckInterval:   time.Hour,
+		AdjustmentStrategy:  holdStrategy{},
+		ErrorSpikeThreshold: 0.2,
+		ErrorSpikeFactor:    0.5,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			return &LoadFeedback{CPULoad: 0.3, ErrorRate: errorRate}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	b.Add(context.Background(), 1)
+	b.Flush(context.Background())
+	if got := b.AdjustNow(); got != 40 {
+		t.Fatalf("Expected the error spike to shrink the size to 40, got %d", got)
+	}
+
+	// No new feedback: the spike was acted on already
+	for i := 0; i < 3; i++ {
+		if got := b.AdjustNow(); got != 40 {
+			t.Fatalf("Expected one spike to shrink the size once, got %d after cycle %d", got, i+2)
+		}
+	}
+
+	// A new spike shrinks again
+	b.Add(context.Background(), 2)
+	b.Flush(context.Background())
+	if got := b.AdjustNow(); got != 20 {
+		t.Errorf("Expected a second spike to shrink the size to 20, got %d", got)
+	}
+}
+
+func TestBatcher_SkipsStaleWindow(t *testing.T) {
+	release := make(chan struct{})
+	b, err := New(Config{
+		InitialBatchSize:  1,
+		MaxBatchSize:      1,
+		LoadCheckInterval: 10 * time.Millisecond,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			if batch[0] == "slow" {
+				<-release
+			}
+			return &LoadFeedback{CPULoad: 0.05}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	b.Add(context.Background(), "fast")
+	b.AdjustNow()
+
+	done := make(chan struct{})
+	go func() {
+		b.Add(context.Background(), "slow")
+		close(done)
+	}()
+	for b.GetStats().InFlightItems == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if stats := b.GetStats(); stats.SkippedAdjustments == 0 {
+		t.Error("Expected adjustments to be skipped while the window is stale")
+	}
+
+	close(release)
+	<-done
+	stats := b.GetStats()
+	if stats.AvgHandlerTime <= b.cfg.LoadCheckInterval || !stats.IntervalMismatch {
+		t.Errorf("Expected an interval mismatch, got average handler time %v", stats.AvgHandlerTime)
+	}
+}
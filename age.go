@@ -0,0 +1,38 @@
+package batcher
+
+import "time"
+
+// --- Internal methods ---
+
+// ageLimitLocked returns when the oldest buffered item reaches
+// MaxItemAge, and false if item age is not bounded or the buffer is empty
+func (b *Batcher) ageLimitLocked() (time.Time, bool) {
+	if b.cfg.MaxItemAge <= 0 || len(b.batch) == 0 {
+		return time.Time{}, false
+	}
+	return b.batch[0].added.Add(b.cfg.MaxItemAge), true
+}
+
+// timerReason returns why the flush timer fired at now: FlushReasonAge if
+// the oldest item reached MaxItemAge, FlushReasonTimer otherwise
+func (b *Batcher) timerReason(now time.Time) FlushReason {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if limit, ok := b.ageLimitLocked(); ok && !now.Before(limit) {
+		return FlushReasonAge
+	}
+	return FlushReasonTimer
+}
+
+// handOffExpiry returns a channel that fires when the oldest item of f
+// reaches MaxItemAge, so a hand-off stuck behind busy flushers can give
+// up, or nil if item age is not bounded, and the function releasing it
+func (b *Batcher) handOffExpiry(f *flight) (<-chan time.Time, func()) {
+	if b.cfg.MaxItemAge <= 0 {
+		return nil, func() {}
+	}
+	// Requeued items go to the front, so the first item is the oldest
+	timer := time.NewTimer(time.Until(f.items[0].added.Add(b.cfg.MaxItemAge)))
+	return timer.C, func() { timer.Stop() }
+}
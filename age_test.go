@@ -0,0 +1,96 @@
+package batcher
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMaxItemAge_Linger(t *testing.T) {
+	var mu sync.Mutex
+	var reasons []FlushReason
+	b, err := New(Config{
+		InitialBatchSize: 2,
+		MaxBatchSize:     100,
+		Timeout:          time.Hour,
+		MinLinger:        time.Hour,
+		MaxItemAge:       20 * time.Millisecond,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			return &LoadFeedback{}, nil
+		},
+		OnBatch: func(r BatchResult) {
+			mu.Lock()
+			defer mu.Unlock()
+			reasons = append(reasons, r.Reason)
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	// The full batch lingers, but not past MaxItemAge
+	for i := 0; i < 3; i++ {
+		b.Add(context.Background(), i)
+	}
+	time.Sleep(60 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(reasons) != 1 || reasons[0] != FlushReasonAge {
+		t.Errorf("Expected one age flush, got %v", reasons)
+	}
+}
+
+func TestMaxItemAge_PipelinedSaturation(t *testing.T) {
+	const maxAge = 20 * time.Millisecond
+
+	var oldest atomic.Int64
+	b, err := New(Config{
+		InitialBatchSize:  10,
+		MaxBatchSize:      10,
+		LoadCheckInterval: time.Hour,
+		Timeout:           time.Hour,
+		Pipelined:         true,
+		MaxItemAge:        maxAge,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			for _, item := range batch {
+				age := int64(time.Since(item.(time.Time)))
+				for {
+					o := oldest.Load()
+					if age <= o || oldest.CompareAndSwap(o, age) {
+						break
+					}
+				}
+			}
+			time.Sleep(100 * time.Millisecond)
+			return &LoadFeedback{}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	// Producers outpace the single flusher
+	var wg sync.WaitGroup
+	for p := 0; p < 4; p++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 30; i++ {
+				b.Add(context.Background(), time.Now())
+			}
+		}()
+	}
+	wg.Wait()
+	b.Close(context.Background())
+
+	if age := time.Duration(oldest.Load()); age > maxAge+50*time.Millisecond {
+		t.Errorf("Expected items handled within about %v, the oldest waited %v", maxAge, age)
+	}
+	if n := b.GetStats().FlushReasons["age"]; n == 0 {
+		t.Error("Expected age flushes interleaved with size flushes")
+	}
+}
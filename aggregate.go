@@ -0,0 +1,130 @@
+package batcher
+
+import (
+	"math"
+	"sort"
+)
+
+// Aggregator reduces the load scores of the feedback window, oldest first,
+// to the single score the adjuster acts on. weights[i] is how many handler
+// feedbacks scores[i] stands for, which is above 1 when feedback sampling
+// skipped some and below 1 when Config.FeedbackHalfLife discounts old
+// samples. It is never called with empty slices and must not modify
+// them.
+type Aggregator func(scores, weights []float64) float64
+
+// MeanAggregator averages the scores. This is the default.
+func MeanAggregator(scores, weights []float64) float64 {
+	sum, total := 0.0, 0.0
+	for i, s := range scores {
+		sum += s * weights[i]
+		total += weights[i]
+	}
+	if total == 0 {
+		return 0
+	}
+	return sum / total
+}
+
+// MaxAggregator reacts to the worst score in the window
+func MaxAggregator(scores, weights []float64) float64 {
+	worst := scores[0]
+	for _, s := range scores[1:] {
+		worst = math.Max(worst, s)
+	}
+	return worst
+}
+
+// PercentileAggregator returns an Aggregator computing the p-th percentile
+// (0-100) of the weighted scores using nearest-rank
+func PercentileAggregator(p float64) Aggregator {
+	p = math.Max(0, math.Min(p, 100))
+	return func(scores, weights []float64) float64 {
+		sorted, sortedWeights, total := sortedCopy(scores, weights)
+		rank := p / 100 * total
+		cum := 0.0
+		for i, s := range sorted {
+			cum += sortedWeights[i]
+			if cum >= rank-1e-9 {
+				return s
+			}
+		}
+		return sorted[len(sorted)-1]
+	}
+}
+
+// TrimmedMeanAggregator returns an Aggregator that discards the given
+// fraction (0-0.5) of the lowest and of the highest weighted scores before
+// averaging
+func TrimmedMeanAggregator(fraction float64) Aggregator {
+	fraction = math.Max(0, math.Min(fraction, 0.49))
+	return func(scores, weights []float64) float64 {
+		sorted, sortedWeights, total := sortedCopy(scores, weights)
+		lo, hi := fraction*total, (1-fraction)*total
+
+		sum, kept, cum := 0.0, 0.0, 0.0
+		for i, s := range sorted {
+			// Keep the part of this sample's weight inside [lo, hi]
+			start, end := cum, cum+sortedWeights[i]
+			cum = end
+			w := math.Min(end, hi) - math.Max(start, lo)
+			if w > 0 {
+				sum += s * w
+				kept += w
+			}
+		}
+		if kept == 0 {
+			return MeanAggregator(scores, weights)
+		}
+		return sum / kept
+	}
+}
+
+// sortedCopy sorts the scores with their weights and returns the total weight
+func sortedCopy(scores, weights []float64) ([]float64, []float64, float64) {
+	idx := make([]int, len(scores))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(a, b int) bool { return scores[idx[a]] < scores[idx[b]] })
+
+	sorted := make([]float64, len(scores))
+	sortedWeights := make([]float64, len(scores))
+	total := 0.0
+	for i, j := range idx {
+		sorted[i] = scores[j]
+		sortedWeights[i] = weights[j]
+		total += weights[j]
+	}
+	return sorted, sortedWeights, total
+}
+
+// windowLocked returns the scores and weights of the feedback window.
+// With Config.FeedbackHalfLife set, weights decay exponentially with the
+// age of the sample.
+func (b *Batcher) windowLocked() (scores, weights []float64) {
+	scores = make([]float64, len(b.recentFeedback))
+	weights = make([]float64, len(b.recentFeedback))
+	now := b.now()
+	for i := range b.recentFeedback {
+		s := &b.recentFeedback[i]
+		scores[i] = s.Score
+		weights[i] = s.Weight
+		if weights[i] <= 0 {
+			weights[i] = 1
+		}
+		if hl := b.cfg.FeedbackHalfLife; hl > 0 {
+			age := max(now.Sub(s.RecordedAt), 0)
+			weights[i] *= math.Exp2(-float64(age) / float64(hl))
+		}
+	}
+	return scores, weights
+}
+
+// aggregateLoadLocked applies the configured Aggregator to the window
+func (b *Batcher) aggregateLoadLocked() float64 {
+	if len(b.recentFeedback) == 0 {
+		return 0
+	}
+	return b.cfg.Aggregator(b.windowLocked())
+}
@@ -0,0 +1,123 @@
+package batcher
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestAggregators(t *testing.T) {
+	scores := []float64{0.1, 0.2, 0.2, 0.3, 0.9}
+	weights := []float64{1, 1, 1, 1, 1}
+
+	tests := []struct {
+		name string
+		agg  Aggregator
+		want float64
+	}{
+		{"mean", MeanAggregator, 0.34},
+		{"max", MaxAggregator, 0.9},
+		{"p90", PercentileAggregator(90), 0.9},
+		{"p50", PercentileAggregator(50), 0.2},
+		{"trimmed mean", TrimmedMeanAggregator(0.2), 0.7 / 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.agg(scores, weights); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Aggregate = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Aggregators must not reorder the window
+	if scores[4] != 0.9 {
+		t.Errorf("Aggregator modified its input: %v", scores)
+	}
+}
+
+func TestAggregators_Weighted(t *testing.T) {
+	// One idle sample standing for 9 feedbacks, one overloaded sample
+	scores := []float64{0.0, 1.0}
+	weights := []float64{9, 1}
+
+	tests := []struct {
+		name string
+		agg  Aggregator
+		want float64
+	}{
+		{"mean", MeanAggregator, 0.1},
+		{"max", MaxAggregator, 1.0},
+		{"p50", PercentileAggregator(50), 0.0},
+		{"p95", PercentileAggregator(95), 1.0},
+		{"trimmed mean", TrimmedMeanAggregator(0.05), 0.5 / 9},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.agg(scores, weights); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Aggregate = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBatcher_AggregatorDrivesAdjustment(t *testing.T) {
+	b, err := New(Config{
+		InitialBatchSize: 20,
+		Aggregator:       MaxAggregator,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			return nil, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	// Mostly idle with one severe spike: the mean would grow the batch
+	b.mu.Lock()
+	for i := 0; i < 9; i++ {
+		b.recordFeedback(LoadFeedback{}, 20, 1)
+	}
+	b.recordFeedback(LoadFeedback{CPULoad: 1, QueueDepth: 100, ErrorRate: 1}, 20, 1)
+	b.mu.Unlock()
+
+	b.adjustBatchSize()
+
+	if size := b.GetCurrentBatchSize(); size >= 20 {
+		t.Errorf("Expected max aggregation to shrink the batch, got %d", size)
+	}
+	if stats := b.GetStats(); stats.AggregatedLoadScore != 0.9 {
+		t.Errorf("AggregatedLoadScore = %v, want 0.9", stats.AggregatedLoadScore)
+	}
+}
+
+func TestBatcher_FeedbackHalfLife(t *testing.T) {
+	b, err := New(Config{
+		InitialBatchSize: 10,
+		FeedbackHalfLife: time.Second,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			return nil, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	now := time.Now()
+	b.mu.Lock()
+	b.recentFeedback = []FeedbackSample{
+		{Score: 1, Weight: 1, RecordedAt: now.Add(-3 * time.Second)}, // spike three half-lives ago
+		{Score: 0, Weight: 1, RecordedAt: now},
+	}
+	score := b.aggregateLoadLocked()
+	b.mu.Unlock()
+
+	// Weights 1/8 and 1 give a mean of (1/8) / (9/8)
+	if want := 1.0 / 9; math.Abs(score-want) > 0.01 {
+		t.Errorf("Expected decayed score %v, got %v", want, score)
+	}
+}
@@ -0,0 +1,71 @@
+package batcher
+
+import (
+	"math/rand"
+	"time"
+)
+
+// --- Internal methods ---
+
+// timedFlushes reports whether a batch is flushed by a timer when it
+// does not fill up
+func (b *Batcher) timedFlushes() bool {
+	return b.cfg.Timeout > 0 || b.cfg.FlushAlignment > 0
+}
+
+// timeoutFlushAt returns when a batch whose first item arrived at now is
+// flushed if it does not fill up: Timeout later, rounded up to the next
+// FlushAlignment boundary and delayed by FlushJitter
+func (b *Batcher) timeoutFlushAt(now time.Time) time.Time {
+	at := now.Add(b.cfg.Timeout)
+	if a := b.cfg.FlushAlignment; a > 0 {
+		if t := at.Truncate(a); t.Before(at) {
+			at = t.Add(a)
+		}
+	}
+	if j := b.cfg.FlushJitter; j > 0 {
+		at = at.Add(time.Duration(rand.Int63n(int64(j))))
+	}
+	return at
+}
+
+// lingerLocked holds a full batch back while its newest item, added at
+// now, is younger than MinLinger, rearming the flush timer for when it
+// will have lingered. Timeout, counted from the oldest item, MaxItemAge
+// and item deadlines cut the wait short, and a batch at MaxBatchSize is never
+// held. It reports whether the batch is held.
+func (b *Batcher) lingerLocked(now time.Time) bool {
+	if b.cfg.MinLinger <= 0 || len(b.batch) >= b.cfg.MaxBatchSize {
+		return false
+	}
+	at := now.Add(b.cfg.MinLinger)
+	if b.cfg.Timeout > 0 {
+		if limit := b.batch[0].added.Add(b.cfg.Timeout); limit.Before(at) {
+			at = limit
+		}
+	}
+	if !b.earliestDeadline.IsZero() {
+		if limit := b.earliestDeadline.Add(-b.cfg.DeadlineMargin); limit.Before(at) {
+			at = limit
+		}
+	}
+	if limit, ok := b.ageLimitLocked(); ok && limit.Before(at) {
+		at = limit
+	}
+	if !now.Before(at) {
+		return false
+	}
+	b.stopTimerLocked()
+	b.scheduleFlushLocked(at)
+	return true
+}
+
+// adjustInterval returns the time until the next automatic adjustment:
+// LoadCheckInterval lengthened by LoadCheckJitter
+func (b *Batcher) adjustInterval() time.Duration {
+	d := b.cfg.LoadCheckInterval
+	if j := b.cfg.LoadCheckJitter; j > 0 {
+		d += time.Duration(rand.Int63n(int64(j)))
+	}
+	return d
+}
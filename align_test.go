@@ -0,0 +1,200 @@
+package batcher
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBatcher_FlushAlignment(t *testing.T) {
+	const alignment = 200 * time.Millisecond
+
+	flushed := make(chan time.Time, 1)
+	b, err := New(Config{
+		InitialBatchSize: 10,
+		FlushAlignment:   alignment,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			flushed <- time.Now()
+			return nil, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	if err := b.Add(context.Background(), 1); err != nil {
+		t.Fatalf("Add() failed: %v", err)
+	}
+
+	select {
+	case at := <-flushed:
+		if off := at.Sub(at.Truncate(alignment)); off > 50*time.Millisecond {
+			t.Errorf("Expected the flush on a %v boundary, got %v past it", alignment, off)
+		}
+	case <-time.After(2 * alignment):
+		t.Fatal("Expected an aligned flush without Timeout")
+	}
+}
+
+func TestBatcher_TimeoutFlushAt(t *testing.T) {
+	b := &Batcher{cfg: Config{Timeout: 10 * time.Second, FlushAlignment: time.Minute}}
+
+	now := time.Date(2024, 1, 1, 12, 0, 55, 0, time.UTC)
+	if at, want := b.timeoutFlushAt(now), now.Add(65*time.Second); !at.Equal(want) {
+		t.Errorf("Expected flush at %v, got %v", want, at)
+	}
+
+	now = time.Date(2024, 1, 1, 12, 0, 50, 0, time.UTC)
+	if at, want := b.timeoutFlushAt(now), now.Add(10*time.Second); !at.Equal(want) {
+		t.Errorf("Expected flush on the boundary at %v, got %v", want, at)
+	}
+
+	b.cfg.FlushJitter = 5 * time.Second
+	base := now.Add(10 * time.Second)
+	for i := 0; i < 100; i++ {
+		at := b.timeoutFlushAt(now)
+		if at.Before(base) || !at.Before(base.Add(5*time.Second)) {
+			t.Fatalf("Expected flush within [%v, %v), got %v", base, base.Add(5*time.Second), at)
+		}
+	}
+}
+
+func TestBatcher_AdjustInterval(t *testing.T) {
+	b := &Batcher{cfg: Config{LoadCheckInterval: time.Second}}
+	if d := b.adjustInterval(); d != time.Second {
+		t.Errorf("Expected interval 1s without jitter, got %v", d)
+	}
+
+	b.cfg.LoadCheckJitter = 500 * time.Millisecond
+	seen := make(map[time.Duration]bool)
+	for i := 0; i < 100; i++ {
+		d := b.adjustInterval()
+		if d < time.Second || d >= 1500*time.Millisecond {
+			t.Fatalf("Expected interval within [1s, 1.5s), got %v", d)
+		}
+		seen[d] = true
+	}
+	if len(seen) < 2 {
+		t.Error("Expected jittered intervals to differ")
+	}
+}
+
+func TestBatcher_LoadCheckJitter(t *testing.T) {
+	adjusted := make(chan AdjustEvent, 16)
+	b, err := New(Config{
+		InitialBatchSize:  10,
+		LoadCheckInterval: 20 * time.Millisecond,
+		LoadCheckJitter:   20 * time.Millisecond,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			return &LoadFeedback{CPULoad: 0.05}, nil
+		},
+		OnAdjust: func(e AdjustEvent) {
+			select {
+			case adjusted <- e:
+			default:
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	b.Add(context.Background(), 1)
+	b.Flush(context.Background())
+
+	select {
+	case e := <-adjusted:
+		if e.Manual {
+			t.Error("Expected an automatic adjustment")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected jittered adjustments to keep running")
+	}
+}
+
+func TestBatcher_MinLinger(t *testing.T) {
+	const linger = 60 * time.Millisecond
+
+	type flush struct {
+		size int
+		at   time.Time
+	}
+	flushed := make(chan flush, 10)
+	b, err := New(Config{
+		InitialBatchSize:  2,
+		MaxBatchSize:      4,
+		LoadCheckInterval: time.Hour,
+		MinLinger:         linger,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			flushed <- flush{len(batch), time.Now()}
+			return nil, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	ctx := context.Background()
+	b.Add(ctx, 1)
+	b.Add(ctx, 2)
+	time.Sleep(linger / 3)
+	last := time.Now()
+	b.Add(ctx, 3)
+
+	select {
+	case f := <-flushed:
+		if f.size != 3 {
+			t.Errorf("Expected the full batch to keep filling to 3, got %d", f.size)
+		}
+		if waited := f.at.Sub(last); waited < linger {
+			t.Errorf("Expected the newest item to linger %v, flushed after %v", linger, waited)
+		}
+	case <-time.After(5 * linger):
+		t.Fatal("Expected a flush once the newest item lingered")
+	}
+
+	// MaxBatchSize is never held back
+	for i := 0; i < 4; i++ {
+		b.Add(ctx, i)
+	}
+	select {
+	case f := <-flushed:
+		if f.size != 4 {
+			t.Errorf("Expected a batch of 4, got %d", f.size)
+		}
+	case <-time.After(linger / 2):
+		t.Error("Expected a batch at MaxBatchSize to flush right away")
+	}
+}
+
+func TestBatcher_MinLingerTimeout(t *testing.T) {
+	flushed := make(chan time.Time, 1)
+	b, err := New(Config{
+		InitialBatchSize:  1,
+		LoadCheckInterval: time.Hour,
+		Timeout:           30 * time.Millisecond,
+		MinLinger:         time.Hour,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			flushed <- time.Now()
+			return nil, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	start := time.Now()
+	b.Add(context.Background(), 1)
+	select {
+	case at := <-flushed:
+		if waited := at.Sub(start); waited < 30*time.Millisecond {
+			t.Errorf("Expected the item held until Timeout, flushed after %v", waited)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected Timeout to cut MinLinger short")
+	}
+}
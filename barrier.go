@@ -0,0 +1,48 @@
+package batcher
+
+import "context"
+
+// Barrier inserts a synchronization point: it flushes the current batch
+// and waits for it and every batch already being handled, like
+// FlushAndWait, and no item added after Barrier was called is handed to
+// the handler before all of those batches are done. Adds that fill a
+// batch meanwhile block until then, as do timer flushes. It returns the
+// joined errors of the prior batches. If ctx is done first, Barrier
+// returns ctx.Err() among them, but the barrier holds until the prior
+// batches are done all the same. A handler must not call Barrier on its
+// own batcher.
+func (b *Batcher) Barrier(ctx context.Context) error {
+	if err := b.waitForResume(ctx); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	own, waitFor := b.detachAllLocked(FlushReasonManual)
+	gate := make(chan struct{})
+	b.barrier = gate
+	b.mu.Unlock()
+
+	prior := waitFor
+	if own != nil {
+		prior = append(waitFor[:len(waitFor):len(waitFor)], own)
+	}
+	go b.liftBarrier(gate, prior)
+
+	return b.handleAndWait(ctx, own, waitFor)
+}
+
+// --- Internal methods ---
+
+// liftBarrier opens gate once every batch of prior is done
+func (b *Batcher) liftBarrier(gate chan struct{}, prior []*flight) {
+	for _, f := range prior {
+		<-f.done
+	}
+
+	b.mu.Lock()
+	if b.barrier == gate {
+		b.barrier = nil
+	}
+	b.mu.Unlock()
+	close(gate)
+}
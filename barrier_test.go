@@ -0,0 +1,85 @@
+package batcher
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBatcher_Barrier(t *testing.T) {
+	var mu sync.Mutex
+	var handled []any
+	release := make(chan struct{})
+
+	b, err := New(Config{
+		InitialBatchSize:  2,
+		LoadCheckInterval: time.Hour,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			if slices.Contains(batch, any("slow")) {
+				<-release
+			}
+			mu.Lock()
+			handled = append(handled, batch...)
+			mu.Unlock()
+			return nil, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	ctx := context.Background()
+	go func() {
+		b.Add(ctx, "slow")
+		b.Add(ctx, "a")
+	}()
+	for b.GetStats().InFlightItems != 2 {
+		time.Sleep(time.Millisecond)
+	}
+	b.Add(ctx, "b")
+
+	barrier := make(chan error, 1)
+	go func() { barrier <- b.Barrier(ctx) }()
+	for b.GetStats().PendingItems != 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// A batch filled after the barrier waits for the slow one
+	added := make(chan struct{})
+	go func() {
+		b.Add(ctx, "c")
+		b.Add(ctx, "d")
+		close(added)
+	}()
+
+	select {
+	case <-added:
+		t.Fatal("Expected the batch after the barrier to wait")
+	case err := <-barrier:
+		t.Fatalf("Barrier() returned %v before the prior batches were done", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	mu.Lock()
+	if slices.Contains(handled, any("c")) {
+		t.Error("Expected no item after the barrier to be handled yet")
+	}
+	mu.Unlock()
+
+	close(release)
+	if err := <-barrier; err != nil {
+		t.Errorf("Barrier() failed: %v", err)
+	}
+	<-added
+
+	mu.Lock()
+	defer mu.Unlock()
+	if i, j := slices.Index(handled, any("slow")), slices.Index(handled, any("c")); i < 0 || j < i {
+		t.Errorf("Expected items after the barrier handled last, got %v", handled)
+	}
+	if len(handled) != 5 {
+		t.Errorf("Expected 5 items handled, got %v", handled)
+	}
+}
@@ -3,9 +3,15 @@ package batcher
 import (
 	"context"
 	"errors"
+	"fmt"
+	"maps"
 	"math"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"go.opentelemetry.io/otel/metric"
 )
 
 // LoadFeedback represents backend load metrics returned by the handler
@@ -27,6 +33,30 @@ type LoadFeedback struct {
 
 	// Custom can hold any additional metrics
 	Custom map[string]interface{}
+
+	// Unhealthy reports that the backend cannot take more work right
+	// now. Automatic flushing pauses for Config.UnhealthyBackoff.
+	Unhealthy bool
+
+	// RetryAfter asks the batcher to pause automatic flushing for the
+	// given duration, e.g. from an HTTP 503 Retry-After header, up to 5
+	// minutes. It takes precedence over UnhealthyBackoff.
+	RetryAfter time.Duration
+
+	// ThrottleFor reports that the backend throttled the batch, like an
+	// HTTP 429 or a Kafka throttle time. Flushing pauses for the given
+	// duration, up to 5 minutes as with RetryAfter, while items keep
+	// buffering within the usual limits, and the batch counts as a full
+	// overload sample (load score 1.0) for sizing, whatever the other
+	// metrics say.
+	ThrottleFor time.Duration
+
+	// SuggestedBatchSize is the batch size the backend considers ideal,
+	// e.g. an advertised page size, or zero if it has no opinion. It is
+	// combined with the computed size according to
+	// Config.SuggestionPolicy and clamped to MinBatchSize and
+	// MaxBatchSize.
+	SuggestedBatchSize int
 }
 
 // LoadScore calculates a normalized load score (0.0 = idle, 1.0 = overloaded)
@@ -35,30 +65,84 @@ func (lf *LoadFeedback) LoadScore() float64 {
 	score := 0.0
 
 	// CPU load (60% weight) - increased from 40% to be more responsive to CPU pressure
-	score += lf.CPULoad * 0.6
+	score += clamp01(lf.CPULoad) * 0.6
 
 	// Queue depth normalized (15% weight)
 	// Assume queue depth > 100 is critical
-	queueScore := math.Min(float64(lf.QueueDepth)/100.0, 1.0)
+	queueScore := clamp01(float64(lf.QueueDepth) / 100.0)
 	score += queueScore * 0.15
 
 	// Error rate (15% weight)
-	score += lf.ErrorRate * 0.15
+	score += clamp01(lf.ErrorRate) * 0.15
 
 	// DB locks normalized (10% weight)
 	// Assume > 50 locks is critical
-	lockScore := math.Min(float64(lf.DBLocks)/50.0, 1.0)
+	lockScore := clamp01(float64(lf.DBLocks) / 50.0)
 	score += lockScore * 0.1
 
 	return math.Min(score, 1.0)
 }
 
+// clamp01 clamps v to [0, 1], mapping NaN to 0 so that one broken metric
+// cannot poison the score
+func clamp01(v float64) float64 {
+	if math.IsNaN(v) {
+		return 0
+	}
+	return math.Max(0, math.Min(v, 1))
+}
+
 // HandlerFunc processes a batch and returns load feedback
 // The batch slice must be treated as read-only and not retained.
 type HandlerFunc func(ctx context.Context, batch []any) (*LoadFeedback, error)
 
+// HandlerFuncV2 is like HandlerFunc but also receives metadata about the batch
+type HandlerFuncV2 func(ctx context.Context, batch []any, meta BatchMeta) (*LoadFeedback, error)
+
+// BatchMeta describes a flushed batch
+type BatchMeta struct {
+	// BatchID uniquely identifies the batch. It is generated once per
+	// flush and stays the same if the batch is handed to the handler
+	// again, so it can be used as an idempotency key.
+	BatchID string
+
+	// TraceIDs holds the distinct trace IDs captured by Config.TraceIDFunc
+	// from the contexts passed to Add, in first-seen order. Handlers can use
+	// them to emit correlation info or create span links.
+	TraceIDs []string
+
+	// ItemValues holds, for every item in batch order, the values of
+	// Config.ContextKeys captured from the context passed to Add, in the
+	// order of ContextKeys; nil if ContextKeys is empty. Value looks one
+	// up by key.
+	ItemValues [][]any
+
+	// Attempt is 1 for a batch of fresh items, and one more than the
+	// number of times its most requeued item was handled before, see
+	// RequeuePolicy
+	Attempt int
+
+	// Reason is why the batch was flushed
+	Reason FlushReason
+
+	// Deadline is the earliest deadline of the contexts its items were
+	// added with, with Config.RespectDeadlines; zero if none had one or
+	// deadlines are not respected
+	Deadline time.Time
+
+	contextKeys []any
+	delivered   []deliveries // per item, in FanOutShared
+}
+
 // Config holds the configuration for the load-aware batcher
 type Config struct {
+	// Name identifies the batcher in pprof labels, metrics and Stats
+	Name string
+
+	// Labels are extra dimensions attached wherever Name is, e.g. the
+	// table or tenant a batcher serves
+	Labels map[string]string
+
 	// InitialBatchSize is the starting batch size
 	InitialBatchSize int
 
@@ -73,16 +157,424 @@ type Config struct {
 	// flushing is used.
 	Timeout time.Duration
 
+	// FlushAlignment rounds the time of each timeout flush up to the next
+	// multiple of it on the wall clock, counted from the Unix epoch, for
+	// backends that ingest fixed partitions: with one minute, batches
+	// flush on the minute. Without Timeout, items wait for the next
+	// boundary. If FlushAlignment <= 0, flushes are not aligned.
+	FlushAlignment time.Duration
+
+	// FlushJitter delays each timeout flush by a random duration of up
+	// to this long, so that replicas started together, or aligned to the
+	// same boundaries, do not flush in lockstep. With FlushAlignment it
+	// should be well below the alignment to keep batches in their
+	// partition.
+	FlushJitter time.Duration
+
+	// MinLinger holds a batch back, even when full, until its newest item
+	// has sat in it this long, so that related items arriving close
+	// together, such as counter deltas, end up in the same batch. The
+	// batch keeps filling meanwhile, up to MaxBatchSize, at which it is
+	// flushed regardless. Timeout, counted from the oldest item, and
+	// item deadlines take precedence; with a steady stream of items a
+	// batch is flushed by them. Explicit flushes ignore MinLinger. If
+	// MinLinger <= 0, full batches are flushed right away.
+	MinLinger time.Duration
+
+	// MaxItemAge bounds the time from Add until an item is handed to the
+	// handler, whatever else holds it back: MinLinger, CoalesceWindow,
+	// FlushAlignment, or, for a Pipelined batcher, a full batch waiting
+	// for a busy flusher, which the Add that cut it then handles itself
+	// alongside the flushers. Pauses the backend asked for still hold
+	// batches back. Such flushes are counted as FlushReasonAge. If
+	// MaxItemAge <= 0, item age is not bounded.
+	MaxItemAge time.Duration
+
+	// Pipelined makes Add hand full batches to a dedicated flusher
+	// goroutine instead of handling them itself, so producers fill the
+	// next batch while the previous one is being handled. At most one
+	// batch is handled at a time this way, unless MaxConcurrency allows
+	// more; Add blocks once the next batch is full too. Errors of
+	// handed-off batches are not returned by Add but reach Stats and the
+	// dead-letter handling as usual.
+	Pipelined bool
+
+	// MaxConcurrency is the number of flusher goroutines a Pipelined
+	// batcher may scale up to. A flusher is added whenever a full batch
+	// finds all of them busy while the batch size is already at
+	// MaxBatchSize, so the backlog cannot be absorbed by larger batches;
+	// a backend whose load keeps batches smaller gets no extra
+	// concurrency. Batches handled by different flushers may complete
+	// out of order (default: 1)
+	MaxConcurrency int
+
+	// FlusherIdleTimeout is how long a flusher added for MaxConcurrency
+	// may go without a batch before it exits (default: 10s)
+	FlusherIdleTimeout time.Duration
+
+	// OnScale is called whenever a flusher is added or exits, outside
+	// the batcher lock, and should return quickly
+	OnScale func(ScaleEvent)
+
+	// Coordinator, if set, is shared with the other batchers of the
+	// process that hit the same backend, so that their batches take
+	// turns instead of reaching it all at once. Time spent waiting for
+	// it does not count as handler time.
+	Coordinator *Coordinator
+
+	// CoalesceWindow lets a timeout flush wait up to this long past
+	// Timeout when the batch is filling fast enough to reach the batch
+	// size within it, trading a little latency for fewer tiny batches
+	// under trickle traffic. Item deadlines are still respected. If
+	// CoalesceWindow <= 0, timeout flushes happen on time.
+	CoalesceWindow time.Duration
+
+	// CoalesceMinFill is the fraction of the batch size a batch must
+	// hold for its timeout flush to be deferred (default: 0.5)
+	CoalesceMinFill float64
+
 	// HandlerFunc is called with each flushed batch
 	HandlerFunc HandlerFunc
 
+	// HandlerFuncV2 is called with each flushed batch and its metadata.
+	// If set it takes precedence over HandlerFunc.
+	HandlerFuncV2 HandlerFuncV2
+
+	// SortFunc, if set, orders each batch before it is handed to the
+	// handler, for handlers that do better on sorted input such as
+	// sequential index inserts or grouped keys. It compares two items
+	// like the cmp function of slices.SortStableFunc; items comparing
+	// equal keep the order they were added in.
+	SortFunc func(a, b any) int
+
+	// PlainHandlerFunc is called with each flushed batch if neither
+	// HandlerFunc nor HandlerFuncV2 is set. It implies SynthesizeFeedback.
+	PlainHandlerFunc PlainHandlerFunc
+
+	// DryRun makes the batcher accept and batch items as usual, but
+	// discard every batch instead of calling the handler, with feedback
+	// synthesized as by SynthesizeFeedback. The batch size then evolves
+	// as it would against an instant backend, so a new configuration can
+	// be tried on production traffic in staging. No handler is required.
+	DryRun bool
+
+	// ClassifyError tells which handler errors are a sign of backend
+	// load. Only ErrorClassOverload errors raise the synthesized load
+	// score; a batch failing with another class is scored as if it had
+	// succeeded. See ClassifyCommonErrors. (default: every error is
+	// overload)
+	ClassifyError func(err error) ErrorClass
+
+	// SynthesizeFeedback makes the batcher measure handler latency and
+	// failures and derive feedback from them whenever the handler returns
+	// none, so load-aware sizing works without backend metrics
+	SynthesizeFeedback bool
+
+	// LatencyTarget is the handler latency considered healthy when
+	// feedback is synthesized. If zero, latency is judged against the
+	// fastest per-item latency observed.
+	LatencyTarget time.Duration
+
+	// SojournLatency makes LatencyTarget apply to the sojourn time of the
+	// oldest item of each batch, from Add to handler completion, rather
+	// than to the handler latency alone, so time spent buffering counts
+	// against the target. It has no effect without LatencyTarget.
+	SojournLatency bool
+
+	// ValidateFunc, if set, checks every item in Add. An invalid item is
+	// rejected right away with an error wrapping both ErrInvalidItem and
+	// the validation error, so it cannot fail a whole batch at flush
+	// time. Rejections are counted in Stats.InvalidItems.
+	ValidateFunc func(item any) error
+
+	// TraceIDFunc extracts a trace ID from the context passed to Add.
+	// Captured IDs are exposed in BatchMeta.TraceIDs.
+	TraceIDFunc func(ctx context.Context) string
+
+	// ContextKeys are the context keys, such as a tenant or trace ID key,
+	// whose values are captured from the context passed to Add and
+	// exposed per item in BatchMeta.ItemValues. Only these values are
+	// kept; the context itself is not retained. Keys must be comparable.
+	ContextKeys []any
+
+	// TenantFunc returns the tenant an item belongs to, given the Add
+	// context and the item. If set, every tenant is held to its quota
+	// and gets its own entry in Stats.Tenants, so the number of tenants
+	// should be bounded.
+	TenantFunc func(ctx context.Context, item any) string
+
+	// TenantQuota is the quota of every tenant not in TenantQuotas
+	// (default: unlimited)
+	TenantQuota TenantQuota
+
+	// TenantQuotas overrides TenantQuota for individual tenants
+	TenantQuotas map[string]TenantQuota
+
+	// RespectDeadlines makes the batcher flush early so that items whose
+	// Add context carries a deadline are handled before it expires
+	RespectDeadlines bool
+
+	// DeadlineMargin is how long before the earliest item deadline the
+	// batch is flushed (default: 10ms)
+	DeadlineMargin time.Duration
+
+	// IDFunc generates batch IDs (default: ULID)
+	IDFunc func() string
+
 	// AdjustmentFactor controls how aggressively batch size changes (default: 0.2)
-	// Higher values = more aggressive adjustments
+	// Higher values = more aggressive adjustments. Negative, NaN and
+	// infinite values are rejected.
 	AdjustmentFactor float64
 
+	// GrowFactor and ShrinkFactor override AdjustmentFactor for growing
+	// and shrinking respectively (default: AdjustmentFactor), so that the
+	// batcher can back off from overload quickly and recover cautiously,
+	// e.g. with a GrowFactor of 0.1 and a ShrinkFactor of 0.4. They apply
+	// to ThresholdStrategy and strategies that honour them.
+	GrowFactor   float64
+	ShrinkFactor float64
+
+	// ErrorSpikeThreshold is the error rate of any batch since the
+	// previous adjustment above which the batch size is multiplied by
+	// ErrorSpikeFactor, whatever the strategy decides. A batch counts in
+	// one adjustment only, so a single spike shrinks the size once. Zero
+	// disables it.
+	ErrorSpikeThreshold float64
+
+	// ErrorSpikeFactor multiplies the batch size on an error spike
+	// (default: 0.5)
+	ErrorSpikeFactor float64
+
+	// DegradeAfter is the number of adjustment cycles in a row the load
+	// score must be pinned at 1.0, or flushing paused, before the batcher
+	// reports itself degraded. Cycles skipped while a batch outlasts
+	// LoadCheckInterval count with the latest feedback, and cycles while
+	// load-awareness is off count as well. Zero disables it. See
+	// Batcher.Degraded.
+	DegradeAfter int
+
+	// OnDegraded is called with true when the batcher becomes degraded
+	// and with false when it recovers, outside the batcher lock. It runs
+	// on the adjuster goroutine, or that of the AdjustNow caller, and
+	// should return quickly.
+	OnDegraded func(degraded bool)
+
+	// FixedSize turns load-awareness off: the batch size stays at
+	// InitialBatchSize and load shedding is off, so the batcher behaves as
+	// a classic size and timeout batcher, e.g. to roll it out before
+	// trusting the feedback. Feedback is still recorded for Stats, and
+	// pauses the backend asks for still apply. Batcher.SetAdaptive turns
+	// load-awareness on and off at runtime.
+	FixedSize bool
+
 	// LoadCheckInterval is how often to recalculate optimal batch size
 	// based on recent load feedback (default: 5 seconds)
 	LoadCheckInterval time.Duration
+
+	// LoadCheckJitter lengthens each interval between adjustments by a
+	// random duration of up to this long, so that replicas started
+	// together do not adjust, and shift their load, in lockstep. See
+	// FlushJitter for the flushes.
+	LoadCheckJitter time.Duration
+
+	// AdjustmentStrategy computes the next batch size from recent feedback
+	// (default: ThresholdStrategy, which steps by AdjustmentFactor)
+	AdjustmentStrategy AdjustmentStrategy
+
+	// ShadowStrategy, if set, runs on every adjustment cycle next to
+	// AdjustmentStrategy, on the same feedback, without controlling
+	// anything: it follows its own hypothetical batch size, starting from
+	// InitialBatchSize, which Stats and OnShadowDecision report next to
+	// the live one. This compares a candidate strategy against the live
+	// one in production before switching to it.
+	ShadowStrategy AdjustmentStrategy
+
+	// OnShadowDecision is called with every decision of ShadowStrategy,
+	// outside the batcher lock. It runs on the adjuster goroutine or on
+	// that of an AdjustNow caller, so calls may overlap and must be safe
+	// for concurrent use; it should return quickly.
+	OnShadowDecision func(ShadowDecision)
+
+	// Strategy names the AdjustmentStrategy to create from those
+	// registered with RegisterStrategy, e.g. "aimd", so it can be chosen
+	// from a configuration file or flag. It is ignored if
+	// AdjustmentStrategy is set.
+	Strategy string
+
+	// SuggestionPolicy selects how LoadFeedback.SuggestedBatchSize is
+	// combined with the size computed by AdjustmentStrategy (default:
+	// SuggestionBlend)
+	SuggestionPolicy SuggestionPolicy
+
+	// SuggestionWeight is the share of the suggested size in
+	// SuggestionBlend, in (0, 1] (default: 0.5)
+	SuggestionWeight float64
+
+	// CustomMetrics lets entries of LoadFeedback.Custom influence the
+	// load score, each with its own weight and normalization bounds
+	CustomMetrics []CustomMetric
+
+	// FeedbackSampleRate records only one in N handler feedbacks, which
+	// saves work when flushes happen thousands of times per second.
+	// Recorded samples are weighted by the feedbacks they stand for.
+	FeedbackSampleRate int
+
+	// FeedbackSampleInterval records at most one handler feedback per
+	// interval. It can be combined with FeedbackSampleRate.
+	FeedbackSampleInterval time.Duration
+
+	// MeterProvider, if set, receives OTel metrics: batch size, handler
+	// latency and item sojourn histograms, flush and error counters and
+	// the number of pending items
+	MeterProvider metric.MeterProvider
+
+	// UnhealthyBackoff is how long flushing pauses when feedback reports
+	// the backend Unhealthy without a RetryAfter (default: 1s)
+	UnhealthyBackoff time.Duration
+
+	// CloseGrace keeps accepting Adds for this long after Close is
+	// called, before rejecting them with ErrClosed, so that producers
+	// racing with shutdown get their last items into the final drain. A
+	// ctx passed to Close that is done first ends the grace window early.
+	// If CloseGrace <= 0, Adds are rejected as soon as Close is called.
+	CloseGrace time.Duration
+
+	// InitFunc, if set, prepares the handler before the first batch is
+	// handled, e.g. by connecting or checking the schema. A failed call
+	// is retried InitRetries times; if all fail, the batch fails with
+	// the error and the next batch tries again.
+	InitFunc func(ctx context.Context) error
+
+	// InitRetries is how often a failed InitFunc is retried per batch
+	// (default: 3)
+	InitRetries int
+
+	// InitBackoff is the delay before the first InitFunc retry, doubled
+	// for every following one (default: 100ms)
+	InitBackoff time.Duration
+
+	// HealthFunc, if set, probes the backend every HealthCheckInterval.
+	// Every failed probe pauses automatic flushing for an interval, as
+	// an Unhealthy feedback does.
+	HealthFunc func(ctx context.Context) error
+
+	// HealthCheckInterval is how often HealthFunc is called, and how
+	// long each call may take (default: 10s)
+	HealthCheckInterval time.Duration
+
+	// FeedbackHalfLife is the age at which a sample counts half as much
+	// as a fresh one in the window score (default: 0, no decay)
+	FeedbackHalfLife time.Duration
+
+	// NilFeedback selects what is recorded when the handler returns
+	// neither feedback nor an error (default: NilFeedbackIgnore)
+	NilFeedback NilFeedbackPolicy
+
+	// NeutralLoadScore is the score recorded by NilFeedbackNeutral. It
+	// should lie where the strategy holds the size steady (default: 0.4)
+	NeutralLoadScore float64
+
+	// Aggregator reduces the feedback window to the load score used by
+	// the adjuster and load shedding (default: MeanAggregator)
+	Aggregator Aggregator
+
+	// DropPolicy selects which item to shed under sustained overload
+	// (default: DropNone)
+	DropPolicy DropPolicy
+
+	// ShedHighWatermark is the number of buffered plus in-flight items at
+	// which shedding starts, provided the average load score is at least
+	// ShedLoadThreshold. Shedding is disabled if ShedHighWatermark <= 0.
+	ShedHighWatermark int
+
+	// ShedLowWatermark is the number of buffered plus in-flight items at
+	// which shedding stops again (default: ShedHighWatermark / 2)
+	ShedLowWatermark int
+
+	// ShedLoadThreshold is the average load score that counts as
+	// sustained overload (default: 0.8)
+	ShedLoadThreshold float64
+
+	// PriorityFunc returns the priority of an item for DropLowestPriority;
+	// lower values are shed first
+	PriorityFunc func(item any) int
+
+	// DeadLetterFunc receives items the batcher dropped and batches
+	// whose handler panicked
+	DeadLetterFunc DeadLetterFunc
+
+	// OnItemDropped is called for every item the batcher gives up on,
+	// with the reason, so producers can compensate, e.g. by enqueueing
+	// it elsewhere. It sees the same items as DeadLetterFunc, one at a
+	// time, and is called outside the batcher lock.
+	OnItemDropped func(item any, reason DropReason)
+
+	// RequeuePolicy selects what happens to the items of a batch whose
+	// handler failed with an error wrapping ErrRetryable (default:
+	// RequeueNone)
+	RequeuePolicy RequeuePolicy
+
+	// MaxRequeues is how often an item may go back to the buffer before
+	// it is given up on and dead-lettered with DropReasonRequeue
+	// (default: 3)
+	MaxRequeues int
+
+	// MaxRequeueElapsed bounds the time an item is retried for,
+	// regardless of MaxRequeues: once this long has passed since its
+	// first attempt started, a failed item is dead-lettered with
+	// DropReasonRequeue instead of requeued (default: 0, no bound)
+	MaxRequeueElapsed time.Duration
+
+	// BisectFailures makes a failed batch recover from poison pills:
+	// instead of failing the batch as a whole, the batcher handles each
+	// half of it on its own, recursing into halves that fail again, and
+	// quarantines the items that fail on their own by dead-lettering
+	// them with DropReasonPoison. The healthy items are delivered and the
+	// flush succeeds. Batches requeued by RequeuePolicy and errors
+	// ClassifyError deems overload are not bisected; without a
+	// ClassifyError every failure is.
+	BisectFailures bool
+
+	// OnAdjust is called whenever an adjustment cycle changes the batch
+	// size, automatic or forced with AdjustNow, outside the batcher lock.
+	// It runs on the adjuster goroutine or on that of an AdjustNow
+	// caller, so calls may overlap, or arrive out of order, and must be
+	// safe for concurrent use; it should return quickly.
+	OnAdjust func(AdjustEvent)
+
+	// OnBatch is called with the outcome of every batch handled, after
+	// its items were released, on the goroutine that handled it. It
+	// should return quickly.
+	OnBatch func(BatchResult)
+
+	// OnPanic is called with the panic value and stack trace when the
+	// handler panics. The panic is converted into a *PanicError.
+	OnPanic func(value any, stack []byte)
+
+	// MemoryPressure, if set, makes the batcher treat high heap usage and
+	// long GC pauses of its own process as overload
+	MemoryPressure *MemoryPressureConfig
+
+	// SizerFunc returns the size in bytes of an item, e.g. the length of
+	// its payload, for MaxPendingBytes and Stats.PendingBytes. It is
+	// called once per item, in Add.
+	SizerFunc func(item any) int
+
+	// MaxPendingBytes caps the bytes of buffered plus in-flight items, as
+	// measured by SizerFunc, which must be set. Beyond it Add returns
+	// ErrPendingBytes, or waits for room with BlockOnPendingBytes. An item
+	// larger than the cap is admitted when nothing else is pending. Zero
+	// disables the cap.
+	MaxPendingBytes int64
+
+	// BlockOnPendingBytes makes Add wait until enough pending bytes are
+	// released, by handled or dropped batches, or until ctx is done,
+	// instead of returning ErrPendingBytes
+	BlockOnPendingBytes bool
+
+	// Probe, if set, runs a capability probe in New and derives the batch
+	// size bounds from it. See Batcher.Probe.
+	Probe *ProbeConfig
 }
 
 var (
@@ -91,31 +583,218 @@ var (
 
 	// ErrInvalidConfig is returned when configuration is invalid
 	ErrInvalidConfig = errors.New("batcher: invalid configuration")
+
+	// ErrInvalidItem is returned by Add for an item Config.ValidateFunc
+	// rejects
+	ErrInvalidItem = errors.New("batcher: invalid item")
 )
 
+// pendingItem is a buffered item together with what was captured at Add time
+type pendingItem struct {
+	item     any
+	traceID  string
+	deadline time.Time
+	added    time.Time
+	firstTry time.Time
+	requeues int   // times the item went back to the buffer after a failure
+	size     int64 // bytes as measured by Config.SizerFunc
+	values   []any // captured Config.ContextKeys values
+	tenant   string
+
+	// delivered records the FanOutShared destinations the item was
+	// delivered to; requeued copies share it
+	delivered deliveries
+}
+
+// flight is a detached batch on its way through the handler
+type flight struct {
+	id    string
+	items []pendingItem
+	done  chan struct{}
+	err   error
+	gate  chan struct{} // barrier to wait for before handling, see Barrier
+
+	reason FlushReason
+}
+
 // Batcher accumulates items in memory and flushes them based on
 // dynamic batch size adjusted by backend load
 type Batcher struct {
 	mu     sync.Mutex
-	batch  []any
+	batch  []pendingItem
 	cfg    Config
 	timer  *time.Timer
 	closed bool
 
+	// closing is set once Close is called; closeDone is closed with
+	// closeErr set when it returns, for later calls to wait on
+	closing   bool
+	closeDone chan struct{}
+	closeErr  error
+
+	// Flush scheduling
+	timerAt          time.Time     // when the armed timer fires
+	earliestDeadline time.Time     // earliest Add deadline among buffered items
+	lastFlush        *flight       // batch detached by the latest flush
+	barrier          chan struct{} // closed when the latest Barrier completes
+	avgFill          float64       // moving average of items per detached batch
+	coalesced        atomic.Int64
+	requeued         atomic.Int64
+
 	// Load tracking
 	currentBatchSize int
-	recentFeedback   []LoadFeedback
+	recentFeedback   []FeedbackSample
 	maxFeedbackLen   int
+	itemsAdded       int
+	lastAdjust       time.Time
+	inFlightItems    int
+	flights          map[*flight]struct{}
 	adjustTicker     *time.Ticker
 	stopAdjust       chan struct{}
 	wg               sync.WaitGroup
+
+	// bgCtx is the context of handler calls made in the background, by
+	// timer flushes and the flusher, cancelled by cancelBg when Close
+	// gives up on them
+	bgCtx    context.Context
+	cancelBg context.CancelFunc
+
+	// Flusher of a Pipelined batcher, see handOffLocked, and the
+	// flushers added for MaxConcurrency. flushers counts them all and is
+	// guarded by mu.
+	pipeline      chan *flight
+	handoffs      sync.WaitGroup
+	flusherDone   chan struct{}
+	extraFlushers sync.WaitGroup
+	flushers      int
+	flusherCount  atomic.Int64
+
+	// adaptive is false while load-awareness is off, see SetAdaptive
+	adaptive atomic.Bool
+
+	// Load shedding
+	shedding  atomic.Bool
+	shedItems atomic.Int64
+
+	invalidItems atomic.Int64
+
+	// Poison-pill recovery, see Config.BisectFailures
+	bisections  atomic.Int64
+	quarantined atomic.Int64
+
+	// Degradation under prolonged overload, see Config.DegradeAfter.
+	// saturatedCycles is guarded by mu, degradedReported by degradedMu.
+	saturatedCycles  int
+	degraded         atomic.Bool
+	degradedMu       sync.Mutex
+	degradedReported bool
+
+	// Shadow evaluation, see Config.ShadowStrategy. shadowSize and
+	// shadowDecision, the decision not yet handed to OnShadowDecision,
+	// are guarded by mu.
+	shadowSize       int
+	shadowDecision   *ShadowDecision
+	shadowBatchSize  atomic.Int64
+	shadowDecisions  atomic.Int64
+	shadowDivergence atomic.Int64 // sum of |shadow - live| over decisions
+
+	// Lock-free mirrors for GetStats and GetCurrentBatchSize
+	batchSize     atomic.Int64
+	minBatchSize  atomic.Int64
+	maxBatchSize  atomic.Int64
+	pending       atomic.Int64
+	oldestPending atomic.Int64 // UnixNano Add time of batch[0], 0 if empty
+	inFlight      atomic.Int64
+	load          atomic.Pointer[loadSnapshot]
+
+	// pendingBytes is the size of the buffered and in-flight items,
+	// updated under mu; bytesFreed, if set, is closed when some of them
+	// are released, to wake Adds waiting for room
+	pendingBytes         atomic.Int64
+	pendingBytesRejected atomic.Int64
+	bytesFreed           chan struct{}
+
+	// Handler panics recovered
+	panics atomic.Int64
+
+	// destinations is the number of destinations of the FanOut in
+	// FanOutShared driven by this batcher, 0 if none
+	destinations int
+
+	// Batches handled by the reason they were flushed for
+	flushReasons [numFlushReasons]atomic.Int64
+
+	// Per-tenant quota accounting, guarded by tenantMu, which is never
+	// held while acquiring mu
+	tenantMu sync.Mutex
+	tenants  map[string]*tenantState
+
+	// Name and Labels as pprof label pairs
+	labels []string
+
+	// OTel instruments, nil without Config.MeterProvider
+	otel *otelMetrics
+
+	// Handler call counters
+	batches       atomic.Int64
+	handlerErrors atomic.Int64
+
+	// Handler time against LoadCheckInterval, see staleWindowLocked
+	avgHandlerTime     float64 // nanoseconds, guarded by mu
+	avgHandlerTimeNs   atomic.Int64
+	skippedAdjustments atomic.Int64
+	overloadErrors     atomic.Int64
+	handlerTime        atomic.Int64
+
+	// Feedback published by handlers, consumed by the adjuster so that
+	// handler completion never waits on the main lock to record it.
+	// feedbackReady wakes the adjuster to drain feedbackCh; samples are
+	// only taken off it under the lock, so an adjustment sees every
+	// sample published before it.
+	feedbackCh        chan FeedbackSample
+	feedbackReady     chan struct{}
+	droppedFeedback   atomic.Int64
+	sanitizedFeedback atomic.Int64
+
+	// Backend-requested pause (UnixNano), and how often one started
+	pausedUntil atomic.Int64
+	pauses      atomic.Int64
+
+	// Handler lifecycle, see Config.InitFunc and Config.HealthFunc
+	initMu         sync.Mutex
+	initDone       atomic.Bool
+	healthFailures atomic.Int64
+
+	// Nil feedback handling
+	nilFeedbacks atomic.Int64
+	pendingDecay atomic.Int64
+	latency      latencyBaseline
+	outcomes     outcomeTracker
+
+	// Feedback sampling
+	feedbackSeen atomic.Int64
+	sampledUpTo  atomic.Int64
+	lastSampled  atomic.Int64
+
+	// Memory pressure
+	readMemory     func() (heap uint64, pause time.Duration)
+	memoryPressure float64
+
+	// Item sojourn times, from Add to handler completion
+	sojourn sojournHistogram
+
+	// now is the clock of the adaptation path: feedback timestamps, the
+	// window decay and the adjustment cycles. Tests replace it to
+	// simulate the passing of time.
+	now func() time.Time
 }
 
-// New creates a new load-aware Batcher with the given configuration
+// New creates a new load-aware Batcher with the given configuration. An
+// invalid configuration is rejected with the error of Validate, which
+// names the offending fields and wraps ErrInvalidConfig.
 func New(cfg Config) (*Batcher, error) {
-	// Validate configuration
-	if cfg.InitialBatchSize <= 0 {
-		return nil, ErrInvalidConfig
+	if err := cfg.Validate(); err != nil {
+		return nil, err
 	}
 	if cfg.MinBatchSize <= 0 {
 		cfg.MinBatchSize = 1
@@ -123,206 +802,1090 @@ func New(cfg Config) (*Batcher, error) {
 	if cfg.MaxBatchSize <= 0 {
 		cfg.MaxBatchSize = 1000
 	}
-	if cfg.MinBatchSize > cfg.MaxBatchSize {
-		return nil, ErrInvalidConfig
-	}
 	if cfg.InitialBatchSize < cfg.MinBatchSize {
 		cfg.InitialBatchSize = cfg.MinBatchSize
 	}
 	if cfg.InitialBatchSize > cfg.MaxBatchSize {
 		cfg.InitialBatchSize = cfg.MaxBatchSize
 	}
-	if cfg.HandlerFunc == nil {
-		return nil, ErrInvalidConfig
+	if cfg.DryRun {
+		cfg.HandlerFunc, cfg.HandlerFuncV2, cfg.PlainHandlerFunc = dryRunHandler, nil, nil
+		cfg.SynthesizeFeedback = true
+	}
+	if cfg.HandlerFunc == nil && cfg.HandlerFuncV2 == nil {
+		plain := cfg.PlainHandlerFunc
+		cfg.HandlerFunc = func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			return nil, plain(ctx, batch)
+		}
+		cfg.SynthesizeFeedback = true
 	}
-	if cfg.AdjustmentFactor <= 0 {
+	if cfg.AdjustmentFactor == 0 {
 		cfg.AdjustmentFactor = 0.2
 	}
+	if cfg.GrowFactor == 0 {
+		cfg.GrowFactor = cfg.AdjustmentFactor
+	}
+	if cfg.ShrinkFactor == 0 {
+		cfg.ShrinkFactor = cfg.AdjustmentFactor
+	}
+	if cfg.ErrorSpikeFactor == 0 {
+		cfg.ErrorSpikeFactor = 0.5
+	}
 	if cfg.LoadCheckInterval <= 0 {
 		cfg.LoadCheckInterval = 5 * time.Second
 	}
+	if cfg.DeadlineMargin <= 0 {
+		cfg.DeadlineMargin = 10 * time.Millisecond
+	}
+	if cfg.CoalesceMinFill <= 0 || cfg.CoalesceMinFill > 1 {
+		cfg.CoalesceMinFill = 0.5
+	}
+	if !(cfg.SuggestionWeight > 0 && cfg.SuggestionWeight <= 1) {
+		cfg.SuggestionWeight = 0.5
+	}
+	if cfg.IDFunc == nil {
+		cfg.IDFunc = newULID
+	}
+	if cfg.AdjustmentStrategy == nil && cfg.Strategy != "" {
+		strategy, err := NewStrategy(cfg.Strategy)
+		if err != nil {
+			return nil, &ConfigError{Field: "Strategy", Reason: err.Error()}
+		}
+		cfg.AdjustmentStrategy = strategy
+	}
+	if cfg.AdjustmentStrategy == nil {
+		cfg.AdjustmentStrategy = &ThresholdStrategy{}
+	}
+	cfg.ContextKeys = slices.Clone(cfg.ContextKeys)
+	cfg.TenantQuotas = maps.Clone(cfg.TenantQuotas)
+	if cfg.Labels != nil {
+		labels := make(map[string]string, len(cfg.Labels))
+		for k, v := range cfg.Labels {
+			labels[k] = v
+		}
+		cfg.Labels = labels
+	}
+	if cfg.UnhealthyBackoff <= 0 {
+		cfg.UnhealthyBackoff = time.Second
+	}
+	if cfg.InitRetries <= 0 {
+		cfg.InitRetries = 3
+	}
+	if cfg.InitBackoff <= 0 {
+		cfg.InitBackoff = 100 * time.Millisecond
+	}
+	if cfg.HealthCheckInterval <= 0 {
+		cfg.HealthCheckInterval = 10 * time.Second
+	}
+	if cfg.NeutralLoadScore <= 0 {
+		cfg.NeutralLoadScore = 0.4
+	}
+	if cfg.Aggregator == nil {
+		cfg.Aggregator = MeanAggregator
+	}
+	if cfg.ShedLowWatermark <= 0 || cfg.ShedLowWatermark >= cfg.ShedHighWatermark {
+		cfg.ShedLowWatermark = cfg.ShedHighWatermark / 2
+	}
+	if cfg.ShedLoadThreshold <= 0 {
+		cfg.ShedLoadThreshold = 0.8
+	}
+	if cfg.MaxRequeues <= 0 {
+		cfg.MaxRequeues = 3
+	}
+	if cfg.MaxConcurrency <= 0 {
+		cfg.MaxConcurrency = 1
+	}
+	if cfg.FlusherIdleTimeout <= 0 {
+		cfg.FlusherIdleTimeout = 10 * time.Second
+	}
+	if mp := cfg.MemoryPressure; mp != nil {
+		c := *mp
+		if c.HeapLimit == 0 {
+			c.HeapLimit = softMemoryLimit()
+		}
+		if c.GCPauseLimit <= 0 {
+			c.GCPauseLimit = 50 * time.Millisecond
+		}
+		if c.Threshold <= 0 || c.Threshold > 1 {
+			c.Threshold = 0.8
+		}
+		cfg.MemoryPressure = &c
+	}
 
 	b := &Batcher{
-		batch:            make([]any, 0, cfg.InitialBatchSize),
 		cfg:              cfg,
 		currentBatchSize: cfg.InitialBatchSize,
-		recentFeedback:   make([]LoadFeedback, 0, 10),
+		shadowSize:       cfg.InitialBatchSize,
+		avgFill:          float64(cfg.InitialBatchSize),
+		recentFeedback:   make([]FeedbackSample, 0, 10),
 		maxFeedbackLen:   10,
+		feedbackCh:       make(chan FeedbackSample, 64),
+		feedbackReady:    make(chan struct{}, 1),
+		lastAdjust:       time.Now(),
+		flights:          make(map[*flight]struct{}),
+		tenants:          make(map[string]*tenantState),
 		stopAdjust:       make(chan struct{}),
+		closeDone:        make(chan struct{}),
+		readMemory:       newMemorySampler().read,
+		now:              time.Now,
+	}
+	b.bgCtx, b.cancelBg = context.WithCancel(context.Background())
+
+	b.labels = b.labelPairs()
+	b.batch = make([]pendingItem, 0, b.nextBatchCapLocked())
+	b.batchSize.Store(int64(cfg.InitialBatchSize))
+	b.shadowBatchSize.Store(int64(cfg.InitialBatchSize))
+	b.adaptive.Store(!cfg.FixedSize)
+	b.minBatchSize.Store(int64(cfg.MinBatchSize))
+	b.maxBatchSize.Store(int64(cfg.MaxBatchSize))
+	b.load.Store(&loadSnapshot{})
+
+	if cfg.Probe != nil {
+		if _, err := b.Probe(context.Background(), *cfg.Probe); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.MeterProvider != nil {
+		m, err := newOTelMetrics(b, cfg.MeterProvider)
+		if err != nil {
+			return nil, err
+		}
+		b.otel = m
 	}
 
 	// Start background goroutine to adjust batch size based on load
-	b.adjustTicker = time.NewTicker(cfg.LoadCheckInterval)
+	b.adjustTicker = time.NewTicker(b.adjustInterval())
 	b.wg.Add(1)
-	go b.adjustBatchSizeLoop()
+	go b.withLabels(context.Background(), roleAdjuster, func(context.Context) {
+		b.adjustBatchSizeLoop()
+	})
+	if cfg.Pipelined {
+		b.pipeline = make(chan *flight)
+		b.flusherDone = make(chan struct{})
+		b.flushers = 1
+		b.flusherCount.Store(1)
+		go b.withLabels(b.bgCtx, roleFlusher, b.flushLoop)
+	}
+	if cfg.HealthFunc != nil {
+		b.wg.Add(1)
+		go b.withLabels(context.Background(), roleHealth, func(context.Context) {
+			b.healthCheckLoop()
+		})
+	}
 
 	return b, nil
 }
 
 // Add adds one item to the batch
 func (b *Batcher) Add(ctx context.Context, item any) error {
+	if b.cfg.ValidateFunc != nil {
+		if err := b.cfg.ValidateFunc(item); err != nil {
+			b.invalidItems.Add(1)
+			return fmt.Errorf("%w: %w", ErrInvalidItem, err)
+		}
+	}
+
+	p := pendingItem{item: item, added: time.Now()}
+	if b.cfg.TraceIDFunc != nil {
+		p.traceID = b.cfg.TraceIDFunc(ctx)
+	}
+	if b.cfg.RespectDeadlines {
+		p.deadline, _ = ctx.Deadline()
+	}
+	if len(b.cfg.ContextKeys) > 0 {
+		p.values = b.captureContext(ctx)
+	}
+	if b.cfg.SizerFunc != nil {
+		p.size = int64(b.cfg.SizerFunc(item))
+	}
+	if b.cfg.TenantFunc != nil {
+		p.tenant = b.cfg.TenantFunc(ctx, item)
+		if err := b.admitTenant(p.tenant, p.added); err != nil {
+			return err
+		}
+	}
+
 	b.mu.Lock()
 	if b.closed {
 		b.mu.Unlock()
+		b.unadmitTenant(p.tenant)
 		return ErrClosed
 	}
 
+	if b.underMemoryPressureLocked() && b.cfg.MemoryPressure.MaxPending > 0 &&
+		len(b.batch)+b.inFlightItems >= b.cfg.MemoryPressure.MaxPending {
+		b.mu.Unlock()
+		b.unadmitTenant(p.tenant)
+		return ErrMemoryPressure
+	}
+
+	if err := b.reserveBytesLocked(ctx, p); err != nil {
+		b.mu.Unlock()
+		b.unadmitTenant(p.tenant)
+		return err
+	}
+
+	if dropped, rejected := b.shedLocked(p); dropped != nil {
+		b.releaseBytesLocked(dropped)
+		if rejected {
+			b.mu.Unlock()
+			b.unadmitTenant(p.tenant)
+			b.deadLetter(ctx, dropped, DropReasonShed, ErrDropped)
+			return ErrDropped
+		}
+		defer b.deadLetter(ctx, dropped, DropReasonShed, ErrDropped)
+	}
+
 	wasEmpty := len(b.batch) == 0
-	b.batch = append(b.batch, item)
+	b.batch = append(b.batch, p)
+	b.setPendingLocked()
+	b.itemsAdded++
+
+	// Flush right away if the item would otherwise miss its deadline
+	deadlineDue := false
+	if !p.deadline.IsZero() {
+		if b.earliestDeadline.IsZero() || p.deadline.Before(b.earliestDeadline) {
+			b.earliestDeadline = p.deadline
+		}
+		deadlineDue = !time.Now().Before(p.deadline.Add(-b.cfg.DeadlineMargin))
+	}
+
+	// Hold full batches back while the backend asked for a pause
+	full := len(b.batch) >= b.currentBatchSize || deadlineDue
+	if until, paused := b.paused(); full && paused {
+		b.scheduleFlushLocked(until)
+		full = false
+	}
+
+	// Hold full batches back until the newest item has lingered
+	if full && !deadlineDue && b.lingerLocked(p.added) {
+		full = false
+	}
 
 	// Check if we've reached the current dynamic batch size
-	if len(b.batch) >= b.currentBatchSize {
-		batch := b.detachBatchLocked()
+	if full {
+		reason := FlushReasonSize
+		if len(b.batch) < b.currentBatchSize {
+			reason = FlushReasonDeadline
+		}
+		f := b.detachBatchLocked(reason)
 		b.stopTimerLocked()
+		if b.cfg.Pipelined {
+			return b.handOffLocked(ctx, f)
+		}
 		b.mu.Unlock()
 
 		// Process batch and get feedback
-		return b.processBatch(ctx, batch)
+		return b.processBatch(ctx, f)
 	}
 
 	// Only schedule a timeout when we transition from empty -> non-empty
-	if wasEmpty && b.cfg.Timeout > 0 && b.timer == nil {
+	if wasEmpty && b.timedFlushes() && b.timer == nil {
 		b.startTimerLocked()
 	}
 
+	// Bound the age of the oldest item, even if a held batch or one that
+	// keeps filling up pushed the timer past it
+	if b.cfg.MaxItemAge > 0 {
+		b.scheduleFlushLocked(b.batch[0].added.Add(b.cfg.MaxItemAge))
+	}
+
+	// Pull the timer forward so the earliest deadline is met
+	if !p.deadline.IsZero() && p.deadline.Equal(b.earliestDeadline) {
+		b.scheduleFlushLocked(p.deadline.Add(-b.cfg.DeadlineMargin))
+	}
+
 	b.mu.Unlock()
 	return nil
 }
 
-// Flush flushes the current batch, if any
+// Flush flushes the current batch, if any. If the backend asked for a
+// pause, Flush waits for it to end or for ctx to be done.
+//
+// Concurrent flushes coalesce: each buffered batch is detached and handled
+// by exactly one of them. Flush also waits for the batches already being
+// handled when it is called, whether another Flush, a timer flush, a full
+// batch in Add or a Pipelined hand-off detached them, so once Flush
+// returns, the items added before it was called have been handled, unless
+// ctx was done first. It returns the error of the batch it handled or, if
+// it found the buffer empty because another flush just detached it, of
+// that batch; FlushAndWait returns the errors of all of them. A handler
+// must therefore not Flush its own batcher.
 func (b *Batcher) Flush(ctx context.Context) error {
-	b.mu.Lock()
-	if len(b.batch) == 0 {
-		b.mu.Unlock()
-		return nil
+	if err := b.waitForResume(ctx); err != nil {
+		return err
 	}
+	return b.flushAndJoin(ctx, FlushReasonManual)
+}
 
-	batch := b.detachBatchLocked()
-	b.stopTimerLocked()
+// FlushNow flushes the current batch right away, even while the backend
+// asked for a pause that Flush would wait out, and waits for the batches
+// already being handled like Flush. It is meant for shutdown paths and
+// operator-triggered drains, where latency matters more than backing off.
+func (b *Batcher) FlushNow(ctx context.Context) error {
+	return b.flushAndJoin(ctx, FlushReasonManual)
+}
+
+// FlushAndWait flushes the current batch and waits for every batch that
+// was already being handled when it was called, e.g. by a concurrent Add
+// or a timer flush. It returns the joined errors of all those batches.
+// If ctx is done before they complete, ctx.Err() is included instead of
+// the errors of the batches still outstanding.
+func (b *Batcher) FlushAndWait(ctx context.Context) error {
+	if err := b.waitForResume(ctx); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	own, waitFor := b.detachAllLocked(FlushReasonManual)
 	b.mu.Unlock()
 
-	return b.processBatch(ctx, batch)
+	return b.handleAndWait(ctx, own, waitFor)
 }
 
-// Close marks the batcher as closed and flushes any remaining items
+// Close marks the batcher as closed, once Config.CloseGrace has passed,
+// and flushes any remaining items. If ctx is done before it returns,
+// handler calls still running for timer flushes or a Pipelined flusher
+// are cancelled as well. If the backend asked for a pause, Close waits it
+// out like Flush; if ctx is done first, the items still buffered are
+// dead-lettered with DropReasonClose and Close returns ctx.Err().
+//
+// Calling Close again, e.g. from a deferred call while AttachLifecycle
+// drains the batcher, waits for the first call to finish and returns its
+// result, or ctx.Err() if ctx is done first.
 func (b *Batcher) Close(ctx context.Context) error {
 	b.mu.Lock()
-	if b.closed {
+	if b.closing {
 		b.mu.Unlock()
-		return nil
+		select {
+		case <-b.closeDone:
+			return b.closeErr
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	}
-	b.closed = true
+	b.closing = true
 	b.mu.Unlock()
 
-	// Stop adjustment goroutine
-	close(b.stopAdjust)
-	b.adjustTicker.Stop()
-	b.wg.Wait()
-
-	return b.Flush(ctx)
+	err := b.close(ctx)
+	b.closeErr = err
+	close(b.closeDone)
+	return err
 }
 
 // GetCurrentBatchSize returns the current dynamic batch size
 func (b *Batcher) GetCurrentBatchSize() int {
-	b.mu.Lock()
-	defer b.mu.Unlock()
-	return b.currentBatchSize
+	return int(b.batchSize.Load())
 }
 
-// GetStats returns current statistics
+// GetStats returns current statistics. It never waits on the main lock,
+// so it is safe to poll at high frequency while producers are adding.
 func (b *Batcher) GetStats() Stats {
-	b.mu.Lock()
-	defer b.mu.Unlock()
-
-	avgLoad := 0.0
-	if len(b.recentFeedback) > 0 {
-		for _, f := range b.recentFeedback {
-			avgLoad += f.LoadScore()
-		}
-		avgLoad /= float64(len(b.recentFeedback))
+	// Fold in queued feedback if nobody else holds the lock
+	if (len(b.feedbackCh) > 0 || b.pendingDecay.Load() > 0) && b.mu.TryLock() {
+		b.drainFeedbackLocked()
+		b.mu.Unlock()
 	}
 
-	return Stats{
-		CurrentBatchSize:   b.currentBatchSize,
-		PendingItems:       len(b.batch),
-		AverageLoadScore:   avgLoad,
-		RecentFeedbackSize: len(b.recentFeedback),
+	load := b.load.Load()
+	stats := Stats{
+		Name:                b.cfg.Name,
+		Labels:              b.cfg.Labels,
+		CurrentBatchSize:    int(b.batchSize.Load()),
+		MinBatchSize:        int(b.minBatchSize.Load()),
+		MaxBatchSize:        int(b.maxBatchSize.Load()),
+		PendingItems:        int(b.pending.Load()),
+		AverageLoadScore:    load.average,
+		AggregatedLoadScore: load.aggregated,
+		RecentFeedbackSize:  load.samples,
+		InFlightItems:       int(b.inFlight.Load()),
+		Flushers:            int(b.flusherCount.Load()),
+		ShedItems:           b.shedItems.Load(),
+		InvalidItems:        b.invalidItems.Load(),
+		Bisections:          b.bisections.Load(),
+		QuarantinedItems:    b.quarantined.Load(),
+		Shedding:            b.shedding.Load(),
+		Degraded:            b.degraded.Load(),
+		Adaptive:            b.adaptive.Load(),
+		MemoryPressure:      load.memoryPressure,
+		Panics:              b.panics.Load(),
+		DroppedFeedback:     b.droppedFeedback.Load(),
+		SanitizedFeedback:   b.sanitizedFeedback.Load(),
+		NilFeedbacks:        b.nilFeedbacks.Load(),
+		NilFeedbackPolicy:   b.cfg.NilFeedback.String(),
+		Pauses:              b.pauses.Load(),
+		HealthCheckFailures: b.healthFailures.Load(),
+		Batches:             b.batches.Load(),
+		HandlerErrors:       b.handlerErrors.Load(),
+		AvgHandlerTime:      time.Duration(b.avgHandlerTimeNs.Load()),
+		SkippedAdjustments:  b.skippedAdjustments.Load(),
+		OverloadErrors:      b.overloadErrors.Load(),
+		HandlerTime:         time.Duration(b.handlerTime.Load()),
+		CoalescedFlushes:    b.coalesced.Load(),
+		RequeuedItems:       b.requeued.Load(),
+	}
+	now := time.Now()
+	stats.OldestPendingAge = b.oldestPendingAge(now)
+	stats.PendingBytes = b.pendingBytes.Load()
+	stats.PendingBytesRejected = b.pendingBytesRejected.Load()
+	stats.IntervalMismatch = stats.AvgHandlerTime > b.cfg.LoadCheckInterval
+	stats.SojournP50 = b.sojourn.percentile(now, 0.50)
+	stats.SojournP95 = b.sojourn.percentile(now, 0.95)
+	stats.SojournP99 = b.sojourn.percentile(now, 0.99)
+	if until, paused := b.paused(); paused {
+		stats.PausedUntil = until
+	}
+	if r, ok := b.cfg.AdjustmentStrategy.(EstimateReporter); ok {
+		stats.StrategyEstimates = r.Estimates()
+	}
+	if b.cfg.ShadowStrategy != nil {
+		stats.Shadow = b.shadowStats()
+	}
+	stats.FlushReasons = b.flushReasonStats()
+	if b.cfg.TenantFunc != nil {
+		stats.Tenants = b.tenantStats()
 	}
+
+	return stats
 }
 
 // Stats holds batcher statistics
 type Stats struct {
-	CurrentBatchSize   int
-	PendingItems       int
+	// Name and Labels identify the batcher, as configured. Labels must
+	// not be modified.
+	Name   string
+	Labels map[string]string
+
+	CurrentBatchSize int
+	PendingItems     int
+
+	// MinBatchSize and MaxBatchSize are the bounds the batch size
+	// currently adapts within, as configured or changed by Probe and
+	// SetLimits
+	MinBatchSize int
+	MaxBatchSize int
+
+	// OldestPendingAge is how long the oldest buffered item has waited
+	// to be flushed, zero if the buffer is empty. See PeekPending.
+	OldestPendingAge time.Duration
+
+	// PendingBytes is the size of the buffered and in-flight items as
+	// measured by Config.SizerFunc, and PendingBytesRejected the number
+	// of Adds refused for lack of room under MaxPendingBytes
+	PendingBytes         int64
+	PendingBytesRejected int64
+
 	AverageLoadScore   float64
 	RecentFeedbackSize int
+
+	// AggregatedLoadScore is the window score computed by Config.Aggregator,
+	// which is what the adjuster acts on
+	AggregatedLoadScore float64
+
+	// InFlightItems is the number of items currently being handled
+	InFlightItems int
+
+	// Flushers is the number of flusher goroutines of a Pipelined
+	// batcher, see Config.MaxConcurrency; zero otherwise
+	Flushers int
+
+	// ShedItems is the total number of items dropped by load shedding
+	ShedItems int64
+
+	// InvalidItems is the number of items rejected by Config.ValidateFunc
+	InvalidItems int64
+
+	// Bisections is the number of handler calls made to isolate poison
+	// pills and QuarantinedItems the number isolated, see
+	// Config.BisectFailures
+	Bisections       int64
+	QuarantinedItems int64
+
+	// Shedding reports whether load shedding is currently active
+	Shedding bool
+
+	// Degraded reports whether the batcher is degraded, see
+	// Batcher.Degraded
+	Degraded bool
+
+	// Adaptive reports whether load-awareness is on, see
+	// Batcher.SetAdaptive
+	Adaptive bool
+
+	// Panics is the number of handler panics recovered
+	Panics int64
+
+	// Batches is the number of handler calls, HandlerErrors how many of
+	// them failed and HandlerTime the total time spent in them
+	Batches       int64
+	HandlerErrors int64
+	HandlerTime   time.Duration
+
+	// AvgHandlerTime is the moving average of the handler time.
+	// IntervalMismatch reports that it exceeds LoadCheckInterval, so most
+	// adjustment cycles find no new feedback: they are skipped, counted
+	// in SkippedAdjustments, and LoadCheckInterval should be raised.
+	AvgHandlerTime     time.Duration
+	IntervalMismatch   bool
+	SkippedAdjustments int64
+
+	// OverloadErrors is how many of the HandlerErrors counted as load,
+	// as classified by Config.ClassifyError
+	OverloadErrors int64
+
+	// SojournP50, SojournP95 and SojournP99 are percentiles of the time
+	// items took from Add to handler completion, over the last one to
+	// two minutes, and zero if no batch completed in that time. They are
+	// bucket bounds, overstating the exact value by at most 19%.
+	SojournP50 time.Duration
+	SojournP95 time.Duration
+	SojournP99 time.Duration
+
+	// CoalescedFlushes is the number of timeout flushes deferred by
+	// Config.CoalesceWindow because the batch was about to fill up
+	CoalescedFlushes int64
+
+	// RequeuedItems is the number of items put back into the buffer
+	// after a retryable handler failure, see Config.RequeuePolicy
+	RequeuedItems int64
+
+	// DroppedFeedback is the number of feedback samples discarded because
+	// the adjuster fell behind
+	DroppedFeedback int64
+
+	// SanitizedFeedback is the number of feedback samples that had
+	// out-of-range values repaired, or were discarded as NaN or infinite
+	SanitizedFeedback int64
+
+	// NilFeedbacks is the number of batches handled without feedback
+	// or error, and NilFeedbackPolicy how they were treated
+	NilFeedbacks      int64
+	NilFeedbackPolicy string
+
+	// Pauses is the number of pauses requested by the backend through
+	// Unhealthy or RetryAfter, and PausedUntil the end of the current
+	// one, zero if flushing is not paused
+	Pauses      int64
+	PausedUntil time.Time
+
+	// HealthCheckFailures is the number of failed Config.HealthFunc
+	// probes, each of which paused flushing
+	HealthCheckFailures int64
+
+	// MemoryPressure is the last sampled memory pressure (0.0 to 1.0),
+	// if Config.MemoryPressure is set
+	MemoryPressure float64
+
+	// StrategyEstimates holds the internal estimates of the adjustment
+	// strategy, if it implements EstimateReporter
+	StrategyEstimates map[string]float64
+
+	// Shadow holds the evaluation of Config.ShadowStrategy, if set
+	Shadow *ShadowStats
+
+	// FlushReasons counts the batches handled by the reason they were
+	// flushed for, keyed by FlushReason.String
+	FlushReasons map[string]int64
+
+	// Tenants holds the statistics of every tenant seen, if
+	// Config.TenantFunc is set
+	Tenants map[string]TenantStats
 }
 
 // --- Internal methods ---
 
-func (b *Batcher) processBatch(ctx context.Context, batch []any) error {
-	feedback, err := b.cfg.HandlerFunc(ctx, batch)
+// close is the first call of Close
+func (b *Batcher) close(ctx context.Context) error {
+	if b.cfg.CloseGrace > 0 {
+		t := time.NewTimer(b.cfg.CloseGrace)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+		}
+	}
+
+	b.mu.Lock()
+	b.closed = true
+	b.wakeBytesWaitersLocked()
+	b.mu.Unlock()
+
+	stop := context.AfterFunc(ctx, b.cancelBg)
+	defer stop()
+
+	// Stop adjustment goroutine
+	close(b.stopAdjust)
+	b.adjustTicker.Stop()
+	b.wg.Wait()
 
-	// Store feedback for batch size adjustment
+	// Wait out a backend pause as Flush does. Nothing can flush the
+	// buffer once Close returns, so if ctx is done first the remaining
+	// items are dead-lettered rather than left behind.
+	err := b.waitForResume(ctx)
+	if err == nil {
+		err = b.flushNow(ctx, FlushReasonClose)
+	} else {
+		b.abandonBuffered(ctx, err)
+	}
+	if b.cfg.Pipelined {
+		b.stopPipeline()
+	}
+	if b.otel != nil {
+		if uerr := b.otel.close(); uerr != nil {
+			err = errors.Join(err, uerr)
+		}
+	}
+	return err
+}
+
+func (b *Batcher) processBatch(ctx context.Context, f *flight) error {
+	if f.gate != nil {
+		<-f.gate
+	}
+	if f.id == "" {
+		f.id = b.cfg.IDFunc()
+	}
+	batch, meta := b.buildBatch(f.items, f.reason)
+	meta.BatchID = f.id
+	feedback, start, err := b.invoke(ctx, batch, meta)
+	elapsed := time.Since(start)
+	done := start.Add(elapsed)
+	sojourn := b.sojourn.record(done, f.items)
+	b.batches.Add(1)
+	b.flushReasons[f.reason].Add(1)
+	b.handlerTime.Add(int64(elapsed))
+	loadErr := b.loadError(err)
+	if err != nil {
+		b.handlerErrors.Add(1)
+		if loadErr != nil {
+			b.overloadErrors.Add(1)
+		}
+	}
+	if b.otel != nil {
+		b.otel.recordBatch(ctx, len(batch), elapsed, err)
+		b.otel.recordSojourn(ctx, done, f.items)
+	}
+	if b.shouldBisect(err) {
+		b.bisect(ctx, b.sortPending(f.items), f.reason, err)
+		err = nil
+	} else if errors.Is(err, ErrHandlerPanic) {
+		b.deadLetter(ctx, f.items, DropReasonPanic, err)
+	}
+
+	sample, record := b.feedbackSample(feedback, len(batch), elapsed, sojourn, loadErr)
+
+	b.mu.Lock()
+	b.recordHandlerTimeLocked(elapsed)
+	b.inFlightItems -= len(f.items)
+	b.inFlight.Store(int64(b.inFlightItems))
+	delete(b.flights, f)
+	released := f.items
+	var exhausted []pendingItem
+	failed := err != nil && !errors.Is(err, ErrHandlerPanic)
+	if b.cfg.RequeuePolicy == RequeueFront && errors.Is(err, ErrRetryable) {
+		released = b.requeueLocked(f.items, start)
+		exhausted = released
+		failed = false
+	}
+	b.releaseBytesLocked(released)
+	f.err = err
+	close(f.done)
+	b.mu.Unlock()
+	b.releaseTenants(released)
+	b.deadLetter(ctx, exhausted, DropReasonRequeue, requeueError(exhausted, err))
+	if failed {
+		for _, p := range released {
+			p.reply(ItemResult{Err: err})
+		}
+	}
+
+	// Hand feedback to the adjuster for batch size adjustment
+	if record {
+		sample.RecordedAt = b.now()
+		b.publishFeedback(sample)
+	}
+
+	if b.cfg.OnBatch != nil {
+		b.cfg.OnBatch(newBatchResult(f, start, elapsed, feedback, err))
+	}
+	return err
+}
+
+// invoke calls the handler with a batch once the batcher is initialised
+// and the Coordinator, if any, granted a slot, which it releases after.
+// It returns when the handler was called.
+func (b *Batcher) invoke(ctx context.Context, batch []any, meta BatchMeta) (feedback *LoadFeedback, start time.Time, err error) {
+	err = b.ensureInit(ctx)
+	if err == nil && b.cfg.Coordinator != nil {
+		if err = b.cfg.Coordinator.acquire(ctx); err == nil {
+			defer b.cfg.Coordinator.release()
+		}
+	}
+	start = time.Now()
+	if err == nil {
+		b.withLabels(ctx, roleHandler, func(ctx context.Context) {
+			feedback, err = b.callHandler(ctx, batch, meta)
+		})
+	}
+	return feedback, start, err
+}
+
+// feedbackSample turns the outcome of a handler call into the sample to
+// hand to the adjuster, if any, and applies a pause the backend asked for
+func (b *Batcher) feedbackSample(feedback *LoadFeedback, size int, elapsed, sojourn time.Duration, loadErr error) (sample FeedbackSample, record bool) {
 	if feedback != nil {
-		b.mu.Lock()
-		b.recordFeedback(*feedback)
+		fb := *feedback
+		changed, usable := sanitizeFeedback(&fb)
+		if d := b.backendPause(&fb); d > 0 {
+			b.pauseFor(d)
+		}
+		if changed {
+			b.sanitizedFeedback.Add(1)
+		}
+		if usable {
+			sample = FeedbackSample{Feedback: fb, BatchSize: size, Score: b.score(&fb)}
+			record = true
+		}
+	} else if b.cfg.SynthesizeFeedback {
+		sample, record = b.synthesizeSample(size, elapsed, sojourn, loadErr), true
+	} else if loadErr == nil {
+		sample, record = b.nilFeedbackSample(size, elapsed, sojourn)
+	}
+
+	if record {
+		sample.Sojourn = sojourn
+		sample.Weight, record = b.sampleFeedback()
+	}
+	return sample, record
+}
+
+func (b *Batcher) buildBatch(pending []pendingItem, reason FlushReason) ([]any, BatchMeta) {
+	b.trackDeliveries(pending)
+	pending = b.sortPending(pending)
+	batch := make([]any, len(pending))
+	meta := BatchMeta{Attempt: attemptOf(pending), Reason: reason}
+	var seen map[string]struct{}
+	if len(b.cfg.ContextKeys) > 0 {
+		meta.ItemValues = make([][]any, len(pending))
+		meta.contextKeys = b.cfg.ContextKeys
+	}
+	if b.destinations > 0 {
+		meta.delivered = make([]deliveries, len(pending))
+	}
+
+	for i, p := range pending {
+		batch[i] = p.item
+		if !p.deadline.IsZero() && (meta.Deadline.IsZero() || p.deadline.Before(meta.Deadline)) {
+			meta.Deadline = p.deadline
+		}
+		if meta.ItemValues != nil {
+			meta.ItemValues[i] = p.values
+		}
+		if meta.delivered != nil {
+			meta.delivered[i] = p.delivered
+		}
+		if p.traceID != "" {
+			if seen == nil {
+				seen = make(map[string]struct{})
+			}
+			if _, ok := seen[p.traceID]; !ok {
+				seen[p.traceID] = struct{}{}
+				meta.TraceIDs = append(meta.TraceIDs, p.traceID)
+			}
+		}
+	}
+
+	return batch, meta
+}
+
+// sortPending returns the items in the order of Config.SortFunc. It sorts
+// a copy so that a requeued batch keeps its original order.
+func (b *Batcher) sortPending(pending []pendingItem) []pendingItem {
+	if b.cfg.SortFunc == nil {
+		return pending
+	}
+	pending = slices.Clone(pending)
+	slices.SortStableFunc(pending, func(x, y pendingItem) int {
+		return b.cfg.SortFunc(x.item, y.item)
+	})
+	return pending
+}
+
+// joinFlushLocked waits for the batch detached by the latest flush, if it
+// is still being handled, and returns its error. It must be called with
+// the lock held, which it releases.
+func (b *Batcher) joinFlushLocked(ctx context.Context) error {
+	f := b.lastFlush
+	if _, inFlight := b.flights[f]; f == nil || !inFlight {
 		b.mu.Unlock()
+		return nil
+	}
+	b.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// flush is Flush for the given reason
+func (b *Batcher) flush(ctx context.Context, reason FlushReason) error {
+	if err := b.waitForResume(ctx); err != nil {
+		return err
+	}
+	return b.flushNow(ctx, reason)
+}
+
+// flushNow is FlushNow for the given reason
+func (b *Batcher) flushNow(ctx context.Context, reason FlushReason) error {
+	b.mu.Lock()
+	if len(b.batch) == 0 {
+		return b.joinFlushLocked(ctx)
+	}
+
+	f := b.detachBatchLocked(reason)
+	b.lastFlush = f
+	b.stopTimerLocked()
+	b.mu.Unlock()
+
+	return b.processBatch(ctx, f)
+}
+
+// flushAndJoin is Flush without waiting for a pause
+func (b *Batcher) flushAndJoin(ctx context.Context, reason FlushReason) error {
+	b.mu.Lock()
+	own, waitFor := b.detachAllLocked(reason)
+	joined := own
+	if own != nil {
+		b.lastFlush = own
+	} else if _, inFlight := b.flights[b.lastFlush]; inFlight {
+		joined = b.lastFlush
 	}
+	b.mu.Unlock()
 
+	var err error
+	if own != nil {
+		err = b.processBatch(ctx, own)
+	}
+	for _, f := range waitFor {
+		select {
+		case <-f.done:
+			if f == joined {
+				err = f.err
+			}
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		}
+	}
 	return err
 }
 
-func (b *Batcher) recordFeedback(feedback LoadFeedback) {
-	b.recentFeedback = append(b.recentFeedback, feedback)
+// detachAllLocked detaches the current batch, if any, and returns it with
+// the batches already being handled
+func (b *Batcher) detachAllLocked(reason FlushReason) (own *flight, waitFor []*flight) {
+	waitFor = make([]*flight, 0, len(b.flights))
+	for f := range b.flights {
+		waitFor = append(waitFor, f)
+	}
+	if len(b.batch) > 0 {
+		own = b.detachBatchLocked(reason)
+		b.stopTimerLocked()
+	}
+	return own, waitFor
+}
+
+// handleAndWait handles own, if any, waits for the batches of waitFor and
+// returns their joined errors, or ctx.Err() in place of those still
+// outstanding when ctx is done
+func (b *Batcher) handleAndWait(ctx context.Context, own *flight, waitFor []*flight) error {
+	var errs []error
+	if own != nil {
+		if err := b.processBatch(ctx, own); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, f := range waitFor {
+		select {
+		case <-f.done:
+			if f.err != nil {
+				errs = append(errs, f.err)
+			}
+		case <-ctx.Done():
+			return errors.Join(append(errs, ctx.Err())...)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// publishFeedback queues a sample for the adjuster without blocking.
+// When the queue is full the sample is dropped; the window only keeps
+// the most recent samples anyway.
+func (b *Batcher) publishFeedback(s FeedbackSample) {
+	select {
+	case b.feedbackCh <- s:
+	default:
+		b.droppedFeedback.Add(1)
+		return
+	}
+	select {
+	case b.feedbackReady <- struct{}{}:
+	default:
+		// The adjuster is already due to drain
+	}
+}
+
+// drainFeedbackLocked moves queued samples into the feedback window
+func (b *Batcher) drainFeedbackLocked() {
+	drained := false
+	for {
+		select {
+		case s := <-b.feedbackCh:
+			b.appendFeedbackLocked(s)
+			drained = true
+		default:
+			// Evictions apply after the samples queued before them
+			if b.decayFeedbackLocked() || drained {
+				b.refreshLoadLocked()
+			}
+			return
+		}
+	}
+}
+
+func (b *Batcher) recordFeedback(feedback LoadFeedback, batchSize int, weight float64) {
+	b.appendFeedbackLocked(FeedbackSample{
+		Feedback:   feedback,
+		Score:      b.score(&feedback),
+		BatchSize:  batchSize,
+		RecordedAt: b.now(),
+		Weight:     weight,
+	})
+	b.refreshLoadLocked()
+}
+
+func (b *Batcher) appendFeedbackLocked(s FeedbackSample) {
+	b.recentFeedback = append(b.recentFeedback, s)
 	if len(b.recentFeedback) > b.maxFeedbackLen {
 		b.recentFeedback = b.recentFeedback[1:]
 	}
 }
 
+func (b *Batcher) averageLoadLocked() float64 {
+	if len(b.recentFeedback) == 0 {
+		return 0
+	}
+	return MeanAggregator(b.windowLocked())
+}
+
 func (b *Batcher) adjustBatchSizeLoop() {
 	defer b.wg.Done()
 
 	for {
 		select {
 		case <-b.adjustTicker.C:
-			b.adjustBatchSize()
+			if b.cfg.LoadCheckJitter > 0 {
+				b.resetAdjustTicker()
+			}
+			b.adjust(false)
+		case <-b.feedbackReady:
+			b.mu.Lock()
+			b.drainFeedbackLocked()
+			b.mu.Unlock()
 		case <-b.stopAdjust:
 			return
 		}
 	}
 }
 
-func (b *Batcher) adjustBatchSize() {
+// adjustBatchSize runs an adjustment cycle and returns the change of the
+// batch size, if there was one
+func (b *Batcher) adjustBatchSize() (AdjustEvent, bool) {
+	// Sample memory outside the lock; runtime/metrics reads are not free,
+	// and the sampler serialises concurrent cycles itself
+	var heap uint64
+	var pause time.Duration
+	if b.cfg.MemoryPressure != nil {
+		heap, pause = b.readMemory()
+	}
+
 	b.mu.Lock()
 	defer b.mu.Unlock()
+	defer b.refreshLoadLocked()
 
-	if len(b.recentFeedback) == 0 {
-		return
+	b.drainFeedbackLocked()
+
+	if mp := b.cfg.MemoryPressure; mp != nil {
+		b.memoryPressure = mp.memoryPressure(heap, pause)
 	}
 
-	// Calculate average load score
-	avgLoad := 0.0
-	for _, f := range b.recentFeedback {
-		avgLoad += f.LoadScore()
+	// A batch outlasting the interval leaves nothing new to act on, but
+	// the backend is as saturated as its latest feedback said
+	if b.staleWindowLocked() && !b.underMemoryPressureLocked() {
+		b.skippedAdjustments.Add(1)
+		b.trackSaturationLocked(b.aggregateLoadLocked())
+		return AdjustEvent{}, false
 	}
-	avgLoad /= float64(len(b.recentFeedback))
 
-	// Adjust batch size based on load
-	// Low load (< 0.25) -> increase batch size
-	// Medium load (0.25 - 0.55) -> keep current size
-	// High load (> 0.55) -> decrease batch size
+	now := b.now()
+	in := AdjustmentInput{
+		CurrentBatchSize: b.currentBatchSize,
+		MinBatchSize:     b.cfg.MinBatchSize,
+		MaxBatchSize:     b.cfg.MaxBatchSize,
+		AdjustmentFactor: b.cfg.AdjustmentFactor,
+		GrowFactor:       b.cfg.GrowFactor,
+		ShrinkFactor:     b.cfg.ShrinkFactor,
+		LoadScore:        b.aggregateLoadLocked(),
+		Samples:          b.recentFeedback,
+		ItemsAdded:       b.itemsAdded,
+		Elapsed:          now.Sub(b.lastAdjust),
+		CycleStart:       b.lastAdjust,
+	}
+	b.itemsAdded = 0
+	b.lastAdjust = now
+
+	// High memory pressure counts as overload regardless of feedback
+	underPressure := b.underMemoryPressureLocked()
+	if underPressure {
+		in.LoadScore = math.Max(in.LoadScore, b.memoryPressure)
+	}
+	b.trackSaturationLocked(in.LoadScore)
+
+	if !b.adaptive.Load() {
+		return AdjustEvent{}, false
+	}
 
 	newSize := b.currentBatchSize
+	if len(b.recentFeedback) > 0 || underPressure {
+		newSize = b.decideLocked(b.cfg.AdjustmentStrategy, in)
+		if b.cfg.ShadowStrategy != nil {
+			b.shadowLocked(in, newSize, now)
+		}
+	}
+
+	// A degraded batcher probes for recovery with the smallest batches
+	if b.degraded.Load() {
+		newSize = b.cfg.MinBatchSize
+	}
+
+	event := AdjustEvent{Previous: b.currentBatchSize, Current: newSize, LoadScore: in.LoadScore, At: now}
+	b.setBatchSizeLocked(newSize)
+	return event, event.Current != event.Previous
+}
+
+// decideLocked returns the batch size the strategy decides on for in,
+// after the suggestion of the backend, the error spike rule and the
+// bounds are applied
+func (b *Batcher) decideLocked(strategy AdjustmentStrategy, in AdjustmentInput) int {
+	newSize := b.applySuggestionLocked(strategy.NextBatchSize(in))
 
-	if avgLoad < 0.25 {
-		// Backend is idle, increase batch size
-		increase := float64(b.currentBatchSize) * b.cfg.AdjustmentFactor
-		newSize = b.currentBatchSize + int(math.Max(increase, 1))
-	} else if avgLoad > 0.55 {
-		// Backend is overloaded, decrease batch size
-		decrease := float64(b.currentBatchSize) * b.cfg.AdjustmentFactor
-		newSize = b.currentBatchSize - int(math.Max(decrease, 1))
+	// An error spike shrinks multiplicatively whatever the strategy decided
+	if t := b.cfg.ErrorSpikeThreshold; t > 0 && recentErrorRate(in) > t {
+		newSize = min(newSize, int(float64(in.CurrentBatchSize)*b.cfg.ErrorSpikeFactor))
 	}
 
 	// Clamp to min/max
@@ -332,17 +1895,39 @@ func (b *Batcher) adjustBatchSize() {
 	if newSize > b.cfg.MaxBatchSize {
 		newSize = b.cfg.MaxBatchSize
 	}
-
-	b.currentBatchSize = newSize
+	return newSize
 }
 
-func (b *Batcher) detachBatchLocked() []any {
+func (b *Batcher) detachBatchLocked(reason FlushReason) *flight {
 	if len(b.batch) == 0 {
 		return nil
 	}
-	batch := b.batch
-	b.batch = make([]any, 0, b.currentBatchSize)
-	return batch
+	f := &flight{items: b.batch, done: make(chan struct{}), gate: b.barrier, reason: reason}
+	b.avgFill += fillSmoothing * (float64(len(f.items)) - b.avgFill)
+	b.batch = make([]pendingItem, 0, b.nextBatchCapLocked())
+	b.setPendingLocked()
+	b.earliestDeadline = time.Time{}
+	b.inFlightItems += len(f.items)
+	b.inFlight.Store(int64(b.inFlightItems))
+	b.flights[f] = struct{}{}
+	return f
+}
+
+const (
+	// fillSmoothing is the weight of the latest batch in the average fill
+	fillSmoothing = 0.2
+
+	// maxPrealloc bounds the buffer allocated up front; larger batches
+	// grow by append
+	maxPrealloc = 4096
+)
+
+// nextBatchCapLocked sizes the next batch buffer by the observed average
+// fill rather than the batch size, so that timeout-driven batches which
+// rarely fill up do not allocate for items that never arrive
+func (b *Batcher) nextBatchCapLocked() int {
+	n := math.Min(math.Ceil(b.avgFill*1.25), maxPrealloc)
+	return max(1, min(int(n), b.currentBatchSize))
 }
 
 func (b *Batcher) stopTimerLocked() {
@@ -353,8 +1938,22 @@ func (b *Batcher) stopTimerLocked() {
 }
 
 func (b *Batcher) startTimerLocked() {
-	timeout := b.cfg.Timeout
-	b.timer = time.AfterFunc(timeout, func() {
-		_ = b.Flush(context.Background())
+	b.scheduleFlushLocked(b.timeoutFlushAt(time.Now()))
+}
+
+// scheduleFlushLocked arms the flush timer to fire at the given time,
+// unless it is already armed to fire earlier
+func (b *Batcher) scheduleFlushLocked(at time.Time) {
+	if b.timer != nil {
+		if !at.Before(b.timerAt) {
+			return
+		}
+		b.timer.Stop()
+	}
+	b.timerAt = at
+	b.timer = time.AfterFunc(time.Until(at), func() {
+		b.withLabels(b.bgCtx, roleTimer, func(ctx context.Context) {
+			_ = b.timerFlush(ctx)
+		})
 	})
 }
@@ -2,6 +2,8 @@ package batcher
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -291,6 +293,322 @@ func TestBatcher_AdaptiveSizing(t *testing.T) {
 	}
 }
 
+func TestBatcher_FlushAndWait(t *testing.T) {
+	errSlow := errors.New("slow batch failed")
+	errOwn := errors.New("own batch failed")
+	release := make(chan struct{})
+
+	b, err := New(Config{
+		InitialBatchSize: 2,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			if len(batch) == 2 {
+				<-release
+				return nil, errSlow
+			}
+			return nil, errOwn
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	ctx := context.Background()
+	go func() {
+		b.Add(ctx, 1)
+		b.Add(ctx, 2)
+	}()
+	for b.GetStats().InFlightItems != 2 {
+		time.Sleep(time.Millisecond)
+	}
+	b.Add(ctx, 3)
+
+	done := make(chan error)
+	go func() { done <- b.FlushAndWait(ctx) }()
+
+	select {
+	case <-done:
+		t.Fatal("FlushAndWait() returned before in-flight batch completed")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	err = <-done
+	if !errors.Is(err, errSlow) || !errors.Is(err, errOwn) {
+		t.Errorf("Expected joined errors, got %v", err)
+	}
+}
+
+func TestBatcher_FlushAndWaitContext(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	b, err := New(Config{
+		InitialBatchSize: 1,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			<-release
+			return nil, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	go b.Add(context.Background(), 1)
+	for b.GetStats().InFlightItems != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := b.FlushAndWait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestBatcher_ConcurrentFlushCoalesces(t *testing.T) {
+	errFlush := errors.New("flush failed")
+	release := make(chan struct{})
+	var calls atomic.Int64
+
+	b, err := New(Config{
+		InitialBatchSize: 100,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			calls.Add(1)
+			<-release
+			return nil, errFlush
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	ctx := context.Background()
+	b.Add(ctx, 1)
+
+	const flushers = 5
+	errs := make(chan error, flushers)
+	for i := 0; i < flushers; i++ {
+		go func() { errs <- b.Flush(ctx) }()
+	}
+	for b.GetStats().InFlightItems != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	select {
+	case err := <-errs:
+		t.Fatalf("Flush() returned %v before the batch was handled", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	for i := 0; i < flushers; i++ {
+		if err := <-errs; !errors.Is(err, errFlush) {
+			t.Errorf("Expected every Flush to return the batch error, got %v", err)
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("Expected the batch handled once, got %d handler calls", n)
+	}
+
+	// Nothing in flight: an empty Flush returns right away
+	if err := b.Flush(ctx); err != nil {
+		t.Errorf("Expected nil from an empty Flush, got %v", err)
+	}
+}
+
+func TestBatcher_FlushWaitsForSizeFlush(t *testing.T) {
+	for _, pipelined := range []bool{false, true} {
+		t.Run(fmt.Sprintf("pipelined=%v", pipelined), func(t *testing.T) {
+			release := make(chan struct{})
+			var once sync.Once
+			unblock := func() { once.Do(func() { close(release) }) }
+			var handled atomic.Int64
+			b, err := New(Config{
+				InitialBatchSize: 2,
+				Pipelined:        pipelined,
+				HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+					<-release
+					handled.Add(int64(len(batch)))
+					return nil, nil
+				},
+			})
+			if err != nil {
+				t.Fatalf("New() failed: %v", err)
+			}
+			defer b.Close(context.Background())
+			defer unblock()
+
+			// A full batch is taken out of the buffer by Add, not by a flush
+			ctx := context.Background()
+			go func() {
+				b.Add(ctx, 1)
+				b.Add(ctx, 2)
+			}()
+			for b.GetStats().InFlightItems != 2 {
+				time.Sleep(time.Millisecond)
+			}
+
+			done := make(chan error, 1)
+			go func() { done <- b.Flush(ctx) }()
+			select {
+			case err := <-done:
+				t.Fatalf("Flush() returned %v while the full batch was being handled", err)
+			case <-time.After(20 * time.Millisecond):
+			}
+
+			unblock()
+			if err := <-done; err != nil {
+				t.Errorf("Expected nil from Flush, got %v", err)
+			}
+			if n := handled.Load(); n != 2 {
+				t.Errorf("Expected 2 items handled when Flush returned, got %d", n)
+			}
+		})
+	}
+}
+
+func TestBatcher_NoDuplicateFlushes(t *testing.T) {
+	var mu sync.Mutex
+	seen := make(map[int]int)
+
+	b, err := New(Config{
+		InitialBatchSize: 7,
+		Timeout:          time.Millisecond,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			mu.Lock()
+			for _, item := range batch {
+				seen[item.(int)]++
+			}
+			mu.Unlock()
+			return nil, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	const producers, perProducer = 8, 500
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < perProducer; i++ {
+				b.Add(ctx, p*perProducer+i)
+				if i%10 == 0 {
+					b.Flush(ctx)
+				}
+			}
+		}(p)
+	}
+	wg.Wait()
+	if err := b.Close(ctx); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(seen) != producers*perProducer {
+		t.Errorf("Expected %d items handled, got %d", producers*perProducer, len(seen))
+	}
+	for item, n := range seen {
+		if n != 1 {
+			t.Errorf("Expected item %d handled once, got %d", item, n)
+		}
+	}
+}
+
+func TestBatcher_RespectDeadlines(t *testing.T) {
+	var processed atomic.Int64
+
+	b, err := New(Config{
+		InitialBatchSize: 100,
+		Timeout:          time.Second,
+		RespectDeadlines: true,
+		DeadlineMargin:   20 * time.Millisecond,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			processed.Add(int64(len(batch)))
+			return nil, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	b.Add(context.Background(), 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 80*time.Millisecond)
+	defer cancel()
+	b.Add(ctx, 2)
+
+	time.Sleep(30 * time.Millisecond)
+	if processed.Load() != 0 {
+		t.Fatalf("Flushed too early: %d items", processed.Load())
+	}
+
+	// Flushed at deadline - margin, long before Timeout
+	time.Sleep(60 * time.Millisecond)
+	if processed.Load() != 2 {
+		t.Errorf("Expected deadline flush of 2 items, got %d", processed.Load())
+	}
+
+	// A deadline already inside the margin flushes immediately
+	ctx2, cancel2 := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel2()
+	b.Add(ctx2, 3)
+	if processed.Load() != 3 {
+		t.Errorf("Expected immediate flush, got %d items processed", processed.Load())
+	}
+}
+
+type traceKey struct{}
+
+func TestBatcher_TraceIDs(t *testing.T) {
+	var mu sync.Mutex
+	var metas []BatchMeta
+
+	b, err := New(Config{
+		InitialBatchSize: 4,
+		TraceIDFunc: func(ctx context.Context) string {
+			id, _ := ctx.Value(traceKey{}).(string)
+			return id
+		},
+		HandlerFuncV2: func(ctx context.Context, batch []any, meta BatchMeta) (*LoadFeedback, error) {
+			mu.Lock()
+			metas = append(metas, meta)
+			mu.Unlock()
+			return &LoadFeedback{CPULoad: 0.3}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	for _, id := range []string{"t1", "t2", "t1", ""} {
+		ctx := context.Background()
+		if id != "" {
+			ctx = context.WithValue(ctx, traceKey{}, id)
+		}
+		b.Add(ctx, id)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(metas) != 1 {
+		t.Fatalf("Expected 1 batch, got %d", len(metas))
+	}
+	got := metas[0].TraceIDs
+	if len(got) != 2 || got[0] != "t1" || got[1] != "t2" {
+		t.Errorf("TraceIDs = %v, want [t1 t2]", got)
+	}
+}
+
 func TestLoadFeedback_LoadScore(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -380,3 +698,193 @@ func BenchmarkBatcher_Concurrent(b *testing.B) {
 		}
 	})
 }
+
+func TestBatcher_FeedbackDoesNotBlockOnLock(t *testing.T) {
+	b, err := New(Config{
+		InitialBatchSize: 1,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			return &LoadFeedback{CPULoad: 0.5}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	// Hold the main lock as a busy producer would
+	b.mu.Lock()
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 100; i++ {
+			b.publishFeedback(FeedbackSample{Feedback: LoadFeedback{CPULoad: 0.5}, BatchSize: 1})
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("publishFeedback blocked on the main lock")
+	}
+	b.mu.Unlock()
+
+	stats := b.GetStats()
+	if stats.RecentFeedbackSize != 10 {
+		t.Errorf("Expected a full feedback window of 10, got %d", stats.RecentFeedbackSize)
+	}
+	if stats.DroppedFeedback == 0 {
+		t.Error("Expected overflowing feedback to be dropped")
+	}
+}
+
+func TestBatcher_AllocationFollowsFill(t *testing.T) {
+	b, err := New(Config{
+		InitialBatchSize: 500,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			return nil, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	// Timeout-style flushes that carry only a few items each
+	ctx := context.Background()
+	for i := 0; i < 30; i++ {
+		for j := 0; j < 4; j++ {
+			b.Add(ctx, j)
+		}
+		b.Flush(ctx)
+	}
+
+	b.mu.Lock()
+	capacity := cap(b.batch)
+	b.mu.Unlock()
+	if capacity > 10 {
+		t.Errorf("Expected buffer capacity near the average fill of 4, got %d", capacity)
+	}
+
+	// Full batches grow the buffer back toward the batch size
+	for i := 0; i < 30*500; i++ {
+		b.Add(ctx, i)
+	}
+	b.mu.Lock()
+	capacity = cap(b.batch)
+	b.mu.Unlock()
+	if capacity < 400 {
+		t.Errorf("Expected buffer capacity near the batch size of 500, got %d", capacity)
+	}
+}
+
+func TestBatcher_GetStatsDoesNotBlock(t *testing.T) {
+	b, err := New(Config{
+		InitialBatchSize: 10,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			return nil, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	for i := 0; i < 3; i++ {
+		b.Add(context.Background(), i)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	done := make(chan Stats)
+	go func() {
+		_ = b.GetCurrentBatchSize()
+		done <- b.GetStats()
+	}()
+
+	select {
+	case stats := <-done:
+		if stats.PendingItems != 3 || stats.CurrentBatchSize != 10 {
+			t.Errorf("Unexpected stats: %+v", stats)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("GetStats blocked on the main lock")
+	}
+}
+
+func TestBatcher_SortFunc(t *testing.T) {
+	type row struct {
+		key   int
+		order int
+	}
+	var got []any
+	b, err := New(Config{
+		InitialBatchSize: 5,
+		SortFunc: func(a, b any) int {
+			return a.(row).key - b.(row).key
+		},
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			got = batch
+			return nil, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	for i, key := range []int{3, 1, 2, 1, 3} {
+		b.Add(context.Background(), row{key: key, order: i})
+	}
+
+	want := []row{{1, 1}, {1, 3}, {2, 2}, {3, 0}, {3, 4}}
+	if len(got) != len(want) {
+		t.Fatalf("Expected a batch of %d, got %v", len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Expected %v sorted stably by key, got %v", want, got)
+			break
+		}
+	}
+}
+
+func TestBatcher_ValidateFunc(t *testing.T) {
+	errNegative := errors.New("negative")
+	var handled []any
+	b, err := New(Config{
+		InitialBatchSize: 2,
+		ValidateFunc: func(item any) error {
+			if item.(int) < 0 {
+				return errNegative
+			}
+			return nil
+		},
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			handled = append(handled, batch...)
+			return nil, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	for _, item := range []int{1, -1, 2} {
+		err := b.Add(context.Background(), item)
+		if item < 0 {
+			if !errors.Is(err, ErrInvalidItem) || !errors.Is(err, errNegative) {
+				t.Errorf("Expected ErrInvalidItem wrapping the validation error, got %v", err)
+			}
+		} else if err != nil {
+			t.Errorf("Add(%d) failed: %v", item, err)
+		}
+	}
+
+	if fmt.Sprint(handled) != "[1 2]" {
+		t.Errorf("Expected only valid items to be batched, got %v", handled)
+	}
+	if stats := b.GetStats(); stats.InvalidItems != 1 {
+		t.Errorf("Expected 1 invalid item, got %d", stats.InvalidItems)
+	}
+}
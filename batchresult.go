@@ -0,0 +1,113 @@
+package batcher
+
+import "time"
+
+// FlushReason is why a batch was flushed
+type FlushReason int
+
+const (
+	// FlushReasonSize is a batch that reached the batch size
+	FlushReasonSize FlushReason = iota
+
+	// FlushReasonTimer is a batch flushed by the flush timer: Timeout, an
+	// item deadline coming due, or the end of a pause
+	FlushReasonTimer
+
+	// FlushReasonDeadline is a batch flushed by Add because the deadline
+	// of the item added was due
+	FlushReasonDeadline
+
+	// FlushReasonManual is a batch flushed by Flush, FlushNow,
+	// FlushAndWait or Barrier
+	FlushReasonManual
+
+	// FlushReasonClose is the last batch, flushed by Close
+	FlushReasonClose
+
+	// FlushReasonAge is a batch flushed because its oldest item reached
+	// Config.MaxItemAge
+	FlushReasonAge
+
+	numFlushReasons = iota
+)
+
+// String returns the string representation of FlushReason
+func (r FlushReason) String() string {
+	switch r {
+	case FlushReasonSize:
+		return "size"
+	case FlushReasonTimer:
+		return "timer"
+	case FlushReasonDeadline:
+		return "deadline"
+	case FlushReasonManual:
+		return "manual"
+	case FlushReasonClose:
+		return "close"
+	case FlushReasonAge:
+		return "age"
+	default:
+		return "unknown"
+	}
+}
+
+// BatchResult is the record of one handled batch, see Config.OnBatch
+type BatchResult struct {
+	// BatchID is the ID the batch was handled with, see BatchMeta
+	BatchID string
+
+	// Size is the number of items in the batch
+	Size int
+
+	// Reason is why the batch was flushed
+	Reason FlushReason
+
+	// StartedAt is when the handler was called, and Duration how long
+	// it took
+	StartedAt time.Time
+	Duration  time.Duration
+
+	// Feedback is the feedback the handler returned, nil if none
+	Feedback *LoadFeedback
+
+	// Err is the error of the batch, nil if it succeeded, or if it failed
+	// but was recovered by BisectFailures
+	Err error
+
+	// Attempt is the attempt number of the batch, see BatchMeta.Attempt
+	Attempt int
+}
+
+// --- Internal methods ---
+
+func newBatchResult(f *flight, start time.Time, elapsed time.Duration, fb *LoadFeedback, err error) BatchResult {
+	return BatchResult{
+		BatchID:   f.id,
+		Size:      len(f.items),
+		Reason:    f.reason,
+		StartedAt: start,
+		Duration:  elapsed,
+		Feedback:  fb,
+		Err:       err,
+		Attempt:   attemptOf(f.items),
+	}
+}
+
+// flushReasonStats returns the number of batches handled by reason
+func (b *Batcher) flushReasonStats() map[string]int64 {
+	counts := make(map[string]int64, numFlushReasons)
+	for r := range b.flushReasons {
+		counts[FlushReason(r).String()] = b.flushReasons[r].Load()
+	}
+	return counts
+}
+
+// attemptOf returns the attempt number of a batch of items, see
+// BatchMeta.Attempt
+func attemptOf(items []pendingItem) int {
+	requeues := 0
+	for _, p := range items {
+		requeues = max(requeues, p.requeues)
+	}
+	return requeues + 1
+}
@@ -0,0 +1,130 @@
+package batcher
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBatcher_OnBatch(t *testing.T) {
+	var mu sync.Mutex
+	var results []BatchResult
+	boom := errors.New("boom")
+
+	b, err := New(Config{
+		InitialBatchSize: 2,
+		Timeout:          10 * time.Millisecond,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			if batch[0] == "fail" {
+				return nil, boom
+			}
+			return &LoadFeedback{CPULoad: 0.5}, nil
+		},
+		OnBatch: func(r BatchResult) {
+			mu.Lock()
+			defer mu.Unlock()
+			results = append(results, r)
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	ctx := context.Background()
+
+	b.Add(ctx, 1)
+	b.Add(ctx, 2) // size
+	b.Add(ctx, "fail")
+	b.Flush(ctx) // manual
+	b.Add(ctx, 3)
+	time.Sleep(50 * time.Millisecond) // timer
+	b.Add(ctx, 4)
+	b.Close(ctx) // close
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []struct {
+		reason FlushReason
+		size   int
+		err    error
+	}{
+		{FlushReasonSize, 2, nil},
+		{FlushReasonManual, 1, boom},
+		{FlushReasonTimer, 1, nil},
+		{FlushReasonClose, 1, nil},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("Expected %d results, got %d", len(want), len(results))
+	}
+	for i, w := range want {
+		r := results[i]
+		if r.Reason != w.reason || r.Size != w.size || !errors.Is(r.Err, w.err) {
+			t.Errorf("Expected result %d to be %v of %d with %v, got %v of %d with %v",
+				i, w.reason, w.size, w.err, r.Reason, r.Size, r.Err)
+		}
+		if r.BatchID == "" || r.StartedAt.IsZero() || r.Attempt != 1 {
+			t.Errorf("Expected result %d to have an ID, a start and attempt 1, got %+v", i, r)
+		}
+	}
+	if fb := results[0].Feedback; fb == nil || fb.CPULoad != 0.5 {
+		t.Errorf("Expected the feedback of the batch, got %+v", fb)
+	}
+
+	reasons := b.GetStats().FlushReasons
+	for _, r := range []string{"size", "manual", "timer", "close"} {
+		if reasons[r] != 1 {
+			t.Errorf("Expected 1 batch flushed for %s, got %d", r, reasons[r])
+		}
+	}
+}
+
+func TestBatcher_OnBatchAttempt(t *testing.T) {
+	var mu sync.Mutex
+	var attempts []int
+	calls := 0
+
+	b, err := New(Config{
+		InitialBatchSize: 2,
+		RequeuePolicy:    RequeueFront,
+		MaxRequeues:      3,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			if calls++; calls < 3 {
+				return nil, ErrRetryable
+			}
+			return &LoadFeedback{}, nil
+		},
+		OnBatch: func(r BatchResult) {
+			mu.Lock()
+			defer mu.Unlock()
+			attempts = append(attempts, r.Attempt)
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	ctx := context.Background()
+	b.Add(ctx, 1)
+	b.Add(ctx, 2)
+	b.Flush(ctx)
+	b.Flush(ctx)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(attempts) != 3 || attempts[0] != 1 || attempts[1] != 2 || attempts[2] != 3 {
+		t.Errorf("Expected attempts 1, 2, 3, got %v", attempts)
+	}
+}
+
+func TestFlushReason_String(t *testing.T) {
+	for r := FlushReasonSize; r < numFlushReasons; r++ {
+		if r.String() == "unknown" {
+			t.Errorf("Expected a name for flush reason %d", r)
+		}
+	}
+	if s := FlushReason(-1).String(); s != "unknown" {
+		t.Errorf("Expected unknown, got %s", s)
+	}
+}
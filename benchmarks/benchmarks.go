@@ -0,0 +1,116 @@
+// Package benchmarks compares load-aware batching against fixed-size
+// batching on the simulated backend, across its load patterns. Every
+// benchmark reports throughput, p99 item latency and error rate in the
+// standard benchmark format, so runs can be compared with benchstat:
+//
+//	go test -run '^$' -bench . -count 6 ./benchmarks > new.txt
+//	benchstat old.txt new.txt
+//
+// Backends are seeded, so runs differ only by scheduling noise.
+package benchmarks
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/amirafroozeh1/Load-Aware-Batcher"
+	"github.com/amirafroozeh1/Load-Aware-Batcher/simulator"
+)
+
+// seed seeds every simulated backend
+const seed = 42
+
+// patterns are the load patterns the strategies are compared on
+var patterns = []simulator.LoadPattern{
+	simulator.PatternConstant,
+	simulator.PatternSpikes,
+	simulator.PatternSineWave,
+	simulator.PatternGradual,
+	simulator.PatternDiurnal,
+}
+
+// strategy is a way of sizing batches under comparison
+type strategy struct {
+	name   string
+	config func() batcher.Config
+}
+
+// strategies are adaptive batching and fixed-size baselines at a small
+// and a large batch size
+var strategies = []strategy{
+	{"adaptive", func() batcher.Config {
+		return batcher.Config{
+			InitialBatchSize:  20,
+			MinBatchSize:      5,
+			MaxBatchSize:      200,
+			LoadCheckInterval: 100 * time.Millisecond,
+		}
+	}},
+	{"fixed-20", func() batcher.Config { return fixed(20) }},
+	{"fixed-100", func() batcher.Config { return fixed(100) }},
+}
+
+// fixed returns the configuration of a batcher that never resizes
+func fixed(size int) batcher.Config {
+	return batcher.Config{
+		InitialBatchSize: size,
+		MinBatchSize:     size,
+		MaxBatchSize:     size,
+	}
+}
+
+// producers is the number of goroutines adding items
+const producers = 4
+
+// result is what one run measured
+type result struct {
+	elapsed   time.Duration
+	items     int
+	p99       time.Duration
+	errorRate float64
+}
+
+// run adds n items through a batcher configured by cfg to a seeded
+// backend with the given load pattern, and measures the outcome
+func run(cfg batcher.Config, pattern simulator.LoadPattern, n int) (result, error) {
+	backend := simulator.NewSeededBackend(pattern, seed)
+	backend.SetDiurnal(simulator.DiurnalConfig{Day: 2 * time.Second})
+	cfg.HandlerFunc = backend.ProcessBatch
+	cfg.Timeout = 50 * time.Millisecond
+
+	b, err := batcher.New(cfg)
+	if err != nil {
+		return result{}, err
+	}
+
+	ctx := context.Background()
+	start := time.Now()
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := next.Add(1); i <= int64(n); i = next.Add(1) {
+				b.Add(ctx, i)
+			}
+		}()
+	}
+	wg.Wait()
+	if err := b.Close(ctx); err != nil {
+		return result{}, err
+	}
+
+	stats, bs := b.GetStats(), backend.GetStats()
+	r := result{
+		elapsed: time.Since(start),
+		items:   n,
+		p99:     stats.SojournP99,
+	}
+	if total := bs.TotalProcessed + bs.TotalErrors; total > 0 {
+		r.errorRate = float64(bs.TotalErrors) / float64(total)
+	}
+	return r, nil
+}
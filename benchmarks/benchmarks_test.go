@@ -0,0 +1,24 @@
+package benchmarks
+
+import (
+	"testing"
+)
+
+// BenchmarkBatching runs every strategy against every load pattern. One
+// op is one item; besides ns/op it reports items/s, the p99 latency of
+// items from Add to handler completion in ms, and the error rate in %.
+func BenchmarkBatching(b *testing.B) {
+	for _, pattern := range patterns {
+		for _, s := range strategies {
+			b.Run("pattern="+pattern.String()+"/strategy="+s.name, func(b *testing.B) {
+				r, err := run(s.config(), pattern, b.N)
+				if err != nil {
+					b.Fatalf("run failed: %v", err)
+				}
+				b.ReportMetric(float64(r.items)/r.elapsed.Seconds(), "items/s")
+				b.ReportMetric(float64(r.p99.Microseconds())/1000, "p99-ms")
+				b.ReportMetric(100*r.errorRate, "err-%")
+			})
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package batcher
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// --- Internal methods ---
+
+// shouldBisect reports whether a failed batch is bisected rather than
+// failed as a whole: BisectFailures is set, the items are not requeued,
+// and the error is not classified as overload, which no item is to
+// blame for
+func (b *Batcher) shouldBisect(err error) bool {
+	if !b.cfg.BisectFailures || err == nil {
+		return false
+	}
+	if b.cfg.RequeuePolicy == RequeueFront && errors.Is(err, ErrRetryable) {
+		return false
+	}
+	return b.cfg.ClassifyError == nil || b.cfg.ClassifyError(err) != ErrorClassOverload
+}
+
+// bisect isolates the items a batch failed on by handling each half of
+// it on its own, recursing into the halves that fail again. Items that
+// fail on their own are quarantined: dead-lettered with
+// DropReasonPoison and the error they failed with. The halves are handled
+// like any batch, within the Coordinator's limit, and their feedback
+// reaches the adjuster.
+func (b *Batcher) bisect(ctx context.Context, items []pendingItem, reason FlushReason, err error) {
+	if len(items) == 1 {
+		b.quarantined.Add(1)
+		b.deadLetter(ctx, items, DropReasonPoison, err)
+		return
+	}
+
+	mid := len(items) / 2
+	for _, half := range [][]pendingItem{items[:mid], items[mid:]} {
+		batch, meta := b.buildBatch(half, reason)
+		meta.BatchID = b.cfg.IDFunc()
+		b.bisections.Add(1)
+		feedback, start, herr := b.invoke(ctx, batch, meta)
+		elapsed := time.Since(start)
+
+		var sojourn time.Duration
+		for _, p := range half {
+			sojourn = max(sojourn, start.Add(elapsed).Sub(p.added))
+		}
+		if sample, record := b.feedbackSample(feedback, len(batch), elapsed, sojourn, b.loadError(herr)); record {
+			sample.RecordedAt = b.now()
+			b.publishFeedback(sample)
+		}
+
+		if herr != nil {
+			b.bisect(ctx, half, reason, herr)
+		}
+	}
+}
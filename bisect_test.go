@@ -0,0 +1,145 @@
+package batcher
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestBatcher_BisectFailures(t *testing.T) {
+	errPoison := errors.New("poison")
+	var mu sync.Mutex
+	var delivered, quarantined []any
+	var reasons []DropReason
+
+	b, err := New(Config{
+		InitialBatchSize: 8,
+		BisectFailures:   true,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			if slices.Contains(batch, any(5)) {
+				return nil, errPoison
+			}
+			mu.Lock()
+			delivered = append(delivered, batch...)
+			mu.Unlock()
+			return &LoadFeedback{CPULoad: 0.5}, nil
+		},
+		DeadLetterFunc: func(ctx context.Context, items []any, err error) {
+			if !errors.Is(err, errPoison) {
+				t.Errorf("Expected poison error, got %v", err)
+			}
+			quarantined = append(quarantined, items...)
+		},
+		OnItemDropped: func(item any, reason DropReason) {
+			reasons = append(reasons, reason)
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	ctx := context.Background()
+	for i := 0; i < 7; i++ {
+		b.Add(ctx, i)
+	}
+	if err := b.Flush(ctx); err != nil {
+		t.Errorf("Expected bisected flush to succeed, got %v", err)
+	}
+
+	slices.SortFunc(delivered, func(x, y any) int { return x.(int) - y.(int) })
+	if !slices.Equal(delivered, []any{0, 1, 2, 3, 4, 6}) {
+		t.Errorf("Expected healthy items delivered, got %v", delivered)
+	}
+	if !slices.Equal(quarantined, []any{5}) {
+		t.Errorf("Expected item 5 quarantined, got %v", quarantined)
+	}
+	if !slices.Equal(reasons, []DropReason{DropReasonPoison}) {
+		t.Errorf("Expected DropReasonPoison, got %v", reasons)
+	}
+
+	stats := b.GetStats()
+	if stats.QuarantinedItems != 1 {
+		t.Errorf("Expected 1 quarantined item, got %d", stats.QuarantinedItems)
+	}
+	// 7 items split into 3+4, then 4 into 2+2, then 2 into 1+1
+	if stats.Bisections != 6 {
+		t.Errorf("Expected 6 bisected batches, got %d", stats.Bisections)
+	}
+}
+
+func TestBatcher_BisectSkipsOverload(t *testing.T) {
+	errOverload := errors.New("overloaded")
+	calls := 0
+
+	b, err := New(Config{
+		InitialBatchSize: 4,
+		BisectFailures:   true,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			calls++
+			return nil, errOverload
+		},
+		ClassifyError: func(err error) ErrorClass {
+			return ErrorClassOverload
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		b.Add(ctx, i)
+	}
+	if err := b.Flush(ctx); !errors.Is(err, errOverload) {
+		t.Errorf("Expected overload error, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected 1 handler call, got %d", calls)
+	}
+	if stats := b.GetStats(); stats.Bisections != 0 || stats.QuarantinedItems != 0 {
+		t.Errorf("Expected no bisection, got %d bisections and %d quarantined", stats.Bisections, stats.QuarantinedItems)
+	}
+}
+
+func TestBatcher_BisectCoordinatedWithFeedback(t *testing.T) {
+	errPoison := errors.New("poison")
+	coord := NewCoordinator(CoordinatorConfig{MaxConcurrent: 1})
+
+	b, err := New(Config{
+		InitialBatchSize: 8,
+		BisectFailures:   true,
+		Coordinator:      coord,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			if slices.Contains(batch, any(5)) {
+				return nil, errPoison
+			}
+			return &LoadFeedback{CPULoad: 0.5}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	ctx := context.Background()
+	for i := 0; i < 7; i++ {
+		b.Add(ctx, i)
+	}
+	if err := b.Flush(ctx); err != nil {
+		t.Errorf("Expected bisected flush to succeed, got %v", err)
+	}
+
+	// The batch and its 6 halves each took a token, and the 3 halves
+	// that succeeded reported their feedback
+	if stats := coord.GetStats(); stats.Admitted != 7 || stats.InFlight != 0 {
+		t.Errorf("Expected 7 admissions and no token held, got %+v", stats)
+	}
+	b.AdjustNow()
+	if n := b.GetStats().RecentFeedbackSize; n != 3 {
+		t.Errorf("Expected feedback of the 3 healthy halves, got %d samples", n)
+	}
+}
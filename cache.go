@@ -0,0 +1,128 @@
+package batcher
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// ResultCache stores the values a Loader loaded by key. Implementations
+// must be safe for concurrent use and decide themselves when entries
+// expire, for example to share a cache such as Redis between replicas.
+type ResultCache interface {
+	// Get returns the value cached for key, if any
+	Get(key any) (any, bool)
+
+	// Set caches the value for key
+	Set(key, value any)
+
+	// Delete removes key from the cache
+	Delete(key any)
+}
+
+// CacheConfig configures the result cache of a Loader
+type CacheConfig struct {
+	// TTL is how long a value is served from the cache (default: 1m)
+	TTL time.Duration
+
+	// MaxEntries bounds the number of cached keys; the least recently
+	// used are evicted beyond it (default: 10000)
+	MaxEntries int
+
+	// Store, if set, replaces the built-in in-memory cache, which keys
+	// must be comparable for. TTL and MaxEntries are then up to it.
+	Store ResultCache
+}
+
+// store returns the configured cache, or an in-memory one
+func (c *CacheConfig) store() ResultCache {
+	if c.Store != nil {
+		return c.Store
+	}
+	ttl, size := c.TTL, c.MaxEntries
+	if ttl <= 0 {
+		ttl = time.Minute
+	}
+	if size <= 0 {
+		size = 10000
+	}
+	return newLRUCache(ttl, size, time.Now)
+}
+
+// lruCache is the built-in ResultCache, an LRU whose entries also expire
+// after a TTL
+type lruCache struct {
+	ttl  time.Duration
+	size int
+	now  func() time.Time
+
+	mu      sync.Mutex
+	entries map[any]*list.Element
+	order   *list.List // most recently used at the front
+}
+
+type cacheEntry struct {
+	key     any
+	value   any
+	expires time.Time
+}
+
+func newLRUCache(ttl time.Duration, size int, now func() time.Time) *lruCache {
+	return &lruCache{
+		ttl:     ttl,
+		size:    size,
+		now:     now,
+		entries: make(map[any]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// Get implements ResultCache
+func (c *lruCache) Get(key any) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*cacheEntry)
+	if !c.now().Before(e.expires) {
+		c.order.Remove(el)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return e.value, true
+}
+
+// Set implements ResultCache
+func (c *lruCache) Set(key, value any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires := c.now().Add(c.ttl)
+	if el, ok := c.entries[key]; ok {
+		e := el.Value.(*cacheEntry)
+		e.value, e.expires = value, expires
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, value: value, expires: expires})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// Delete implements ResultCache
+func (c *lruCache) Delete(key any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		c.order.Remove(el)
+		delete(c.entries, key)
+	}
+}
@@ -0,0 +1,44 @@
+package batcher
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLRUCache_TTL(t *testing.T) {
+	now := time.Unix(1000, 0)
+	c := newLRUCache(time.Minute, 10, func() time.Time { return now })
+
+	c.Set("a", 1)
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Errorf("Expected a cached 1, got %v, %v", v, ok)
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := c.Get("a"); ok {
+		t.Error("Expected the entry to expire after the TTL")
+	}
+}
+
+func TestLRUCache_Eviction(t *testing.T) {
+	c := newLRUCache(time.Minute, 2, time.Now)
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Get("a") // b is now the least recently used
+	c.Set("c", 3)
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("Expected the least recently used entry to be evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := c.Get(key); !ok {
+			t.Errorf("Expected %q to be cached", key)
+		}
+	}
+
+	c.Delete("a")
+	if _, ok := c.Get("a"); ok {
+		t.Error("Expected a deleted entry to be gone")
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/amirafroozeh1/Load-Aware-Batcher/simulator"
+)
+
+// runConfig is a demo scenario loaded with -config. Every setting has a
+// flag of the same meaning; flags given on the command line win over the
+// file, and settings left out keep the flag defaults.
+//
+//	batcher:
+//	  initialBatchSize: 30
+//	  minBatchSize: 5
+//	  maxBatchSize: 200
+//	  timeout: 1s
+//	  adjustmentFactor: 0.4
+//	  loadCheckInterval: 2s
+//	  strategy: aimd
+//	  fixedSize: false
+//	simulator:
+//	  pattern: sinewave       # or replay: trace.json, or a script:
+//	  script:
+//	    - {at: 0s, cpuLoad: 0.2}
+//	    - {at: 20s, cpuLoad: 0.9, errorRate: 0.1}
+//	workers: 8
+//	count: 5000               # or duration: 1m
+type runConfig struct {
+	Batcher struct {
+		InitialBatchSize  int           `yaml:"initialBatchSize"`
+		MinBatchSize      int           `yaml:"minBatchSize"`
+		MaxBatchSize      int           `yaml:"maxBatchSize"`
+		Timeout           time.Duration `yaml:"timeout"`
+		AdjustmentFactor  float64       `yaml:"adjustmentFactor"`
+		LoadCheckInterval time.Duration `yaml:"loadCheckInterval"`
+		Strategy          string        `yaml:"strategy"`
+		FixedSize         bool          `yaml:"fixedSize"`
+	} `yaml:"batcher"`
+
+	Simulator struct {
+		Pattern string `yaml:"pattern"`
+		Replay  string `yaml:"replay"`
+		Record  string `yaml:"record"`
+
+		// Script is a load trace written by hand: each step holds from
+		// its time on, measured from the first batch
+		Script []scriptStep `yaml:"script"`
+
+		// Seed seeds the jitter and error draws of a script (default: 1)
+		Seed int64 `yaml:"seed"`
+	} `yaml:"simulator"`
+
+	Workers  int           `yaml:"workers"`
+	Count    int           `yaml:"count"`
+	Duration time.Duration `yaml:"duration"`
+}
+
+type scriptStep struct {
+	At        time.Duration `yaml:"at"`
+	CPULoad   float64       `yaml:"cpuLoad"`
+	ErrorRate float64       `yaml:"errorRate"`
+}
+
+// loadConfig reads a scenario file; unknown keys are an error so typos
+// do not silently fall back to defaults
+func loadConfig(path string) (*runConfig, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var cfg runConfig
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	steps := cfg.Simulator.Script
+	if !sort.SliceIsSorted(steps, func(i, j int) bool { return steps[i].At < steps[j].At }) {
+		return nil, fmt.Errorf("%s: script steps out of order", path)
+	}
+	sources := 0
+	for _, set := range []bool{cfg.Simulator.Pattern != "", cfg.Simulator.Replay != "", len(steps) > 0} {
+		if set {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return nil, fmt.Errorf("%s: set only one of simulator pattern, replay and script", path)
+	}
+	return &cfg, nil
+}
+
+// apply sets the flags the file configures, except those given on the
+// command line
+func (c *runConfig) apply(fs *flag.FlagSet) error {
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	if explicit["pattern"] || explicit["replay"] {
+		c.Simulator.Script = nil
+	}
+
+	settings := []struct {
+		flag  string
+		value any
+		set   bool
+	}{
+		{"initial-batch", c.Batcher.InitialBatchSize, c.Batcher.InitialBatchSize != 0},
+		{"min-batch", c.Batcher.MinBatchSize, c.Batcher.MinBatchSize != 0},
+		{"max-batch", c.Batcher.MaxBatchSize, c.Batcher.MaxBatchSize != 0},
+		{"timeout", c.Batcher.Timeout, c.Batcher.Timeout != 0},
+		{"adjust-factor", c.Batcher.AdjustmentFactor, c.Batcher.AdjustmentFactor != 0},
+		{"adjust-interval", c.Batcher.LoadCheckInterval, c.Batcher.LoadCheckInterval != 0},
+		{"strategy", c.Batcher.Strategy, c.Batcher.Strategy != ""},
+		{"fixed", c.Batcher.FixedSize, c.Batcher.FixedSize},
+		{"pattern", c.Simulator.Pattern, c.Simulator.Pattern != ""},
+		{"replay", c.Simulator.Replay, c.Simulator.Replay != ""},
+		{"record", c.Simulator.Record, c.Simulator.Record != ""},
+		{"workers", c.Workers, c.Workers != 0},
+		{"count", c.Count, c.Count != 0},
+		{"duration", c.Duration, c.Duration != 0},
+	}
+	for _, s := range settings {
+		if !s.set || explicit[s.flag] {
+			continue
+		}
+		if err := fs.Set(s.flag, fmt.Sprint(s.value)); err != nil {
+			return fmt.Errorf("%s: %w", s.flag, err)
+		}
+	}
+	return nil
+}
+
+// script returns the scripted load as a trace to replay, or nil
+func (c *runConfig) script() *simulator.Trace {
+	if len(c.Simulator.Script) == 0 {
+		return nil
+	}
+	trace := &simulator.Trace{Pattern: "script", Seed: c.Simulator.Seed}
+	if trace.Seed == 0 {
+		trace.Seed = 1
+	}
+	for _, step := range c.Simulator.Script {
+		trace.Points = append(trace.Points, simulator.TracePoint{
+			Elapsed:   step.At,
+			CPULoad:   step.CPULoad,
+			ErrorRate: step.ErrorRate,
+		})
+	}
+	return trace
+}
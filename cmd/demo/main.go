@@ -5,6 +5,8 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"os"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -21,26 +23,80 @@ func main() {
 	maxBatchSize := flag.Int("max-batch", 100, "maximum batch size")
 	timeout := flag.Duration("timeout", 2*time.Second, "flush timeout")
 	workers := flag.Int("workers", 4, "number of worker goroutines")
-	loadPattern := flag.String("pattern", "spikes", "load pattern: constant, sinewave, spikes, gradual")
+	loadPattern := flag.String("pattern", "spikes", "load pattern: constant, sinewave, spikes, gradual, diurnal")
 	adjustInterval := flag.Duration("adjust-interval", 3*time.Second, "batch size adjustment interval")
 	adjustFactor := flag.Float64("adjust-factor", 0.3, "adjustment factor (0.1-1.0)")
+	fixedSize := flag.Bool("fixed", false, "keep the batch size at -initial-batch, without load-awareness")
+	strategyName := flag.String("strategy", "threshold", "adjustment strategy: "+strings.Join(batcher.Strategies(), ", ")+"; the TUI always uses threshold")
+	recordPath := flag.String("record", "", "write the load trace of the run to this file")
+	replayPath := flag.String("replay", "", "replay the load trace in this file instead of -pattern")
+	tui := flag.Bool("tui", false, "show a live terminal UI; items are produced until you quit it")
+	runFor := flag.Duration("duration", 0, "produce items for this long instead of -count items")
+	configPath := flag.String("config", "", "YAML scenario file; flags given on the command line override it")
 	flag.Parse()
 
+	var script *simulator.Trace
+	if *configPath != "" {
+		cfg, err := loadConfig(*configPath)
+		if err != nil {
+			log.Fatalf("Failed to load config: %v", err)
+		}
+		if err := cfg.apply(flag.CommandLine); err != nil {
+			log.Fatalf("Failed to apply config: %v", err)
+		}
+		script = cfg.script()
+	}
+
+	items, load := fmt.Sprint(*itemCount), *loadPattern
+	switch {
+	case *tui:
+		items = "until quit"
+	case *runFor > 0:
+		items = fmt.Sprintf("for %v", *runFor)
+	}
+	switch {
+	case *replayPath != "":
+		load = "replay of " + *replayPath
+	case script != nil:
+		load = "script"
+	}
+
 	fmt.Println("🚀 Load-Aware Batcher Demo")
 	fmt.Println("=" + repeat("=", 60))
-	fmt.Printf("Items: %d | Workers: %d | Pattern: %s\n", *itemCount, *workers, *loadPattern)
+	fmt.Printf("Items: %s | Workers: %d | Pattern: %s\n", items, *workers, load)
 	fmt.Printf("Batch Size: %d (min: %d, max: %d)\n", *initialBatchSize, *minBatchSize, *maxBatchSize)
 	fmt.Println("=" + repeat("=", 60))
 	fmt.Println()
 
 	startTime := time.Now()
 
-	// Create backend simulator with chosen pattern
-	pattern := parseLoadPattern(*loadPattern)
-	backend := simulator.NewBackend(pattern)
+	// Create backend simulator with chosen pattern, or the recorded trace
+	var backend *simulator.Backend
+	var err error
+	if *replayPath != "" {
+		trace, err := readTrace(*replayPath)
+		if err != nil {
+			log.Fatalf("Failed to read trace: %v", err)
+		}
+		if backend, err = simulator.NewReplayBackend(trace); err != nil {
+			log.Fatalf("Failed to replay trace: %v", err)
+		}
+		fmt.Printf("Replaying %s trace from %s (%v)\n\n", trace.Pattern, *replayPath, trace.Duration())
+	} else if script != nil {
+		if backend, err = simulator.NewReplayBackend(script); err != nil {
+			log.Fatalf("Failed to replay script: %v", err)
+		}
+		fmt.Printf("Running scripted load (%v)\n\n", script.Duration())
+	} else {
+		backend = simulator.NewBackend(parseLoadPattern(*loadPattern))
+	}
+	if *recordPath != "" {
+		backend.StartRecording()
+	}
 
-	// Create load-aware batcher
-	b, err := batcher.New(batcher.Config{
+	// Create load-aware batcher; the TUI changes its adjustment factor
+	// through the strategy
+	cfg := batcher.Config{
 		InitialBatchSize:  *initialBatchSize,
 		MinBatchSize:      *minBatchSize,
 		MaxBatchSize:      *maxBatchSize,
@@ -48,7 +104,15 @@ func main() {
 		HandlerFunc:       backend.ProcessBatch,
 		AdjustmentFactor:  *adjustFactor,
 		LoadCheckInterval: *adjustInterval,
-	})
+		Strategy:          *strategyName,
+		FixedSize:         *fixedSize,
+	}
+	strategy := &tunableStrategy{}
+	strategy.SetFactor(*adjustFactor)
+	if *tui {
+		cfg.AdjustmentStrategy = strategy
+	}
+	b, err := batcher.New(cfg)
 	if err != nil {
 		log.Fatalf("Failed to create batcher: %v", err)
 	}
@@ -57,14 +121,16 @@ func main() {
 	var itemsAdded atomic.Int64
 	var itemsProcessed atomic.Int64
 
-	// Start monitoring goroutine
+	// Start monitoring goroutine, unless the TUI shows the statistics
 	stopMonitor := make(chan struct{})
 	var monitorWg sync.WaitGroup
-	monitorWg.Add(1)
-	go func() {
-		defer monitorWg.Done()
-		monitor(b, backend, &itemsAdded, &itemsProcessed, stopMonitor)
-	}()
+	if !*tui {
+		monitorWg.Add(1)
+		go func() {
+			defer monitorWg.Done()
+			monitor(b, backend, &itemsAdded, &itemsProcessed, stopMonitor)
+		}()
+	}
 
 	// Worker pool
 	itemChan := make(chan int, *workers*10)
@@ -84,10 +150,21 @@ func main() {
 		}(i)
 	}
 
-	// Generate items
+	// Generate items; with -tui until it is quit, with -duration until
+	// the time is up
+	stopProducing := make(chan struct{})
+	stop := sync.OnceFunc(func() { close(stopProducing) })
+	if *runFor > 0 {
+		time.AfterFunc(*runFor, stop)
+	}
 	go func() {
-		for i := 0; i < *itemCount; i++ {
-			itemChan <- i
+		defer close(itemChan)
+		for i := 0; *tui || *runFor > 0 || i < *itemCount; i++ {
+			select {
+			case itemChan <- i:
+			case <-stopProducing:
+				return
+			}
 			itemsAdded.Add(1)
 			
 			// Simulate varying production rate
@@ -95,9 +172,15 @@ func main() {
 				time.Sleep(10 * time.Millisecond)
 			}
 		}
-		close(itemChan)
 	}()
 
+	if *tui {
+		if err := runTUI(b, backend, strategy, load); err != nil {
+			log.Printf("TUI error: %v", err)
+		}
+		stop()
+	}
+
 	// Wait for workers to finish
 	workerWg.Wait()
 
@@ -138,6 +221,34 @@ func main() {
 	throughput := float64(backendStats.TotalProcessed) / duration.Seconds()
 	fmt.Printf("Throughput: %.1f items/sec\n", throughput)
 	fmt.Println("=" + repeat("=", 60))
+
+	if *recordPath != "" {
+		if err := writeTrace(*recordPath, backend.Trace()); err != nil {
+			log.Fatalf("Failed to write trace: %v", err)
+		}
+		fmt.Printf("Load trace written to %s\n", *recordPath)
+	}
+}
+
+func readTrace(path string) (*simulator.Trace, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return simulator.ReadTrace(f)
+}
+
+func writeTrace(path string, trace *simulator.Trace) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := trace.WriteJSON(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
 }
 
 // monitor displays real-time statistics
@@ -186,6 +297,8 @@ func parseLoadPattern(pattern string) simulator.LoadPattern {
 		return simulator.PatternSpikes
 	case "gradual":
 		return simulator.PatternGradual
+	case "diurnal":
+		return simulator.PatternDiurnal
 	default:
 		return simulator.PatternSpikes
 	}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/amirafroozeh1/Load-Aware-Batcher"
+	"github.com/amirafroozeh1/Load-Aware-Batcher/simulator"
+)
+
+const (
+	tuiInterval = 250 * time.Millisecond
+	tuiHistory  = 240 // samples kept, a minute at tuiInterval
+	spikeLength = 5 * time.Second
+)
+
+// tunableStrategy is the default threshold strategy with an adjustment
+// factor that can be changed while the batcher runs
+type tunableStrategy struct {
+	batcher.ThresholdStrategy
+
+	mu     sync.Mutex
+	factor float64
+}
+
+func (s *tunableStrategy) NextBatchSize(in batcher.AdjustmentInput) int {
+	in.AdjustmentFactor = s.Factor()
+	return s.ThresholdStrategy.NextBatchSize(in)
+}
+
+func (s *tunableStrategy) Factor() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.factor
+}
+
+// SetFactor sets the adjustment factor, kept within [0.05, 1]
+func (s *tunableStrategy) SetFactor(f float64) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.factor = math.Round(math.Max(0.05, math.Min(f, 1))*100) / 100
+	return s.factor
+}
+
+// series is one sparkline of the monitor
+type series struct {
+	name   string
+	format string
+	fixed  bool // scaled to [0, 1] rather than to its own range
+	values []float64
+}
+
+func (s *series) add(v float64) {
+	s.values = append(s.values, v)
+	if len(s.values) > tuiHistory {
+		s.values = s.values[len(s.values)-tuiHistory:]
+	}
+}
+
+type tickMsg time.Time
+
+// tuiModel is the bubbletea model of the live monitor
+type tuiModel struct {
+	b        *batcher.Batcher
+	backend  *simulator.Backend
+	strategy *tunableStrategy
+	pattern  string
+
+	width     int
+	started   time.Time
+	last      time.Time
+	processed int64
+	backendSt simulator.BackendStats
+	stats     batcher.Stats
+
+	batchSize, loadScore, pending, throughput series
+}
+
+// runTUI shows the live monitor until the user quits
+func runTUI(b *batcher.Batcher, backend *simulator.Backend, strategy *tunableStrategy, pattern string) error {
+	now := time.Now()
+	m := &tuiModel{
+		b:          b,
+		backend:    backend,
+		strategy:   strategy,
+		pattern:    pattern,
+		width:      80,
+		started:    now,
+		last:       now,
+		batchSize:  series{name: "Batch size", format: "%.0f"},
+		loadScore:  series{name: "Load score", format: "%.2f", fixed: true},
+		pending:    series{name: "Pending", format: "%.0f"},
+		throughput: series{name: "Items/sec", format: "%.0f"},
+	}
+	_, err := tea.NewProgram(m, tea.WithAltScreen()).Run()
+	return err
+}
+
+func (m *tuiModel) Init() tea.Cmd {
+	return tick()
+}
+
+func (m *tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "q", "ctrl+c", "esc":
+			return m, tea.Quit
+		case "s", " ":
+			m.backend.InjectSpike(spikeLength)
+		case "+", "=", "up":
+			m.strategy.SetFactor(m.strategy.Factor() + 0.05)
+		case "-", "down":
+			m.strategy.SetFactor(m.strategy.Factor() - 0.05)
+		case "f":
+			m.b.SetAdaptive(!m.b.Adaptive())
+		}
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+	case tickMsg:
+		m.sample(time.Time(msg))
+		return m, tick()
+	}
+	return m, nil
+}
+
+func (m *tuiModel) View() string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, " Load-Aware Batcher  pattern: %s  adjustment factor: %.2f  up: %v\n\n",
+		m.pattern, m.strategy.Factor(), time.Since(m.started).Truncate(time.Second))
+
+	// Label and current value take 24 columns, the sparkline the rest
+	width := max(m.width-26, 10)
+	for _, s := range []*series{&m.batchSize, &m.loadScore, &m.pending, &m.throughput} {
+		current := 0.0
+		if n := len(s.values); n > 0 {
+			current = s.values[n-1]
+		}
+		fmt.Fprintf(&sb, " %-12s %9s  %s\n\n", s.name, fmt.Sprintf(s.format, current), sparkline(s, width))
+	}
+
+	fmt.Fprintf(&sb, " Backend  %s\n", formatBackendStatus(m.backendSt))
+	mode := "adaptive"
+	if !m.stats.Adaptive {
+		mode = "fixed size"
+	}
+	fmt.Fprintf(&sb, " Batcher  %s, %d batches, %d errors, %d shed\n\n",
+		mode, m.stats.Batches, m.stats.HandlerErrors, m.stats.ShedItems)
+	sb.WriteString(" s spike load   +/- adjustment factor   f fixed size   q quit\n")
+	return sb.String()
+}
+
+// --- Internal methods ---
+
+func tick() tea.Cmd {
+	return tea.Tick(tuiInterval, func(t time.Time) tea.Msg { return tickMsg(t) })
+}
+
+func (m *tuiModel) sample(now time.Time) {
+	m.stats = m.b.GetStats()
+	m.backendSt = m.backend.GetStats()
+
+	rate := 0.0
+	if dt := now.Sub(m.last).Seconds(); dt > 0 {
+		rate = float64(m.backendSt.TotalProcessed-m.processed) / dt
+	}
+	m.last, m.processed = now, m.backendSt.TotalProcessed
+
+	m.batchSize.add(float64(m.stats.CurrentBatchSize))
+	m.loadScore.add(m.stats.AverageLoadScore)
+	m.pending.add(float64(m.stats.PendingItems))
+	m.throughput.add(rate)
+}
+
+var sparks = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline renders the last width values of s, scaled to the range of
+// the values shown
+func sparkline(s *series, width int) string {
+	values := s.values
+	if len(values) > width {
+		values = values[len(values)-width:]
+	}
+
+	lo, hi := 0.0, 1.0
+	if !s.fixed {
+		hi = 0
+		for _, v := range values {
+			hi = math.Max(hi, v)
+		}
+	}
+
+	var sb strings.Builder
+	for _, v := range values {
+		i := 0
+		if hi > lo {
+			i = int((v - lo) / (hi - lo) * float64(len(sparks)-1))
+		}
+		sb.WriteRune(sparks[max(0, min(i, len(sparks)-1))])
+	}
+	return sb.String()
+}
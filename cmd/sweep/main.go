@@ -0,0 +1,235 @@
+// Command sweep runs the simulator across a grid of batcher settings and
+// reports how each combination converges, to guide tuning:
+//
+//	go run ./cmd/sweep -pattern spikes -factors 0.1,0.3 -intervals 500ms,1s -format csv
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/amirafroozeh1/Load-Aware-Batcher"
+	"github.com/amirafroozeh1/Load-Aware-Batcher/simulator"
+)
+
+// sampleInterval is how often a run samples the batch size
+const sampleInterval = 100 * time.Millisecond
+
+// point is one combination of the grid
+type point struct {
+	factor   float64
+	interval time.Duration
+	strategy string
+}
+
+// sample is the state of a run at one time
+type sample struct {
+	at        time.Duration
+	batchSize int
+	processed int64
+}
+
+func main() {
+	factors := flag.String("factors", "0.1,0.2,0.4", "comma-separated adjustment factors")
+	intervals := flag.String("intervals", "500ms,1s,2s", "comma-separated load check intervals")
+	names := flag.String("strategies", "threshold,aimd,pid", "comma-separated strategies: "+strings.Join(batcher.Strategies(), ", "))
+	pattern := flag.String("pattern", "spikes", "load pattern: constant, sinewave, spikes, gradual, diurnal")
+	replayPath := flag.String("replay", "", "replay the load trace in this file instead of -pattern, identical for every run")
+	duration := flag.Duration("duration", 10*time.Second, "length of each run")
+	workers := flag.Int("workers", 4, "producer goroutines per run")
+	parallel := flag.Int("parallel", 4, "runs at a time")
+	initialBatchSize := flag.Int("initial-batch", 20, "initial batch size")
+	minBatchSize := flag.Int("min-batch", 5, "minimum batch size")
+	maxBatchSize := flag.Int("max-batch", 200, "maximum batch size")
+	format := flag.String("format", "markdown", "report format: markdown, csv")
+	outPath := flag.String("out", "", "write the report to this file instead of stdout")
+	flag.Parse()
+
+	grid, err := parseGrid(*factors, *intervals, *names)
+	if err != nil {
+		log.Fatalf("Invalid grid: %v", err)
+	}
+	var trace *simulator.Trace
+	if *replayPath != "" {
+		f, err := os.Open(*replayPath)
+		if err != nil {
+			log.Fatalf("Failed to read trace: %v", err)
+		}
+		trace, err = simulator.ReadTrace(f)
+		f.Close()
+		if err != nil {
+			log.Fatalf("Failed to read trace: %v", err)
+		}
+	}
+	write := writeMarkdown
+	switch *format {
+	case "markdown":
+	case "csv":
+		write = writeCSV
+	default:
+		log.Fatalf("Unknown format %q", *format)
+	}
+
+	base := batcher.Config{
+		InitialBatchSize: *initialBatchSize,
+		MinBatchSize:     *minBatchSize,
+		MaxBatchSize:     *maxBatchSize,
+		Timeout:          time.Second,
+	}
+	load := *pattern
+	if trace != nil {
+		load = "replay of " + *replayPath
+	}
+	fmt.Fprintf(os.Stderr, "Sweeping %d combinations, %v each, %d at a time (about %v)\n",
+		len(grid), *duration, *parallel, *duration*time.Duration((len(grid)+*parallel-1)/max(*parallel, 1)))
+
+	results := make([]result, len(grid))
+	sem := make(chan struct{}, max(*parallel, 1))
+	var wg sync.WaitGroup
+	for i, p := range grid {
+		wg.Add(1)
+		go func(i int, p point) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			backend, err := newBackend(*pattern, trace)
+			if err != nil {
+				log.Fatalf("Failed to create backend: %v", err)
+			}
+			samples, err := run(base, p, backend, *workers, *duration)
+			if err != nil {
+				log.Fatalf("Run %v failed: %v", p, err)
+			}
+			results[i] = analyze(p, samples)
+			fmt.Fprintf(os.Stderr, "  done: %s\n", p)
+		}(i, p)
+	}
+	wg.Wait()
+
+	out := os.Stdout
+	if *outPath != "" {
+		f, err := os.Create(*outPath)
+		if err != nil {
+			log.Fatalf("Failed to create report: %v", err)
+		}
+		defer f.Close()
+		out = f
+	}
+	if err := write(out, report{load: load, duration: *duration, results: results}); err != nil {
+		log.Fatalf("Failed to write report: %v", err)
+	}
+}
+
+func (p point) String() string {
+	return fmt.Sprintf("%s factor=%g interval=%v", p.strategy, p.factor, p.interval)
+}
+
+// parseGrid returns every combination of the comma-separated values
+func parseGrid(factors, intervals, names string) ([]point, error) {
+	var fs []float64
+	for _, s := range strings.Split(factors, ",") {
+		f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil || f <= 0 {
+			return nil, fmt.Errorf("factor %q", s)
+		}
+		fs = append(fs, f)
+	}
+	var is []time.Duration
+	for _, s := range strings.Split(intervals, ",") {
+		d, err := time.ParseDuration(strings.TrimSpace(s))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("interval %q", s)
+		}
+		is = append(is, d)
+	}
+	var grid []point
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if !slices.Contains(batcher.Strategies(), name) {
+			return nil, fmt.Errorf("strategy %q", name)
+		}
+		for _, f := range fs {
+			for _, i := range is {
+				grid = append(grid, point{factor: f, interval: i, strategy: name})
+			}
+		}
+	}
+	return grid, nil
+}
+
+func newBackend(pattern string, trace *simulator.Trace) (*simulator.Backend, error) {
+	if trace != nil {
+		return simulator.NewReplayBackend(trace)
+	}
+	switch pattern {
+	case "constant":
+		return simulator.NewBackend(simulator.PatternConstant), nil
+	case "sinewave":
+		return simulator.NewBackend(simulator.PatternSineWave), nil
+	case "spikes":
+		return simulator.NewBackend(simulator.PatternSpikes), nil
+	case "gradual":
+		return simulator.NewBackend(simulator.PatternGradual), nil
+	case "diurnal":
+		return simulator.NewBackend(simulator.PatternDiurnal), nil
+	default:
+		return nil, fmt.Errorf("unknown pattern %q", pattern)
+	}
+}
+
+// run drives a batcher with the settings of p against the backend for d,
+// producing items as fast as the batcher takes them, and samples it
+func run(base batcher.Config, p point, backend *simulator.Backend, workers int, d time.Duration) ([]sample, error) {
+	cfg := base
+	cfg.AdjustmentFactor = p.factor
+	cfg.LoadCheckInterval = p.interval
+	cfg.Strategy = p.strategy
+	cfg.HandlerFunc = backend.ProcessBatch
+	b, err := batcher.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; ctx.Err() == nil; i++ {
+				b.Add(ctx, i)
+			}
+		}()
+	}
+
+	start := time.Now()
+	samples := []sample{{batchSize: b.GetCurrentBatchSize()}}
+	ticker := time.NewTicker(sampleInterval)
+	defer ticker.Stop()
+	for done := false; !done; {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			done = true
+		}
+		samples = append(samples, sample{
+			at:        time.Since(start),
+			batchSize: b.GetCurrentBatchSize(),
+			processed: backend.GetStats().TotalProcessed,
+		})
+	}
+
+	wg.Wait()
+	return samples, b.Close(context.Background())
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	// settleBand is how close to the steady-state size the batch size
+	// must stay to count as converged, as a fraction of it
+	settleBand = 0.1
+
+	// steadyFraction is the tail of a run taken as its steady state
+	steadyFraction = 0.25
+)
+
+// result is what one run of the grid achieved
+type result struct {
+	point
+
+	// steadySize is the median batch size over the steady state
+	steadySize int
+
+	// convergence is when the batch size entered the settle band around
+	// steadySize for good, and converged whether it did before the
+	// steady state began
+	convergence time.Duration
+	converged   bool
+
+	// overshoot is how far the batch size went past steadySize on its
+	// way there, as a fraction of it
+	overshoot float64
+
+	// throughput is the items per second processed in the steady state
+	throughput float64
+}
+
+// report is the outcome of a sweep
+type report struct {
+	load     string
+	duration time.Duration
+	results  []result
+}
+
+// analyze derives the convergence figures of a run from its samples
+func analyze(p point, samples []sample) result {
+	r := result{point: p}
+	if len(samples) < 2 {
+		return r
+	}
+
+	first := max(1, len(samples)-max(1, int(float64(len(samples))*steadyFraction)))
+	steady := samples[first:]
+	sizes := make([]int, len(steady))
+	for i, s := range steady {
+		sizes[i] = s.batchSize
+	}
+	sort.Ints(sizes)
+	r.steadySize = sizes[len(sizes)/2]
+
+	if dt := (steady[len(steady)-1].at - samples[first-1].at).Seconds(); dt > 0 {
+		r.throughput = float64(steady[len(steady)-1].processed-samples[first-1].processed) / dt
+	}
+
+	band := math.Max(1, settleBand*float64(r.steadySize))
+	settled := len(samples)
+	for i := len(samples) - 1; i >= 0; i-- {
+		if math.Abs(float64(samples[i].batchSize-r.steadySize)) > band {
+			break
+		}
+		settled = i
+	}
+	if settled < len(samples) {
+		r.convergence = samples[settled].at
+		r.converged = settled <= first
+	}
+
+	// Overshoot is past the steady state in the direction of travel
+	initial := samples[0].batchSize
+	for _, s := range samples[:settled] {
+		var over int
+		if initial <= r.steadySize {
+			over = s.batchSize - r.steadySize
+		} else {
+			over = r.steadySize - s.batchSize
+		}
+		if r.steadySize > 0 {
+			r.overshoot = math.Max(r.overshoot, float64(over)/float64(r.steadySize))
+		}
+	}
+	return r
+}
+
+// best returns the recommended result: the highest steady-state
+// throughput among converged runs, ties going to the faster convergence
+func (rep report) best() (result, bool) {
+	var best result
+	found := false
+	for _, r := range rep.results {
+		if !r.converged {
+			continue
+		}
+		if !found || r.throughput > best.throughput ||
+			(r.throughput == best.throughput && r.convergence < best.convergence) {
+			best, found = r, true
+		}
+	}
+	return best, found
+}
+
+func writeMarkdown(w io.Writer, rep report) error {
+	fmt.Fprintf(w, "# Batcher parameter sweep\n\n")
+	fmt.Fprintf(w, "Load: %s, %v per run, %d runs.\n\n", rep.load, rep.duration, len(rep.results))
+	fmt.Fprintln(w, "| Strategy | Factor | Interval | Steady size | Convergence | Overshoot | Throughput (items/s) |")
+	fmt.Fprintln(w, "|---|---:|---:|---:|---:|---:|---:|")
+	for _, r := range rep.results {
+		convergence := "did not converge"
+		if r.converged {
+			convergence = r.convergence.Round(sampleInterval).String()
+		}
+		fmt.Fprintf(w, "| %s | %g | %v | %d | %s | %.0f%% | %.0f |\n",
+			r.strategy, r.factor, r.interval, r.steadySize, convergence, 100*r.overshoot, r.throughput)
+	}
+
+	fmt.Fprintln(w)
+	if best, ok := rep.best(); ok {
+		fmt.Fprintf(w, "**Recommendation:** %s with AdjustmentFactor %g and LoadCheckInterval %v, "+
+			"settling at batch size %d within %v for %.0f items/s.\n",
+			best.strategy, best.factor, best.interval, best.steadySize, best.convergence.Round(sampleInterval), best.throughput)
+	} else {
+		fmt.Fprintln(w, "**Recommendation:** none of the runs converged; try longer runs or smaller factors.")
+	}
+	_, err := fmt.Fprintln(w)
+	return err
+}
+
+func writeCSV(w io.Writer, rep report) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"strategy", "factor", "interval", "steady_size", "converged", "convergence_s", "overshoot", "throughput"})
+	for _, r := range rep.results {
+		cw.Write([]string{
+			r.strategy,
+			strconv.FormatFloat(r.factor, 'g', -1, 64),
+			r.interval.String(),
+			strconv.Itoa(r.steadySize),
+			strconv.FormatBool(r.converged),
+			strconv.FormatFloat(r.convergence.Seconds(), 'f', 1, 64),
+			strconv.FormatFloat(r.overshoot, 'f', 3, 64),
+			strconv.FormatFloat(r.throughput, 'f', 1, 64),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	batcher "github.com/amirafroozeh1/Load-Aware-Batcher"
+	"github.com/amirafroozeh1/Load-Aware-Batcher/dashboard"
+)
+
+// MultiDemo routes items over several shards, one batcher each, whose
+// backends carry different loads, and shows them side by side
+type MultiDemo struct {
+	mu     sync.RWMutex
+	loads  map[string]float64 // 0.0 to 1.0, per shard
+	router *batcher.Router
+	panel  *dashboard.Multi
+	store  *dashboard.Store // optional; records every shard as a run
+}
+
+func NewMultiDemo(shards []string, store *dashboard.Store) (*MultiDemo, error) {
+	md := &MultiDemo{
+		loads: make(map[string]float64, len(shards)),
+		panel: dashboard.NewMulti("Load-Aware Batcher - Sharded Demo"),
+		store: store,
+	}
+	for i, shard := range shards {
+		md.loads[shard] = 0.2 + 0.6*float64(i)/float64(max(len(shards)-1, 1))
+	}
+
+	router, err := batcher.NewRouter(batcher.RouterConfig{
+		Shards:     shards,
+		KeyFunc:    func(item any) string { return item.(string) },
+		NewBatcher: md.newShard,
+	})
+	if err != nil {
+		md.panel.Close()
+		return nil, err
+	}
+	md.router = router
+	return md, nil
+}
+
+func (md *MultiDemo) newShard(shard string) (*batcher.Batcher, error) {
+	cfg := batcher.Config{
+		Name:              shard,
+		Labels:            map[string]string{"shard": shard},
+		InitialBatchSize:  20,
+		MinBatchSize:      5,
+		MaxBatchSize:      100,
+		Timeout:           2 * time.Second,
+		AdjustmentFactor:  0.5,
+		LoadCheckInterval: 1 * time.Second,
+		HandlerFunc: func(ctx context.Context, batch []any) (*batcher.LoadFeedback, error) {
+			return md.handleBatch(shard, batch)
+		},
+	}
+	b, err := batcher.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	err = md.panel.Add(shard, b, dashboard.Config{
+		Extra: map[string]func() float64{
+			"backend load": func() float64 { return md.load(shard) },
+		},
+		Actions: map[string]func(ctx context.Context) error{
+			"Spike": func(ctx context.Context) error { md.SetLoad(shard, 0.95); return nil },
+			"Calm":  func(ctx context.Context) error { md.SetLoad(shard, 0.1); return nil },
+			"Flush": b.Flush,
+		},
+		Store: md.store,
+		RunMeta: map[string]string{
+			"initialBatchSize": fmt.Sprint(cfg.InitialBatchSize),
+			"batchSizeRange":   fmt.Sprintf("%d-%d", cfg.MinBatchSize, cfg.MaxBatchSize),
+			"adjustmentFactor": fmt.Sprint(cfg.AdjustmentFactor),
+			"initialLoad":      fmt.Sprintf("%.2f", md.load(shard)),
+		},
+	})
+	if err != nil {
+		b.Close(context.Background())
+		return nil, err
+	}
+	return b, nil
+}
+
+func (md *MultiDemo) load(shard string) float64 {
+	md.mu.RLock()
+	defer md.mu.RUnlock()
+	return md.loads[shard]
+}
+
+func (md *MultiDemo) SetLoad(shard string, load float64) {
+	md.mu.Lock()
+	defer md.mu.Unlock()
+	md.loads[shard] = max(0, min(load, 1))
+}
+
+func (md *MultiDemo) handleBatch(shard string, batch []any) (*batcher.LoadFeedback, error) {
+	load := md.load(shard)
+
+	// Simulate processing based on load
+	processingTime := time.Duration(float64(len(batch)) * (1 + load*3) * float64(time.Millisecond))
+	time.Sleep(processingTime)
+
+	return &batcher.LoadFeedback{
+		CPULoad:        load,
+		QueueDepth:     int(load * 50),
+		ProcessingTime: processingTime,
+		ErrorRate:      load * 0.2,
+	}, nil
+}
+
+// produce adds items for random customers; the router spreads them over
+// the shards by key
+func (md *MultiDemo) produce() {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		key := fmt.Sprintf("customer-%d", rand.Intn(1000))
+		if err := md.router.Add(context.Background(), key); err != nil {
+			log.Printf("add: %v", err)
+		}
+	}
+}
+
+func mainMulti(dbPath string) {
+	var store *dashboard.Store
+	if dbPath != "" {
+		var err error
+		if store, err = dashboard.OpenStore(dbPath); err != nil {
+			log.Fatal(err)
+		}
+		defer store.Close()
+	}
+
+	demo, err := NewMultiDemo([]string{"shard-a", "shard-b", "shard-c"}, store)
+	if err != nil {
+		log.Fatal(err)
+	}
+	go demo.produce()
+
+	port := ":8080"
+	log.Printf("🚀 Sharded Load-Aware Batcher Demo at http://localhost%s", port)
+	log.Fatal(http.ListenAndServe(port, demo.panel))
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"embed"
+	"html/template"
+	"net/http"
+)
+
+// The demo pages are compiled into the binary; their scripts come from
+// the dashboard package, so the demo needs no network access.
+//
+//go:embed templates/*.html
+var templateFS embed.FS
+
+var pages = template.Must(template.ParseFS(templateFS, "templates/*.html"))
+
+func renderPage(w http.ResponseWriter, name string) {
+	if err := pages.ExecuteTemplate(w, name, nil); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
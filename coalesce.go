@@ -0,0 +1,65 @@
+package batcher
+
+import (
+	"context"
+	"time"
+)
+
+// timerFlush runs when the flush timer fires. With Config.CoalesceWindow
+// a timeout flush is deferred while the batch is about to fill up, so
+// that trickle traffic does not turn into a stream of tiny batches.
+func (b *Batcher) timerFlush(ctx context.Context) error {
+	if b.cfg.CoalesceWindow > 0 {
+		now := time.Now()
+
+		b.mu.Lock()
+		if b.timer != nil && now.Before(b.timerAt) {
+			// A stale timer; the one armed now flushes this batch
+			b.mu.Unlock()
+			return nil
+		}
+		if at, ok := b.coalesceLocked(now); ok {
+			b.stopTimerLocked()
+			b.scheduleFlushLocked(at)
+			b.coalesced.Add(1)
+			b.mu.Unlock()
+			return nil
+		}
+		b.mu.Unlock()
+	}
+	return b.flush(ctx, b.timerReason(time.Now()))
+}
+
+// coalesceLocked decides whether a due timeout flush is deferred, and
+// until when. It is deferred if the batch holds at least CoalesceMinFill
+// of the batch size and, at the rate it filled so far, reaches the batch
+// size before its oldest item is Timeout + CoalesceWindow old, or
+// MaxItemAge if sooner, and before the earliest item deadline.
+func (b *Batcher) coalesceLocked(now time.Time) (time.Time, bool) {
+	n, size := len(b.batch), b.currentBatchSize
+	if b.closed || n == 0 || n >= size || float64(n) < b.cfg.CoalesceMinFill*float64(size) {
+		return time.Time{}, false
+	}
+
+	oldest := b.batch[0].added
+	limit := oldest.Add(b.cfg.Timeout + b.cfg.CoalesceWindow)
+	if age, ok := b.ageLimitLocked(); ok && age.Before(limit) {
+		limit = age
+	}
+	if !b.earliestDeadline.IsZero() {
+		if d := b.earliestDeadline.Add(-b.cfg.DeadlineMargin); d.Before(limit) {
+			limit = d
+		}
+	}
+	age := now.Sub(oldest)
+	if age <= 0 || !now.Before(limit) {
+		return time.Time{}, false
+	}
+
+	rate := float64(n) / age.Seconds()
+	at := now.Add(time.Duration(float64(size-n) / rate * float64(time.Second)))
+	if at.After(limit) {
+		return time.Time{}, false
+	}
+	return at, true
+}
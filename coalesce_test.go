@@ -0,0 +1,122 @@
+package batcher
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// batchRecorder records the sizes of handled batches and when they arrived
+type batchRecorder struct {
+	mu    sync.Mutex
+	sizes []int
+	at    []time.Time
+}
+
+func (r *batchRecorder) handle(ctx context.Context, batch []any) (*LoadFeedback, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sizes = append(r.sizes, len(batch))
+	r.at = append(r.at, time.Now())
+	return nil, nil
+}
+
+func (r *batchRecorder) get() ([]int, []time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]int(nil), r.sizes...), append([]time.Time(nil), r.at...)
+}
+
+func TestCoalesce_DefersTimeoutWhileFilling(t *testing.T) {
+	rec := &batchRecorder{}
+	b, err := New(Config{
+		InitialBatchSize:  10,
+		Timeout:           50 * time.Millisecond,
+		CoalesceWindow:    200 * time.Millisecond,
+		HandlerFunc:       rec.handle,
+		LoadCheckInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	// One item every 8ms: the timeout fires with 6 or 7 items buffered,
+	// and the batch fills some 30ms later
+	ctx := context.Background()
+	for i := 0; i < 10; i++ {
+		b.Add(ctx, i)
+		time.Sleep(8 * time.Millisecond)
+	}
+
+	sizes, _ := rec.get()
+	if len(sizes) != 1 || sizes[0] != 10 {
+		t.Errorf("Expected one full batch of 10, got %v", sizes)
+	}
+	if got := b.GetStats().CoalescedFlushes; got == 0 {
+		t.Error("Expected the timeout flush to be deferred")
+	}
+}
+
+func TestCoalesce_SparseBatchFlushesOnTime(t *testing.T) {
+	rec := &batchRecorder{}
+	b, err := New(Config{
+		InitialBatchSize:  10,
+		Timeout:           30 * time.Millisecond,
+		CoalesceWindow:    time.Second,
+		HandlerFunc:       rec.handle,
+		LoadCheckInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	start := time.Now()
+	b.Add(context.Background(), 1)
+	b.Add(context.Background(), 2)
+	time.Sleep(150 * time.Millisecond)
+
+	sizes, at := rec.get()
+	if len(sizes) != 1 || sizes[0] != 2 {
+		t.Fatalf("Expected one batch of 2, got %v", sizes)
+	}
+	if d := at[0].Sub(start); d > 100*time.Millisecond {
+		t.Errorf("Expected a batch below CoalesceMinFill to flush at the timeout, took %v", d)
+	}
+	if got := b.GetStats().CoalescedFlushes; got != 0 {
+		t.Errorf("Expected no deferred flush, got %d", got)
+	}
+}
+
+func TestCoalesce_HardAgeCap(t *testing.T) {
+	rec := &batchRecorder{}
+	b, err := New(Config{
+		InitialBatchSize:  10,
+		Timeout:           40 * time.Millisecond,
+		CoalesceWindow:    60 * time.Millisecond,
+		HandlerFunc:       rec.handle,
+		LoadCheckInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	// A burst that looks like it will fill the batch, then nothing
+	start := time.Now()
+	for i := 0; i < 7; i++ {
+		b.Add(context.Background(), i)
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(200 * time.Millisecond)
+
+	sizes, at := rec.get()
+	if len(sizes) != 1 || sizes[0] != 7 {
+		t.Fatalf("Expected one batch of 7, got %v", sizes)
+	}
+	if d := at[0].Sub(start); d < 40*time.Millisecond || d > 150*time.Millisecond {
+		t.Errorf("Expected the flush between Timeout and Timeout + CoalesceWindow, took %v", d)
+	}
+}
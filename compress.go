@@ -0,0 +1,246 @@
+package batcher
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// BatchBytesMetric is the key of LoadFeedback.Custom under which an
+// EncodedBatcher reports the size in bytes of each batch as sent, after
+// compression. A CustomMetric of this name makes the batch size adapt to
+// the payload size as well as to the backend load, e.g. with Critical at
+// the request size limit of the backend.
+const BatchBytesMetric = "batch_bytes"
+
+// Compression selects how an EncodedBatcher compresses batches
+type Compression int
+
+const (
+	// CompressionNone sends batches as encoded
+	CompressionNone Compression = iota
+
+	// CompressionGzip compresses batches with gzip
+	CompressionGzip
+)
+
+// String returns the string representation of Compression
+func (c Compression) String() string {
+	switch c {
+	case CompressionNone:
+		return "none"
+	case CompressionGzip:
+		return "gzip"
+	default:
+		return "unknown"
+	}
+}
+
+// EncodedBatch is a batch encoded and compressed for the wire
+type EncodedBatch struct {
+	// Data is the payload, owned by the handler
+	Data []byte
+
+	// ContentType is the media type of the encoded batch, and
+	// ContentEncoding its compression, e.g. for the HTTP headers of the
+	// same names; empty if it is not compressed
+	ContentType     string
+	ContentEncoding string
+
+	// Items is the number of items in the batch, and RawBytes the size
+	// of the payload before compression
+	Items    int
+	RawBytes int
+}
+
+// EncodedConfig holds the configuration for an EncodedBatcher
+type EncodedConfig struct {
+	// Config is the batcher configuration. Its handlers are ignored;
+	// HandlerFunc below handles the batches.
+	Config Config
+
+	// HandlerFunc is called with each flushed batch, encoded and
+	// compressed
+	HandlerFunc func(ctx context.Context, batch EncodedBatch) (*LoadFeedback, error)
+
+	// Encoder encodes the batches (default: a JSONEncoder)
+	Encoder BatchEncoder
+
+	// Compression is the compression of the batches (default:
+	// CompressionNone)
+	Compression Compression
+
+	// NewCompressor, if set, overrides Compression with another codec,
+	// such as zstd, wrapping w in a writer that compresses into it.
+	// ContentEncoding then names the codec.
+	NewCompressor   func(w io.Writer) (io.WriteCloser, error)
+	ContentEncoding string
+}
+
+// EncodedBatcher batches items and encodes and compresses each batch once
+// before handing it to the handler, for network-heavy sinks, so handlers
+// ship bytes rather than each doing their own encoding. The size of every
+// batch as sent is reported to the adjuster as BatchBytesMetric.
+type EncodedBatcher struct {
+	b   *Batcher
+	cfg EncodedConfig
+
+	gzips sync.Pool
+
+	batches         atomic.Int64
+	rawBytes        atomic.Int64
+	compressedBytes atomic.Int64
+}
+
+// NewEncodedBatcher creates an EncodedBatcher with the given
+// configuration. HandlerFunc must be set.
+func NewEncodedBatcher(cfg EncodedConfig) (*EncodedBatcher, error) {
+	if cfg.HandlerFunc == nil {
+		return nil, ErrInvalidConfig
+	}
+	if cfg.NewCompressor == nil && cfg.Compression != CompressionNone && cfg.Compression != CompressionGzip {
+		return nil, ErrInvalidConfig
+	}
+	if cfg.Encoder == nil {
+		cfg.Encoder = &JSONEncoder{}
+	}
+
+	e := &EncodedBatcher{cfg: cfg}
+	bcfg := cfg.Config
+	bcfg.HandlerFuncV2 = nil
+	bcfg.PlainHandlerFunc = nil
+	bcfg.HandlerFunc = e.handle
+
+	b, err := New(bcfg)
+	if err != nil {
+		return nil, err
+	}
+	e.b = b
+	return e, nil
+}
+
+// Add adds one item to the batch
+func (e *EncodedBatcher) Add(ctx context.Context, item any) error {
+	return e.b.Add(ctx, item)
+}
+
+// Flush flushes the current batch, if any
+func (e *EncodedBatcher) Flush(ctx context.Context) error {
+	return e.b.Flush(ctx)
+}
+
+// Close closes the batcher, flushing the items still buffered
+func (e *EncodedBatcher) Close(ctx context.Context) error {
+	return e.b.Close(ctx)
+}
+
+// Batcher returns the underlying Batcher, e.g. for its statistics
+func (e *EncodedBatcher) Batcher() *Batcher {
+	return e.b
+}
+
+// GetStats returns the encoding statistics
+func (e *EncodedBatcher) GetStats() EncodedStats {
+	stats := EncodedStats{
+		Batches:         e.batches.Load(),
+		RawBytes:        e.rawBytes.Load(),
+		CompressedBytes: e.compressedBytes.Load(),
+	}
+	if stats.RawBytes > 0 {
+		stats.CompressionRatio = float64(stats.CompressedBytes) / float64(stats.RawBytes)
+	}
+	return stats
+}
+
+// EncodedStats holds encoding statistics
+type EncodedStats struct {
+	// Batches is the number of batches encoded
+	Batches int64
+
+	// RawBytes and CompressedBytes are the total sizes of the batches
+	// before and after compression, and CompressionRatio the second
+	// over the first
+	RawBytes         int64
+	CompressedBytes  int64
+	CompressionRatio float64
+}
+
+// --- Internal methods ---
+
+// handle encodes and compresses a batch and hands it to the handler
+func (e *EncodedBatcher) handle(ctx context.Context, batch []any) (*LoadFeedback, error) {
+	encoded, err := e.encode(batch)
+	if err != nil {
+		return nil, err
+	}
+	e.batches.Add(1)
+	e.rawBytes.Add(int64(encoded.RawBytes))
+	e.compressedBytes.Add(int64(len(encoded.Data)))
+
+	fb, err := e.cfg.HandlerFunc(ctx, encoded)
+	if fb != nil {
+		out := *fb
+		out.Custom = make(map[string]interface{}, len(fb.Custom)+1)
+		for k, v := range fb.Custom {
+			out.Custom[k] = v
+		}
+		out.Custom[BatchBytesMetric] = float64(len(encoded.Data))
+		fb = &out
+	}
+	return fb, err
+}
+
+// encode encodes the batch and compresses the result
+func (e *EncodedBatcher) encode(batch []any) (EncodedBatch, error) {
+	var raw bytes.Buffer
+	if err := e.cfg.Encoder.Encode(&raw, batch); err != nil {
+		return EncodedBatch{}, err
+	}
+	out := EncodedBatch{
+		Data:        raw.Bytes(),
+		ContentType: e.cfg.Encoder.ContentType(),
+		Items:       len(batch),
+		RawBytes:    raw.Len(),
+	}
+
+	var compressed bytes.Buffer
+	switch {
+	case e.cfg.NewCompressor != nil:
+		w, err := e.cfg.NewCompressor(&compressed)
+		if err != nil {
+			return EncodedBatch{}, err
+		}
+		if err := writeAndClose(w, raw.Bytes()); err != nil {
+			return EncodedBatch{}, err
+		}
+		out.ContentEncoding = e.cfg.ContentEncoding
+	case e.cfg.Compression == CompressionGzip:
+		w, _ := e.gzips.Get().(*gzip.Writer)
+		if w == nil {
+			w = gzip.NewWriter(&compressed)
+		} else {
+			w.Reset(&compressed)
+		}
+		err := writeAndClose(w, raw.Bytes())
+		e.gzips.Put(w)
+		if err != nil {
+			return EncodedBatch{}, err
+		}
+		out.ContentEncoding = "gzip"
+	default:
+		return out, nil
+	}
+	out.Data = compressed.Bytes()
+	return out, nil
+}
+
+func writeAndClose(w io.WriteCloser, data []byte) error {
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
@@ -0,0 +1,124 @@
+package batcher
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestEncodedBatcher_Gzip(t *testing.T) {
+	var got EncodedBatch
+	e, err := NewEncodedBatcher(EncodedConfig{
+		Config: Config{
+			InitialBatchSize: 3,
+			CustomMetrics:    []CustomMetric{{Name: BatchBytesMetric, Weight: 0.2, Critical: 1 << 20}},
+		},
+		Compression: CompressionGzip,
+		HandlerFunc: func(ctx context.Context, batch EncodedBatch) (*LoadFeedback, error) {
+			got = batch
+			return &LoadFeedback{CPULoad: 0.1}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewEncodedBatcher() failed: %v", err)
+	}
+	defer e.Close(context.Background())
+
+	item := strings.Repeat("a", 200)
+	for i := 0; i < 3; i++ {
+		if err := e.Add(context.Background(), item); err != nil {
+			t.Fatalf("Add() failed: %v", err)
+		}
+	}
+
+	if got.Items != 3 || got.ContentType != "application/json" || got.ContentEncoding != "gzip" {
+		t.Fatalf("Unexpected batch %+v", got)
+	}
+	r, err := gzip.NewReader(bytes.NewReader(got.Data))
+	if err != nil {
+		t.Fatalf("Expected gzip data: %v", err)
+	}
+	raw, _ := io.ReadAll(r)
+	var items []string
+	if err := json.Unmarshal(raw, &items); err != nil || len(items) != 3 || items[0] != item {
+		t.Errorf("Expected the 3 items back, got %q (%v)", raw, err)
+	}
+	if got.RawBytes != len(raw) {
+		t.Errorf("Expected RawBytes %d, got %d", len(raw), got.RawBytes)
+	}
+
+	stats := e.GetStats()
+	if stats.Batches != 1 || stats.CompressedBytes != int64(len(got.Data)) || stats.CompressionRatio >= 0.5 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+	if score := e.Batcher().GetStats().AverageLoadScore; score <= 0 {
+		t.Errorf("Expected a load score from the feedback, got %v", score)
+	}
+}
+
+func TestEncodedBatcher_BatchBytesFeedback(t *testing.T) {
+	var sizes []float64
+	e, err := NewEncodedBatcher(EncodedConfig{
+		Config: Config{
+			InitialBatchSize: 2,
+			CustomMetrics: []CustomMetric{{
+				Name:   BatchBytesMetric,
+				Weight: 0.5,
+				Extract: func(custom map[string]interface{}) (float64, bool) {
+					v, ok := custom[BatchBytesMetric].(float64)
+					sizes = append(sizes, v)
+					return v, ok
+				},
+				Critical: 100,
+			}},
+		},
+		HandlerFunc: func(ctx context.Context, batch EncodedBatch) (*LoadFeedback, error) {
+			return &LoadFeedback{}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewEncodedBatcher() failed: %v", err)
+	}
+	defer e.Close(context.Background())
+
+	e.Add(context.Background(), 1)
+	e.Add(context.Background(), 2)
+	if len(sizes) != 1 || sizes[0] != float64(len("[1,2]")) {
+		t.Errorf("Expected the batch size in bytes reported, got %v", sizes)
+	}
+}
+
+func TestEncodedBatcher_CustomCompressor(t *testing.T) {
+	var got EncodedBatch
+	e, err := NewEncodedBatcher(EncodedConfig{
+		Encoder:         &JSONEncoder{Format: NDJSON},
+		ContentEncoding: "upper",
+		NewCompressor: func(w io.Writer) (io.WriteCloser, error) {
+			return upperWriter{w}, nil
+		},
+		HandlerFunc: func(ctx context.Context, batch EncodedBatch) (*LoadFeedback, error) {
+			got = batch
+			return nil, nil
+		},
+		Config: Config{InitialBatchSize: 1},
+	})
+	if err != nil {
+		t.Fatalf("NewEncodedBatcher() failed: %v", err)
+	}
+	defer e.Close(context.Background())
+
+	e.Add(context.Background(), "abc")
+	if string(got.Data) != "\"ABC\"\n" || got.ContentEncoding != "upper" {
+		t.Errorf("Expected the custom compressor applied, got %q (%q)", got.Data, got.ContentEncoding)
+	}
+}
+
+// upperWriter is a toy compressor upper-casing its input
+type upperWriter struct{ w io.Writer }
+
+func (u upperWriter) Write(p []byte) (int, error) { return u.w.Write(bytes.ToUpper(p)) }
+func (u upperWriter) Close() error                { return nil }
@@ -0,0 +1,90 @@
+package batcher
+
+import (
+	"context"
+	"time"
+)
+
+// Value returns the value of a Config.ContextKeys key captured from the
+// context item i of the batch was added with, or nil if the key is not
+// in ContextKeys or the context had no value for it
+func (m BatchMeta) Value(i int, key any) any {
+	if i < 0 || i >= len(m.ItemValues) {
+		return nil
+	}
+	for k, ck := range m.contextKeys {
+		if ck == key {
+			return m.ItemValues[i][k]
+		}
+	}
+	return nil
+}
+
+// batchMetaKey is the context key of the BatchMeta of the batch being
+// handled
+type batchMetaKey struct{}
+
+// BatchMetaFromContext returns the metadata of the batch whose handler
+// ctx was passed to, and false outside a handler. It gives handlers with
+// the HandlerFunc or PlainHandlerFunc signature what HandlerFuncV2
+// receives as an argument.
+func BatchMetaFromContext(ctx context.Context) (BatchMeta, bool) {
+	meta, ok := ctx.Value(batchMetaKey{}).(*BatchMeta)
+	if !ok {
+		return BatchMeta{}, false
+	}
+	return *meta, true
+}
+
+// BatchIDFromContext returns the BatchMeta.BatchID of the batch being
+// handled, or "" outside a handler
+func BatchIDFromContext(ctx context.Context) string {
+	meta, _ := ctx.Value(batchMetaKey{}).(*BatchMeta)
+	if meta == nil {
+		return ""
+	}
+	return meta.BatchID
+}
+
+// AttemptFromContext returns the BatchMeta.Attempt of the batch being
+// handled, or 0 outside a handler
+func AttemptFromContext(ctx context.Context) int {
+	meta, _ := ctx.Value(batchMetaKey{}).(*BatchMeta)
+	if meta == nil {
+		return 0
+	}
+	return meta.Attempt
+}
+
+// FlushReasonFromContext returns the BatchMeta.Reason of the batch being
+// handled, and false outside a handler
+func FlushReasonFromContext(ctx context.Context) (FlushReason, bool) {
+	meta, _ := ctx.Value(batchMetaKey{}).(*BatchMeta)
+	if meta == nil {
+		return 0, false
+	}
+	return meta.Reason, true
+}
+
+// BatchDeadlineFromContext returns the BatchMeta.Deadline of the batch
+// being handled, and false outside a handler or if its items had no
+// deadline
+func BatchDeadlineFromContext(ctx context.Context) (time.Time, bool) {
+	meta, _ := ctx.Value(batchMetaKey{}).(*BatchMeta)
+	if meta == nil || meta.Deadline.IsZero() {
+		return time.Time{}, false
+	}
+	return meta.Deadline, true
+}
+
+// --- Internal methods ---
+
+// captureContext snapshots the values of Config.ContextKeys, so the
+// context itself need not be kept
+func (b *Batcher) captureContext(ctx context.Context) []any {
+	values := make([]any, len(b.cfg.ContextKeys))
+	for i, key := range b.cfg.ContextKeys {
+		values[i] = ctx.Value(key)
+	}
+	return values
+}
@@ -0,0 +1,139 @@
+package batcher
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type tenantKey struct{}
+
+func TestContextKeys_CapturedPerItem(t *testing.T) {
+	var meta BatchMeta
+	var batch []any
+	b, err := New(Config{
+		InitialBatchSize: 3,
+		ContextKeys:      []any{tenantKey{}, traceKey{}},
+		SortFunc:         func(a, b any) int { return a.(int) - b.(int) },
+		HandlerFuncV2: func(ctx context.Context, items []any, m BatchMeta) (*LoadFeedback, error) {
+			batch, meta = items, m
+			return nil, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	ctx := context.Background()
+	b.Add(context.WithValue(context.WithValue(ctx, tenantKey{}, "acme"), traceKey{}, "t1"), 3)
+	b.Add(context.WithValue(ctx, tenantKey{}, "globex"), 1)
+	b.Add(ctx, 2)
+
+	if len(meta.ItemValues) != 3 {
+		t.Fatalf("Expected values for 3 items, got %v", meta.ItemValues)
+	}
+	// Values follow their items through SortFunc
+	want := []struct {
+		item          int
+		tenant, trace any
+	}{
+		{1, "globex", nil},
+		{2, nil, nil},
+		{3, "acme", "t1"},
+	}
+	for i, w := range want {
+		if batch[i] != w.item {
+			t.Fatalf("Expected item %d at %d, got %v", w.item, i, batch)
+		}
+		if got := meta.Value(i, tenantKey{}); got != w.tenant {
+			t.Errorf("Expected tenant %v for item %d, got %v", w.tenant, w.item, got)
+		}
+		if got := meta.Value(i, traceKey{}); got != w.trace {
+			t.Errorf("Expected trace %v for item %d, got %v", w.trace, w.item, got)
+		}
+	}
+
+	if got := meta.Value(0, "unknown"); got != nil {
+		t.Errorf("Expected nil for a key not in ContextKeys, got %v", got)
+	}
+	if got := meta.Value(5, tenantKey{}); got != nil {
+		t.Errorf("Expected nil for an item out of range, got %v", got)
+	}
+}
+
+func TestContextKeys_NotCapturedByDefault(t *testing.T) {
+	var meta BatchMeta
+	b, err := New(Config{
+		InitialBatchSize: 1,
+		HandlerFuncV2: func(ctx context.Context, items []any, m BatchMeta) (*LoadFeedback, error) {
+			meta = m
+			return nil, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	b.Add(context.WithValue(context.Background(), tenantKey{}, "acme"), 1)
+	if meta.ItemValues != nil || meta.Value(0, tenantKey{}) != nil {
+		t.Errorf("Expected no captured values without ContextKeys, got %v", meta.ItemValues)
+	}
+}
+
+func TestBatchMetaFromContext(t *testing.T) {
+	type seen struct {
+		id       string
+		attempt  int
+		reason   FlushReason
+		deadline time.Time
+		ok       bool
+	}
+	var got []seen
+	calls := 0
+	b, err := New(Config{
+		InitialBatchSize: 2,
+		Timeout:          time.Hour,
+		RequeuePolicy:    RequeueFront,
+		RespectDeadlines: true,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			reason, ok := FlushReasonFromContext(ctx)
+			deadline, _ := BatchDeadlineFromContext(ctx)
+			got = append(got, seen{BatchIDFromContext(ctx), AttemptFromContext(ctx), reason, deadline, ok})
+			if calls++; calls == 1 {
+				return nil, ErrRetryable
+			}
+			return &LoadFeedback{}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	deadline := time.Now().Add(time.Hour)
+	dctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+	b.Add(context.Background(), 1)
+	b.Add(dctx, 2)
+	b.Flush(context.Background())
+
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 handler calls, got %d", len(got))
+	}
+	if g := got[0]; g.id == "" || g.attempt != 1 || g.reason != FlushReasonSize || !g.ok || !g.deadline.Equal(deadline) {
+		t.Errorf("Expected the first attempt of a size flush with the item deadline, got %+v", g)
+	}
+	if g := got[1]; g.attempt != 2 || g.reason != FlushReasonManual {
+		t.Errorf("Expected the second attempt of a manual flush, got %+v", g)
+	}
+
+	ctx := context.Background()
+	if _, ok := BatchMetaFromContext(ctx); ok || BatchIDFromContext(ctx) != "" || AttemptFromContext(ctx) != 0 {
+		t.Error("Expected no batch metadata outside a handler")
+	}
+	if _, ok := BatchDeadlineFromContext(ctx); ok {
+		t.Error("Expected no batch deadline outside a handler")
+	}
+}
@@ -0,0 +1,146 @@
+package batcher
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// The convergence tests run every controller against load patterns
+// modelled on those of the simulator package and hold the resulting
+// batch size curves to golden thresholds, so that tuning a default which
+// slows convergence or makes a controller oscillate fails a test rather
+// than going unnoticed. QueueingStrategy sizes batches from service
+// times rather than the load score and is not covered here.
+
+// convergenceCycles is the length of every convergence run
+const convergenceCycles = 80
+
+// convergencePatterns are the canonical load patterns, by name
+var convergencePatterns = map[string]func() func(int) float64{
+	"constant": func() func(int) float64 {
+		return steps(1, 0.5)
+	},
+	"sinewave": func() func(int) float64 {
+		return func(cycle int) float64 {
+			return 0.5 + 0.4*math.Sin(float64(cycle)/3)
+		}
+	},
+	"spikes": func() func(int) float64 {
+		rng := rand.New(rand.NewSource(1))
+		return func(int) float64 {
+			if rng.Float64() < 0.1 {
+				return 0.9 + rng.Float64()*0.1
+			}
+			return 0.2 + rng.Float64()*0.3
+		}
+	},
+	"gradual": func() func(int) float64 {
+		return ramp(0.2, 0.95, 60)
+	},
+}
+
+// convergence summarizes a batch size curve
+type convergence struct {
+	// steady is the median size over the last quarter of the run
+	steady int
+
+	// settle is the first cycle from which the size stays within 10% of
+	// steady, or -1 if it never does
+	settle int
+
+	// amplitude is the spread of the size over the last quarter of the
+	// run, relative to steady
+	amplitude float64
+}
+
+// measureConvergence derives the convergence of a batch size curve
+func measureConvergence(sizes []int) convergence {
+	tail := slices.Clone(sizes[len(sizes)*3/4:])
+	slices.Sort(tail)
+	c := convergence{steady: tail[len(tail)/2], settle: -1}
+	c.amplitude = float64(tail[len(tail)-1]-tail[0]) / float64(c.steady)
+
+	band := math.Max(1, 0.1*float64(c.steady))
+	for i := len(sizes) - 1; i >= 0; i-- {
+		if math.Abs(float64(sizes[i]-c.steady)) > band {
+			break
+		}
+		c.settle = i
+	}
+	return c
+}
+
+// goldenConvergence bounds the convergence of a controller on a pattern
+type goldenConvergence struct {
+	pattern, controller string
+
+	// steadyMin and steadyMax bound the steady size
+	steadyMin, steadyMax int
+
+	// maxSettle is the latest cycle the size may settle at; -1 means the
+	// pattern keeps the size moving and only the amplitude is bounded
+	maxSettle int
+
+	// maxAmplitude bounds the spread of the steady state
+	maxAmplitude float64
+}
+
+// goldenConvergences are the thresholds every controller is held to, set
+// with some slack around the curves of the current defaults. Runs start
+// at 50 within [5, 200].
+var goldenConvergences = []goldenConvergence{
+	// Steady load inside the band: no change, or a quick settle
+	{"constant", "threshold", 50, 50, 0, 0},
+	{"constant", "aimd", 50, 50, 0, 0},
+	{"constant", "pid", 5, 10, 20, 0.1},
+
+	// A slow sine wave over the whole load range: bounded oscillation
+	{"sinewave", "threshold", 5, 10, -1, 1.0},
+	{"sinewave", "aimd", 5, 10, -1, 1.5},
+	{"sinewave", "pid", 5, 10, -1, 1.25},
+
+	// Rare spikes on a light load
+	{"spikes", "threshold", 5, 10, 65, 0.1},
+	{"spikes", "aimd", 25, 45, -1, 0.5},
+	{"spikes", "pid", 5, 15, -1, 3.5},
+
+	// Load ramping to overload over 60 cycles: reach the minimum soon
+	// after it crosses the threshold
+	{"gradual", "threshold", 5, 5, 50, 0},
+	{"gradual", "aimd", 5, 5, 50, 0},
+	{"gradual", "pid", 5, 5, 50, 0},
+}
+
+func TestSimulation_Convergence(t *testing.T) {
+	controllers := make(map[string]simController, len(simControllers))
+	for _, c := range simControllers {
+		controllers[c.name] = c
+	}
+
+	for _, g := range goldenConvergences {
+		t.Run(g.pattern+"/"+g.controller, func(t *testing.T) {
+			sc := simScenario{
+				initial: 50, min: 5, max: 200,
+				cycles: convergenceCycles,
+				load:   convergencePatterns[g.pattern](),
+			}
+			sizes := simulate(t, sc, controllers[g.controller].new())
+			c := measureConvergence(sizes)
+
+			if c.steady < g.steadyMin || c.steady > g.steadyMax {
+				t.Errorf("Expected steady size in [%d, %d], got %d", g.steadyMin, g.steadyMax, c.steady)
+			}
+			if g.maxSettle >= 0 && (c.settle < 0 || c.settle > g.maxSettle) {
+				t.Errorf("Expected to settle by cycle %d, got %d", g.maxSettle, c.settle)
+			}
+			if c.amplitude > g.maxAmplitude {
+				t.Errorf("Expected amplitude at most %.2f, got %.2f", g.maxAmplitude, c.amplitude)
+			}
+			if t.Failed() {
+				t.Logf("Sizes: %v", sizes)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package batcher
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CoordinatorConfig holds the configuration for a Coordinator
+type CoordinatorConfig struct {
+	// MaxConcurrent is the number of batches the batchers sharing the
+	// coordinator may hand to their handlers at once (default: 1)
+	MaxConcurrent int
+
+	// MinInterval is the least time between two admissions, so that
+	// batchers flushing at the same moment reach the backend one after
+	// the other rather than in a burst (default: 0)
+	MinInterval time.Duration
+}
+
+// Coordinator staggers the flushes of the batchers of one process that
+// share a backend, such as the batchers of several tables writing to the
+// same database. Batchers configured with the same Config.Coordinator
+// take a token from it before they call the handler and return it once
+// the handler returned, so at most MaxConcurrent of their batches are
+// handled at once, admitted in the order they asked and MinInterval
+// apart. Batches waiting for a token keep their place; the items added
+// meanwhile buffer as usual.
+type Coordinator struct {
+	cfg    CoordinatorConfig
+	tokens chan struct{}
+
+	mu   sync.Mutex
+	next time.Time // earliest time of the next admission, with MinInterval
+
+	admitted atomic.Int64
+	waiting  atomic.Int64
+	waitTime atomic.Int64
+}
+
+// NewCoordinator creates a Coordinator with the given configuration
+func NewCoordinator(cfg CoordinatorConfig) *Coordinator {
+	if cfg.MaxConcurrent <= 0 {
+		cfg.MaxConcurrent = 1
+	}
+	return &Coordinator{
+		cfg:    cfg,
+		tokens: make(chan struct{}, cfg.MaxConcurrent),
+	}
+}
+
+// GetStats returns the coordinator statistics
+func (c *Coordinator) GetStats() CoordinatorStats {
+	return CoordinatorStats{
+		InFlight: len(c.tokens),
+		Waiting:  int(c.waiting.Load()),
+		Admitted: c.admitted.Load(),
+		WaitTime: time.Duration(c.waitTime.Load()),
+	}
+}
+
+// CoordinatorStats holds coordinator statistics
+type CoordinatorStats struct {
+	// InFlight is the number of batches being handled, and Waiting the
+	// number waiting for a token
+	InFlight int
+	Waiting  int
+
+	// Admitted is the number of batches handed a token
+	Admitted int64
+
+	// WaitTime is the total time batches waited for a token
+	WaitTime time.Duration
+}
+
+// --- Internal methods ---
+
+// acquire waits for a token and the next admission slot. It gives up
+// with the error of ctx; otherwise the token must be returned with
+// release.
+func (c *Coordinator) acquire(ctx context.Context) error {
+	start := time.Now()
+	c.waiting.Add(1)
+	defer func() {
+		c.waiting.Add(-1)
+		c.waitTime.Add(int64(time.Since(start)))
+	}()
+
+	select {
+	case c.tokens <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if c.cfg.MinInterval > 0 {
+		if err := c.waitForSlot(ctx); err != nil {
+			c.release()
+			return err
+		}
+	}
+	c.admitted.Add(1)
+	return nil
+}
+
+// release returns a token taken by acquire
+func (c *Coordinator) release() {
+	<-c.tokens
+}
+
+// waitForSlot waits until MinInterval passed since the previous admission
+func (c *Coordinator) waitForSlot(ctx context.Context) error {
+	c.mu.Lock()
+	now := time.Now()
+	slot := now
+	if c.next.After(now) {
+		slot = c.next
+	}
+	c.next = slot.Add(c.cfg.MinInterval)
+	c.mu.Unlock()
+
+	if d := slot.Sub(now); d > 0 {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package batcher
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newCoordinatedBatchers returns n batchers sharing c whose handlers take
+// d, the highest number of batches handled at once and the times the
+// handlers were called
+func newCoordinatedBatchers(t *testing.T, c *Coordinator, n int, d time.Duration) ([]*Batcher, func() int64, *[]time.Time) {
+	t.Helper()
+
+	var active, peak atomic.Int64
+	var mu sync.Mutex
+	var starts []time.Time
+	batchers := make([]*Batcher, n)
+	for i := range batchers {
+		b, err := New(Config{
+			InitialBatchSize: 1,
+			Coordinator:      c,
+			HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+				mu.Lock()
+				starts = append(starts, time.Now())
+				mu.Unlock()
+				now := active.Add(1)
+				for {
+					p := peak.Load()
+					if now <= p || peak.CompareAndSwap(p, now) {
+						break
+					}
+				}
+				time.Sleep(d)
+				active.Add(-1)
+				return &LoadFeedback{}, nil
+			},
+		})
+		if err != nil {
+			t.Fatalf("New() failed: %v", err)
+		}
+		t.Cleanup(func() { b.Close(context.Background()) })
+		batchers[i] = b
+	}
+	return batchers, peak.Load, &starts
+}
+
+func TestCoordinator_MaxConcurrent(t *testing.T) {
+	c := NewCoordinator(CoordinatorConfig{MaxConcurrent: 2})
+	batchers, peak, _ := newCoordinatedBatchers(t, c, 5, 10*time.Millisecond)
+
+	var wg sync.WaitGroup
+	for _, b := range batchers {
+		b := b
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 3; i++ {
+				if err := b.Add(context.Background(), i); err != nil {
+					t.Errorf("Add() failed: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if p := peak(); p != 2 {
+		t.Errorf("Expected at most 2 batches handled at once, got %d", p)
+	}
+	stats := c.GetStats()
+	if stats.Admitted != 15 || stats.InFlight != 0 || stats.Waiting != 0 {
+		t.Errorf("Expected 15 admitted and none left, got %+v", stats)
+	}
+	if stats.WaitTime <= 0 {
+		t.Errorf("Expected batches to wait for a token, got %v", stats.WaitTime)
+	}
+}
+
+func TestCoordinator_MinInterval(t *testing.T) {
+	c := NewCoordinator(CoordinatorConfig{MaxConcurrent: 3, MinInterval: 20 * time.Millisecond})
+	batchers, _, starts := newCoordinatedBatchers(t, c, 3, 0)
+
+	var wg sync.WaitGroup
+	for _, b := range batchers {
+		b := b
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b.Add(context.Background(), 1)
+		}()
+	}
+	wg.Wait()
+
+	if len(*starts) != 3 {
+		t.Fatalf("Expected 3 batches, got %d", len(*starts))
+	}
+	for i := 1; i < 3; i++ {
+		if gap := (*starts)[i].Sub((*starts)[i-1]); gap < 15*time.Millisecond {
+			t.Errorf("Expected batches staggered by MinInterval, got a gap of %v", gap)
+		}
+	}
+}
+
+func TestCoordinator_ContextCancelled(t *testing.T) {
+	c := NewCoordinator(CoordinatorConfig{})
+	handled := make(chan struct{})
+	release := make(chan struct{})
+
+	b, err := New(Config{
+		InitialBatchSize: 1,
+		Coordinator:      c,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			if batch[0] == "slow" {
+				close(handled)
+				<-release
+			}
+			return &LoadFeedback{}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	go b.Add(context.Background(), "slow")
+	<-handled
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := b.Add(ctx, "blocked"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded waiting for a token, got %v", err)
+	}
+	close(release)
+
+	if err := b.Add(context.Background(), "next"); err != nil {
+		t.Errorf("Expected the token to be returned, got %v", err)
+	}
+	if stats := c.GetStats(); stats.Admitted != 2 {
+		t.Errorf("Expected 2 admitted batches, got %d", stats.Admitted)
+	}
+}
@@ -0,0 +1,29 @@
+package dashboard
+
+import (
+	"embed"
+	"html/template"
+	"net/http"
+)
+
+// The page and its assets are compiled into the binary so the dashboard
+// works without network access.
+var (
+	//go:embed templates/*.html
+	templateFS embed.FS
+
+	//go:embed static
+	staticFS embed.FS
+
+	templates     = template.Must(template.ParseFS(templateFS, "templates/*.html"))
+	pageTemplate  = templates.Lookup("index.html")
+	multiTemplate = templates.Lookup("multi.html")
+)
+
+// StaticHandler serves the embedded scripts and stylesheets under
+// /static/. Besides the dashboard's own assets it includes chart.js, a
+// dependency-free line chart implementing the subset of the Chart.js
+// API the dashboards use, for pages that draw their own charts.
+func StaticHandler() http.Handler {
+	return http.FileServer(http.FS(staticFS))
+}
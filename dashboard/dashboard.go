@@ -0,0 +1,489 @@
+// Package dashboard serves a live web dashboard for a batcher. It can be
+// mounted in any service next to its own handlers:
+//
+//	d := dashboard.New(b)
+//	defer d.Close()
+//	mux.Handle("/debug/batcher/", http.StripPrefix("/debug/batcher", d))
+package dashboard
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	batcher "github.com/amirafroozeh1/Load-Aware-Batcher"
+)
+
+// Config holds the configuration for a Dashboard
+type Config struct {
+	// Title is shown in the page header (default: the batcher name, or
+	// "Load-Aware Batcher")
+	Title string
+
+	// SampleInterval is how often the batcher statistics are sampled
+	// (default: 500ms)
+	SampleInterval time.Duration
+
+	// History is the number of samples kept for the charts (default: 120)
+	History int
+
+	// Extra are additional series sampled with the batcher statistics,
+	// such as backend metrics the batcher does not see, keyed by name.
+	// Each one gets its own chart.
+	Extra map[string]func() float64
+
+	// Actions are controls shown as buttons on the page, keyed by label,
+	// such as a flush or a simulated load spike. They are invoked with
+	// POST api/actions/{label}, so labels must not contain a slash.
+	// Nothing can be changed through the dashboard unless it is listed
+	// here.
+	Actions map[string]func(ctx context.Context) error
+
+	// PendingLimit is the number of buffered items GET api/pending
+	// shows, oldest first, for debugging a stuck pipeline. The endpoint
+	// is not served if zero (default: 0), since items may hold data that
+	// should not be exposed.
+	PendingLimit int
+
+	// BatchLog is the number of recent batches GET api/batches shows,
+	// newest last, as passed to ObserveBatch. The endpoint is not served
+	// if zero (default: 0).
+	BatchLog int
+
+	// Store, if set, records the session as a run, appending every
+	// sample, and lets the page replay the runs recorded earlier. The
+	// Dashboard does not close the Store.
+	Store *Store
+
+	// RunName and RunMeta describe the recorded run (default name: the
+	// Title)
+	RunName string
+	RunMeta map[string]string
+}
+
+// Snapshot is one sample of the batcher statistics
+type Snapshot struct {
+	// Timestamp is the sample time in Unix milliseconds
+	Timestamp int64 `json:"timestamp"`
+
+	BatchSize           int     `json:"batchSize"`
+	PendingItems        int     `json:"pendingItems"`
+	InFlightItems       int     `json:"inFlightItems"`
+	LoadScore           float64 `json:"loadScore"`
+	AggregatedLoadScore float64 `json:"aggregatedLoadScore"`
+	Batches             int64   `json:"batches"`
+	HandlerErrors       int64   `json:"handlerErrors"`
+	ShedItems           int64   `json:"shedItems"`
+	Shedding            bool    `json:"shedding"`
+	Paused              bool    `json:"paused"`
+	MemoryPressure      float64 `json:"memoryPressure"`
+
+	// HandlerTimeMs is the mean handler time of the batches completed
+	// since the previous sample, zero if there were none
+	HandlerTimeMs float64 `json:"handlerTimeMs"`
+
+	// Extra holds the values of Config.Extra
+	Extra map[string]float64 `json:"extra,omitempty"`
+}
+
+// Dashboard is an http.Handler serving a live view of one batcher:
+//
+//	/             the dashboard page
+//	/api/metrics  the sampled history as a JSON array of Snapshot
+//	/api/status   the current batcher.Stats as JSON
+//	/api/actions/ the Config.Actions, invoked with POST
+//	/api/batches  the recent batches as a JSON array of Batch, with Config.BatchLog
+//	/api/runs     the runs recorded in Config.Store, see Store.Handler
+//	/static/      the embedded scripts and stylesheets
+//
+// The page uses relative URLs, so it can be mounted under any prefix with
+// http.StripPrefix as long as the prefix is visited with a trailing slash.
+type Dashboard struct {
+	b    *batcher.Batcher
+	cfg  Config
+	mux  *http.ServeMux
+	page []byte
+
+	mu      sync.RWMutex
+	history []Snapshot // ring buffer of cfg.History samples
+	next    int
+	full    bool
+	last    batcher.Stats
+
+	run       Run // recorded run, empty without Config.Store
+	recordErr error
+
+	batches     []Batch // ring buffer of cfg.BatchLog results
+	nextBatch   int
+	batchesFull bool
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// New starts sampling b with the default configuration
+func New(b *batcher.Batcher) *Dashboard {
+	return NewWithConfig(b, Config{})
+}
+
+// NewWithConfig starts sampling b with the given configuration. Close
+// stops the sampling; it does not close the batcher.
+func NewWithConfig(b *batcher.Batcher, cfg Config) *Dashboard {
+	if cfg.SampleInterval <= 0 {
+		cfg.SampleInterval = 500 * time.Millisecond
+	}
+	if cfg.History <= 0 {
+		cfg.History = 120
+	}
+
+	d := &Dashboard{
+		b:       b,
+		cfg:     cfg,
+		history: make([]Snapshot, cfg.History),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	d.last = b.GetStats()
+	if cfg.Title == "" {
+		d.cfg.Title = d.last.Name
+	}
+	if d.cfg.Title == "" {
+		d.cfg.Title = "Load-Aware Batcher"
+	}
+	if cfg.Store != nil {
+		name := cfg.RunName
+		if name == "" {
+			name = d.cfg.Title
+		}
+		d.run, d.recordErr = cfg.Store.StartRun(name, cfg.RunMeta)
+	}
+	d.page = d.render()
+
+	d.mux = http.NewServeMux()
+	d.mux.HandleFunc("/", d.serveIndex)
+	d.mux.HandleFunc("/api/metrics", d.serveMetrics)
+	d.mux.HandleFunc("/api/status", d.serveStatus)
+	d.mux.HandleFunc("/api/actions/", d.serveAction)
+	if cfg.PendingLimit > 0 {
+		d.mux.HandleFunc("/api/pending", d.servePending)
+	}
+	if cfg.BatchLog > 0 {
+		d.batches = make([]Batch, cfg.BatchLog)
+		d.mux.HandleFunc("/api/batches", d.serveBatches)
+	}
+	if cfg.Store != nil {
+		d.mux.Handle("/api/runs", cfg.Store.Handler())
+		d.mux.Handle("/api/runs/", cfg.Store.Handler())
+	}
+	d.mux.Handle("/static/", StaticHandler())
+
+	d.sample()
+	go d.loop()
+	return d
+}
+
+// ServeHTTP implements http.Handler
+func (d *Dashboard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.mux.ServeHTTP(w, r)
+}
+
+// History returns the sampled snapshots, oldest first
+func (d *Dashboard) History() []Snapshot {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if !d.full {
+		return append([]Snapshot(nil), d.history[:d.next]...)
+	}
+	out := make([]Snapshot, 0, len(d.history))
+	out = append(out, d.history[d.next:]...)
+	return append(out, d.history[:d.next]...)
+}
+
+// ObserveBatch logs a batch for api/batches. It is meant to be called
+// from batcher.Config.OnBatch, and does nothing without Config.BatchLog.
+func (d *Dashboard) ObserveBatch(r batcher.BatchResult) {
+	if len(d.batches) == 0 {
+		return
+	}
+	batch := Batch{
+		ID:         r.BatchID,
+		Size:       r.Size,
+		Reason:     r.Reason.String(),
+		StartedAt:  r.StartedAt.UnixMilli(),
+		DurationMs: float64(r.Duration) / float64(time.Millisecond),
+		Attempt:    r.Attempt,
+	}
+	if r.Feedback != nil {
+		batch.LoadScore = finite(r.Feedback.LoadScore())
+	}
+	if r.Err != nil {
+		batch.Error = r.Err.Error()
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.batches[d.nextBatch] = batch
+	d.nextBatch = (d.nextBatch + 1) % len(d.batches)
+	if d.nextBatch == 0 {
+		d.batchesFull = true
+	}
+}
+
+// Run returns the run recorded in Config.Store, and false if there is
+// none
+func (d *Dashboard) Run() (Run, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.run, d.run.ID != ""
+}
+
+// RecordErr returns the first error recording the run in Config.Store.
+// Recording errors do not affect the live view.
+func (d *Dashboard) RecordErr() error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.recordErr
+}
+
+// Close stops sampling and, with Config.Store, ends the recorded run.
+// The handler keeps serving the last history.
+func (d *Dashboard) Close() {
+	d.once.Do(func() {
+		close(d.stop)
+		<-d.done
+		if id := d.run.ID; id != "" {
+			d.record(d.cfg.Store.EndRun(id))
+		}
+	})
+}
+
+// --- Internal methods ---
+
+func (d *Dashboard) loop() {
+	defer close(d.done)
+
+	ticker := time.NewTicker(d.cfg.SampleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			d.sample()
+		case <-d.stop:
+			return
+		}
+	}
+}
+
+func (d *Dashboard) sample() {
+	stats := d.b.GetStats()
+
+	s := Snapshot{
+		Timestamp:           time.Now().UnixMilli(),
+		BatchSize:           stats.CurrentBatchSize,
+		PendingItems:        stats.PendingItems,
+		InFlightItems:       stats.InFlightItems,
+		LoadScore:           stats.AverageLoadScore,
+		AggregatedLoadScore: stats.AggregatedLoadScore,
+		Batches:             stats.Batches,
+		HandlerErrors:       stats.HandlerErrors,
+		ShedItems:           stats.ShedItems,
+		Shedding:            stats.Shedding,
+		Paused:              !stats.PausedUntil.IsZero(),
+		MemoryPressure:      stats.MemoryPressure,
+	}
+	if len(d.cfg.Extra) > 0 {
+		s.Extra = make(map[string]float64, len(d.cfg.Extra))
+		for name, fn := range d.cfg.Extra {
+			s.Extra[name] = finite(fn())
+		}
+	}
+
+	d.mu.Lock()
+	if n := stats.Batches - d.last.Batches; n > 0 {
+		s.HandlerTimeMs = float64(stats.HandlerTime-d.last.HandlerTime) / float64(n) / float64(time.Millisecond)
+	}
+	d.last = stats
+
+	d.history[d.next] = s
+	d.next = (d.next + 1) % len(d.history)
+	if d.next == 0 {
+		d.full = true
+	}
+	d.mu.Unlock()
+
+	if id := d.run.ID; id != "" {
+		d.record(d.cfg.Store.Append(id, s))
+	}
+}
+
+// record keeps the first recording error
+func (d *Dashboard) record(err error) {
+	if err == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.recordErr == nil {
+		d.recordErr = err
+	}
+}
+
+func (d *Dashboard) serveIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(d.page)
+}
+
+func (d *Dashboard) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, d.History())
+}
+
+func (d *Dashboard) serveStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, d.status())
+}
+
+func (d *Dashboard) serveAction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	action, ok := d.cfg.Actions[strings.TrimPrefix(r.URL.Path, "/api/actions/")]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if err := action(r.Context()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, map[string]string{"status": "ok"})
+}
+
+// Batch is one entry of api/batches, see batcher.BatchResult
+type Batch struct {
+	ID     string `json:"id"`
+	Size   int    `json:"size"`
+	Reason string `json:"reason"`
+
+	// StartedAt is when the handler was called, in Unix milliseconds
+	StartedAt  int64   `json:"startedAt"`
+	DurationMs float64 `json:"durationMs"`
+	Attempt    int     `json:"attempt"`
+
+	// LoadScore is the score of the feedback of the batch, zero if it
+	// returned none
+	LoadScore float64 `json:"loadScore"`
+
+	// Error is the error of the batch, empty if it succeeded
+	Error string `json:"error,omitempty"`
+}
+
+func (d *Dashboard) serveBatches(w http.ResponseWriter, r *http.Request) {
+	d.mu.RLock()
+	var out []Batch
+	if d.batchesFull {
+		out = append(out, d.batches[d.nextBatch:]...)
+	}
+	out = append(out, d.batches[:d.nextBatch]...)
+	d.mu.RUnlock()
+
+	if out == nil {
+		out = []Batch{}
+	}
+	writeJSON(w, out)
+}
+
+// Pending is the response of api/pending
+type Pending struct {
+	// Count is the number of buffered items, which may exceed len(Items)
+	Count int `json:"count"`
+
+	// OldestAgeMs is how long the oldest item has waited
+	OldestAgeMs float64 `json:"oldestAgeMs"`
+
+	// Items are the oldest buffered items, JSON encoded where possible
+	// and formatted with fmt otherwise
+	Items []any `json:"items"`
+}
+
+func (d *Dashboard) servePending(w http.ResponseWriter, r *http.Request) {
+	stats := d.b.GetStats()
+	items := d.b.PeekPending(d.cfg.PendingLimit)
+	p := Pending{
+		Count:       stats.PendingItems,
+		OldestAgeMs: float64(stats.OldestPendingAge) / float64(time.Millisecond),
+		Items:       make([]any, len(items)),
+	}
+	for i, item := range items {
+		if raw, err := json.Marshal(item); err == nil {
+			p.Items[i] = json.RawMessage(raw)
+		} else {
+			p.Items[i] = fmt.Sprint(item)
+		}
+	}
+	writeJSON(w, p)
+}
+
+// status returns the current statistics in a form JSON can encode
+func (d *Dashboard) status() batcher.Stats {
+	stats := d.b.GetStats()
+	for k, v := range stats.StrategyEstimates {
+		stats.StrategyEstimates[k] = finite(v)
+	}
+	return stats
+}
+
+func (d *Dashboard) render() []byte {
+	var buf bytes.Buffer
+	err := pageTemplate.Execute(&buf, struct {
+		Title   string
+		Extra   []string
+		Actions []string
+		Runs    bool
+	}{d.cfg.Title, sortedKeys(d.cfg.Extra), sortedKeys(d.cfg.Actions), d.cfg.Store != nil})
+	if err != nil {
+		// The template is static, so this is a programming error
+		panic(err)
+	}
+	return buf.Bytes()
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// finite maps NaN and infinities, which JSON cannot encode, to zero
+func finite(v float64) float64 {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0
+	}
+	return v
+}
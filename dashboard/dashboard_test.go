@@ -0,0 +1,255 @@
+package dashboard
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	batcher "github.com/amirafroozeh1/Load-Aware-Batcher"
+)
+
+func newBatcher(t *testing.T) *batcher.Batcher {
+	t.Helper()
+	b, err := batcher.New(batcher.Config{
+		Name:             "orders",
+		Labels:           map[string]string{"tenant": "acme"},
+		InitialBatchSize: 2,
+		HandlerFunc: func(ctx context.Context, batch []any) (*batcher.LoadFeedback, error) {
+			return &batcher.LoadFeedback{CPULoad: 0.5}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	t.Cleanup(func() { b.Close(context.Background()) })
+	return b
+}
+
+func get(t *testing.T, h http.Handler, path string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec
+}
+
+func TestDashboard_Endpoints(t *testing.T) {
+	b := newBatcher(t)
+	for i := 0; i < 5; i++ {
+		b.Add(context.Background(), i)
+	}
+
+	d := NewWithConfig(b, Config{
+		SampleInterval: time.Hour,
+		Extra:          map[string]func() float64{"queue": func() float64 { return 7 }},
+	})
+	defer d.Close()
+
+	rec := get(t, d, "/")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 for /, got %d", rec.Code)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, "<title>orders</title>") || !strings.Contains(body, `"queue"`) {
+		t.Errorf("Expected page titled after the batcher with the extra series, got %q", body[:200])
+	}
+
+	rec = get(t, d, "/api/metrics")
+	var metrics []Snapshot
+	if err := json.Unmarshal(rec.Body.Bytes(), &metrics); err != nil {
+		t.Fatalf("Invalid metrics JSON: %v", err)
+	}
+	if len(metrics) != 1 {
+		t.Fatalf("Expected 1 snapshot, got %d", len(metrics))
+	}
+	if m := metrics[0]; m.BatchSize != 2 || m.PendingItems != 1 || m.Extra["queue"] != 7 {
+		t.Errorf("Unexpected snapshot: %+v", m)
+	}
+
+	rec = get(t, d, "/api/status")
+	var stats batcher.Stats
+	if err := json.Unmarshal(rec.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("Invalid status JSON: %v", err)
+	}
+	if stats.Name != "orders" || stats.Labels["tenant"] != "acme" || stats.Batches != 2 {
+		t.Errorf("Unexpected status: %+v", stats)
+	}
+
+	if rec := get(t, d, "/missing"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown path, got %d", rec.Code)
+	}
+}
+
+func TestDashboard_MountedUnderPrefix(t *testing.T) {
+	d := New(newBatcher(t))
+	defer d.Close()
+
+	mux := http.NewServeMux()
+	mux.Handle("/debug/batcher/", http.StripPrefix("/debug/batcher", d))
+
+	if rec := get(t, mux, "/debug/batcher/api/metrics"); rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 under prefix, got %d", rec.Code)
+	}
+	if rec := get(t, mux, "/debug/batcher"); rec.Code != http.StatusMovedPermanently {
+		t.Errorf("Expected redirect to the trailing slash, got %d", rec.Code)
+	}
+}
+
+func TestDashboard_HistoryIsBounded(t *testing.T) {
+	b := newBatcher(t)
+	d := NewWithConfig(b, Config{SampleInterval: time.Hour, History: 3})
+	d.Close()
+
+	for i := 0; i < 4; i++ {
+		b.Add(context.Background(), i)
+		d.sample()
+	}
+
+	history := d.History()
+	if len(history) != 3 {
+		t.Fatalf("Expected 3 snapshots, got %d", len(history))
+	}
+	for i, want := range []int64{1, 1, 2} {
+		if history[i].Batches != want {
+			t.Errorf("Expected snapshot %d to have %d batches, got %d", i, want, history[i].Batches)
+		}
+	}
+}
+
+func TestDashboard_ServesEmbeddedAssets(t *testing.T) {
+	d := New(newBatcher(t))
+	defer d.Close()
+
+	page := get(t, d, "/").Body.String()
+	if strings.Contains(page, "http://") || strings.Contains(page, "https://") {
+		t.Errorf("Expected no external URLs in the page")
+	}
+
+	for _, path := range []string{"/static/chart.js", "/static/dashboard.js", "/static/dashboard.css"} {
+		rec := get(t, d, path)
+		if rec.Code != http.StatusOK || rec.Body.Len() == 0 {
+			t.Errorf("Expected %s to be served, got %d", path, rec.Code)
+		}
+	}
+}
+
+func TestDashboard_Actions(t *testing.T) {
+	b := newBatcher(t)
+	flushed := 0
+	d := NewWithConfig(b, Config{
+		SampleInterval: time.Hour,
+		Actions: map[string]func(ctx context.Context) error{
+			"Flush": func(ctx context.Context) error {
+				flushed++
+				return b.Flush(ctx)
+			},
+		},
+	})
+	defer d.Close()
+
+	if !strings.Contains(get(t, d, "/").Body.String(), `"Flush"`) {
+		t.Errorf("Expected the page to list the action")
+	}
+
+	post := func(path string) int {
+		rec := httptest.NewRecorder()
+		d.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, nil))
+		return rec.Code
+	}
+	if code := post("/api/actions/Flush"); code != http.StatusOK || flushed != 1 {
+		t.Errorf("Expected the action to run, got %d and %d calls", code, flushed)
+	}
+	if code := post("/api/actions/Drop"); code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown action, got %d", code)
+	}
+	if code := get(t, d, "/api/actions/Flush").Code; code != http.StatusMethodNotAllowed || flushed != 1 {
+		t.Errorf("Expected GET to be rejected, got %d", code)
+	}
+}
+
+func TestDashboard_Pending(t *testing.T) {
+	b, err := batcher.New(batcher.Config{
+		InitialBatchSize: 10,
+		Timeout:          time.Hour,
+		HandlerFunc: func(ctx context.Context, batch []any) (*batcher.LoadFeedback, error) {
+			return nil, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+	b.Add(context.Background(), map[string]int{"order": 1})
+	b.Add(context.Background(), func() {})
+	b.Add(context.Background(), 3)
+
+	d := NewWithConfig(b, Config{SampleInterval: time.Hour})
+	if code := get(t, d, "/api/pending").Code; code != http.StatusNotFound {
+		t.Errorf("Expected api/pending to be disabled by default, got %d", code)
+	}
+	d.Close()
+
+	d = NewWithConfig(b, Config{SampleInterval: time.Hour, PendingLimit: 2})
+	defer d.Close()
+	rec := get(t, d, "/api/pending")
+	var p struct {
+		Count int               `json:"count"`
+		Items []json.RawMessage `json:"items"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+		t.Fatalf("Failed to decode api/pending: %v", err)
+	}
+	if p.Count != 3 || len(p.Items) != 2 {
+		t.Fatalf("Expected 3 pending with 2 shown, got %d and %d", p.Count, len(p.Items))
+	}
+	if string(p.Items[0]) != `{"order":1}` {
+		t.Errorf("Expected the item JSON encoded, got %s", p.Items[0])
+	}
+	if !strings.HasPrefix(string(p.Items[1]), `"0x`) {
+		t.Errorf("Expected an item JSON cannot encode to be formatted, got %s", p.Items[1])
+	}
+}
+
+func TestDashboard_Batches(t *testing.T) {
+	var d *Dashboard
+	b, err := batcher.New(batcher.Config{
+		InitialBatchSize: 2,
+		HandlerFunc: func(ctx context.Context, batch []any) (*batcher.LoadFeedback, error) {
+			return &batcher.LoadFeedback{CPULoad: 0.5}, nil
+		},
+		OnBatch: func(r batcher.BatchResult) { d.ObserveBatch(r) },
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+	d = NewWithConfig(b, Config{SampleInterval: time.Hour, BatchLog: 2})
+	defer d.Close()
+
+	for i := 0; i < 5; i++ {
+		b.Add(context.Background(), i)
+	}
+	b.Flush(context.Background())
+
+	var batches []Batch
+	if err := json.Unmarshal(get(t, d, "/api/batches").Body.Bytes(), &batches); err != nil {
+		t.Fatalf("Expected JSON batches: %v", err)
+	}
+	if len(batches) != 2 {
+		t.Fatalf("Expected the last 2 batches, got %d", len(batches))
+	}
+	if batches[0].Reason != "size" || batches[0].Size != 2 {
+		t.Errorf("Expected a size flush of 2, got %+v", batches[0])
+	}
+	if batches[1].Reason != "manual" || batches[1].Size != 1 || batches[1].LoadScore == 0 {
+		t.Errorf("Expected a manual flush of 1 with a load score, got %+v", batches[1])
+	}
+
+	plain := New(newBatcher(t))
+	defer plain.Close()
+	if rec := get(t, plain, "/api/batches"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 without BatchLog, got %d", rec.Code)
+	}
+}
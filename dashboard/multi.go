@@ -0,0 +1,187 @@
+package dashboard
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	batcher "github.com/amirafroozeh1/Load-Aware-Batcher"
+)
+
+var (
+	// ErrDuplicate is returned when adding a batcher under a name already in use
+	ErrDuplicate = errors.New("dashboard: batcher already added")
+
+	// ErrInvalidName is returned for an empty name or one containing a slash
+	ErrInvalidName = errors.New("dashboard: invalid batcher name")
+)
+
+// Multi is an http.Handler serving one page with a panel per batcher, for
+// services running several batchers side by side, such as one per tenant
+// or the shards of a batcher.Router:
+//
+//	/               the overview page
+//	/api/batchers   every batcher with its stats and history as JSON
+//	/b/{name}/      the full Dashboard of one batcher
+//
+// Batchers can be added and removed while the page is open.
+type Multi struct {
+	mux  *http.ServeMux
+	page []byte
+
+	mu         sync.RWMutex
+	dashboards map[string]*Dashboard
+}
+
+// panel is the overview of one batcher served by /api/batchers
+type panel struct {
+	Name    string            `json:"name"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	Actions []string          `json:"actions,omitempty"`
+	Stats   batcher.Stats     `json:"stats"`
+	Metrics []Snapshot        `json:"metrics"`
+}
+
+// NewMulti creates an empty Multi with the given page title (default:
+// "Load-Aware Batchers")
+func NewMulti(title string) *Multi {
+	if title == "" {
+		title = "Load-Aware Batchers"
+	}
+
+	m := &Multi{dashboards: make(map[string]*Dashboard)}
+
+	var buf bytes.Buffer
+	if err := multiTemplate.Execute(&buf, struct{ Title string }{title}); err != nil {
+		// The template is static, so this is a programming error
+		panic(err)
+	}
+	m.page = buf.Bytes()
+
+	m.mux = http.NewServeMux()
+	m.mux.HandleFunc("/", m.serveIndex)
+	m.mux.HandleFunc("/api/batchers", m.serveBatchers)
+	m.mux.HandleFunc("/b/", m.serveBatcher)
+	m.mux.Handle("/static/", StaticHandler())
+	return m
+}
+
+// Add starts sampling b and shows it under name. cfg.Title defaults to
+// name.
+func (m *Multi) Add(name string, b *batcher.Batcher, cfg Config) error {
+	if name == "" || strings.Contains(name, "/") {
+		return ErrInvalidName
+	}
+	if cfg.Title == "" {
+		cfg.Title = name
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.dashboards[name]; ok {
+		return ErrDuplicate
+	}
+	m.dashboards[name] = NewWithConfig(b, cfg)
+	return nil
+}
+
+// Remove stops sampling the named batcher and removes its panel. It
+// reports whether the batcher was shown.
+func (m *Multi) Remove(name string) bool {
+	m.mu.Lock()
+	d, ok := m.dashboards[name]
+	delete(m.dashboards, name)
+	m.mu.Unlock()
+
+	if ok {
+		d.Close()
+	}
+	return ok
+}
+
+// Names returns the names of the batchers shown, sorted
+func (m *Multi) Names() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return sortedKeys(m.dashboards)
+}
+
+// Close stops sampling every batcher
+func (m *Multi) Close() {
+	m.mu.Lock()
+	dashboards := m.dashboards
+	m.dashboards = make(map[string]*Dashboard)
+	m.mu.Unlock()
+
+	for _, d := range dashboards {
+		d.Close()
+	}
+}
+
+// ServeHTTP implements http.Handler
+func (m *Multi) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mux.ServeHTTP(w, r)
+}
+
+// --- Internal methods ---
+
+func (m *Multi) lookup(name string) (*Dashboard, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	d, ok := m.dashboards[name]
+	return d, ok
+}
+
+func (m *Multi) serveIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(m.page)
+}
+
+func (m *Multi) serveBatchers(w http.ResponseWriter, r *http.Request) {
+	m.mu.RLock()
+	panels := make([]panel, 0, len(m.dashboards))
+	for name, d := range m.dashboards {
+		stats := d.status()
+		panels = append(panels, panel{
+			Name:    name,
+			Labels:  stats.Labels,
+			Actions: sortedKeys(d.cfg.Actions),
+			Stats:   stats,
+			Metrics: d.History(),
+		})
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(panels, func(i, j int) bool { return panels[i].Name < panels[j].Name })
+	writeJSON(w, panels)
+}
+
+// serveBatcher hands /b/{name}/... to the Dashboard of that batcher
+func (m *Multi) serveBatcher(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, "/b/")
+	name, _, found := strings.Cut(rest, "/")
+
+	d, ok := m.lookup(name)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if !found {
+		// The page uses relative URLs, so it must be served below name/.
+		// The Location is relative too, as the Multi may itself be
+		// mounted below a stripped prefix.
+		w.Header().Set("Location", url.PathEscape(name)+"/")
+		w.WriteHeader(http.StatusMovedPermanently)
+		return
+	}
+	http.StripPrefix("/b/"+name, d).ServeHTTP(w, r)
+}
@@ -0,0 +1,57 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestMulti(t *testing.T) {
+	m := NewMulti("")
+	defer m.Close()
+
+	if err := m.Add("tenant-a", newBatcher(t), Config{}); err != nil {
+		t.Fatalf("Add() failed: %v", err)
+	}
+	if err := m.Add("tenant-b", newBatcher(t), Config{}); err != nil {
+		t.Fatalf("Add() failed: %v", err)
+	}
+	if err := m.Add("tenant-a", newBatcher(t), Config{}); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("Expected ErrDuplicate, got %v", err)
+	}
+	for _, name := range []string{"", "a/b"} {
+		if err := m.Add(name, newBatcher(t), Config{}); !errors.Is(err, ErrInvalidName) {
+			t.Errorf("Expected ErrInvalidName for %q, got %v", name, err)
+		}
+	}
+
+	var panels []panel
+	if err := json.Unmarshal(get(t, m, "/api/batchers").Body.Bytes(), &panels); err != nil {
+		t.Fatalf("Invalid batchers JSON: %v", err)
+	}
+	if len(panels) != 2 || panels[0].Name != "tenant-a" || panels[1].Name != "tenant-b" {
+		t.Fatalf("Expected two sorted panels, got %+v", panels)
+	}
+	if panels[0].Stats.Name != "orders" || len(panels[0].Metrics) != 1 {
+		t.Errorf("Unexpected panel: %+v", panels[0])
+	}
+
+	rec := get(t, m, "/b/tenant-a")
+	if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "tenant-a/" {
+		t.Errorf("Expected relative redirect to tenant-a/, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+	if rec := get(t, m, "/b/tenant-a/api/status"); rec.Code != http.StatusOK {
+		t.Errorf("Expected the batcher dashboard to be served, got %d", rec.Code)
+	}
+	if rec := get(t, m, "/b/unknown/"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown batcher, got %d", rec.Code)
+	}
+
+	if !m.Remove("tenant-a") || m.Remove("tenant-a") {
+		t.Errorf("Expected Remove to report the batcher once")
+	}
+	if names := m.Names(); len(names) != 1 || names[0] != "tenant-b" {
+		t.Errorf("Expected [tenant-b], got %v", names)
+	}
+}
@@ -0,0 +1,254 @@
+// chart.js draws the line charts of the batcher dashboards. It implements
+// the small subset of the Chart.js configuration the dashboards use, so
+// the pages work without loading anything from a CDN:
+//
+//   new Chart(canvasOrContext, {
+//       type: 'line',
+//       data: { labels: [...], datasets: [{ label, data, borderColor,
+//               backgroundColor, borderWidth, fill, tension, yAxisID }] },
+//       options: { plugins: { legend: { display, labels: { color } } },
+//                  scales: { y: { min, max, position, display, grid: { color,
+//                  drawOnChartArea }, ticks: { color } }, y1: {...} } }
+//   });
+//   chart.update();
+//
+// Points that are null are skipped. Unknown options are ignored.
+(function (global) {
+    'use strict';
+
+    const FONT = '11px -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, sans-serif';
+    const PAD = 6;
+    const TICKS = 4;
+
+    function axisIDs(datasets) {
+        const ids = [];
+        for (const ds of datasets) {
+            const id = ds.yAxisID || 'y';
+            if (!ids.includes(id)) ids.push(id);
+        }
+        return ids.length ? ids : ['y'];
+    }
+
+    function niceMax(v) {
+        if (v <= 0) return 1;
+        const step = Math.pow(10, Math.floor(Math.log10(v)));
+        for (const m of [1, 2, 2.5, 5, 10]) {
+            if (m * step >= v) return m * step;
+        }
+        return 10 * step;
+    }
+
+    function formatTick(v, range) {
+        if (range >= 10) return Math.round(v).toString();
+        if (range >= 1) return v.toFixed(1);
+        return v.toFixed(2);
+    }
+
+    class Chart {
+        constructor(target, config) {
+            this.canvas = target.canvas || target;
+            this.ctx = this.canvas.getContext('2d');
+            this.config = config || {};
+            this.data = this.config.data || { labels: [], datasets: [] };
+            this.options = this.config.options || {};
+            if (this.options.responsive !== false) {
+                this._onResize = () => this.update();
+                global.addEventListener('resize', this._onResize);
+            }
+            this.update();
+        }
+
+        destroy() {
+            if (this._onResize) global.removeEventListener('resize', this._onResize);
+        }
+
+        update() {
+            const canvas = this.canvas;
+            const ratio = global.devicePixelRatio || 1;
+            const width = canvas.clientWidth || canvas.width;
+            const height = canvas.clientHeight || canvas.height;
+            canvas.width = width * ratio;
+            canvas.height = height * ratio;
+
+            const ctx = this.ctx;
+            ctx.setTransform(ratio, 0, 0, ratio, 0, 0);
+            ctx.clearRect(0, 0, width, height);
+            ctx.font = FONT;
+
+            const datasets = this.data.datasets || [];
+            const scales = this.options.scales || {};
+            const area = { left: PAD, right: width - PAD, top: PAD, bottom: height - PAD };
+
+            area.top += this._drawLegend(datasets, width);
+
+            const axes = axisIDs(datasets).map(id => this._axis(id, scales[id] || {}, datasets));
+            for (const axis of axes) {
+                if (!axis.display) continue;
+                const w = Math.max(...axis.ticks.map(t => ctx.measureText(t.label).width)) + PAD;
+                if (axis.position === 'right') area.right -= w;
+                else area.left += w;
+            }
+            if (area.right <= area.left || area.bottom <= area.top) return;
+
+            axes.forEach((axis, i) => this._drawAxis(axis, area, i === 0));
+
+            const points = Math.max(
+                (this.data.labels || []).length,
+                ...datasets.map(ds => (ds.data || []).length)
+            );
+            for (const ds of datasets) {
+                const axis = axes.find(a => a.id === (ds.yAxisID || 'y'));
+                this._drawLine(ds, axis, area, points);
+            }
+        }
+
+        // --- Internal methods ---
+
+        _axis(id, opts, datasets) {
+            let lo = Infinity, hi = -Infinity;
+            for (const ds of datasets) {
+                if ((ds.yAxisID || 'y') !== id) continue;
+                for (const v of ds.data || []) {
+                    if (v === null || v === undefined || !isFinite(v)) continue;
+                    lo = Math.min(lo, v);
+                    hi = Math.max(hi, v);
+                }
+            }
+            if (lo === Infinity) { lo = 0; hi = 1; }
+
+            const min = opts.min !== undefined ? opts.min : Math.min(0, lo);
+            let max = opts.max !== undefined ? opts.max : min + niceMax(hi - min);
+            if (max <= min) max = min + 1;
+
+            const ticks = [];
+            for (let i = 0; i <= TICKS; i++) {
+                const v = min + (max - min) * i / TICKS;
+                ticks.push({ value: v, label: formatTick(v, max - min) });
+            }
+            return {
+                id,
+                min,
+                max,
+                ticks,
+                display: opts.display !== false,
+                position: opts.position || (id === 'y' ? 'left' : 'right'),
+                grid: opts.grid || {},
+                tickColor: (opts.ticks && opts.ticks.color) || '#8e8e8e',
+            };
+        }
+
+        _drawLegend(datasets, width) {
+            const legend = (this.options.plugins || {}).legend || {};
+            if (legend.display === false || !datasets.some(ds => ds.label)) return 0;
+
+            const ctx = this.ctx;
+            const color = (legend.labels && legend.labels.color) || '#8e8e8e';
+            const box = 10, gap = 16, lineHeight = 18;
+            const items = datasets.filter(ds => ds.label);
+            const total = items.reduce((w, ds) => w + box + 6 + ctx.measureText(ds.label).width + gap, -gap);
+
+            let x = Math.max(PAD, (width - total) / 2);
+            const y = PAD + lineHeight / 2;
+            ctx.textBaseline = 'middle';
+            for (const ds of items) {
+                ctx.fillStyle = ds.borderColor || color;
+                ctx.fillRect(x, y - box / 2, box, box);
+                x += box + 6;
+                ctx.fillStyle = color;
+                ctx.textAlign = 'left';
+                ctx.fillText(ds.label, x, y);
+                x += ctx.measureText(ds.label).width + gap;
+            }
+            return lineHeight + PAD;
+        }
+
+        _drawAxis(axis, area, primary) {
+            const ctx = this.ctx;
+            const drawGrid = primary && axis.grid.drawOnChartArea !== false && axis.grid.display !== false;
+            for (const t of axis.ticks) {
+                const y = this._y(t.value, axis, area);
+                if (drawGrid) {
+                    ctx.strokeStyle = axis.grid.color || 'rgba(128, 128, 128, 0.2)';
+                    ctx.lineWidth = 1;
+                    ctx.beginPath();
+                    ctx.moveTo(area.left, Math.round(y) + 0.5);
+                    ctx.lineTo(area.right, Math.round(y) + 0.5);
+                    ctx.stroke();
+                }
+                if (axis.display) {
+                    ctx.fillStyle = axis.tickColor;
+                    ctx.textBaseline = 'middle';
+                    if (axis.position === 'right') {
+                        ctx.textAlign = 'left';
+                        ctx.fillText(t.label, area.right + PAD, y);
+                    } else {
+                        ctx.textAlign = 'right';
+                        ctx.fillText(t.label, area.left - PAD, y);
+                    }
+                }
+            }
+        }
+
+        _drawLine(ds, axis, area, points) {
+            const data = ds.data || [];
+            if (points < 2) return;
+
+            const ctx = this.ctx;
+            const x = i => area.left + (area.right - area.left) * i / (points - 1);
+            const tension = ds.tension || 0;
+
+            // Split the data into runs of consecutive non-null points
+            const runs = [];
+            let run = [];
+            for (let i = 0; i < data.length; i++) {
+                const v = data[i];
+                if (v === null || v === undefined || !isFinite(v)) {
+                    if (run.length) runs.push(run);
+                    run = [];
+                    continue;
+                }
+                const clamped = Math.min(Math.max(v, axis.min), axis.max);
+                run.push({ x: x(i), y: this._y(clamped, axis, area) });
+            }
+            if (run.length) runs.push(run);
+
+            for (const pts of runs) {
+                ctx.beginPath();
+                this._path(pts, tension);
+                if (ds.fill && ds.backgroundColor) {
+                    ctx.lineTo(pts[pts.length - 1].x, area.bottom);
+                    ctx.lineTo(pts[0].x, area.bottom);
+                    ctx.closePath();
+                    ctx.fillStyle = ds.backgroundColor;
+                    ctx.fill();
+                    ctx.beginPath();
+                    this._path(pts, tension);
+                }
+                ctx.strokeStyle = ds.borderColor || '#5794f2';
+                ctx.lineWidth = ds.borderWidth || 2;
+                ctx.lineJoin = 'round';
+                ctx.stroke();
+            }
+        }
+
+        _path(pts, tension) {
+            const ctx = this.ctx;
+            ctx.moveTo(pts[0].x, pts[0].y);
+            for (let i = 1; i < pts.length; i++) {
+                const p = pts[i - 1], q = pts[i];
+                if (tension > 0) {
+                    const dx = (q.x - p.x) * Math.min(tension, 0.5);
+                    ctx.bezierCurveTo(p.x + dx, p.y, q.x - dx, q.y, q.x, q.y);
+                } else {
+                    ctx.lineTo(q.x, q.y);
+                }
+            }
+        }
+
+        _y(v, axis, area) {
+            return area.bottom - (v - axis.min) / (axis.max - axis.min) * (area.bottom - area.top);
+        }
+    }
+
+    global.Chart = Chart;
+})(window);
@@ -0,0 +1,145 @@
+// dashboard.js polls the dashboard API and renders the batcher state
+(function () {
+    'use strict';
+
+    const series = [
+        { title: 'Batch Size', color: '#4ade80', value: m => m.batchSize },
+        { title: 'Load Score', color: '#f59e0b', value: m => m.loadScore, max: 1 },
+        { title: 'Pending Items', color: '#3b82f6', value: m => m.pendingItems },
+        { title: 'Handler Time (ms)', color: '#a78bfa', value: m => m.handlerTimeMs },
+    ].concat((extraSeries || []).map(name => (
+        { title: name, color: '#f472b6', value: m => (m.extra || {})[name] || 0 }
+    )));
+
+    const charts = document.getElementById('charts');
+    for (const s of series) {
+        const div = document.createElement('div');
+        div.className = 'chart';
+        const title = document.createElement('div');
+        title.className = 'chart-title';
+        title.textContent = s.title;
+        const canvas = document.createElement('canvas');
+        div.append(title, canvas);
+        charts.append(div);
+
+        s.chart = new Chart(canvas, {
+            type: 'line',
+            data: {
+                labels: [],
+                datasets: [{
+                    data: [],
+                    borderColor: s.color,
+                    backgroundColor: s.color + '26',
+                    fill: true,
+                    tension: 0.3,
+                }],
+            },
+            options: {
+                plugins: { legend: { display: false } },
+                scales: {
+                    y: { max: s.max, grid: { color: '#334155' }, ticks: { color: '#94a3b8' } },
+                },
+            },
+        });
+    }
+
+    const bar = document.getElementById('actions');
+    for (const name of actions || []) {
+        const button = document.createElement('button');
+        button.textContent = name;
+        button.onclick = async () => {
+            button.disabled = true;
+            try {
+                const res = await fetch('api/actions/' + encodeURIComponent(name), { method: 'POST' });
+                if (!res.ok) console.error('Action ' + name + ' failed:', await res.text());
+            } finally {
+                button.disabled = false;
+            }
+            update();
+        };
+        bar.append(button);
+    }
+
+    // With a store, earlier runs can be replayed instead of the live view
+    let replay = null;
+    const runs = document.getElementById('runs');
+    if (runs) {
+        fetch('api/runs').then(r => r.json()).then(list => {
+            for (const run of list) {
+                const started = new Date(run.started).toLocaleString();
+                const option = document.createElement('option');
+                option.value = run.id;
+                option.textContent = run.name + ' - ' + started + ' (' + run.samples + ' samples)';
+                runs.append(option);
+            }
+        }).catch(error => console.error('Error listing runs:', error));
+
+        runs.onchange = async () => {
+            replay = runs.value ? await fetch('api/runs/' + runs.value).then(r => r.json()) : null;
+            update();
+        };
+    }
+
+    function set(id, text, cls) {
+        const el = document.getElementById(id);
+        el.textContent = text;
+        el.className = 'stat-value' + (cls ? ' ' + cls : '');
+    }
+
+    function draw(metrics) {
+        for (const s of series) {
+            s.chart.data.labels = metrics.map(m => m.timestamp);
+            s.chart.data.datasets[0].data = metrics.map(s.value);
+            s.chart.update();
+        }
+    }
+
+    function showReplay(run) {
+        const metrics = run.metrics || [];
+        const last = metrics[metrics.length - 1] || {};
+        const meta = Object.entries(run.meta || {}).map(([k, v]) => k + '=' + v);
+        document.getElementById('labels').textContent = meta.join('  ');
+        set('batchSize', last.batchSize ?? '-');
+        set('pending', last.pendingItems ?? '-');
+        set('inFlight', last.inFlightItems ?? '-');
+        set('loadScore', last.loadScore !== undefined ? last.loadScore.toFixed(2) : '-');
+        set('batches', last.batches ?? '-');
+        set('errors', last.handlerErrors ?? '-');
+        set('state', run.ended.startsWith('0001-') ? 'Unfinished' : 'Recorded');
+        draw(metrics);
+    }
+
+    async function update() {
+        if (replay) {
+            showReplay(replay);
+            return;
+        }
+        try {
+            const [metrics, status] = await Promise.all([
+                fetch('api/metrics').then(r => r.json()),
+                fetch('api/status').then(r => r.json()),
+            ]);
+
+            const labels = Object.entries(status.Labels || {}).map(([k, v]) => k + '=' + v);
+            document.getElementById('labels').textContent = labels.join('  ');
+
+            set('batchSize', status.CurrentBatchSize);
+            set('pending', status.PendingItems);
+            set('inFlight', status.InFlightItems);
+            const load = status.AverageLoadScore;
+            set('loadScore', load.toFixed(2), load > 0.7 ? 'danger' : load > 0.4 ? 'warning' : '');
+            set('batches', status.Batches);
+            set('errors', status.HandlerErrors, status.HandlerErrors > 0 ? 'warning' : '');
+            const paused = !status.PausedUntil.startsWith('0001-');
+            set('state', status.Shedding ? 'Shedding' : paused ? 'Paused' : 'OK',
+                status.Shedding ? 'danger' : paused ? 'warning' : '');
+
+            draw(metrics);
+        } catch (error) {
+            console.error('Error updating dashboard:', error);
+        }
+    }
+
+    update();
+    setInterval(update, 1000);
+})();
@@ -0,0 +1,128 @@
+// multi.js polls the overview API and renders one panel per batcher
+(function () {
+    'use strict';
+
+    const panels = new Map();
+    const container = document.getElementById('panels');
+
+    const STATS = [
+        ['batchSize', 'Batch Size'],
+        ['pending', 'Pending'],
+        ['loadScore', 'Load Score'],
+        ['errors', 'Errors'],
+    ];
+
+    function element(tag, cls, text) {
+        const el = document.createElement(tag);
+        if (cls) el.className = cls;
+        if (text !== undefined) el.textContent = text;
+        return el;
+    }
+
+    function createPanel(p) {
+        const base = 'b/' + encodeURIComponent(p.name) + '/';
+        const div = element('div', 'panel');
+
+        const header = element('div', 'panel-header');
+        const link = element('a', '', 'Details →');
+        link.href = base;
+        header.append(element('h2', '', p.name), link);
+
+        const labels = Object.entries(p.labels || {}).map(([k, v]) => k + '=' + v);
+        const actions = element('div', 'actions');
+        for (const name of p.actions || []) {
+            const button = element('button', '', name);
+            button.onclick = async () => {
+                button.disabled = true;
+                try {
+                    const res = await fetch(base + 'api/actions/' + encodeURIComponent(name), { method: 'POST' });
+                    if (!res.ok) console.error('Action ' + name + ' failed:', await res.text());
+                } finally {
+                    button.disabled = false;
+                }
+            };
+            actions.append(button);
+        }
+
+        const stats = element('div', 'panel-stats');
+        const values = {};
+        for (const [key, title] of STATS) {
+            const stat = element('div', 'stat');
+            values[key] = element('div', 'stat-value', '-');
+            stat.append(element('div', 'stat-label', title), values[key]);
+            stats.append(stat);
+        }
+
+        const canvas = element('canvas');
+        div.append(header, element('div', 'labels', labels.join('  ')), actions, stats, canvas);
+        container.append(div);
+
+        const chart = new Chart(canvas, {
+            type: 'line',
+            data: {
+                labels: [],
+                datasets: [
+                    { label: 'Batch Size', data: [], borderColor: '#4ade80', backgroundColor: '#4ade8026', fill: true, tension: 0.3 },
+                    { label: 'Load Score', data: [], borderColor: '#f59e0b', tension: 0.3, yAxisID: 'y1' },
+                ],
+            },
+            options: {
+                plugins: { legend: { labels: { color: '#94a3b8' } } },
+                scales: {
+                    y: { grid: { color: '#334155' }, ticks: { color: '#94a3b8' } },
+                    y1: { position: 'right', min: 0, max: 1, grid: { drawOnChartArea: false }, ticks: { color: '#94a3b8' } },
+                },
+            },
+        });
+
+        return { div, chart, values };
+    }
+
+    function set(el, text, cls) {
+        el.textContent = text;
+        el.className = 'stat-value' + (cls ? ' ' + cls : '');
+    }
+
+    function render(panel, p) {
+        const s = p.stats;
+        set(panel.values.batchSize, s.CurrentBatchSize);
+        set(panel.values.pending, s.PendingItems);
+        const load = s.AverageLoadScore;
+        set(panel.values.loadScore, load.toFixed(2), load > 0.7 ? 'danger' : load > 0.4 ? 'warning' : '');
+        set(panel.values.errors, s.HandlerErrors, s.HandlerErrors > 0 ? 'warning' : '');
+
+        const metrics = p.metrics || [];
+        panel.chart.data.labels = metrics.map(m => m.timestamp);
+        panel.chart.data.datasets[0].data = metrics.map(m => m.batchSize);
+        panel.chart.data.datasets[1].data = metrics.map(m => m.loadScore);
+        panel.chart.update();
+    }
+
+    async function update() {
+        try {
+            const list = await fetch('api/batchers').then(r => r.json());
+            const seen = new Set();
+            for (const p of list) {
+                seen.add(p.name);
+                if (!panels.has(p.name)) panels.set(p.name, createPanel(p));
+                render(panels.get(p.name), p);
+            }
+            for (const [name, panel] of panels) {
+                if (!seen.has(name)) {
+                    panel.div.remove();
+                    panel.chart.destroy();
+                    panels.delete(name);
+                }
+            }
+
+            const pending = list.reduce((n, p) => n + p.stats.PendingItems, 0);
+            document.getElementById('summary').textContent =
+                list.length + ' batchers, ' + pending + ' items pending';
+        } catch (error) {
+            console.error('Error updating dashboard:', error);
+        }
+    }
+
+    update();
+    setInterval(update, 1000);
+})();
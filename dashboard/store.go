@@ -0,0 +1,281 @@
+package dashboard
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// ErrUnknownRun is returned for a run ID that is not in the store
+var ErrUnknownRun = errors.New("dashboard: unknown run")
+
+var (
+	runsBucket    = []byte("runs")
+	samplesBucket = []byte("samples")
+)
+
+// Run is the metadata of one recorded dashboard session
+type Run struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+
+	// Meta holds free-form details of the run, such as the batcher
+	// configuration or the load pattern, to compare runs by
+	Meta map[string]string `json:"meta,omitempty"`
+
+	Started time.Time `json:"started"`
+
+	// Ended is zero while the run is recording, or if the process
+	// stopped without closing its Dashboard
+	Ended time.Time `json:"ended"`
+
+	Samples int `json:"samples"`
+}
+
+// Store persists dashboard runs and their snapshots in an embedded bbolt
+// database, so tuning sessions can be compared after the process or the
+// page is gone. A Store is safe for concurrent use; the database file can
+// only be opened by one process at a time.
+type Store struct {
+	db *bolt.DB
+}
+
+// OpenStore opens or creates the database at path
+func OpenStore(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("dashboard: open store: %w", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(runsBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(samplesBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("dashboard: open store: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Close closes the database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// StartRun records the start of a new run and returns it
+func (s *Store) StartRun(name string, meta map[string]string) (Run, error) {
+	run := Run{Name: name, Meta: meta, Started: time.Now()}
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		runs := tx.Bucket(runsBucket)
+		seq, err := runs.NextSequence()
+		if err != nil {
+			return err
+		}
+		run.ID = strconv.FormatUint(seq, 10)
+		if _, err := tx.Bucket(samplesBucket).CreateBucket(itob(seq)); err != nil {
+			return err
+		}
+		return putRun(runs, seq, run)
+	})
+	if err != nil {
+		return Run{}, fmt.Errorf("dashboard: start run: %w", err)
+	}
+	return run, nil
+}
+
+// Append adds snapshots to a run
+func (s *Store) Append(id string, snapshots ...Snapshot) error {
+	return s.updateRun(id, func(run *Run, samples *bolt.Bucket) error {
+		for _, snap := range snapshots {
+			seq, err := samples.NextSequence()
+			if err != nil {
+				return err
+			}
+			value, err := json.Marshal(snap)
+			if err != nil {
+				return err
+			}
+			if err := samples.Put(itob(seq), value); err != nil {
+				return err
+			}
+		}
+		run.Samples += len(snapshots)
+		return nil
+	})
+}
+
+// EndRun marks a run as finished
+func (s *Store) EndRun(id string) error {
+	return s.updateRun(id, func(run *Run, _ *bolt.Bucket) error {
+		run.Ended = time.Now()
+		return nil
+	})
+}
+
+// Runs returns every run, newest first
+func (s *Store) Runs() ([]Run, error) {
+	var runs []Run
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(runsBucket).Cursor()
+		for k, v := c.Last(); k != nil; k, v = c.Prev() {
+			var run Run
+			if err := json.Unmarshal(v, &run); err != nil {
+				return err
+			}
+			runs = append(runs, run)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("dashboard: list runs: %w", err)
+	}
+	return runs, nil
+}
+
+// Run returns a run and its snapshots in the order they were appended
+func (s *Store) Run(id string) (Run, []Snapshot, error) {
+	seq, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return Run{}, nil, ErrUnknownRun
+	}
+
+	var run Run
+	var snapshots []Snapshot
+	err = s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(runsBucket).Get(itob(seq))
+		if v == nil {
+			return ErrUnknownRun
+		}
+		if err := json.Unmarshal(v, &run); err != nil {
+			return err
+		}
+		snapshots = make([]Snapshot, 0, run.Samples)
+		return tx.Bucket(samplesBucket).Bucket(itob(seq)).ForEach(func(_, v []byte) error {
+			var snap Snapshot
+			if err := json.Unmarshal(v, &snap); err != nil {
+				return err
+			}
+			snapshots = append(snapshots, snap)
+			return nil
+		})
+	})
+	if errors.Is(err, ErrUnknownRun) {
+		return Run{}, nil, err
+	}
+	if err != nil {
+		return Run{}, nil, fmt.Errorf("dashboard: read run: %w", err)
+	}
+	return run, snapshots, nil
+}
+
+// DeleteRun removes a run and its snapshots
+func (s *Store) DeleteRun(id string) error {
+	seq, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return ErrUnknownRun
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		runs := tx.Bucket(runsBucket)
+		if runs.Get(itob(seq)) == nil {
+			return ErrUnknownRun
+		}
+		if err := runs.Delete(itob(seq)); err != nil {
+			return err
+		}
+		return tx.Bucket(samplesBucket).DeleteBucket(itob(seq))
+	})
+}
+
+// Handler serves the stored runs:
+//
+//	/api/runs        every run, newest first
+//	/api/runs/{id}   one run with its snapshots
+//
+// A Dashboard with Config.Store serves these itself.
+func (s *Store) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/runs", s.serveRuns)
+	mux.HandleFunc("/api/runs/", s.serveRun)
+	return mux
+}
+
+// --- Internal methods ---
+
+func (s *Store) updateRun(id string, fn func(run *Run, samples *bolt.Bucket) error) error {
+	seq, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return ErrUnknownRun
+	}
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		runs := tx.Bucket(runsBucket)
+		v := runs.Get(itob(seq))
+		if v == nil {
+			return ErrUnknownRun
+		}
+		var run Run
+		if err := json.Unmarshal(v, &run); err != nil {
+			return err
+		}
+		if err := fn(&run, tx.Bucket(samplesBucket).Bucket(itob(seq))); err != nil {
+			return err
+		}
+		return putRun(runs, seq, run)
+	})
+	if err != nil && !errors.Is(err, ErrUnknownRun) {
+		return fmt.Errorf("dashboard: update run: %w", err)
+	}
+	return err
+}
+
+func (s *Store) serveRuns(w http.ResponseWriter, r *http.Request) {
+	runs, err := s.Runs()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if runs == nil {
+		runs = []Run{}
+	}
+	writeJSON(w, runs)
+}
+
+func (s *Store) serveRun(w http.ResponseWriter, r *http.Request) {
+	run, snapshots, err := s.Run(strings.TrimPrefix(r.URL.Path, "/api/runs/"))
+	if errors.Is(err, ErrUnknownRun) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, struct {
+		Run
+		Metrics []Snapshot `json:"metrics"`
+	}{run, snapshots})
+}
+
+func putRun(runs *bolt.Bucket, seq uint64, run Run) error {
+	value, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	return runs.Put(itob(seq), value)
+}
+
+// itob encodes a sequence number as a key that sorts numerically
+func itob(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return b
+}
@@ -0,0 +1,118 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openStore(t *testing.T, path string) *Store {
+	t.Helper()
+	s, err := OpenStore(path)
+	if err != nil {
+		t.Fatalf("OpenStore() failed: %v", err)
+	}
+	return s
+}
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "runs.db")
+	s := openStore(t, path)
+
+	first, err := s.StartRun("constant", map[string]string{"factor": "0.3"})
+	if err != nil {
+		t.Fatalf("StartRun() failed: %v", err)
+	}
+	if err := s.Append(first.ID, Snapshot{BatchSize: 10}, Snapshot{BatchSize: 12}); err != nil {
+		t.Fatalf("Append() failed: %v", err)
+	}
+	if err := s.EndRun(first.ID); err != nil {
+		t.Fatalf("EndRun() failed: %v", err)
+	}
+	second, _ := s.StartRun("spikes", nil)
+
+	if err := s.Append("42", Snapshot{}); !errors.Is(err, ErrUnknownRun) {
+		t.Errorf("Expected ErrUnknownRun, got %v", err)
+	}
+	s.Close()
+
+	// Runs survive reopening the database
+	s = openStore(t, path)
+	defer s.Close()
+
+	runs, err := s.Runs()
+	if err != nil {
+		t.Fatalf("Runs() failed: %v", err)
+	}
+	if len(runs) != 2 || runs[0].ID != second.ID || runs[1].ID != first.ID {
+		t.Fatalf("Expected runs newest first, got %+v", runs)
+	}
+	if runs[1].Samples != 2 || runs[1].Ended.IsZero() || runs[1].Meta["factor"] != "0.3" {
+		t.Errorf("Unexpected run metadata: %+v", runs[1])
+	}
+	if !runs[0].Ended.IsZero() {
+		t.Errorf("Expected the unfinished run to have no end")
+	}
+
+	run, snapshots, err := s.Run(first.ID)
+	if err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	if run.Name != "constant" || len(snapshots) != 2 || snapshots[0].BatchSize != 10 || snapshots[1].BatchSize != 12 {
+		t.Errorf("Unexpected run %+v with snapshots %+v", run, snapshots)
+	}
+
+	if err := s.DeleteRun(first.ID); err != nil {
+		t.Fatalf("DeleteRun() failed: %v", err)
+	}
+	if _, _, err := s.Run(first.ID); !errors.Is(err, ErrUnknownRun) {
+		t.Errorf("Expected ErrUnknownRun after delete, got %v", err)
+	}
+}
+
+func TestDashboard_RecordsRun(t *testing.T) {
+	s := openStore(t, filepath.Join(t.TempDir(), "runs.db"))
+	defer s.Close()
+
+	d := NewWithConfig(newBatcher(t), Config{
+		SampleInterval: time.Hour,
+		Store:          s,
+		RunMeta:        map[string]string{"pattern": "spikes"},
+	})
+	d.sample()
+	d.Close()
+
+	if err := d.RecordErr(); err != nil {
+		t.Fatalf("Recording failed: %v", err)
+	}
+	run, ok := d.Run()
+	if !ok {
+		t.Fatal("Expected a recorded run")
+	}
+
+	var got struct {
+		Run
+		Metrics []Snapshot `json:"metrics"`
+	}
+	rec := get(t, d, "/api/runs/"+run.ID)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("Invalid run JSON: %v", err)
+	}
+	if got.Name != "orders" || got.Meta["pattern"] != "spikes" || got.Ended.IsZero() || len(got.Metrics) != 2 {
+		t.Errorf("Unexpected recorded run: %+v", got)
+	}
+
+	var runs []Run
+	if err := json.Unmarshal(get(t, d, "/api/runs").Body.Bytes(), &runs); err != nil || len(runs) != 1 {
+		t.Errorf("Expected one listed run, got %v (%v)", runs, err)
+	}
+	if rec := get(t, d, "/api/runs/99"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown run, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,45 @@
+package batcher
+
+// Degraded reports whether the batcher is degraded: the backend has been
+// saturated, its load score pinned at 1.0 or flushing paused, for
+// Config.DegradeAfter adjustment cycles in a row. A degraded batcher keeps
+// flushing at MinBatchSize, probing for recovery, while the embedding
+// service may divert items to a fallback such as a local spool; with
+// load-awareness off, it keeps its fixed size. It recovers at the first
+// cycle the backend is no longer saturated.
+func (b *Batcher) Degraded() bool {
+	return b.degraded.Load()
+}
+
+// --- Internal methods ---
+
+// trackSaturationLocked counts the adjustment cycles in a row the backend
+// was saturated and updates the degraded state
+func (b *Batcher) trackSaturationLocked(score float64) {
+	if b.cfg.DegradeAfter <= 0 {
+		return
+	}
+	if _, paused := b.paused(); score >= 1 || paused {
+		b.saturatedCycles++
+	} else {
+		b.saturatedCycles = 0
+	}
+	b.degraded.Store(b.saturatedCycles >= b.cfg.DegradeAfter)
+}
+
+// notifyDegraded reports a change of the degraded state to OnDegraded.
+// Callers race to report, so the state reported last is tracked and each
+// change is reported once, in order.
+func (b *Batcher) notifyDegraded() {
+	if b.cfg.OnDegraded == nil {
+		return
+	}
+
+	b.degradedMu.Lock()
+	defer b.degradedMu.Unlock()
+
+	if d := b.degraded.Load(); d != b.degradedReported {
+		b.degradedReported = d
+		b.cfg.OnDegraded(d)
+	}
+}
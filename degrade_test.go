@@ -0,0 +1,146 @@
+package batcher
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBatcher_Degraded(t *testing.T) {
+	saturated := &LoadFeedback{CPULoad: 1, QueueDepth: 1 << 20, ProcessingTime: time.Hour, ErrorRate: 1, DBLocks: 1 << 20}
+	feedback := saturated
+	var events []bool
+
+	b, err := New(Config{
+		InitialBatchSize:  1,
+		LoadCheckInterval: time.Hour,
+		DegradeAfter:      3,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			return feedback, nil
+		},
+		OnDegraded: func(degraded bool) {
+			events = append(events, degraded)
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		if b.Degraded() {
+			t.Fatalf("Expected not degraded after %d saturated cycles", i)
+		}
+		b.Add(ctx, i)
+		b.AdjustNow()
+	}
+	if !b.Degraded() || !b.GetStats().Degraded {
+		t.Fatal("Expected degraded after 3 saturated cycles")
+	}
+
+	// Still saturated: no new event
+	b.Add(ctx, 3)
+	b.AdjustNow()
+
+	feedback = &LoadFeedback{CPULoad: 0.1}
+	b.Add(ctx, 4)
+	b.AdjustNow()
+	if b.Degraded() {
+		t.Error("Expected recovery once the backend is no longer saturated")
+	}
+	if len(events) != 2 || !events[0] || events[1] {
+		t.Errorf("Expected events [true false], got %v", events)
+	}
+}
+
+// newDegradeTestBatcher returns a batcher whose handler reports a
+// saturated backend, after waiting for hold if it is not nil
+func newDegradeTestBatcher(t *testing.T, cfg Config, hold chan struct{}) *Batcher {
+	t.Helper()
+
+	saturated := &LoadFeedback{CPULoad: 1, QueueDepth: 1 << 20, ProcessingTime: time.Hour, ErrorRate: 1, DBLocks: 1 << 20}
+	cfg.LoadCheckInterval = time.Hour
+	cfg.DegradeAfter = 3
+	cfg.HandlerFunc = func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+		if hold != nil && batch[0] == "hold" {
+			<-hold
+		}
+		return saturated, nil
+	}
+	b, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	return b
+}
+
+func TestBatcher_DegradedMinBatchSize(t *testing.T) {
+	b := newDegradeTestBatcher(t, Config{InitialBatchSize: 500, MinBatchSize: 5}, nil)
+	defer b.Close(context.Background())
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		b.Add(ctx, i)
+		b.Flush(ctx)
+		b.AdjustNow()
+		if i < 2 && b.GetCurrentBatchSize() <= 5 {
+			t.Fatalf("Expected the strategy to shrink gradually, got %d after %d cycles", b.GetCurrentBatchSize(), i+1)
+		}
+	}
+	if !b.Degraded() {
+		t.Fatal("Expected degraded after 3 saturated cycles")
+	}
+	if size := b.GetCurrentBatchSize(); size != 5 {
+		t.Errorf("Expected a degraded batcher at MinBatchSize 5, got %d", size)
+	}
+}
+
+func TestBatcher_DegradedStaleWindows(t *testing.T) {
+	hold := make(chan struct{})
+	b := newDegradeTestBatcher(t, Config{InitialBatchSize: 100}, hold)
+	defer b.Close(context.Background())
+	ctx := context.Background()
+
+	b.Add(ctx, 1)
+	b.Flush(ctx)
+
+	// The next batch outlasts the interval: the cycles while it is
+	// handled are skipped, but count towards degradation
+	b.Add(ctx, "hold")
+	flushed := make(chan error, 1)
+	go func() { flushed <- b.Flush(ctx) }()
+	for b.GetStats().InFlightItems == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	for i := 0; i < 3; i++ {
+		b.AdjustNow()
+	}
+	if skipped := b.GetStats().SkippedAdjustments; skipped != 2 {
+		t.Errorf("Expected 2 skipped adjustments, got %d", skipped)
+	}
+	if !b.Degraded() {
+		t.Error("Expected stale cycles to count as saturated")
+	}
+	close(hold)
+	<-flushed
+}
+
+func TestBatcher_DegradedNotAdaptive(t *testing.T) {
+	b := newDegradeTestBatcher(t, Config{InitialBatchSize: 50, MinBatchSize: 5}, nil)
+	defer b.Close(context.Background())
+	b.SetAdaptive(false)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		b.Add(ctx, i)
+		b.Flush(ctx)
+		b.AdjustNow()
+	}
+	if !b.Degraded() {
+		t.Error("Expected cycles with load-awareness off to count as saturated")
+	}
+	if size := b.GetCurrentBatchSize(); size != 50 {
+		t.Errorf("Expected the fixed batch size kept, got %d", size)
+	}
+}
@@ -0,0 +1,195 @@
+package batcher
+
+import (
+	"bufio"
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// BatchEncoder writes a batch to an io.Writer in a wire format, for
+// handlers that ship batches over HTTP or to object storage
+type BatchEncoder interface {
+	Encode(w io.Writer, batch []any) error
+
+	// ContentType is the media type of the encoded batch
+	ContentType() string
+}
+
+// JSONFormat selects how a JSONEncoder lays out a batch
+type JSONFormat int
+
+const (
+	// JSONArray encodes a batch as one JSON array
+	JSONArray JSONFormat = iota
+
+	// NDJSON encodes a batch as newline-delimited JSON, one item per line
+	NDJSON
+)
+
+// String returns the string representation of JSONFormat
+func (f JSONFormat) String() string {
+	switch f {
+	case JSONArray:
+		return "array"
+	case NDJSON:
+		return "ndjson"
+	default:
+		return "unknown"
+	}
+}
+
+// ContentType returns the HTTP content type of the format
+func (f JSONFormat) ContentType() string {
+	if f == NDJSON {
+		return "application/x-ndjson"
+	}
+	return "application/json"
+}
+
+// JSONEncoder streams batches as JSON into an io.Writer one item at a
+// time, for handlers that post large batches over HTTP, so the encoded
+// batch is never held in memory as a whole. Buffers are reused across
+// batches. A JSONEncoder is safe for concurrent use and must not be
+// copied after first use.
+type JSONEncoder struct {
+	// Format is the layout of the encoded batch (default: JSONArray)
+	Format JSONFormat
+
+	// BufferSize is the size of the write buffer (default: 4096)
+	BufferSize int
+
+	pool sync.Pool
+}
+
+// Encode writes the batch to w. Items are encoded with encoding/json.
+func (e *JSONEncoder) Encode(w io.Writer, batch []any) error {
+	s := e.getState(w)
+	defer e.putState(s)
+
+	if e.Format == JSONArray {
+		s.w.WriteByte('[')
+	}
+	for i, item := range batch {
+		s.item.Reset()
+		if err := s.enc.Encode(item); err != nil {
+			return err
+		}
+		data := s.item.Bytes()
+		if e.Format == JSONArray {
+			if i > 0 {
+				s.w.WriteByte(',')
+			}
+			data = bytes.TrimSuffix(data, []byte{'\n'})
+		}
+		if _, err := s.w.Write(data); err != nil {
+			return err
+		}
+	}
+	if e.Format == JSONArray {
+		s.w.WriteByte(']')
+	}
+	return s.w.Flush()
+}
+
+// ContentType implements BatchEncoder
+func (e *JSONEncoder) ContentType() string {
+	return e.Format.ContentType()
+}
+
+// Reader returns the encoded batch as a reader, e.g. an HTTP request
+// body, encoding it as it is read. The reader must be read to the end
+// or closed.
+func (e *JSONEncoder) Reader(batch []any) io.ReadCloser {
+	r, w := io.Pipe()
+	go func() {
+		w.CloseWithError(e.Encode(w, batch))
+	}()
+	return r
+}
+
+// DelimitedEncoder writes a batch as a stream of binary records, each
+// prefixed with its length as a uvarint. This is the framing of
+// length-delimited protobuf streams, so with Marshal set to proto.Marshal
+// a batch of messages can be read back with protodelim. There is no Avro
+// object container encoder and no schema registry support yet; a
+// registry-aware serializer can be plugged in as Marshal for the records.
+type DelimitedEncoder struct {
+	// Marshal encodes one item (default: the MarshalBinary method of
+	// items implementing encoding.BinaryMarshaler)
+	Marshal func(item any) ([]byte, error)
+
+	// MediaType is returned by ContentType (default:
+	// "application/octet-stream")
+	MediaType string
+}
+
+// Encode implements BatchEncoder
+func (e *DelimitedEncoder) Encode(w io.Writer, batch []any) error {
+	bw := bufio.NewWriter(w)
+	var prefix [binary.MaxVarintLen64]byte
+	for i, item := range batch {
+		data, err := e.marshal(item)
+		if err != nil {
+			return fmt.Errorf("item %d: %w", i, err)
+		}
+		n := binary.PutUvarint(prefix[:], uint64(len(data)))
+		if _, err := bw.Write(prefix[:n]); err != nil {
+			return err
+		}
+		if _, err := bw.Write(data); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// ContentType implements BatchEncoder
+func (e *DelimitedEncoder) ContentType() string {
+	if e.MediaType != "" {
+		return e.MediaType
+	}
+	return "application/octet-stream"
+}
+
+// --- Internal methods ---
+
+func (e *DelimitedEncoder) marshal(item any) ([]byte, error) {
+	if e.Marshal != nil {
+		return e.Marshal(item)
+	}
+	if m, ok := item.(encoding.BinaryMarshaler); ok {
+		return m.MarshalBinary()
+	}
+	return nil, fmt.Errorf("batcher: cannot marshal %T", item)
+}
+
+// encoderState is the reusable state of one Encode call
+type encoderState struct {
+	w    *bufio.Writer
+	item bytes.Buffer
+	enc  *json.Encoder
+}
+
+func (e *JSONEncoder) getState(w io.Writer) *encoderState {
+	if s, ok := e.pool.Get().(*encoderState); ok {
+		s.w.Reset(w)
+		return s
+	}
+	size := e.BufferSize
+	if size <= 0 {
+		size = 4096
+	}
+	s := &encoderState{w: bufio.NewWriterSize(w, size)}
+	s.enc = json.NewEncoder(&s.item)
+	return s
+}
+
+func (e *JSONEncoder) putState(s *encoderState) {
+	s.w.Reset(nil)
+	e.pool.Put(s)
+}
@@ -0,0 +1,97 @@
+package batcher
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestJSONEncoder(t *testing.T) {
+	batch := []any{map[string]int{"a": 1}, "b", 3}
+
+	tests := []struct {
+		format JSONFormat
+		want   string
+	}{
+		{JSONArray, `[{"a":1},"b",3]`},
+		{NDJSON, "{\"a\":1}\n\"b\"\n3\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format.String(), func(t *testing.T) {
+			e := &JSONEncoder{Format: tt.format, BufferSize: 4}
+
+			// Twice, so the second batch reuses the buffers
+			for i := 0; i < 2; i++ {
+				var buf bytes.Buffer
+				if err := e.Encode(&buf, batch); err != nil {
+					t.Fatalf("Encode() failed: %v", err)
+				}
+				if buf.String() != tt.want {
+					t.Errorf("Expected %q, got %q", tt.want, buf.String())
+				}
+			}
+		})
+	}
+}
+
+func TestJSONEncoder_EmptyBatch(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (&JSONEncoder{}).Encode(&buf, nil); err != nil {
+		t.Fatalf("Encode() failed: %v", err)
+	}
+	if buf.String() != "[]" {
+		t.Errorf("Expected an empty array, got %q", buf.String())
+	}
+}
+
+func TestJSONEncoder_Reader(t *testing.T) {
+	e := &JSONEncoder{}
+	data, err := io.ReadAll(e.Reader([]any{1, 2, 3}))
+	if err != nil {
+		t.Fatalf("ReadAll() failed: %v", err)
+	}
+	var got []int
+	if err := json.Unmarshal(data, &got); err != nil || len(got) != 3 {
+		t.Errorf("Expected a valid array of 3 items, got %s (%v)", data, err)
+	}
+
+	// Encoding errors reach the reader
+	_, err = io.ReadAll(e.Reader([]any{func() {}}))
+	if err == nil {
+		t.Error("Expected an error for an unencodable item")
+	}
+}
+
+func TestDelimitedEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	e := &DelimitedEncoder{}
+	var _ BatchEncoder = e
+
+	batch := []any{time.Unix(1, 0).UTC(), time.Unix(2, 0).UTC()}
+	if err := e.Encode(&buf, batch); err != nil {
+		t.Fatalf("Encode() failed: %v", err)
+	}
+
+	r := bufio.NewReader(&buf)
+	for i, item := range batch {
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			t.Fatalf("Expected a length prefix for item %d: %v", i, err)
+		}
+		data := make([]byte, n)
+		io.ReadFull(r, data)
+		var got time.Time
+		if err := got.UnmarshalBinary(data); err != nil || !got.Equal(item.(time.Time)) {
+			t.Errorf("Expected item %d to be %v, got %v (%v)", i, item, got, err)
+		}
+	}
+
+	if err := e.Encode(io.Discard, []any{struct{}{}}); err == nil {
+		t.Error("Expected an error for an item without a marshaler")
+	}
+}
@@ -0,0 +1,65 @@
+package batcher
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// ErrorClass tells whether a handler error is a sign of backend load
+type ErrorClass int
+
+const (
+	// ErrorClassOverload is an error caused by a loaded backend, such as
+	// a timeout, throttling or a deadlock. It counts as load.
+	ErrorClassOverload ErrorClass = iota
+
+	// ErrorClassClient is an error caused by the batch itself, such as a
+	// validation error. It does not count as load, so the batch size is
+	// not shrunk for bad input.
+	ErrorClassClient
+)
+
+// String returns the string representation of ErrorClass
+func (c ErrorClass) String() string {
+	switch c {
+	case ErrorClassOverload:
+		return "overload"
+	case ErrorClassClient:
+		return "client"
+	default:
+		return "unknown"
+	}
+}
+
+// ClassifyCommonErrors is a ClassifyError that treats timeouts, HTTP 429
+// and 503 responses and ErrRetryable as overload and every other error as
+// a client error. An error reports its HTTP status through a
+// StatusCode() int method anywhere in its chain.
+func ClassifyCommonErrors(err error) ErrorClass {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrRetryable) {
+		return ErrorClassOverload
+	}
+	var timeout interface{ Timeout() bool }
+	if errors.As(err, &timeout) && timeout.Timeout() {
+		return ErrorClassOverload
+	}
+	var status interface{ StatusCode() int }
+	if errors.As(err, &status) {
+		switch status.StatusCode() {
+		case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+			return ErrorClassOverload
+		}
+	}
+	return ErrorClassClient
+}
+
+// --- Internal methods ---
+
+// loadError returns err if it counts as load, and nil otherwise
+func (b *Batcher) loadError(err error) error {
+	if err == nil || b.cfg.ClassifyError == nil || b.cfg.ClassifyError(err) == ErrorClassOverload {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package batcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// statusError is an error carrying an HTTP status
+type statusError int
+
+func (e statusError) Error() string   { return fmt.Sprintf("status %d", int(e)) }
+func (e statusError) StatusCode() int { return int(e) }
+
+// timeoutError is an error reporting a timeout, like net.Error
+type timeoutError struct{}
+
+func (timeoutError) Error() string { return "i/o timeout" }
+func (timeoutError) Timeout() bool { return true }
+
+func TestClassifyCommonErrors(t *testing.T) {
+	tests := []struct {
+		err  error
+		want ErrorClass
+	}{
+		{context.DeadlineExceeded, ErrorClassOverload},
+		{fmt.Errorf("insert: %w", timeoutError{}), ErrorClassOverload},
+		{statusError(429), ErrorClassOverload},
+		{fmt.Errorf("bulk: %w", statusError(503)), ErrorClassOverload},
+		{fmt.Errorf("%w: lock wait", ErrRetryable), ErrorClassOverload},
+		{statusError(400), ErrorClassClient},
+		{errors.New("invalid field"), ErrorClassClient},
+	}
+
+	for _, tt := range tests {
+		if got := ClassifyCommonErrors(tt.err); got != tt.want {
+			t.Errorf("ClassifyCommonErrors(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestBatcher_ClassifyError(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		wantOverload int64
+		overloaded   bool
+	}{
+		{"client errors do not count as load", statusError(400), 0, false},
+		{"overload errors count as load", statusError(503), 5, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := New(Config{
+				InitialBatchSize:  1,
+				LoadCheckInterval: time.Hour,
+				LatencyTarget:     time.Second,
+				ClassifyError:     ClassifyCommonErrors,
+				PlainHandlerFunc: func(ctx context.Context, batch []any) error {
+					return tt.err
+				},
+			})
+			if err != nil {
+				t.Fatalf("New() failed: %v", err)
+			}
+			defer b.Close(context.Background())
+
+			for i := 0; i < 5; i++ {
+				b.Add(context.Background(), i)
+			}
+
+			stats := b.GetStats()
+			if stats.HandlerErrors != 5 || stats.OverloadErrors != tt.wantOverload {
+				t.Errorf("Expected 5 handler errors, %d overload, got %d, %d",
+					tt.wantOverload, stats.HandlerErrors, stats.OverloadErrors)
+			}
+			if overloaded := stats.AverageLoadScore > 0.3; overloaded != tt.overloaded {
+				t.Errorf("Expected overloaded %v, got load score %.2f", tt.overloaded, stats.AverageLoadScore)
+			}
+		})
+	}
+}
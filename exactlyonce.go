@@ -0,0 +1,224 @@
+package batcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// WAL is the write-ahead log of an ExactlyOnce batcher: batches are
+// appended to it under their BatchID before they are written to the sink
+// and committed after, so the batches a crash interrupted can be written
+// again under the same ID. Implementations must be safe for concurrent
+// use and durable by the time Append and Commit return.
+type WAL interface {
+	// Append records the items of a batch under id
+	Append(ctx context.Context, id string, items []any) error
+
+	// Commit marks the batch id as written to the sink
+	Commit(ctx context.Context, id string) error
+
+	// Pending returns the batches appended but not committed, in the
+	// order they were appended
+	Pending(ctx context.Context) ([]WALEntry, error)
+}
+
+// WALEntry is a batch recorded in a WAL
+type WALEntry struct {
+	ID    string
+	Items []any
+}
+
+// ErrUncommitted is returned for a batch the sink applied but the WAL
+// could not mark committed. Recover writes it again, which the sink
+// recognizes.
+var ErrUncommitted = errors.New("batcher: batch written but not committed in the wal")
+
+// SinkFunc writes a batch to a transactional sink. It must apply the
+// batch at most once per id, e.g. by storing id in the same transaction
+// as the items and skipping ids it has stored before, or by using it as
+// the idempotency key or transactional ID of the request, and report
+// success for a batch it applied before.
+type SinkFunc func(ctx context.Context, id string, items []any) (*LoadFeedback, error)
+
+// ExactlyOnceConfig holds the configuration for an ExactlyOnce batcher
+type ExactlyOnceConfig struct {
+	// Config is the batcher configuration. Its handlers are ignored;
+	// Sink below handles the batches. RequeuePolicy and BisectFailures
+	// are ignored as well, as they would hand the items to the sink
+	// under another BatchID: failed batches stay in the WAL and are
+	// retried by Recover.
+	Config Config
+
+	// WAL records the batches ahead of the sink
+	WAL WAL
+
+	// Sink writes the batches
+	Sink SinkFunc
+}
+
+// ExactlyOnce batches items into a transactional sink, such as a SQL
+// table or a transactional Kafka producer, in two phases: every batch is
+// appended to the WAL under its BatchID, written to the sink with the
+// BatchID as its idempotency marker, and then committed in the WAL.
+// After a restart, Recover writes the batches left uncommitted again
+// under their original BatchID, which the sink recognizes if it applied
+// them before the crash, so no batch is applied twice and none is lost.
+type ExactlyOnce struct {
+	b   *Batcher
+	cfg ExactlyOnceConfig
+
+	// recovering serializes Recover calls
+	recovering sync.Mutex
+
+	// inFlight holds the IDs of the batches being handled, which Recover
+	// leaves to their handler
+	mu       sync.Mutex
+	inFlight map[string]struct{}
+
+	recovered atomic.Int64
+}
+
+// NewExactlyOnce creates an ExactlyOnce batcher with the given
+// configuration and recovers the batches left uncommitted in the WAL. WAL
+// and Sink must be set.
+func NewExactlyOnce(ctx context.Context, cfg ExactlyOnceConfig) (*ExactlyOnce, error) {
+	if cfg.WAL == nil || cfg.Sink == nil {
+		return nil, ErrInvalidConfig
+	}
+
+	e := &ExactlyOnce{cfg: cfg, inFlight: make(map[string]struct{})}
+	bcfg := cfg.Config
+	bcfg.HandlerFunc = nil
+	bcfg.PlainHandlerFunc = nil
+	bcfg.HandlerFuncV2 = e.handle
+	bcfg.RequeuePolicy = RequeueNone
+	bcfg.BisectFailures = false
+
+	b, err := New(bcfg)
+	if err != nil {
+		return nil, err
+	}
+	e.b = b
+
+	if err := e.Recover(ctx); err != nil {
+		b.Close(ctx)
+		return nil, err
+	}
+	return e, nil
+}
+
+// Add adds an item to the current batch, see Batcher.Add
+func (e *ExactlyOnce) Add(ctx context.Context, item any) error {
+	return e.b.Add(ctx, item)
+}
+
+// Flush flushes the current batch, if any
+func (e *ExactlyOnce) Flush(ctx context.Context) error {
+	return e.b.Flush(ctx)
+}
+
+// Close closes the batcher, flushing the items still buffered. Batches
+// that fail stay in the WAL for Recover.
+func (e *ExactlyOnce) Close(ctx context.Context) error {
+	return e.b.Close(ctx)
+}
+
+// Recover writes the batches left uncommitted in the WAL to the sink
+// again, under their BatchID, and commits them. It runs on
+// NewExactlyOnce, and can be called again to retry the batches the sink
+// failed on since; batches being handled at the time are skipped. It
+// stops at the first batch the sink fails on, which stays in the WAL,
+// and returns its error.
+func (e *ExactlyOnce) Recover(ctx context.Context) error {
+	e.recovering.Lock()
+	defer e.recovering.Unlock()
+
+	pending, err := e.cfg.WAL.Pending(ctx)
+	if err != nil {
+		return fmt.Errorf("batcher: wal: %w", err)
+	}
+	for _, entry := range pending {
+		if !e.begin(entry.ID) {
+			continue
+		}
+		err := e.replay(ctx, entry)
+		e.end(entry.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Batcher returns the underlying Batcher, e.g. for its statistics
+func (e *ExactlyOnce) Batcher() *Batcher {
+	return e.b
+}
+
+// GetStats returns the recovery statistics
+func (e *ExactlyOnce) GetStats() ExactlyOnceStats {
+	return ExactlyOnceStats{Recovered: e.recovered.Load()}
+}
+
+// ExactlyOnceStats holds exactly-once statistics
+type ExactlyOnceStats struct {
+	// Recovered is the number of batches Recover wrote and committed
+	Recovered int64
+}
+
+// --- Internal methods ---
+
+// replay writes a batch left in the WAL to the sink and commits it
+func (e *ExactlyOnce) replay(ctx context.Context, entry WALEntry) error {
+	if _, err := e.cfg.Sink(ctx, entry.ID, entry.Items); err != nil {
+		return fmt.Errorf("batcher: recover batch %s: %w", entry.ID, err)
+	}
+	if err := e.cfg.WAL.Commit(ctx, entry.ID); err != nil {
+		return fmt.Errorf("batcher: wal: %w", err)
+	}
+	e.recovered.Add(1)
+	return nil
+}
+
+// begin marks the batch id as being handled, and reports false if it
+// already is
+func (e *ExactlyOnce) begin(id string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, ok := e.inFlight[id]; ok {
+		return false
+	}
+	e.inFlight[id] = struct{}{}
+	return true
+}
+
+// end unmarks the batch id
+func (e *ExactlyOnce) end(id string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	delete(e.inFlight, id)
+}
+
+// handle appends a batch to the WAL, writes it to the sink and commits it
+func (e *ExactlyOnce) handle(ctx context.Context, batch []any, meta BatchMeta) (*LoadFeedback, error) {
+	e.begin(meta.BatchID)
+	defer e.end(meta.BatchID)
+
+	if err := e.cfg.WAL.Append(ctx, meta.BatchID, batch); err != nil {
+		return nil, fmt.Errorf("batcher: wal: %w", err)
+	}
+	fb, err := e.cfg.Sink(ctx, meta.BatchID, batch)
+	if err != nil {
+		return fb, err
+	}
+	if err := e.cfg.WAL.Commit(ctx, meta.BatchID); err != nil {
+		// The sink applied the batch; Recover will find it applied
+		return fb, errors.Join(ErrUncommitted, err)
+	}
+	return fb, nil
+}
@@ -0,0 +1,168 @@
+package batcher
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// dedupSink is a transactional sink that applies every batch ID once
+type dedupSink struct {
+	mu      sync.Mutex
+	applied map[string][]any
+	fail    bool
+}
+
+func newDedupSink() *dedupSink {
+	return &dedupSink{applied: make(map[string][]any)}
+}
+
+func (s *dedupSink) write(ctx context.Context, id string, items []any) (*LoadFeedback, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.fail {
+		return nil, errors.New("sink down")
+	}
+	if _, ok := s.applied[id]; !ok {
+		s.applied[id] = append([]any(nil), items...)
+	}
+	return &LoadFeedback{}, nil
+}
+
+func (s *dedupSink) items() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for _, items := range s.applied {
+		n += len(items)
+	}
+	return n
+}
+
+func openTestWAL(t *testing.T, path string) *FileWAL {
+	t.Helper()
+	w, err := OpenFileWAL(path, nil)
+	if err != nil {
+		t.Fatalf("OpenFileWAL() failed: %v", err)
+	}
+	t.Cleanup(func() { w.Close() })
+	return w
+}
+
+func TestExactlyOnce_CommitsBatches(t *testing.T) {
+	ctx := context.Background()
+	wal := openTestWAL(t, filepath.Join(t.TempDir(), "wal"))
+	sink := newDedupSink()
+
+	e, err := NewExactlyOnce(ctx, ExactlyOnceConfig{
+		Config: Config{InitialBatchSize: 3},
+		WAL:    wal,
+		Sink:   sink.write,
+	})
+	if err != nil {
+		t.Fatalf("NewExactlyOnce() failed: %v", err)
+	}
+	for i := 0; i < 7; i++ {
+		if err := e.Add(ctx, i); err != nil {
+			t.Fatalf("Add() failed: %v", err)
+		}
+	}
+	if err := e.Close(ctx); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	if len(sink.applied) != 3 || sink.items() != 7 {
+		t.Errorf("Expected 7 items in 3 batches, got %d items in %d", sink.items(), len(sink.applied))
+	}
+	if pending, _ := wal.Pending(ctx); len(pending) != 0 {
+		t.Errorf("Expected every batch committed, got %d pending", len(pending))
+	}
+}
+
+func TestExactlyOnce_RecoversAfterCrash(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "wal")
+	sink := newDedupSink()
+
+	// Before the crash, b1 reached the sink but was not committed, and
+	// b2 did not reach it
+	wal, err := OpenFileWAL(path, nil)
+	if err != nil {
+		t.Fatalf("OpenFileWAL() failed: %v", err)
+	}
+	wal.Append(ctx, "b0", []any{0})
+	wal.Commit(ctx, "b0")
+	wal.Append(ctx, "b1", []any{1, 2})
+	sink.write(ctx, "b1", []any{1, 2})
+	wal.Append(ctx, "b2", []any{3})
+	wal.Close()
+
+	e, err := NewExactlyOnce(ctx, ExactlyOnceConfig{
+		Config: Config{InitialBatchSize: 10},
+		WAL:    openTestWAL(t, path),
+		Sink:   sink.write,
+	})
+	if err != nil {
+		t.Fatalf("NewExactlyOnce() failed: %v", err)
+	}
+	defer e.Close(ctx)
+
+	if n := e.GetStats().Recovered; n != 2 {
+		t.Errorf("Expected 2 batches recovered, got %d", n)
+	}
+	if sink.items() != 3 {
+		t.Errorf("Expected 3 items applied once each, got %d", sink.items())
+	}
+	if got := sink.applied["b2"]; len(got) != 1 || got[0] != 3.0 {
+		t.Errorf("Expected b2 applied with its decoded item, got %v", got)
+	}
+}
+
+func TestExactlyOnce_FailedBatchStaysInWAL(t *testing.T) {
+	ctx := context.Background()
+	wal := openTestWAL(t, filepath.Join(t.TempDir(), "wal"))
+	sink := newDedupSink()
+	sink.fail = true
+
+	e, err := NewExactlyOnce(ctx, ExactlyOnceConfig{
+		Config: Config{InitialBatchSize: 2, RequeuePolicy: RequeueFront},
+		WAL:    wal,
+		Sink:   sink.write,
+	})
+	if err != nil {
+		t.Fatalf("NewExactlyOnce() failed: %v", err)
+	}
+	defer e.Close(ctx)
+
+	e.Add(ctx, "a")
+	if err := e.Add(ctx, "b"); err == nil {
+		t.Fatal("Expected the sink error")
+	}
+	if pending, _ := wal.Pending(ctx); len(pending) != 1 || len(pending[0].Items) != 2 {
+		t.Fatalf("Expected the failed batch pending in the WAL, got %v", pending)
+	}
+	if err := e.Recover(ctx); err == nil {
+		t.Error("Expected Recover to fail while the sink is down")
+	}
+
+	sink.mu.Lock()
+	sink.fail = false
+	sink.mu.Unlock()
+	if err := e.Recover(ctx); err != nil {
+		t.Fatalf("Recover() failed: %v", err)
+	}
+	if pending, _ := wal.Pending(ctx); len(pending) != 0 || sink.items() != 2 {
+		t.Errorf("Expected the batch applied and committed, got %d pending and %d applied", len(pending), sink.items())
+	}
+}
+
+func TestNewExactlyOnce_Invalid(t *testing.T) {
+	_, err := NewExactlyOnce(context.Background(), ExactlyOnceConfig{Config: Config{InitialBatchSize: 10}})
+	if !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig, got %v", err)
+	}
+}
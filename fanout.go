@@ -0,0 +1,513 @@
+package batcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Destination is one sink of a FanOut
+type Destination struct {
+	// Name identifies the destination in errors and stats
+	Name string
+
+	// HandlerFunc delivers a batch to this destination
+	HandlerFunc HandlerFunc
+
+	// Weight is the share of this destination in CombineWeighted (default: 1)
+	Weight float64
+
+	// MaxRetries is how often a failed batch is retried for this
+	// destination only (default: 0)
+	MaxRetries int
+
+	// RetryBackoff is the delay before the first retry, doubled for every
+	// following attempt (default: 100ms)
+	RetryBackoff time.Duration
+
+	// MaxInFlight is a bulkhead: the number of batches this destination
+	// handles at once. An attempt beyond it fails right away with
+	// ErrBulkheadFull, and is retried like any other failure, so a slow
+	// destination cannot tie up the goroutines flushing for the others.
+	// Zero means unlimited.
+	MaxInFlight int
+}
+
+// ErrBulkheadFull is returned for a destination that already handles
+// Destination.MaxInFlight batches
+var ErrBulkheadFull = errors.New("batcher: destination bulkhead full")
+
+// DestinationError is the error of one fan-out destination. Errors of
+// several destinations are joined with errors.Join.
+type DestinationError struct {
+	// Destination is the Destination.Name
+	Destination string
+
+	// Err is the error of the destination
+	Err error
+}
+
+// Error implements error
+func (e *DestinationError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Destination, e.Err)
+}
+
+// Unwrap returns the error of the destination
+func (e *DestinationError) Unwrap() error {
+	return e.Err
+}
+
+// FanOutMode selects how a FanOut sizes batches
+type FanOutMode int
+
+const (
+	// FanOutShared drives all destinations from one batcher, with one
+	// batch size derived from the combined load of all destinations. A
+	// batch that fails at some destinations fails as a whole, but when it
+	// is retried, requeued or bisected its items only go to the
+	// destinations they were not delivered to yet.
+	FanOutShared FanOutMode = iota
+
+	// FanOutSeparate runs one batcher per destination, each adapting its
+	// own batch size to the load of its destination
+	FanOutSeparate
+)
+
+// ScoreCombiner selects how destination feedback is combined in
+// FanOutShared. Whatever the combiner, a pause asked for by any
+// destination is kept: the combined feedback has the longest RetryAfter
+// and ThrottleFor, is Unhealthy if any destination is, and suggests the
+// smallest SuggestedBatchSize.
+type ScoreCombiner int
+
+const (
+	// CombineMax sizes batches for the most loaded destination, as scored
+	// with Config.CustomMetrics
+	CombineMax ScoreCombiner = iota
+
+	// CombineWeighted blends destination feedback by Destination.Weight,
+	// averaging numeric Custom metrics as well
+	CombineWeighted
+)
+
+// FanOutConfig holds the configuration for a FanOut
+type FanOutConfig struct {
+	// Config is the batcher configuration. HandlerFunc and HandlerFuncV2
+	// are ignored; the destinations handle the batches.
+	Config Config
+
+	// Destinations receive every item
+	Destinations []Destination
+
+	// Mode selects shared or per-destination batch sizing
+	Mode FanOutMode
+
+	// Combine selects how feedback is combined in FanOutShared
+	Combine ScoreCombiner
+}
+
+// FanOut delivers every item to several destinations, each with
+// independent retries and feedback
+type FanOut struct {
+	cfg      FanOutConfig
+	shared   *Batcher
+	separate map[string]*Batcher
+	stats    map[string]*destinationCounters
+}
+
+type destinationCounters struct {
+	batches  atomic.Int64
+	retries  atomic.Int64
+	failures atomic.Int64
+	rejected atomic.Int64
+
+	// slots holds a token per batch in flight, nil if unlimited
+	slots chan struct{}
+}
+
+// NewFanOut creates a FanOut with the given configuration
+func NewFanOut(cfg FanOutConfig) (*FanOut, error) {
+	if len(cfg.Destinations) == 0 {
+		return nil, ErrInvalidConfig
+	}
+
+	f := &FanOut{
+		cfg:   cfg,
+		stats: make(map[string]*destinationCounters, len(cfg.Destinations)),
+	}
+	for i := range cfg.Destinations {
+		d := &cfg.Destinations[i]
+		if d.Name == "" || d.HandlerFunc == nil {
+			return nil, ErrInvalidConfig
+		}
+		if _, ok := f.stats[d.Name]; ok {
+			return nil, ErrInvalidConfig
+		}
+		if d.Weight <= 0 {
+			d.Weight = 1
+		}
+		if d.RetryBackoff <= 0 {
+			d.RetryBackoff = 100 * time.Millisecond
+		}
+		c := &destinationCounters{}
+		if d.MaxInFlight > 0 {
+			c.slots = make(chan struct{}, d.MaxInFlight)
+		}
+		f.stats[d.Name] = c
+	}
+
+	bcfg := cfg.Config
+	bcfg.HandlerFuncV2 = nil
+
+	if cfg.Mode == FanOutSeparate {
+		f.separate = make(map[string]*Batcher, len(cfg.Destinations))
+		for _, d := range cfg.Destinations {
+			d := d
+			bcfg.HandlerFunc = func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+				return f.deliver(ctx, d, batch)
+			}
+			bcfg.Labels = make(map[string]string, len(cfg.Config.Labels)+1)
+			for k, v := range cfg.Config.Labels {
+				bcfg.Labels[k] = v
+			}
+			bcfg.Labels["destination"] = d.Name
+			b, err := New(bcfg)
+			if err != nil {
+				f.Close(context.Background())
+				return nil, err
+			}
+			f.separate[d.Name] = b
+		}
+		return f, nil
+	}
+
+	bcfg.HandlerFunc = f.handleShared
+	b, err := New(bcfg)
+	if err != nil {
+		return nil, err
+	}
+	b.destinations = len(cfg.Destinations)
+	f.shared = b
+	return f, nil
+}
+
+// Add adds one item for delivery to every destination. In FanOutSeparate
+// the item is added to each destination's batcher in turn, and those not
+// reported with a *DestinationError in the returned error have accepted
+// it; adding it again would deliver it to them twice.
+func (f *FanOut) Add(ctx context.Context, item any) error {
+	if f.shared != nil {
+		return f.shared.Add(ctx, item)
+	}
+	var errs []error
+	for _, d := range f.cfg.Destinations {
+		if err := f.separate[d.Name].Add(ctx, item); err != nil {
+			errs = append(errs, &DestinationError{Destination: d.Name, Err: err})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Flush flushes pending items to every destination
+func (f *FanOut) Flush(ctx context.Context) error {
+	return f.each(func(b *Batcher) error { return b.Flush(ctx) })
+}
+
+// Close closes the fan-out, flushing pending items to every destination
+func (f *FanOut) Close(ctx context.Context) error {
+	return f.each(func(b *Batcher) error { return b.Close(ctx) })
+}
+
+// GetStats returns the batcher and per-destination statistics
+func (f *FanOut) GetStats() FanOutStats {
+	stats := FanOutStats{Destinations: make(map[string]DestinationStats, len(f.stats))}
+	if f.shared != nil {
+		stats.Shared = f.shared.GetStats()
+	}
+	for name, c := range f.stats {
+		ds := DestinationStats{
+			Batches:  c.batches.Load(),
+			Retries:  c.retries.Load(),
+			Failures: c.failures.Load(),
+			Rejected: c.rejected.Load(),
+			InFlight: len(c.slots),
+		}
+		if b, ok := f.separate[name]; ok {
+			ds.Batcher = b.GetStats()
+		}
+		stats.Destinations[name] = ds
+	}
+	return stats
+}
+
+// FanOutStats holds fan-out statistics
+type FanOutStats struct {
+	// Shared holds the statistics of the shared batcher in FanOutShared
+	Shared Stats
+
+	// Destinations holds the statistics of each destination
+	Destinations map[string]DestinationStats
+}
+
+// DestinationStats holds the statistics of one fan-out destination
+type DestinationStats struct {
+	// Batcher holds the statistics of the destination batcher in FanOutSeparate
+	Batcher Stats
+
+	Batches  int64
+	Retries  int64
+	Failures int64
+
+	// Rejected is the number of attempts refused by the bulkhead, and
+	// InFlight the number of batches being handled, if
+	// Destination.MaxInFlight is set
+	Rejected int64
+	InFlight int
+}
+
+// --- Internal methods ---
+
+func (f *FanOut) each(fn func(b *Batcher) error) error {
+	if f.shared != nil {
+		return fn(f.shared)
+	}
+	var errs []error
+	for _, d := range f.cfg.Destinations {
+		if b, ok := f.separate[d.Name]; ok {
+			if err := fn(b); err != nil {
+				errs = append(errs, &DestinationError{Destination: d.Name, Err: err})
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// handleShared delivers the batch to all destinations concurrently and
+// combines their feedback into one. Each destination only gets the items
+// not delivered to it before, and the items it accepts are recorded as
+// delivered to it.
+func (f *FanOut) handleShared(ctx context.Context, batch []any) (*LoadFeedback, error) {
+	meta, _ := ctx.Value(batchMetaKey{}).(*BatchMeta)
+	feedbacks := make([]*LoadFeedback, len(f.cfg.Destinations))
+	errs := make([]error, len(f.cfg.Destinations))
+
+	var wg sync.WaitGroup
+	for i, d := range f.cfg.Destinations {
+		wg.Add(1)
+		go func(i int, d Destination) {
+			defer wg.Done()
+			ctx, batch, delivered := undelivered(ctx, meta, i, batch)
+			if len(batch) == 0 {
+				return
+			}
+			feedback, err := f.deliver(ctx, d, batch)
+			feedbacks[i] = feedback
+			if err != nil {
+				errs[i] = &DestinationError{Destination: d.Name, Err: err}
+				return
+			}
+			for _, del := range delivered {
+				del[i].Store(true)
+			}
+		}(i, d)
+	}
+	wg.Wait()
+
+	return f.combine(feedbacks), errors.Join(errs...)
+}
+
+// deliveries records, per destination of a FanOutShared in the order of
+// FanOutConfig.Destinations, whether an item was delivered to it
+type deliveries []atomic.Bool
+
+// trackDeliveries gives the items of a batch of a FanOutShared batcher
+// their delivery records, unless they already have them from an earlier
+// attempt. It must be called on the items that are requeued or bisected
+// if the batch fails, not on a copy.
+func (b *Batcher) trackDeliveries(pending []pendingItem) {
+	if b.destinations == 0 {
+		return
+	}
+	for i := range pending {
+		if pending[i].delivered == nil {
+			pending[i].delivered = make(deliveries, b.destinations)
+		}
+	}
+}
+
+// undelivered returns the items of the batch not yet delivered to
+// destination i, their delivery records, and ctx carrying their
+// BatchMeta. It returns the batch as it is if none was delivered there.
+func undelivered(ctx context.Context, meta *BatchMeta, i int, batch []any) (context.Context, []any, []deliveries) {
+	if meta == nil || len(meta.delivered) != len(batch) {
+		return ctx, batch, nil
+	}
+	all := true
+	for _, del := range meta.delivered {
+		if del[i].Load() {
+			all = false
+			break
+		}
+	}
+	if all {
+		return ctx, batch, meta.delivered
+	}
+
+	sub := *meta
+	sub.ItemValues, sub.delivered = nil, nil
+	var items []any
+	for k, del := range meta.delivered {
+		if del[i].Load() {
+			continue
+		}
+		items = append(items, batch[k])
+		sub.delivered = append(sub.delivered, del)
+		if meta.ItemValues != nil {
+			sub.ItemValues = append(sub.ItemValues, meta.ItemValues[k])
+		}
+	}
+	return context.WithValue(ctx, batchMetaKey{}, &sub), items, sub.delivered
+}
+
+// deliver calls the destination handler, retrying failures with backoff
+func (f *FanOut) deliver(ctx context.Context, d Destination, batch []any) (*LoadFeedback, error) {
+	c := f.stats[d.Name]
+	c.batches.Add(1)
+
+	backoff := d.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		feedback, err := f.attempt(ctx, d, c, batch)
+		if err == nil || attempt >= d.MaxRetries {
+			if err != nil {
+				c.failures.Add(1)
+			}
+			return feedback, err
+		}
+
+		c.retries.Add(1)
+		t := time.NewTimer(backoff)
+		select {
+		case <-t.C:
+			backoff *= 2
+		case <-ctx.Done():
+			t.Stop()
+			c.failures.Add(1)
+			return feedback, errors.Join(err, ctx.Err())
+		}
+	}
+}
+
+// attempt calls the destination handler once, unless its bulkhead is full
+func (f *FanOut) attempt(ctx context.Context, d Destination, c *destinationCounters, batch []any) (*LoadFeedback, error) {
+	if c.slots != nil {
+		select {
+		case c.slots <- struct{}{}:
+			defer func() { <-c.slots }()
+		default:
+			c.rejected.Add(1)
+			return nil, ErrBulkheadFull
+		}
+	}
+	return d.HandlerFunc(ctx, batch)
+}
+
+// combine merges destination feedback according to the ScoreCombiner,
+// ranking it as the shared batcher scores feedback. The
+// pause and health signals of every destination are kept whatever the
+// combiner: the longest RetryAfter and ThrottleFor, Unhealthy if any
+// destination is, and the smallest SuggestedBatchSize.
+func (f *FanOut) combine(feedbacks []*LoadFeedback) *LoadFeedback {
+	var combined *LoadFeedback
+	if f.cfg.Combine == CombineWeighted {
+		combined = f.weighted(feedbacks)
+	} else {
+		combined = f.worst(feedbacks)
+	}
+	if combined == nil {
+		return nil
+	}
+
+	for _, fb := range feedbacks {
+		if fb == nil {
+			continue
+		}
+		combined.Unhealthy = combined.Unhealthy || fb.Unhealthy
+		combined.RetryAfter = max(combined.RetryAfter, fb.RetryAfter)
+		combined.ThrottleFor = max(combined.ThrottleFor, fb.ThrottleFor)
+		if s := fb.SuggestedBatchSize; s > 0 && (combined.SuggestedBatchSize == 0 || s < combined.SuggestedBatchSize) {
+			combined.SuggestedBatchSize = s
+		}
+	}
+	return combined
+}
+
+// worst returns a copy of the feedback with the highest load score
+func (f *FanOut) worst(feedbacks []*LoadFeedback) *LoadFeedback {
+	var worst *LoadFeedback
+	worstScore := 0.0
+	for _, fb := range feedbacks {
+		if fb == nil {
+			continue
+		}
+		if score := scoreFeedback(fb, f.cfg.Config.CustomMetrics); worst == nil || score > worstScore {
+			worst, worstScore = fb, score
+		}
+	}
+	if worst == nil {
+		return nil
+	}
+	combined := *worst
+	return &combined
+}
+
+// weighted blends the feedback by Destination.Weight. Numeric Custom
+// metrics are averaged over the destinations reporting them, so that
+// CustomMetrics score the blend; other Custom values are taken from the
+// first destination reporting them.
+func (f *FanOut) weighted(feedbacks []*LoadFeedback) *LoadFeedback {
+	var combined LoadFeedback
+	total := 0.0
+	var custom map[string]any
+	var sums, weights map[string]float64
+	for i, fb := range feedbacks {
+		if fb == nil {
+			continue
+		}
+		w := f.cfg.Destinations[i].Weight
+		total += w
+		combined.CPULoad += w * fb.CPULoad
+		combined.ErrorRate += w * fb.ErrorRate
+		combined.QueueDepth += int(w * float64(fb.QueueDepth))
+		combined.DBLocks += int(w * float64(fb.DBLocks))
+		combined.ProcessingTime = max(combined.ProcessingTime, fb.ProcessingTime)
+
+		for k, v := range fb.Custom {
+			if custom == nil {
+				custom = make(map[string]any, len(fb.Custom))
+				sums = make(map[string]float64, len(fb.Custom))
+				weights = make(map[string]float64, len(fb.Custom))
+			}
+			if x, ok := toFloat(v); ok {
+				sums[k] += w * x
+				weights[k] += w
+			} else if _, ok := custom[k]; !ok {
+				custom[k] = v
+			}
+		}
+	}
+	if total == 0 {
+		return nil
+	}
+	combined.CPULoad /= total
+	combined.ErrorRate /= total
+	combined.QueueDepth = int(float64(combined.QueueDepth) / total)
+	combined.DBLocks = int(float64(combined.DBLocks) / total)
+	for k, sum := range sums {
+		custom[k] = sum / weights[k]
+	}
+	combined.Custom = custom
+	return &combined
+}
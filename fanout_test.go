@@ -0,0 +1,393 @@
+package batcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFanOut_SharedDeliversToAll(t *testing.T) {
+	var primary, analytics atomic.Int64
+	var failures atomic.Int64
+
+	f, err := NewFanOut(FanOutConfig{
+		Config: Config{InitialBatchSize: 5},
+		Destinations: []Destination{
+			{
+				Name: "primary",
+				HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+					primary.Add(int64(len(batch)))
+					return &LoadFeedback{CPULoad: 0.2}, nil
+				},
+			},
+			{
+				Name:         "analytics",
+				MaxRetries:   2,
+				RetryBackoff: time.Millisecond,
+				HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+					// Fail once per batch, then succeed on retry
+					if failures.Add(1)%2 == 1 {
+						return nil, errors.New("transient")
+					}
+					analytics.Add(int64(len(batch)))
+					return &LoadFeedback{CPULoad: 0.9}, nil
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewFanOut() failed: %v", err)
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 10; i++ {
+		if err := f.Add(ctx, i); err != nil {
+			t.Errorf("Add() error: %v", err)
+		}
+	}
+	if err := f.Close(ctx); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+
+	if primary.Load() != 10 || analytics.Load() != 10 {
+		t.Errorf("Expected 10 items at each destination, got %d and %d", primary.Load(), analytics.Load())
+	}
+
+	stats := f.GetStats()
+	if ds := stats.Destinations["analytics"]; ds.Retries != 2 || ds.Failures != 0 {
+		t.Errorf("Expected 2 retries and no failures for analytics, got %+v", ds)
+	}
+
+	// CombineMax: the loaded analytics sink drives the shared score
+	if stats.Shared.AverageLoadScore < 0.5 {
+		t.Errorf("Expected combined score from the most loaded destination, got %v", stats.Shared.AverageLoadScore)
+	}
+}
+
+func TestFanOut_SeparateSizes(t *testing.T) {
+	var mu sync.Mutex
+	sizes := make(map[string][]int)
+	record := func(name string, cpu float64) HandlerFunc {
+		return func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			mu.Lock()
+			sizes[name] = append(sizes[name], len(batch))
+			mu.Unlock()
+			return &LoadFeedback{CPULoad: cpu}, nil
+		}
+	}
+
+	f, err := NewFanOut(FanOutConfig{
+		Config: Config{InitialBatchSize: 10, MaxBatchSize: 100},
+		Mode:   FanOutSeparate,
+		Destinations: []Destination{
+			{Name: "fast", HandlerFunc: record("fast", 0.0)},
+			{Name: "slow", HandlerFunc: record("slow", 1.0)},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewFanOut() failed: %v", err)
+	}
+	defer f.Close(context.Background())
+
+	ctx := context.Background()
+	for i := 0; i < 10; i++ {
+		f.Add(ctx, i)
+	}
+
+	for _, b := range f.separate {
+		b.adjustBatchSize()
+	}
+
+	stats := f.GetStats()
+	fast := stats.Destinations["fast"].Batcher.CurrentBatchSize
+	slow := stats.Destinations["slow"].Batcher.CurrentBatchSize
+	if fast <= 10 || slow >= 10 {
+		t.Errorf("Expected independent sizes (fast grows, slow shrinks), got fast=%d slow=%d", fast, slow)
+	}
+}
+
+func TestFanOut_Combine(t *testing.T) {
+	f := &FanOut{cfg: FanOutConfig{
+		Combine: CombineWeighted,
+		Destinations: []Destination{
+			{Name: "a", Weight: 3},
+			{Name: "b", Weight: 1},
+		},
+	}}
+
+	combined := f.combine([]*LoadFeedback{{CPULoad: 0.0}, {CPULoad: 1.0}})
+	if combined.CPULoad != 0.25 {
+		t.Errorf("Weighted CPULoad = %v, want 0.25", combined.CPULoad)
+	}
+
+	f.cfg.Combine = CombineMax
+	combined = f.combine([]*LoadFeedback{{CPULoad: 0.1}, nil, {CPULoad: 0.8}})
+	if combined.CPULoad != 0.8 {
+		t.Errorf("Max CPULoad = %v, want 0.8", combined.CPULoad)
+	}
+}
+
+func TestFanOut_CombineKeepsSignals(t *testing.T) {
+	f := &FanOut{cfg: FanOutConfig{
+		Config: Config{
+			CustomMetrics: []CustomMetric{{Name: "lag", Weight: 1, Critical: 100}},
+		},
+		Combine: CombineMax,
+		Destinations: []Destination{
+			{Name: "a", Weight: 3},
+			{Name: "b", Weight: 1},
+		},
+	}}
+	a := &LoadFeedback{CPULoad: 0.9, SuggestedBatchSize: 100, Custom: map[string]any{"lag": 0}}
+	b := &LoadFeedback{
+		CPULoad:            0.1,
+		Unhealthy:          true,
+		RetryAfter:         2 * time.Second,
+		SuggestedBatchSize: 50,
+		Custom:             map[string]any{"lag": 100},
+	}
+
+	// The custom metric makes b the most loaded, whatever its CPU
+	combined := f.combine([]*LoadFeedback{a, b})
+	if combined.CPULoad != 0.1 {
+		t.Errorf("Expected the destination scored worst by CustomMetrics, got CPULoad %v", combined.CPULoad)
+	}
+	if !combined.Unhealthy || combined.RetryAfter != 2*time.Second || combined.SuggestedBatchSize != 50 {
+		t.Errorf("Expected the pause and suggestion kept, got %+v", combined)
+	}
+
+	// A pause from a destination that is not the worst survives too
+	b.Custom["lag"] = 0
+	combined = f.combine([]*LoadFeedback{a, b})
+	if combined.CPULoad != 0.9 || !combined.Unhealthy || combined.RetryAfter != 2*time.Second {
+		t.Errorf("Expected a's load with b's pause, got %+v", combined)
+	}
+	if a.Unhealthy || a.RetryAfter != 0 {
+		t.Error("Expected the destination feedback to be left untouched")
+	}
+
+	f.cfg.Combine = CombineWeighted
+	b.Custom["lag"] = 100
+	b.ThrottleFor = time.Second
+	combined = f.combine([]*LoadFeedback{a, b})
+	if lag := combined.Custom["lag"]; lag != 25.0 {
+		t.Errorf("Expected the weighted lag 25, got %v", lag)
+	}
+	if combined.ThrottleFor != time.Second || combined.RetryAfter != 2*time.Second || !combined.Unhealthy {
+		t.Errorf("Expected the pause and throttle kept, got %+v", combined)
+	}
+	if combined.SuggestedBatchSize != 50 {
+		t.Errorf("Expected the smallest suggestion, got %d", combined.SuggestedBatchSize)
+	}
+}
+
+func TestNewFanOut_InvalidConfig(t *testing.T) {
+	handler := func(ctx context.Context, batch []any) (*LoadFeedback, error) { return nil, nil }
+
+	tests := []struct {
+		name string
+		cfg  FanOutConfig
+	}{
+		{"no destinations", FanOutConfig{Config: Config{InitialBatchSize: 1}}},
+		{"missing handler", FanOutConfig{
+			Config:       Config{InitialBatchSize: 1},
+			Destinations: []Destination{{Name: "a"}},
+		}},
+		{"duplicate name", FanOutConfig{
+			Config:       Config{InitialBatchSize: 1},
+			Destinations: []Destination{{Name: "a", HandlerFunc: handler}, {Name: "a", HandlerFunc: handler}},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewFanOut(tt.cfg); err != ErrInvalidConfig {
+				t.Errorf("Expected ErrInvalidConfig, got %v", err)
+			}
+		})
+	}
+}
+
+func TestFanOut_SeparateLabelsDestination(t *testing.T) {
+	f, err := NewFanOut(FanOutConfig{
+		Config: Config{Name: "events", InitialBatchSize: 5},
+		Destinations: []Destination{
+			{Name: "kafka", HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) { return nil, nil }},
+		},
+		Mode: FanOutSeparate,
+	})
+	if err != nil {
+		t.Fatalf("NewFanOut() failed: %v", err)
+	}
+	defer f.Close(context.Background())
+
+	stats := f.GetStats().Destinations["kafka"].Batcher
+	if stats.Name != "events" || stats.Labels["destination"] != "kafka" {
+		t.Errorf("Expected name events with destination label kafka, got %q %v", stats.Name, stats.Labels)
+	}
+}
+
+func TestFanOut_Bulkhead(t *testing.T) {
+	release := make(chan struct{})
+	entered := make(chan struct{}, 1)
+	var fast atomic.Int64
+
+	f, err := NewFanOut(FanOutConfig{
+		Config: Config{InitialBatchSize: 1},
+		Destinations: []Destination{
+			{
+				Name:        "slow",
+				MaxInFlight: 1,
+				HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+					entered <- struct{}{}
+					<-release
+					return nil, nil
+				},
+			},
+			{
+				Name: "fast",
+				HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+					fast.Add(int64(len(batch)))
+					return nil, nil
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewFanOut() failed: %v", err)
+	}
+	ctx := context.Background()
+
+	first := make(chan error, 1)
+	go func() { first <- f.Add(ctx, 1) }()
+	<-entered
+
+	// The slow destination is full: the second batch is refused there
+	// without waiting and still delivered to the fast one
+	if err := f.Add(ctx, 2); !errors.Is(err, ErrBulkheadFull) {
+		t.Errorf("Expected ErrBulkheadFull from the full destination, got %v", err)
+	}
+	if ds := f.GetStats().Destinations["slow"]; ds.Rejected != 1 || ds.InFlight != 1 {
+		t.Errorf("Expected 1 rejected and 1 in-flight batch, got %+v", ds)
+	}
+
+	close(release)
+	if err := <-first; err != nil {
+		t.Errorf("Expected the first batch to succeed, got %v", err)
+	}
+	if fast.Load() != 2 {
+		t.Errorf("Expected both items at the fast destination, got %d", fast.Load())
+	}
+	if ds := f.GetStats().Destinations["slow"]; ds.InFlight != 0 {
+		t.Errorf("Expected the slot to be released, got %d in flight", ds.InFlight)
+	}
+	f.Close(ctx)
+}
+
+func TestFanOut_SharedRetriesOnlyFailedDestinations(t *testing.T) {
+	var mu sync.Mutex
+	got := map[string][]any{}
+	calls := 0
+	record := func(name string, batch []any) {
+		mu.Lock()
+		defer mu.Unlock()
+		got[name] = append(got[name], batch...)
+	}
+
+	f, err := NewFanOut(FanOutConfig{
+		Config: Config{InitialBatchSize: 3, RequeuePolicy: RequeueFront, Timeout: time.Hour},
+		Destinations: []Destination{
+			{
+				Name: "primary",
+				HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+					record("primary", batch)
+					return nil, nil
+				},
+			},
+			{
+				Name: "analytics",
+				HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+					mu.Lock()
+					calls++
+					first := calls == 1
+					mu.Unlock()
+					if first {
+						return nil, errTransient
+					}
+					record("analytics", batch)
+					return nil, nil
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewFanOut() failed: %v", err)
+	}
+	ctx := context.Background()
+
+	for i := 1; i <= 3; i++ {
+		err := f.Add(ctx, i)
+		if i == 3 && !errors.Is(err, ErrRetryable) {
+			t.Errorf("Expected the analytics error from the flushing Add, got %v", err)
+		}
+	}
+	f.Add(ctx, 4)
+	if err := f.Close(ctx); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+
+	// The requeued items went to analytics again, but not to primary,
+	// which had accepted them
+	if fmt.Sprint(got["primary"]) != "[1 2 3 4]" {
+		t.Errorf("Expected every item once at primary, got %v", got["primary"])
+	}
+	if fmt.Sprint(got["analytics"]) != "[1 2 3 4]" {
+		t.Errorf("Expected every item once at analytics, got %v", got["analytics"])
+	}
+}
+
+func TestFanOut_SeparateDestinationError(t *testing.T) {
+	boom := errors.New("boom")
+	var primary atomic.Int64
+
+	f, err := NewFanOut(FanOutConfig{
+		Config: Config{InitialBatchSize: 1},
+		Mode:   FanOutSeparate,
+		Destinations: []Destination{
+			{
+				Name: "primary",
+				HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+					primary.Add(int64(len(batch)))
+					return nil, nil
+				},
+			},
+			{
+				Name: "analytics",
+				HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+					return nil, boom
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewFanOut() failed: %v", err)
+	}
+	defer f.Close(context.Background())
+
+	// Only the failed destination is reported; primary took the item
+	err = f.Add(context.Background(), 1)
+	var de *DestinationError
+	if !errors.As(err, &de) || de.Destination != "analytics" || !errors.Is(err, boom) {
+		t.Errorf("Expected a DestinationError for analytics, got %v", err)
+	}
+	if err.Error() != "analytics: boom" {
+		t.Errorf("Expected the destination in the message, got %q", err.Error())
+	}
+	if primary.Load() != 1 {
+		t.Errorf("Expected the item delivered to primary, got %d", primary.Load())
+	}
+}
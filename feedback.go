@@ -0,0 +1,230 @@
+package batcher
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidFeedback is returned by FeedbackBuilder.Build for values out
+// of range
+var ErrInvalidFeedback = errors.New("batcher: invalid feedback")
+
+// FeedbackBuilder builds a LoadFeedback field by field and checks the
+// ranges and units on Build, for adapters that translate backend
+// telemetry:
+//
+//	fb, err := batcher.NewFeedback().WithCPU(0.7).WithLatency(elapsed).Build()
+type FeedbackBuilder struct {
+	fb   LoadFeedback
+	errs []error
+}
+
+// NewFeedback starts a LoadFeedback
+func NewFeedback() *FeedbackBuilder {
+	return &FeedbackBuilder{}
+}
+
+// WithCPU sets the CPU load, a fraction between 0 and 1
+func (fb *FeedbackBuilder) WithCPU(load float64) *FeedbackBuilder {
+	fb.checkRatio("CPULoad", load)
+	fb.fb.CPULoad = load
+	return fb
+}
+
+// WithCPUPercent sets the CPU load from a percentage between 0 and 100
+func (fb *FeedbackBuilder) WithCPUPercent(percent float64) *FeedbackBuilder {
+	fb.checkRatio("CPULoad", percent/100)
+	fb.fb.CPULoad = percent / 100
+	return fb
+}
+
+// WithQueueDepth sets the number of items waiting in the backend
+func (fb *FeedbackBuilder) WithQueueDepth(depth int) *FeedbackBuilder {
+	fb.checkCount("QueueDepth", depth)
+	fb.fb.QueueDepth = depth
+	return fb
+}
+
+// WithLatency sets how long the backend took to process the batch
+func (fb *FeedbackBuilder) WithLatency(d time.Duration) *FeedbackBuilder {
+	if d < 0 {
+		fb.invalid("ProcessingTime", "must not be negative, got %v", d)
+	}
+	fb.fb.ProcessingTime = d
+	return fb
+}
+
+// WithErrorRate sets the fraction of the batch that failed, between 0
+// and 1
+func (fb *FeedbackBuilder) WithErrorRate(rate float64) *FeedbackBuilder {
+	fb.checkRatio("ErrorRate", rate)
+	fb.fb.ErrorRate = rate
+	return fb
+}
+
+// WithErrors sets the error rate from the number of failed items out of
+// total. A total of zero leaves the error rate at zero.
+func (fb *FeedbackBuilder) WithErrors(failed, total int) *FeedbackBuilder {
+	if failed < 0 || total < 0 || failed > total {
+		fb.invalid("ErrorRate", "%d failed out of %d", failed, total)
+		return fb
+	}
+	if total > 0 {
+		fb.fb.ErrorRate = float64(failed) / float64(total)
+	}
+	return fb
+}
+
+// WithDBLocks sets the number of database lock contentions
+func (fb *FeedbackBuilder) WithDBLocks(locks int) *FeedbackBuilder {
+	fb.checkCount("DBLocks", locks)
+	fb.fb.DBLocks = locks
+	return fb
+}
+
+// WithCustom sets a custom metric, see Config.CustomMetrics
+func (fb *FeedbackBuilder) WithCustom(name string, value any) *FeedbackBuilder {
+	if fb.fb.Custom == nil {
+		fb.fb.Custom = make(map[string]interface{})
+	}
+	fb.fb.Custom[name] = value
+	return fb
+}
+
+// WithRetryAfter asks the batcher to pause flushing for d
+func (fb *FeedbackBuilder) WithRetryAfter(d time.Duration) *FeedbackBuilder {
+	if d < 0 {
+		fb.invalid("RetryAfter", "must not be negative, got %v", d)
+	}
+	fb.fb.RetryAfter = d
+	return fb
+}
+
+// WithThrottle reports that the backend throttled the batch for d
+func (fb *FeedbackBuilder) WithThrottle(d time.Duration) *FeedbackBuilder {
+	if d < 0 {
+		fb.invalid("ThrottleFor", "must not be negative, got %v", d)
+	}
+	fb.fb.ThrottleFor = d
+	return fb
+}
+
+// WithSuggestedBatchSize sets the batch size the backend considers ideal
+func (fb *FeedbackBuilder) WithSuggestedBatchSize(size int) *FeedbackBuilder {
+	fb.checkCount("SuggestedBatchSize", size)
+	fb.fb.SuggestedBatchSize = size
+	return fb
+}
+
+// Unhealthy reports that the backend cannot take more work right now
+func (fb *FeedbackBuilder) Unhealthy() *FeedbackBuilder {
+	fb.fb.Unhealthy = true
+	return fb
+}
+
+// Build returns the feedback, or every out-of-range value as an error
+// wrapping ErrInvalidFeedback
+func (fb *FeedbackBuilder) Build() (*LoadFeedback, error) {
+	if len(fb.errs) > 0 {
+		return nil, errors.Join(fb.errs...)
+	}
+	out := fb.fb
+	return &out, nil
+}
+
+// FeedbackFromHTTP derives feedback from the response of a backend that
+// took latency to answer a batch. A 429 throttles and a 503 pauses
+// flushing for the Retry-After of the response, if any; a 503 without one
+// marks the backend unhealthy. Other 5xx responses count as a failed
+// batch. A nil response, e.g. after a transport error, counts as a
+// failed batch too.
+func FeedbackFromHTTP(resp *http.Response, latency time.Duration) *LoadFeedback {
+	fb := &LoadFeedback{ProcessingTime: max(latency, 0)}
+	if resp == nil {
+		fb.ErrorRate = 1
+		return fb
+	}
+
+	retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		fb.ErrorRate = 1
+		fb.ThrottleFor = retryAfter
+	case resp.StatusCode == http.StatusServiceUnavailable:
+		fb.ErrorRate = 1
+		fb.RetryAfter = retryAfter
+		fb.Unhealthy = retryAfter == 0
+	case resp.StatusCode >= 500:
+		fb.ErrorRate = 1
+	}
+	return fb
+}
+
+// FeedbackFromSQLResult derives feedback from the outcome of a batched
+// SQL statement that took latency. A failed statement counts as a failed
+// batch, and as a lock contention if the error mentions a deadlock or a
+// lock timeout, as the messages of the common drivers do. The rows
+// affected, if the driver reports them, are kept as the custom metric
+// "rows_affected".
+func FeedbackFromSQLResult(res sql.Result, latency time.Duration, err error) *LoadFeedback {
+	fb := &LoadFeedback{ProcessingTime: max(latency, 0)}
+	if err != nil {
+		fb.ErrorRate = 1
+		if isLockError(err) {
+			fb.DBLocks = 1
+		}
+		return fb
+	}
+	if res != nil {
+		if n, err := res.RowsAffected(); err == nil {
+			fb.Custom = map[string]interface{}{"rows_affected": n}
+		}
+	}
+	return fb
+}
+
+// --- Internal methods ---
+
+func (fb *FeedbackBuilder) invalid(field, format string, args ...any) {
+	fb.errs = append(fb.errs, fmt.Errorf("%w: %s %s", ErrInvalidFeedback, field, fmt.Sprintf(format, args...)))
+}
+
+func (fb *FeedbackBuilder) checkRatio(field string, v float64) {
+	if !isFinite(v) || v < 0 || v > 1 {
+		fb.invalid(field, "must be in [0, 1], got %g", v)
+	}
+}
+
+func (fb *FeedbackBuilder) checkCount(field string, n int) {
+	if n < 0 {
+		fb.invalid(field, "must not be negative, got %d", n)
+	}
+}
+
+// parseRetryAfter returns the delay of a Retry-After header, given in
+// seconds or as an HTTP date, or zero if it has none
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return max(time.Duration(secs)*time.Second, 0)
+	}
+	if at, err := http.ParseTime(v); err == nil {
+		return max(at.Sub(now), 0)
+	}
+	return 0
+}
+
+// isLockError reports whether a database error is a deadlock or lock
+// timeout, from its message
+func isLockError(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "deadlock") || strings.Contains(msg, "lock wait timeout") ||
+		strings.Contains(msg, "lock timeout") || strings.Contains(msg, "could not obtain lock")
+}
@@ -0,0 +1,111 @@
+package batcher
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestFeedbackBuilder(t *testing.T) {
+	fb, err := NewFeedback().
+		WithCPUPercent(70).
+		WithQueueDepth(12).
+		WithLatency(80*time.Millisecond).
+		WithErrors(1, 4).
+		WithCustom("replication_lag", 0.3).
+		Build()
+	if err != nil {
+		t.Fatalf("Build() failed: %v", err)
+	}
+	if fb.CPULoad != 0.7 || fb.QueueDepth != 12 || fb.ProcessingTime != 80*time.Millisecond || fb.ErrorRate != 0.25 {
+		t.Errorf("Expected the set values, got %+v", fb)
+	}
+	if fb.Custom["replication_lag"] != 0.3 {
+		t.Errorf("Expected custom metric, got %v", fb.Custom)
+	}
+
+	_, err = NewFeedback().WithCPU(70).WithLatency(-time.Second).WithErrors(5, 4).Build()
+	if !errors.Is(err, ErrInvalidFeedback) {
+		t.Fatalf("Expected ErrInvalidFeedback, got %v", err)
+	}
+	if n := len(err.(interface{ Unwrap() []error }).Unwrap()); n != 3 {
+		t.Errorf("Expected 3 problems reported, got %d: %v", n, err)
+	}
+}
+
+func TestFeedbackFromHTTP(t *testing.T) {
+	header := func(retryAfter string) http.Header {
+		h := http.Header{}
+		if retryAfter != "" {
+			h.Set("Retry-After", retryAfter)
+		}
+		return h
+	}
+
+	tests := []struct {
+		name       string
+		resp       *http.Response
+		errorRate  float64
+		throttle   time.Duration
+		retryAfter time.Duration
+		unhealthy  bool
+	}{
+		{"ok", &http.Response{StatusCode: 200, Header: header("")}, 0, 0, 0, false},
+		{"client error", &http.Response{StatusCode: 400, Header: header("")}, 0, 0, 0, false},
+		{"throttled", &http.Response{StatusCode: 429, Header: header("2")}, 1, 2 * time.Second, 0, false},
+		{"unavailable", &http.Response{StatusCode: 503, Header: header("5")}, 1, 0, 5 * time.Second, false},
+		{"unavailable without retry", &http.Response{StatusCode: 503, Header: header("")}, 1, 0, 0, true},
+		{"server error", &http.Response{StatusCode: 500, Header: header("")}, 1, 0, 0, false},
+		{"transport error", nil, 1, 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fb := FeedbackFromHTTP(tt.resp, 30*time.Millisecond)
+			if fb.ProcessingTime != 30*time.Millisecond {
+				t.Errorf("Expected latency 30ms, got %v", fb.ProcessingTime)
+			}
+			if fb.ErrorRate != tt.errorRate || fb.ThrottleFor != tt.throttle ||
+				fb.RetryAfter != tt.retryAfter || fb.Unhealthy != tt.unhealthy {
+				t.Errorf("Unexpected feedback %+v", fb)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	if d := parseRetryAfter("Mon, 01 Jan 2024 12:00:30 GMT", now); d != 30*time.Second {
+		t.Errorf("Expected 30s from an HTTP date, got %v", d)
+	}
+	if d := parseRetryAfter("soon", now); d != 0 {
+		t.Errorf("Expected 0 for garbage, got %v", d)
+	}
+}
+
+// rowsResult is a sql.Result reporting a fixed row count
+type rowsResult int64
+
+func (r rowsResult) LastInsertId() (int64, error) { return 0, errors.New("not supported") }
+func (r rowsResult) RowsAffected() (int64, error) { return int64(r), nil }
+
+var _ sql.Result = rowsResult(0)
+
+func TestFeedbackFromSQLResult(t *testing.T) {
+	fb := FeedbackFromSQLResult(rowsResult(42), 10*time.Millisecond, nil)
+	if fb.ErrorRate != 0 || fb.ProcessingTime != 10*time.Millisecond || fb.Custom["rows_affected"] != int64(42) {
+		t.Errorf("Unexpected feedback %+v", fb)
+	}
+
+	fb = FeedbackFromSQLResult(nil, time.Second, errors.New("Error 1213: Deadlock found when trying to get lock"))
+	if fb.ErrorRate != 1 || fb.DBLocks != 1 {
+		t.Errorf("Expected a failed batch with a lock contention, got %+v", fb)
+	}
+
+	fb = FeedbackFromSQLResult(nil, time.Second, errors.New("duplicate key"))
+	if fb.ErrorRate != 1 || fb.DBLocks != 0 {
+		t.Errorf("Expected a failed batch without lock contention, got %+v", fb)
+	}
+}
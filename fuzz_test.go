@@ -0,0 +1,117 @@
+package batcher
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+func FuzzNew(f *testing.F) {
+	f.Add(10, 1, 100, 0.2, int64(time.Second))
+	f.Add(1, 0, 0, -1.0, int64(-1))
+	f.Add(5, 10, 2, math.NaN(), int64(0))
+	f.Add(math.MaxInt, 1, math.MaxInt, 10.0, int64(1))
+
+	f.Fuzz(func(t *testing.T, initial, min, max int, factor float64, interval int64) {
+		b, err := New(Config{
+			InitialBatchSize:  initial,
+			MinBatchSize:      min,
+			MaxBatchSize:      max,
+			AdjustmentFactor:  factor,
+			LoadCheckInterval: time.Duration(interval),
+			HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+				return nil, nil
+			},
+		})
+		if err != nil {
+			return
+		}
+		defer b.Close(context.Background())
+
+		cfg := b.cfg
+		if cfg.MinBatchSize < 1 || cfg.MinBatchSize > cfg.MaxBatchSize {
+			t.Fatalf("Invalid bounds [%d, %d] accepted", cfg.MinBatchSize, cfg.MaxBatchSize)
+		}
+		if size := b.GetCurrentBatchSize(); size < cfg.MinBatchSize || size > cfg.MaxBatchSize {
+			t.Fatalf("Initial size %d outside [%d, %d]", size, cfg.MinBatchSize, cfg.MaxBatchSize)
+		}
+		if !(cfg.AdjustmentFactor > 0) || math.IsInf(cfg.AdjustmentFactor, 0) {
+			t.Fatalf("Invalid AdjustmentFactor %v accepted", cfg.AdjustmentFactor)
+		}
+	})
+}
+
+func FuzzLoadScore(f *testing.F) {
+	f.Add(0.5, 10, int64(time.Millisecond), 0.01, 5)
+	f.Add(math.NaN(), -10, int64(-1), math.Inf(1), -5)
+	f.Add(-1.0, math.MaxInt, int64(math.MaxInt64), -1.0, math.MinInt)
+
+	f.Fuzz(func(t *testing.T, cpu float64, queue int, proc int64, errRate float64, locks int) {
+		fb := LoadFeedback{
+			CPULoad:        cpu,
+			QueueDepth:     queue,
+			ProcessingTime: time.Duration(proc),
+			ErrorRate:      errRate,
+			DBLocks:        locks,
+		}
+		if score := fb.LoadScore(); !(score >= 0 && score <= 1) {
+			t.Fatalf("LoadScore() = %v for %+v, want within [0, 1]", score, fb)
+		}
+	})
+}
+
+func FuzzAdjustBatchSize(f *testing.F) {
+	f.Add(uint8(0), 10, 1, 100, 0.2, 0.5, 10, 0.01, []byte{1, 2, 3})
+	f.Add(uint8(1), 1, 1, 1, 5.0, math.NaN(), -1, math.Inf(-1), []byte{})
+	f.Add(uint8(2), 500, 1, math.MaxInt, 1e300, 1.0, 1000, 1.0, []byte{255, 0, 255})
+	f.Add(uint8(3), 7, 3, 9, 0.9, -3.0, 0, -1.0, []byte{9})
+
+	f.Fuzz(func(t *testing.T, strategy uint8, initial, min, max int, factor, cpu float64, queue int, errRate float64, steps []byte) {
+		strategies := []func() AdjustmentStrategy{
+			func() AdjustmentStrategy { return &ThresholdStrategy{} },
+			func() AdjustmentStrategy { return &QueueingStrategy{} },
+			func() AdjustmentStrategy { return &PIDStrategy{} },
+			func() AdjustmentStrategy { return &AIMDStrategy{} },
+		}
+
+		b, err := New(Config{
+			InitialBatchSize:   initial,
+			MinBatchSize:       min,
+			MaxBatchSize:       max,
+			AdjustmentFactor:   factor,
+			AdjustmentStrategy: strategies[int(strategy)%len(strategies)](),
+			LoadCheckInterval:  time.Hour,
+			HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+				return nil, nil
+			},
+		})
+		if err != nil {
+			return
+		}
+		defer b.Close(context.Background())
+
+		for i, step := range steps {
+			b.mu.Lock()
+			b.itemsAdded = int(step) * 10
+			b.lastAdjust = time.Now().Add(-time.Duration(step) * time.Millisecond)
+			b.recordFeedback(LoadFeedback{
+				CPULoad:        cpu * float64(step) / 255,
+				QueueDepth:     queue * i,
+				ProcessingTime: time.Duration(step) * time.Millisecond,
+				ErrorRate:      errRate,
+			}, int(step), 1)
+			b.mu.Unlock()
+
+			b.adjustBatchSize()
+
+			size := b.GetCurrentBatchSize()
+			if size < b.cfg.MinBatchSize || size > b.cfg.MaxBatchSize {
+				t.Fatalf("Step %d: size %d outside [%d, %d]", i, size, b.cfg.MinBatchSize, b.cfg.MaxBatchSize)
+			}
+			if score := b.GetStats().AggregatedLoadScore; math.IsNaN(score) || math.IsInf(score, 0) {
+				t.Fatalf("Step %d: aggregated load score %v", i, score)
+			}
+		}
+	})
+}
@@ -1,3 +1,10 @@
 module github.com/amirafroozeh1/Load-Aware-Batcher
 
 go 1.21
+
+require (
+	go.etcd.io/bbolt v1.3.10
+	go.opentelemetry.io/otel v1.28.0
+	go.opentelemetry.io/otel/metric v1.28.0
+	golang.org/x/sys v0.4.0 // indirect
+)
@@ -0,0 +1,69 @@
+package batcher
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// --- Internal methods ---
+
+// ensureInit runs Config.InitFunc before the first batch is handled,
+// retrying with a doubling backoff. If every attempt fails, the batch
+// fails with the last error and the next batch tries again.
+func (b *Batcher) ensureInit(ctx context.Context) error {
+	if b.cfg.InitFunc == nil || b.initDone.Load() {
+		return nil
+	}
+
+	b.initMu.Lock()
+	defer b.initMu.Unlock()
+
+	if b.initDone.Load() {
+		return nil
+	}
+
+	backoff := b.cfg.InitBackoff
+	var err error
+	for attempt := 0; attempt <= b.cfg.InitRetries; attempt++ {
+		if attempt > 0 {
+			t := time.NewTimer(backoff)
+			select {
+			case <-t.C:
+			case <-ctx.Done():
+				t.Stop()
+				return fmt.Errorf("batcher: init: %w", ctx.Err())
+			}
+			backoff *= 2
+		}
+		if err = b.cfg.InitFunc(ctx); err == nil {
+			b.initDone.Store(true)
+			return nil
+		}
+	}
+	return fmt.Errorf("batcher: init: %w", err)
+}
+
+// healthCheckLoop runs Config.HealthFunc every HealthCheckInterval and
+// pauses flushing for an interval after every failed check
+func (b *Batcher) healthCheckLoop() {
+	defer b.wg.Done()
+
+	ticker := time.NewTicker(b.cfg.HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), b.cfg.HealthCheckInterval)
+			err := b.cfg.HealthFunc(ctx)
+			cancel()
+			if err != nil {
+				b.healthFailures.Add(1)
+				b.pauseFor(b.cfg.HealthCheckInterval)
+			}
+		case <-b.stopAdjust:
+			return
+		}
+	}
+}
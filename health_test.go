@@ -0,0 +1,117 @@
+package batcher
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBatcher_InitFunc(t *testing.T) {
+	var calls, handled atomic.Int64
+	b, err := New(Config{
+		InitialBatchSize: 1,
+		InitRetries:      2,
+		InitBackoff:      time.Millisecond,
+		InitFunc: func(ctx context.Context) error {
+			if calls.Add(1) < 3 {
+				return errors.New("connection refused")
+			}
+			return nil
+		},
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			handled.Add(1)
+			return nil, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	if calls.Load() != 0 {
+		t.Errorf("Expected no InitFunc call before the first flush, got %d", calls.Load())
+	}
+	for i := 0; i < 3; i++ {
+		if err := b.Add(context.Background(), i); err != nil {
+			t.Fatalf("Add() failed: %v", err)
+		}
+	}
+	if calls.Load() != 3 {
+		t.Errorf("Expected InitFunc to succeed on its third call and not run again, got %d calls", calls.Load())
+	}
+	if handled.Load() != 3 {
+		t.Errorf("Expected 3 batches handled, got %d", handled.Load())
+	}
+}
+
+func TestBatcher_InitFuncFails(t *testing.T) {
+	initErr := errors.New("schema mismatch")
+	var calls atomic.Int64
+	b, err := New(Config{
+		InitialBatchSize: 1,
+		InitRetries:      1,
+		InitBackoff:      time.Millisecond,
+		InitFunc: func(ctx context.Context) error {
+			calls.Add(1)
+			return initErr
+		},
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			t.Error("Expected the handler not to run without init")
+			return nil, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	if err := b.Add(context.Background(), 1); !errors.Is(err, initErr) {
+		t.Errorf("Expected the init error, got %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("Expected 2 InitFunc calls, got %d", calls.Load())
+	}
+	if stats := b.GetStats(); stats.HandlerErrors != 1 {
+		t.Errorf("Expected the batch to count as failed, got %d errors", stats.HandlerErrors)
+	}
+}
+
+func TestBatcher_HealthFunc(t *testing.T) {
+	var healthy atomic.Bool
+	b, err := New(Config{
+		InitialBatchSize:    10,
+		HealthCheckInterval: 20 * time.Millisecond,
+		HealthFunc: func(ctx context.Context) error {
+			if !healthy.Load() {
+				return errors.New("unreachable")
+			}
+			return nil
+		},
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			return nil, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	deadline := time.Now().Add(2 * time.Second)
+	for b.GetStats().HealthCheckFailures == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected a failed health check")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if stats := b.GetStats(); stats.PausedUntil.IsZero() {
+		t.Error("Expected a failed health check to pause flushing")
+	}
+
+	healthy.Store(true)
+	time.Sleep(60 * time.Millisecond)
+	if stats := b.GetStats(); !stats.PausedUntil.IsZero() {
+		t.Errorf("Expected flushing to resume once healthy, paused until %v", stats.PausedUntil)
+	}
+}
@@ -0,0 +1,63 @@
+package batcher
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"sync"
+	"time"
+)
+
+// crockford is the Crockford base32 alphabet used by ULIDs
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+var (
+	ulidMu      sync.Mutex
+	ulidLastMs  uint64
+	ulidLastRnd [10]byte
+)
+
+// newULID returns a ULID: a 26 character, lexicographically sortable
+// identifier made of a 48-bit millisecond timestamp and 80 random bits.
+// IDs generated within the same millisecond increment the random part so
+// they stay strictly ordered.
+func newULID() string {
+	ms := uint64(time.Now().UnixMilli())
+
+	ulidMu.Lock()
+	var rnd [10]byte
+	if ms == ulidLastMs {
+		rnd = ulidLastRnd
+		for i := len(rnd) - 1; i >= 0; i-- {
+			rnd[i]++
+			if rnd[i] != 0 {
+				break
+			}
+		}
+	} else {
+		_, _ = rand.Read(rnd[:])
+	}
+	ulidLastMs, ulidLastRnd = ms, rnd
+	ulidMu.Unlock()
+
+	var raw [16]byte
+	var ts [8]byte
+	binary.BigEndian.PutUint64(ts[:], ms)
+	copy(raw[:6], ts[2:])
+	copy(raw[6:], rnd[:])
+
+	return encodeBase32(raw)
+}
+
+// encodeBase32 encodes 128 bits as 26 Crockford base32 characters
+func encodeBase32(raw [16]byte) string {
+	hi := binary.BigEndian.Uint64(raw[:8])
+	lo := binary.BigEndian.Uint64(raw[8:])
+
+	var out [26]byte
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
@@ -0,0 +1,79 @@
+package batcher
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestNewULID(t *testing.T) {
+	seen := make(map[string]bool)
+	prev := ""
+	for i := 0; i < 1000; i++ {
+		id := newULID()
+		if len(id) != 26 {
+			t.Fatalf("Expected 26 characters, got %q", id)
+		}
+		for _, c := range id {
+			if !strings.ContainsRune(crockford, c) {
+				t.Fatalf("Invalid character %q in %q", c, id)
+			}
+		}
+		if seen[id] {
+			t.Fatalf("Duplicate ID %q", id)
+		}
+		if id <= prev {
+			t.Fatalf("IDs not increasing: %q after %q", id, prev)
+		}
+		seen[id] = true
+		prev = id
+	}
+}
+
+func TestEncodeBase32(t *testing.T) {
+	var raw [16]byte
+	if got := encodeBase32(raw); got != strings.Repeat("0", 26) {
+		t.Errorf("encodeBase32(zero) = %q", got)
+	}
+	for i := range raw {
+		raw[i] = 0xff
+	}
+	if got := encodeBase32(raw); got != "7"+strings.Repeat("Z", 25) {
+		t.Errorf("encodeBase32(max) = %q", got)
+	}
+}
+
+func TestBatcher_BatchID(t *testing.T) {
+	var mu sync.Mutex
+	var ids []string
+
+	n := 0
+	b, err := New(Config{
+		InitialBatchSize: 2,
+		IDFunc: func() string {
+			n++
+			return "batch-" + string(rune('a'+n-1))
+		},
+		HandlerFuncV2: func(ctx context.Context, batch []any, meta BatchMeta) (*LoadFeedback, error) {
+			mu.Lock()
+			ids = append(ids, meta.BatchID)
+			mu.Unlock()
+			return nil, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	for i := 0; i < 4; i++ {
+		b.Add(context.Background(), i)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(ids) != 2 || ids[0] != "batch-a" || ids[1] != "batch-b" {
+		t.Errorf("Expected [batch-a batch-b], got %v", ids)
+	}
+}
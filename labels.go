@@ -0,0 +1,41 @@
+package batcher
+
+import (
+	"context"
+	"runtime/pprof"
+	"sort"
+)
+
+// Goroutine roles used in pprof labels
+const (
+	roleAdjuster = "adjuster"
+	roleTimer    = "timer-flush"
+	roleHandler  = "handler"
+	roleHealth   = "health-check"
+	roleFlusher  = "flusher"
+)
+
+// withLabels runs f with pprof labels naming this batcher, its Labels and
+// the role of the goroutine, so CPU and goroutine profiles of services
+// embedding many batchers can be attributed
+func (b *Batcher) withLabels(ctx context.Context, role string, f func(ctx context.Context)) {
+	pairs := append(b.labels[:len(b.labels):len(b.labels)], "role", role)
+	pprof.Do(ctx, pprof.Labels(pairs...), f)
+}
+
+// labelPairs returns the batcher name and Labels as key/value pairs,
+// sorted by key after the name
+func (b *Batcher) labelPairs() []string {
+	keys := make([]string, 0, len(b.cfg.Labels))
+	for k := range b.cfg.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, 2+2*len(keys))
+	pairs = append(pairs, "batcher", b.cfg.Name)
+	for _, k := range keys {
+		pairs = append(pairs, k, b.cfg.Labels[k])
+	}
+	return pairs
+}
@@ -0,0 +1,41 @@
+package batcher
+
+import (
+	"context"
+	"runtime/pprof"
+	"testing"
+)
+
+func TestBatcher_PprofLabels(t *testing.T) {
+	labels := make(chan map[string]string, 1)
+	b, err := New(Config{
+		Name:             "orders",
+		Labels:           map[string]string{"table": "order_items"},
+		InitialBatchSize: 1,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			got := make(map[string]string)
+			pprof.ForLabels(ctx, func(k, v string) bool {
+				got[k] = v
+				return true
+			})
+			labels <- got
+			return nil, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	b.Add(context.Background(), 1)
+
+	got := <-labels
+	if got["batcher"] != "orders" || got["table"] != "order_items" || got["role"] != roleHandler {
+		t.Errorf("Expected batcher=orders table=order_items role=%s labels, got %v", roleHandler, got)
+	}
+
+	stats := b.GetStats()
+	if stats.Name != "orders" || stats.Labels["table"] != "order_items" {
+		t.Errorf("Expected name and labels in stats, got %q %v", stats.Name, stats.Labels)
+	}
+}
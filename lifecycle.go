@@ -0,0 +1,72 @@
+package batcher
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// LifecycleConfig configures AttachLifecycleWithConfig
+type LifecycleConfig struct {
+	// Signals that trigger a drain (default: SIGINT and SIGTERM)
+	Signals []os.Signal
+
+	// DrainTimeout bounds how long the final flush may take (default: 30s)
+	DrainTimeout time.Duration
+}
+
+// AttachLifecycle drains the batcher when ctx is cancelled or the process
+// receives SIGINT or SIGTERM, so no buffered items are lost on shutdown.
+// See AttachLifecycleWithConfig.
+func AttachLifecycle(ctx context.Context, b *Batcher) <-chan error {
+	return AttachLifecycleWithConfig(ctx, b, LifecycleConfig{})
+}
+
+// AttachLifecycleWithConfig drains the batcher when ctx is cancelled or one
+// of the configured signals arrives: the batcher is closed, flushing
+// buffered items, and batches already being handled are waited for. The
+// returned channel receives the drain result once and is then closed, which
+// lets graceful-shutdown code block until the batcher is fully drained.
+//
+// The signals are taken from their default handling, so they no longer
+// terminate the process: after a signal, the caller must exit once the
+// channel fires. Signals are handed back as soon as the drain starts, so
+// a second one terminates the process while draining. If the batcher is
+// closed otherwise, the channel receives the result of that Close and the
+// signals are handed back as well.
+func AttachLifecycleWithConfig(ctx context.Context, b *Batcher, cfg LifecycleConfig) <-chan error {
+	if len(cfg.Signals) == 0 {
+		cfg.Signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	if cfg.DrainTimeout <= 0 {
+		cfg.DrainTimeout = 30 * time.Second
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, cfg.Signals...)
+
+	done := make(chan error, 1)
+	go func() {
+		defer close(done)
+
+		select {
+		case <-ctx.Done():
+		case <-sigCh:
+		case <-b.closeDone:
+		}
+		signal.Stop(sigCh)
+
+		// The parent context is typically already cancelled here, so the
+		// drain gets its own deadline
+		drainCtx, cancel := context.WithTimeout(context.Background(), cfg.DrainTimeout)
+		defer cancel()
+
+		err := b.Close(drainCtx)
+		done <- errors.Join(err, b.FlushAndWait(drainCtx))
+	}()
+
+	return done
+}
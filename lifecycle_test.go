@@ -0,0 +1,192 @@
+package batcher
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAttachLifecycle_ContextCancel(t *testing.T) {
+	var processed atomic.Int64
+
+	b, err := New(Config{
+		InitialBatchSize: 100,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			processed.Add(int64(len(batch)))
+			return nil, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := AttachLifecycle(ctx, b)
+
+	for i := 0; i < 10; i++ {
+		b.Add(context.Background(), i)
+	}
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Drain error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Lifecycle did not drain after cancellation")
+	}
+
+	if processed.Load() != 10 {
+		t.Errorf("Expected 10 items drained, got %d", processed.Load())
+	}
+	if err := b.Add(context.Background(), 11); err != ErrClosed {
+		t.Errorf("Expected ErrClosed after drain, got %v", err)
+	}
+}
+
+func TestAttachLifecycle_ClosedElsewhere(t *testing.T) {
+	errFlush := errors.New("flush failed")
+	b, err := New(Config{
+		InitialBatchSize: 100,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			return nil, errFlush
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	// ctx is never cancelled: closing the batcher ends the lifecycle
+	done := AttachLifecycle(context.Background(), b)
+	b.Add(context.Background(), 1)
+	b.Close(context.Background())
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, errFlush) {
+			t.Errorf("Expected the result of the Close, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Lifecycle did not end after the batcher was closed")
+	}
+}
+
+func TestBatcher_CloseGrace(t *testing.T) {
+	var processed atomic.Int64
+
+	b, err := New(Config{
+		InitialBatchSize: 100,
+		CloseGrace:       50 * time.Millisecond,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			processed.Add(int64(len(batch)))
+			return nil, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	closed := make(chan error, 1)
+	start := time.Now()
+	go func() { closed <- b.Close(context.Background()) }()
+	time.Sleep(10 * time.Millisecond)
+
+	// Still within the grace window
+	if err := b.Add(context.Background(), 1); err != nil {
+		t.Fatalf("Expected Add to be accepted during the grace window, got %v", err)
+	}
+	if err := b.Close(context.Background()); err != nil {
+		t.Errorf("Expected a second Close to return nil, got %v", err)
+	}
+
+	if err := <-closed; err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Expected Close to wait out the grace window, took %v", elapsed)
+	}
+	if n := processed.Load(); n != 1 {
+		t.Errorf("Expected the late item in the final drain, got %d processed", n)
+	}
+	if err := b.Add(context.Background(), 2); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed after the grace window, got %v", err)
+	}
+}
+
+func TestBatcher_SecondCloseWaits(t *testing.T) {
+	errFlush := errors.New("flush failed")
+	var processed atomic.Int64
+
+	b, err := New(Config{
+		InitialBatchSize: 100,
+		CloseGrace:       50 * time.Millisecond,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			processed.Add(int64(len(batch)))
+			return nil, errFlush
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	b.Add(context.Background(), 1)
+
+	first := make(chan error, 1)
+	go func() { first <- b.Close(context.Background()) }()
+	time.Sleep(10 * time.Millisecond)
+
+	// A deferred Close racing with the drain reports its outcome
+	if err := b.Close(context.Background()); !errors.Is(err, errFlush) {
+		t.Errorf("Expected the second Close to return the drain error, got %v", err)
+	}
+	if n := processed.Load(); n != 1 {
+		t.Errorf("Expected the second Close to return after the drain, got %d processed", n)
+	}
+	if err := <-first; !errors.Is(err, errFlush) {
+		t.Errorf("Expected the first Close to return the drain error, got %v", err)
+	}
+
+	// Its ctx still bounds the wait
+	b2, err := New(Config{
+		InitialBatchSize: 10,
+		CloseGrace:       time.Hour,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			return nil, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	closeCtx, cancel := context.WithCancel(context.Background())
+	go b2.Close(closeCtx)
+	time.Sleep(10 * time.Millisecond)
+	short, cancelShort := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelShort()
+	if err := b2.Close(short); err != context.DeadlineExceeded {
+		t.Errorf("Expected the second Close to give up with its ctx, got %v", err)
+	}
+	cancel()
+}
+
+func TestBatcher_CloseGraceContext(t *testing.T) {
+	b, err := New(Config{
+		InitialBatchSize: 10,
+		CloseGrace:       time.Hour,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			return nil, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	b.Close(ctx)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected a done ctx to end the grace window, took %v", elapsed)
+	}
+}
@@ -0,0 +1,48 @@
+//go:build unix
+
+package batcher
+
+import (
+	"context"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestAttachLifecycle_Signal(t *testing.T) {
+	var processed atomic.Int64
+
+	b, err := New(Config{
+		InitialBatchSize: 100,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			processed.Add(int64(len(batch)))
+			return nil, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	done := AttachLifecycleWithConfig(context.Background(), b, LifecycleConfig{Signals: []os.Signal{syscall.SIGUSR1}})
+	b.Add(context.Background(), 1)
+
+	// The signal is taken from its default handling, which would
+	// terminate the test binary
+	if err := syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatalf("Kill() failed: %v", err)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Drain error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Lifecycle did not drain after the signal")
+	}
+	if processed.Load() != 1 {
+		t.Errorf("Expected 1 item drained, got %d", processed.Load())
+	}
+}
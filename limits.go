@@ -0,0 +1,40 @@
+package batcher
+
+// SetLimits changes the bounds the batch size adapts within, for example
+// to give one shard of a Router a different capacity than the rest. A
+// zero min or max keeps the current bound. The current batch size is
+// clamped into the new bounds. It returns ErrInvalidConfig if the bounds
+// are negative or min exceeds max.
+func (b *Batcher) SetLimits(min, max int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if min == 0 {
+		min = b.cfg.MinBatchSize
+	}
+	if max == 0 {
+		max = b.cfg.MaxBatchSize
+	}
+	if min < 1 || max < min {
+		return ErrInvalidConfig
+	}
+
+	b.setLimitsLocked(min, max)
+	if b.currentBatchSize < min {
+		b.setBatchSizeLocked(min)
+	} else if b.currentBatchSize > max {
+		b.setBatchSizeLocked(max)
+	}
+	return nil
+}
+
+// --- Internal methods ---
+
+// setLimitsLocked updates the batch size bounds and their lock-free
+// mirrors
+func (b *Batcher) setLimitsLocked(min, max int) {
+	b.cfg.MinBatchSize = min
+	b.cfg.MaxBatchSize = max
+	b.minBatchSize.Store(int64(min))
+	b.maxBatchSize.Store(int64(max))
+}
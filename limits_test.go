@@ -0,0 +1,51 @@
+package batcher
+
+import (
+	"context"
+	"testing"
+)
+
+func TestBatcher_SetLimits(t *testing.T) {
+	b, err := New(Config{
+		InitialBatchSize: 50,
+		MinBatchSize:     10,
+		MaxBatchSize:     100,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			return nil, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	if err := b.SetLimits(5, 20); err != nil {
+		t.Fatalf("SetLimits() failed: %v", err)
+	}
+	stats := b.GetStats()
+	if stats.MinBatchSize != 5 || stats.MaxBatchSize != 20 {
+		t.Errorf("Expected limits [5, 20], got [%d, %d]", stats.MinBatchSize, stats.MaxBatchSize)
+	}
+	if stats.CurrentBatchSize != 20 {
+		t.Errorf("Expected batch size clamped to 20, got %d", stats.CurrentBatchSize)
+	}
+
+	// Zero keeps the current bound
+	if err := b.SetLimits(30, 0); err == nil {
+		t.Error("Expected an error for min above the kept max")
+	}
+	if err := b.SetLimits(0, 200); err != nil {
+		t.Fatalf("SetLimits() failed: %v", err)
+	}
+	stats = b.GetStats()
+	if stats.MinBatchSize != 5 || stats.MaxBatchSize != 200 {
+		t.Errorf("Expected limits [5, 200], got [%d, %d]", stats.MinBatchSize, stats.MaxBatchSize)
+	}
+	if stats.CurrentBatchSize != 20 {
+		t.Errorf("Expected batch size 20 to be kept, got %d", stats.CurrentBatchSize)
+	}
+
+	if err := b.SetLimits(-1, 10); err != ErrInvalidConfig {
+		t.Errorf("Expected ErrInvalidConfig, got %v", err)
+	}
+}
@@ -0,0 +1,156 @@
+package batcher
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// LoaderConfig holds the configuration for a Loader
+type LoaderConfig struct {
+	// Config is the batcher configuration. Its handlers are ignored;
+	// HandlerFunc below handles the batches.
+	Config Config
+
+	// HandlerFunc loads a batch of keys and returns one result per key,
+	// in batch order. An error fails every key of the batch.
+	HandlerFunc func(ctx context.Context, keys []any) ([]ItemResult, *LoadFeedback, error)
+
+	// Cache, if set, caches the values loaded, so that keys loaded again
+	// within its TTL are served without being batched. Errors are not
+	// cached.
+	Cache *CacheConfig
+}
+
+// Loader batches the keys of concurrent Load calls into one call of the
+// handler, dataloader style, and hands each caller the result for its
+// key. Keys are batched like items: the batch size adapts to the load the
+// handler reports.
+type Loader struct {
+	b     *Batcher
+	cfg   LoaderConfig
+	cache ResultCache
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// loaderReplyKey is the context key of the reply channel of a Load
+type loaderReplyKey struct{}
+
+// NewLoader creates a Loader with the given configuration. HandlerFunc
+// must be set.
+func NewLoader(cfg LoaderConfig) (*Loader, error) {
+	if cfg.HandlerFunc == nil {
+		return nil, ErrInvalidConfig
+	}
+
+	l := &Loader{cfg: cfg}
+	if cfg.Cache != nil {
+		l.cache = cfg.Cache.store()
+	}
+	bcfg := cfg.Config
+	bcfg.HandlerFunc = nil
+	bcfg.PlainHandlerFunc = nil
+	bcfg.HandlerFuncV2 = l.handle
+	bcfg.ContextKeys = append(bcfg.ContextKeys[:len(bcfg.ContextKeys):len(bcfg.ContextKeys)], loaderReplyKey{})
+
+	b, err := New(bcfg)
+	if err != nil {
+		return nil, err
+	}
+	l.b = b
+	return l, nil
+}
+
+// Load returns the value for key, from the cache or else from the batch
+// the key is added to. It waits for the batch to be handled or for ctx
+// to be done.
+func (l *Loader) Load(ctx context.Context, key any) (any, error) {
+	if l.cache != nil {
+		if v, ok := l.cache.Get(key); ok {
+			l.hits.Add(1)
+			return v, nil
+		}
+		l.misses.Add(1)
+	}
+
+	reply := make(chan ItemResult, 1)
+	err := l.b.Add(context.WithValue(ctx, loaderReplyKey{}, reply), key)
+
+	// A batch flushed by the Add itself has answered already; otherwise
+	// an error means the key was not admitted
+	select {
+	case r := <-reply:
+		return r.Value, r.Err
+	default:
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case r := <-reply:
+		return r.Value, r.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Forget removes key from the cache, e.g. after it was written
+func (l *Loader) Forget(key any) {
+	if l.cache != nil {
+		l.cache.Delete(key)
+	}
+}
+
+// Flush flushes the current batch, if any
+func (l *Loader) Flush(ctx context.Context) error {
+	return l.b.Flush(ctx)
+}
+
+// Close closes the loader, flushing the keys still buffered
+func (l *Loader) Close(ctx context.Context) error {
+	return l.b.Close(ctx)
+}
+
+// Batcher returns the underlying Batcher, e.g. for its statistics
+func (l *Loader) Batcher() *Batcher {
+	return l.b
+}
+
+// GetStats returns the cache statistics
+func (l *Loader) GetStats() LoaderStats {
+	return LoaderStats{
+		CacheHits:   l.hits.Load(),
+		CacheMisses: l.misses.Load(),
+	}
+}
+
+// LoaderStats holds loader statistics
+type LoaderStats struct {
+	// CacheHits and CacheMisses count the Loads served from the cache and
+	// those that were batched, respectively; both zero without a cache
+	CacheHits   int64
+	CacheMisses int64
+}
+
+// --- Internal methods ---
+
+// handle loads a batch of keys, caches the values and answers every Load
+func (l *Loader) handle(ctx context.Context, keys []any, meta BatchMeta) (*LoadFeedback, error) {
+	results, fb, err := l.cfg.HandlerFunc(ctx, keys)
+	if err != nil {
+		return fb, err
+	}
+	err = DemuxResults(len(keys), results, func(i int, r ItemResult) {
+		if r.Err == nil && l.cache != nil {
+			l.cache.Set(keys[i], r.Value)
+		}
+		reply, _ := meta.Value(i, loaderReplyKey{}).(chan ItemResult)
+		select {
+		case reply <- r:
+		default:
+		}
+	})
+	return fb, err
+}
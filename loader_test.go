@@ -0,0 +1,142 @@
+package batcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestLoader returns a loader that loads key k as "v<k>" and counts
+// the keys it was asked for
+func newTestLoader(t *testing.T, batchSize int, cache *CacheConfig, loaded *atomic.Int64) *Loader {
+	t.Helper()
+	l, err := NewLoader(LoaderConfig{
+		Config: Config{
+			InitialBatchSize: batchSize,
+			Timeout:          5 * time.Millisecond,
+		},
+		HandlerFunc: func(ctx context.Context, keys []any) ([]ItemResult, *LoadFeedback, error) {
+			loaded.Add(int64(len(keys)))
+			results := make([]ItemResult, len(keys))
+			for i, k := range keys {
+				results[i] = ItemResult{Value: fmt.Sprintf("v%d", k)}
+			}
+			return results, &LoadFeedback{}, nil
+		},
+		Cache: cache,
+	})
+	if err != nil {
+		t.Fatalf("NewLoader() failed: %v", err)
+	}
+	t.Cleanup(func() { l.Close(context.Background()) })
+	return l
+}
+
+func TestLoader_Load(t *testing.T) {
+	var loaded atomic.Int64
+	l := newTestLoader(t, 10, nil, &loaded)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 25; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			v, err := l.Load(context.Background(), i)
+			if err != nil {
+				t.Errorf("Load(%d) failed: %v", i, err)
+				return
+			}
+			if want := fmt.Sprintf("v%d", i); v != want {
+				t.Errorf("Expected %q for key %d, got %v", want, i, v)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if n := l.Batcher().GetStats().Batches; n >= 25 {
+		t.Errorf("Expected keys to be batched, got %d batches for 25 keys", n)
+	}
+	if stats := l.GetStats(); stats.CacheHits != 0 || stats.CacheMisses != 0 {
+		t.Errorf("Expected no cache statistics without a cache, got %+v", stats)
+	}
+}
+
+func TestLoader_Cache(t *testing.T) {
+	var loaded atomic.Int64
+	l := newTestLoader(t, 1, &CacheConfig{TTL: time.Hour}, &loaded)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if v, err := l.Load(ctx, 7); err != nil || v != "v7" {
+			t.Fatalf("Expected v7, got %v, %v", v, err)
+		}
+	}
+	if n := loaded.Load(); n != 1 {
+		t.Errorf("Expected the key to be loaded once, got %d", n)
+	}
+	if stats := l.GetStats(); stats.CacheHits != 2 || stats.CacheMisses != 1 {
+		t.Errorf("Expected 2 hits and 1 miss, got %+v", stats)
+	}
+
+	l.Forget(7)
+	l.Load(ctx, 7)
+	if n := loaded.Load(); n != 2 {
+		t.Errorf("Expected a forgotten key to be loaded again, got %d loads", n)
+	}
+}
+
+func TestLoader_Errors(t *testing.T) {
+	boom := errors.New("boom")
+	notFound := errors.New("not found")
+	var calls atomic.Int64
+
+	l, err := NewLoader(LoaderConfig{
+		Config: Config{InitialBatchSize: 1},
+		HandlerFunc: func(ctx context.Context, keys []any) ([]ItemResult, *LoadFeedback, error) {
+			calls.Add(1)
+			switch keys[0] {
+			case "fail":
+				return nil, nil, boom
+			case "short":
+				return nil, nil, nil
+			default:
+				return []ItemResult{{Err: notFound}}, nil, nil
+			}
+		},
+		Cache: &CacheConfig{},
+	})
+	if err != nil {
+		t.Fatalf("NewLoader() failed: %v", err)
+	}
+	defer l.Close(context.Background())
+	ctx := context.Background()
+
+	if _, err := l.Load(ctx, "fail"); !errors.Is(err, boom) {
+		t.Errorf("Expected the batch error, got %v", err)
+	}
+	if _, err := l.Load(ctx, "short"); !errors.Is(err, ErrResultMismatch) {
+		t.Errorf("Expected ErrResultMismatch, got %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := l.Load(ctx, "missing"); !errors.Is(err, notFound) {
+			t.Errorf("Expected the item error, got %v", err)
+		}
+	}
+	if n := calls.Load(); n != 4 {
+		t.Errorf("Expected errors not to be cached, got %d handler calls", n)
+	}
+}
+
+func TestLoader_Closed(t *testing.T) {
+	var loaded atomic.Int64
+	l := newTestLoader(t, 10, nil, &loaded)
+	l.Close(context.Background())
+
+	if _, err := l.Load(context.Background(), 1); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+}
@@ -0,0 +1,119 @@
+package batcher
+
+import (
+	"errors"
+	"math"
+	"runtime/metrics"
+	"sync"
+	"time"
+)
+
+// MemoryPressureConfig makes the batcher watch its own process heap and GC
+// pauses and treat high memory pressure as backend overload
+type MemoryPressureConfig struct {
+	// HeapLimit is the heap size in bytes that counts as full pressure
+	// (default: the GOMEMLIMIT soft limit, if one is set)
+	HeapLimit uint64
+
+	// GCPauseLimit is the GC pause that counts as full pressure
+	// (default: 50ms)
+	GCPauseLimit time.Duration
+
+	// Threshold is the pressure (0.0 to 1.0) above which the batcher
+	// shrinks batches and caps the pending buffer (default: 0.8)
+	Threshold float64
+
+	// MaxPending caps buffered plus in-flight items while under pressure.
+	// Add returns ErrMemoryPressure beyond it. Zero disables the cap.
+	MaxPending int
+}
+
+// ErrMemoryPressure is returned by Add when the pending buffer is capped
+// because of memory pressure
+var ErrMemoryPressure = errors.New("batcher: memory pressure")
+
+const (
+	metricHeapObjects = "/memory/classes/heap/objects:bytes"
+	metricGCPauses    = "/gc/pauses:seconds"
+	metricMemLimit    = "/gc/gomemlimit:bytes"
+)
+
+// memorySampler reads heap size and the longest GC pause since the
+// previous read from runtime/metrics. Reads are serialised, as adjustments
+// run on the adjuster and on goroutines calling AdjustNow.
+type memorySampler struct {
+	mu         sync.Mutex
+	samples    []metrics.Sample
+	prevCounts []uint64
+}
+
+func newMemorySampler() *memorySampler {
+	return &memorySampler{
+		samples: []metrics.Sample{
+			{Name: metricHeapObjects},
+			{Name: metricGCPauses},
+		},
+	}
+}
+
+func (m *memorySampler) read() (heap uint64, pause time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	metrics.Read(m.samples)
+
+	if v := m.samples[0].Value; v.Kind() == metrics.KindUint64 {
+		heap = v.Uint64()
+	}
+
+	if v := m.samples[1].Value; v.Kind() == metrics.KindFloat64Histogram {
+		h := v.Float64Histogram()
+		if len(m.prevCounts) == len(h.Counts) {
+			// Longest bucket that received new pauses since the last read
+			for i := len(h.Counts) - 1; i >= 0; i-- {
+				if h.Counts[i] > m.prevCounts[i] {
+					upper := h.Buckets[i+1]
+					if math.IsInf(upper, 1) {
+						upper = h.Buckets[i]
+					}
+					pause = time.Duration(upper * float64(time.Second))
+					break
+				}
+			}
+		}
+		m.prevCounts = append(m.prevCounts[:0], h.Counts...)
+	}
+
+	return heap, pause
+}
+
+// softMemoryLimit returns the GOMEMLIMIT soft limit, or 0 if none is set
+func softMemoryLimit() uint64 {
+	s := []metrics.Sample{{Name: metricMemLimit}}
+	metrics.Read(s)
+	if s[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	limit := s[0].Value.Uint64()
+	if limit >= math.MaxInt64 {
+		return 0
+	}
+	return limit
+}
+
+// memoryPressure converts heap size and GC pause into a pressure in [0, 1]
+func (c *MemoryPressureConfig) memoryPressure(heap uint64, pause time.Duration) float64 {
+	pressure := 0.0
+	if c.HeapLimit > 0 {
+		pressure = float64(heap) / float64(c.HeapLimit)
+	}
+	if c.GCPauseLimit > 0 {
+		pressure = math.Max(pressure, float64(pause)/float64(c.GCPauseLimit))
+	}
+	return math.Min(pressure, 1)
+}
+
+// underMemoryPressureLocked reports whether memory pressure is above threshold
+func (b *Batcher) underMemoryPressureLocked() bool {
+	return b.cfg.MemoryPressure != nil && b.memoryPressure >= b.cfg.MemoryPressure.Threshold
+}
@@ -0,0 +1,128 @@
+package batcher
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemoryPressureConfig_Pressure(t *testing.T) {
+	c := &MemoryPressureConfig{HeapLimit: 1000, GCPauseLimit: 10 * time.Millisecond}
+
+	tests := []struct {
+		name  string
+		heap  uint64
+		pause time.Duration
+		want  float64
+	}{
+		{"idle", 0, 0, 0},
+		{"heap bound", 500, time.Millisecond, 0.5},
+		{"pause bound", 100, 8 * time.Millisecond, 0.8},
+		{"saturated", 5000, 0, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.memoryPressure(tt.heap, tt.pause); got != tt.want {
+				t.Errorf("memoryPressure() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemorySampler(t *testing.T) {
+	m := newMemorySampler()
+	heap, _ := m.read()
+	if heap == 0 {
+		t.Error("Expected non-zero heap size")
+	}
+}
+
+func TestMemorySampler_Concurrent(t *testing.T) {
+	m := newMemorySampler()
+
+	// Adjustments sample from the adjuster and AdjustNow callers at
+	// once; run with -race
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				m.read()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestBatcher_MemoryPressure(t *testing.T) {
+	b, err := New(Config{
+		InitialBatchSize: 20,
+		MemoryPressure:   &MemoryPressureConfig{HeapLimit: 1000, MaxPending: 3},
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			return &LoadFeedback{}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	b.readMemory = func() (uint64, time.Duration) { return 900, 0 }
+	b.adjustBatchSize()
+
+	// Memory pressure shrinks the batch even without feedback
+	if size := b.GetCurrentBatchSize(); size >= 20 {
+		t.Errorf("Expected batch size to shrink under memory pressure, got %d", size)
+	}
+	if p := b.GetStats().MemoryPressure; p != 0.9 {
+		t.Errorf("MemoryPressure = %v, want 0.9", p)
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		if err := b.Add(ctx, i); err != nil {
+			t.Fatalf("Add() error: %v", err)
+		}
+	}
+	if err := b.Add(ctx, 3); err != ErrMemoryPressure {
+		t.Errorf("Expected ErrMemoryPressure, got %v", err)
+	}
+
+	// Pressure relieved: the cap is lifted
+	b.readMemory = func() (uint64, time.Duration) { return 100, 0 }
+	b.adjustBatchSize()
+	if err := b.Add(ctx, 3); err != nil {
+		t.Errorf("Expected Add to succeed after pressure relief, got %v", err)
+	}
+}
+
+func TestBatcher_ConcurrentAdjustNowMemory(t *testing.T) {
+	b, err := New(Config{
+		InitialBatchSize: 20,
+		MemoryPressure:   &MemoryPressureConfig{HeapLimit: 1 << 40},
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			return &LoadFeedback{}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	// AdjustNow callers sample memory concurrently with each other and
+	// the adjuster; run with -race
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				b.AdjustNow()
+			}
+		}()
+	}
+	wg.Wait()
+}
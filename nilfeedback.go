@@ -0,0 +1,106 @@
+package batcher
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// NilFeedbackPolicy selects what the batcher records when a handler
+// returns neither feedback nor an error
+type NilFeedbackPolicy int
+
+const (
+	// NilFeedbackIgnore records nothing. The window keeps its last
+	// samples, so the batch size stays where they put it.
+	NilFeedbackIgnore NilFeedbackPolicy = iota
+
+	// NilFeedbackNeutral records a sample scored Config.NeutralLoadScore,
+	// pulling the window toward a load at which the size holds steady
+	NilFeedbackNeutral
+
+	// NilFeedbackDecay treats nil as "no data" and evicts the oldest
+	// sample, so adaptation stops once stale samples have aged out
+	NilFeedbackDecay
+
+	// NilFeedbackLatency synthesizes a sample from the measured handler
+	// latency, as Config.SynthesizeFeedback does
+	NilFeedbackLatency
+)
+
+// String returns the string representation of NilFeedbackPolicy
+func (p NilFeedbackPolicy) String() string {
+	switch p {
+	case NilFeedbackIgnore:
+		return "ignore"
+	case NilFeedbackNeutral:
+		return "neutral"
+	case NilFeedbackDecay:
+		return "decay"
+	case NilFeedbackLatency:
+		return "latency"
+	default:
+		return "unknown"
+	}
+}
+
+// nilFeedbackSample applies the NilFeedbackPolicy to a batch that
+// returned no feedback. It returns false if nothing is to be recorded.
+func (b *Batcher) nilFeedbackSample(batchSize int, elapsed, sojourn time.Duration) (FeedbackSample, bool) {
+	b.nilFeedbacks.Add(1)
+
+	switch b.cfg.NilFeedback {
+	case NilFeedbackNeutral:
+		return FeedbackSample{
+			Feedback:  LoadFeedback{ProcessingTime: elapsed},
+			BatchSize: batchSize,
+			Score:     b.cfg.NeutralLoadScore,
+		}, true
+	case NilFeedbackDecay:
+		b.pendingDecay.Add(1)
+	case NilFeedbackLatency:
+		return b.synthesizeSample(batchSize, elapsed, sojourn, nil), true
+	}
+	return FeedbackSample{}, false
+}
+
+// decayFeedbackLocked evicts one oldest sample per pending decay
+func (b *Batcher) decayFeedbackLocked() bool {
+	n := int(b.pendingDecay.Swap(0))
+	if n == 0 {
+		return false
+	}
+	n = min(n, len(b.recentFeedback))
+	b.recentFeedback = b.recentFeedback[n:]
+	return true
+}
+
+// latencyBaseline scores handler latency against the fastest per-item
+// latency observed. The baseline creeps up slowly so that a permanent
+// shift in backend speed is eventually accepted as the new normal.
+type latencyBaseline struct {
+	mu   sync.Mutex
+	best float64 // seconds per item
+}
+
+// latencyBaselineDrift is the fraction the baseline relaxes per sample
+const latencyBaselineDrift = 0.01
+
+// score maps latency onto [0, 1]: 0 at the baseline, 0.5 at twice the
+// baseline, approaching 1 as latency grows
+func (l *latencyBaseline) score(elapsed time.Duration, batchSize int) float64 {
+	if batchSize <= 0 || elapsed <= 0 {
+		return 0
+	}
+	perItem := elapsed.Seconds() / float64(batchSize)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.best == 0 || perItem < l.best {
+		l.best = perItem
+	} else {
+		l.best = math.Min(l.best*(1+latencyBaselineDrift), perItem)
+	}
+	return clamp01(1 - l.best/perItem)
+}
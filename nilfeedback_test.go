@@ -0,0 +1,113 @@
+package batcher
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func newNilFeedbackBatcher(t *testing.T, policy NilFeedbackPolicy, nilFeedback *bool) *Batcher {
+	t.Helper()
+	b, err := New(Config{
+		InitialBatchSize: 1,
+		NilFeedback:      policy,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			if *nilFeedback {
+				return nil, nil
+			}
+			return &LoadFeedback{CPULoad: 1}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	t.Cleanup(func() { b.Close(context.Background()) })
+	return b
+}
+
+func TestNilFeedbackPolicies(t *testing.T) {
+	tests := []struct {
+		policy      NilFeedbackPolicy
+		wantSamples int
+		wantScore   float64
+	}{
+		{NilFeedbackIgnore, 4, 0.6},
+		{NilFeedbackNeutral, 10, 0.4},
+		{NilFeedbackDecay, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy.String(), func(t *testing.T) {
+			nilFeedback := false
+			b := newNilFeedbackBatcher(t, tt.policy, &nilFeedback)
+			ctx := context.Background()
+
+			for i := 0; i < 4; i++ {
+				b.Add(ctx, i)
+			}
+			nilFeedback = true
+			for i := 0; i < 10; i++ {
+				b.Add(ctx, i)
+			}
+
+			stats := b.GetStats()
+			if stats.NilFeedbacks != 10 {
+				t.Errorf("Expected 10 nil feedbacks, got %d", stats.NilFeedbacks)
+			}
+			if stats.NilFeedbackPolicy != tt.policy.String() {
+				t.Errorf("Expected policy %s in stats, got %s", tt.policy, stats.NilFeedbackPolicy)
+			}
+			if stats.RecentFeedbackSize != tt.wantSamples {
+				t.Errorf("Expected %d samples, got %d", tt.wantSamples, stats.RecentFeedbackSize)
+			}
+			if diff := stats.AverageLoadScore - tt.wantScore; diff > 1e-9 || diff < -1e-9 {
+				t.Errorf("Expected average score %v, got %v", tt.wantScore, stats.AverageLoadScore)
+			}
+		})
+	}
+}
+
+func TestNilFeedbackLatency(t *testing.T) {
+	delay := time.Millisecond
+	b, err := New(Config{
+		InitialBatchSize: 1,
+		NilFeedback:      NilFeedbackLatency,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			time.Sleep(delay)
+			return nil, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		b.Add(ctx, i)
+	}
+	calm := b.GetStats().AggregatedLoadScore
+
+	delay = 20 * time.Millisecond
+	for i := 0; i < 3; i++ {
+		b.Add(ctx, i)
+	}
+	slow := b.GetStats().AggregatedLoadScore
+
+	if slow <= calm {
+		t.Errorf("Expected rising latency to raise the score, got %v then %v", calm, slow)
+	}
+}
+
+func TestLatencyBaseline(t *testing.T) {
+	var l latencyBaseline
+	if s := l.score(10*time.Millisecond, 10); s != 0 {
+		t.Errorf("Expected 0 at the baseline, got %v", s)
+	}
+	if s := l.score(20*time.Millisecond, 10); s < 0.49 || s > 0.5 {
+		t.Errorf("Expected about 0.5 at twice the baseline, got %v", s)
+	}
+	if s := l.score(5*time.Millisecond, 10); s != 0 {
+		t.Errorf("Expected a faster batch to reset the baseline, got %v", s)
+	}
+}
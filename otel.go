@@ -0,0 +1,106 @@
+package batcher
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// meterName is the instrumentation scope of the batcher's OTel metrics
+const meterName = "github.com/amirafroozeh1/Load-Aware-Batcher"
+
+// otelMetrics holds the OTel instruments of a batcher
+type otelMetrics struct {
+	batchSize       metric.Int64Histogram
+	handlerDuration metric.Float64Histogram
+	sojourn         metric.Float64Histogram
+	flushes         metric.Int64Counter
+	errors          metric.Int64Counter
+	registration    metric.Registration
+	attrs           metric.MeasurementOption
+}
+
+// newOTelMetrics creates the instruments on mp. Pending items and the
+// current batch size are observed from the lock-free stats mirrors.
+func newOTelMetrics(b *Batcher, mp metric.MeterProvider) (*otelMetrics, error) {
+	meter := mp.Meter(meterName)
+	attrs := []attribute.KeyValue{attribute.String("batcher.name", b.cfg.Name)}
+	for k, v := range b.cfg.Labels {
+		attrs = append(attrs, attribute.String(k, v))
+	}
+	m := &otelMetrics{attrs: metric.WithAttributes(attrs...)}
+
+	var err error
+	if m.batchSize, err = meter.Int64Histogram("batcher.batch.size",
+		metric.WithDescription("Number of items per flushed batch"),
+		metric.WithUnit("{item}")); err != nil {
+		return nil, err
+	}
+	if m.handlerDuration, err = meter.Float64Histogram("batcher.handler.duration",
+		metric.WithDescription("Time spent in the batch handler"),
+		metric.WithUnit("s")); err != nil {
+		return nil, err
+	}
+	if m.sojourn, err = meter.Float64Histogram("batcher.item.sojourn",
+		metric.WithDescription("Time from Add to handler completion per item"),
+		metric.WithUnit("s")); err != nil {
+		return nil, err
+	}
+	if m.flushes, err = meter.Int64Counter("batcher.flushes",
+		metric.WithDescription("Number of batches handed to the handler"),
+		metric.WithUnit("{batch}")); err != nil {
+		return nil, err
+	}
+	if m.errors, err = meter.Int64Counter("batcher.errors",
+		metric.WithDescription("Number of batches the handler failed"),
+		metric.WithUnit("{batch}")); err != nil {
+		return nil, err
+	}
+
+	pending, err := meter.Int64ObservableUpDownCounter("batcher.pending.items",
+		metric.WithDescription("Number of items buffered and not yet flushed"),
+		metric.WithUnit("{item}"))
+	if err != nil {
+		return nil, err
+	}
+	current, err := meter.Int64ObservableGauge("batcher.batch.size.current",
+		metric.WithDescription("Batch size the batcher currently flushes at"),
+		metric.WithUnit("{item}"))
+	if err != nil {
+		return nil, err
+	}
+	m.registration, err = meter.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		o.ObserveInt64(pending, b.pending.Load(), m.attrs)
+		o.ObserveInt64(current, b.batchSize.Load(), m.attrs)
+		return nil
+	}, pending, current)
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// close stops observing the batcher
+func (m *otelMetrics) close() error {
+	return m.registration.Unregister()
+}
+
+// recordBatch records one handler call
+func (m *otelMetrics) recordBatch(ctx context.Context, size int, elapsed time.Duration, err error) {
+	m.batchSize.Record(ctx, int64(size), m.attrs)
+	m.handlerDuration.Record(ctx, elapsed.Seconds(), m.attrs)
+	m.flushes.Add(ctx, 1, m.attrs)
+	if err != nil {
+		m.errors.Add(ctx, 1, m.attrs)
+	}
+}
+
+// recordSojourn records the sojourn time of every item of a completed batch
+func (m *otelMetrics) recordSojourn(ctx context.Context, done time.Time, items []pendingItem) {
+	for _, p := range items {
+		m.sojourn.Record(ctx, done.Sub(p.added).Seconds(), m.attrs)
+	}
+}
@@ -0,0 +1,185 @@
+package batcher
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+// fakeMeter records measurements by instrument name on top of the no-op
+// implementation, so the tests need no SDK
+type fakeMeter struct {
+	noop.Meter
+
+	mu        sync.Mutex
+	values    map[string][]float64
+	callbacks []metric.Callback
+}
+
+func newFakeMeter() *fakeMeter {
+	return &fakeMeter{values: make(map[string][]float64)}
+}
+
+func (m *fakeMeter) record(name string, v float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[name] = append(m.values[name], v)
+}
+
+func (m *fakeMeter) get(name string) []float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]float64(nil), m.values[name]...)
+}
+
+func (m *fakeMeter) collect() {
+	m.mu.Lock()
+	callbacks := m.callbacks
+	m.mu.Unlock()
+	for _, cb := range callbacks {
+		cb(context.Background(), fakeObserver{m: m})
+	}
+}
+
+type fakeMeterProvider struct {
+	noop.MeterProvider
+	meter *fakeMeter
+}
+
+func (p fakeMeterProvider) Meter(string, ...metric.MeterOption) metric.Meter { return p.meter }
+
+type fakeInt64Histogram struct {
+	noop.Int64Histogram
+	m    *fakeMeter
+	name string
+}
+
+func (h fakeInt64Histogram) Record(_ context.Context, v int64, _ ...metric.RecordOption) {
+	h.m.record(h.name, float64(v))
+}
+
+type fakeFloat64Histogram struct {
+	noop.Float64Histogram
+	m    *fakeMeter
+	name string
+}
+
+func (h fakeFloat64Histogram) Record(_ context.Context, v float64, _ ...metric.RecordOption) {
+	h.m.record(h.name, v)
+}
+
+type fakeInt64Counter struct {
+	noop.Int64Counter
+	m    *fakeMeter
+	name string
+}
+
+func (c fakeInt64Counter) Add(_ context.Context, v int64, _ ...metric.AddOption) {
+	c.m.record(c.name, float64(v))
+}
+
+type fakeObserver struct {
+	noop.Observer
+	m *fakeMeter
+}
+
+func (o fakeObserver) ObserveInt64(obs metric.Int64Observable, v int64, _ ...metric.ObserveOption) {
+	switch obs := obs.(type) {
+	case fakeUpDown:
+		o.m.record(obs.name, float64(v))
+	case fakeGauge:
+		o.m.record(obs.name, float64(v))
+	}
+}
+
+type fakeUpDown struct {
+	noop.Int64ObservableUpDownCounter
+	name string
+}
+
+type fakeGauge struct {
+	noop.Int64ObservableGauge
+	name string
+}
+
+func (m *fakeMeter) Int64Histogram(name string, _ ...metric.Int64HistogramOption) (metric.Int64Histogram, error) {
+	return fakeInt64Histogram{m: m, name: name}, nil
+}
+
+func (m *fakeMeter) Float64Histogram(name string, _ ...metric.Float64HistogramOption) (metric.Float64Histogram, error) {
+	return fakeFloat64Histogram{m: m, name: name}, nil
+}
+
+func (m *fakeMeter) Int64Counter(name string, _ ...metric.Int64CounterOption) (metric.Int64Counter, error) {
+	return fakeInt64Counter{m: m, name: name}, nil
+}
+
+func (m *fakeMeter) Int64ObservableUpDownCounter(name string, _ ...metric.Int64ObservableUpDownCounterOption) (metric.Int64ObservableUpDownCounter, error) {
+	return fakeUpDown{name: name}, nil
+}
+
+func (m *fakeMeter) Int64ObservableGauge(name string, _ ...metric.Int64ObservableGaugeOption) (metric.Int64ObservableGauge, error) {
+	return fakeGauge{name: name}, nil
+}
+
+func (m *fakeMeter) RegisterCallback(cb metric.Callback, _ ...metric.Observable) (metric.Registration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.callbacks = append(m.callbacks, cb)
+	return noop.Registration{}, nil
+}
+
+func TestBatcher_OTelMetrics(t *testing.T) {
+	meter := newFakeMeter()
+	fail := false
+	b, err := New(Config{
+		InitialBatchSize: 3,
+		MeterProvider:    fakeMeterProvider{meter: meter},
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			if fail {
+				return nil, errors.New("boom")
+			}
+			return nil, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	ctx := context.Background()
+	for i := 0; i < 6; i++ {
+		b.Add(ctx, i)
+	}
+	fail = true
+	for i := 0; i < 4; i++ {
+		b.Add(ctx, i)
+	}
+	meter.collect()
+
+	if got := meter.get("batcher.batch.size"); len(got) != 3 || got[0] != 3 {
+		t.Errorf("Expected 3 batch size records of 3, got %v", got)
+	}
+	if got := meter.get("batcher.handler.duration"); len(got) != 3 {
+		t.Errorf("Expected 3 handler duration records, got %v", got)
+	}
+	if got := meter.get("batcher.item.sojourn"); len(got) != 9 {
+		t.Errorf("Expected 9 item sojourn records, got %v", got)
+	}
+	if got := meter.get("batcher.flushes"); len(got) != 3 {
+		t.Errorf("Expected 3 flushes, got %v", got)
+	}
+	if got := meter.get("batcher.errors"); len(got) != 1 {
+		t.Errorf("Expected 1 error, got %v", got)
+	}
+	if got := meter.get("batcher.pending.items"); len(got) != 1 || got[0] != 1 {
+		t.Errorf("Expected 1 pending item observed, got %v", got)
+	}
+	if got := meter.get("batcher.batch.size.current"); len(got) != 1 || got[0] != 3 {
+		t.Errorf("Expected current batch size 3 observed, got %v", got)
+	}
+}
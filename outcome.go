@@ -0,0 +1,142 @@
+package batcher
+
+import (
+	"errors"
+	"sync"
+)
+
+// SucceededItemsMetric and FailedItemsMetric are the keys of
+// LoadFeedback.Custom under which OutcomeRecorder.Feedback reports the
+// number of items of the batch that succeeded and failed
+const (
+	SucceededItemsMetric = "succeeded_items"
+	FailedItemsMetric    = "failed_items"
+)
+
+// ErrNoOutcome is the result of the items of a batch an OutcomeRecorder
+// was not told about; they count as failed
+var ErrNoOutcome = errors.New("batcher: no outcome recorded for item")
+
+// OutcomeRecorder collects the outcome of every item of a batch as the
+// handler learns it, and derives the per-item results and the error rate
+// of the feedback from them, so handlers of backends that fail items
+// individually need not count themselves:
+//
+//	rec := batcher.NewOutcomeRecorder(len(batch))
+//	for i, item := range batch {
+//		id, err := insert(ctx, item)
+//		rec.Record(i, id, err)
+//	}
+//	batcher.DeliverResults(meta, replyKey{}, rec.Results())
+//	return rec.Feedback(nil), nil
+//
+// It is safe for concurrent use, e.g. by goroutines sending parts of the
+// batch in parallel.
+type OutcomeRecorder struct {
+	mu       sync.Mutex
+	results  []ItemResult
+	recorded []bool
+}
+
+// NewOutcomeRecorder returns a recorder for a batch of n items
+func NewOutcomeRecorder(n int) *OutcomeRecorder {
+	return &OutcomeRecorder{
+		results:  make([]ItemResult, n),
+		recorded: make([]bool, n),
+	}
+}
+
+// Record sets the outcome of item i: its value if err is nil, and a
+// failure otherwise. Recording an item again replaces its outcome.
+func (r *OutcomeRecorder) Record(i int, value any, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.results[i] = ItemResult{Value: value, Err: err}
+	r.recorded[i] = true
+}
+
+// Succeed records that item i succeeded with value
+func (r *OutcomeRecorder) Succeed(i int, value any) {
+	r.Record(i, value, nil)
+}
+
+// Fail records that item i failed with err, which must not be nil
+func (r *OutcomeRecorder) Fail(i int, err error) {
+	r.Record(i, nil, err)
+}
+
+// FailAll records that every item failed with err, e.g. when the whole
+// request was rejected
+func (r *OutcomeRecorder) FailAll(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.results {
+		r.results[i] = ItemResult{Err: err}
+		r.recorded[i] = true
+	}
+}
+
+// Counts returns the number of items that succeeded and that failed,
+// items without an outcome counting as failed
+func (r *OutcomeRecorder) Counts() (succeeded, failed int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.countsLocked()
+}
+
+// Results returns the result of every item in batch order, for
+// DemuxResults and DeliverResults. Items without an outcome get
+// ErrNoOutcome.
+func (r *OutcomeRecorder) Results() []ItemResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make([]ItemResult, len(r.results))
+	for i, res := range r.results {
+		if !r.recorded[i] {
+			res = ItemResult{Err: ErrNoOutcome}
+		}
+		out[i] = res
+	}
+	return out
+}
+
+// Feedback returns a copy of base, or of empty feedback if base is nil,
+// with ErrorRate set to the fraction of the batch that failed and the
+// counts reported as SucceededItemsMetric and FailedItemsMetric
+func (r *OutcomeRecorder) Feedback(base *LoadFeedback) *LoadFeedback {
+	r.mu.Lock()
+	succeeded, failed := r.countsLocked()
+	r.mu.Unlock()
+
+	var fb LoadFeedback
+	if base != nil {
+		fb = *base
+	}
+	fb.ErrorRate = 0
+	if total := succeeded + failed; total > 0 {
+		fb.ErrorRate = float64(failed) / float64(total)
+	}
+	custom := make(map[string]interface{}, len(fb.Custom)+2)
+	for k, v := range fb.Custom {
+		custom[k] = v
+	}
+	custom[SucceededItemsMetric] = float64(succeeded)
+	custom[FailedItemsMetric] = float64(failed)
+	fb.Custom = custom
+	return &fb
+}
+
+// --- Internal methods ---
+
+func (r *OutcomeRecorder) countsLocked() (succeeded, failed int) {
+	for i, res := range r.results {
+		if r.recorded[i] && res.Err == nil {
+			succeeded++
+		}
+	}
+	return succeeded, len(r.results) - succeeded
+}
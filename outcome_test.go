@@ -0,0 +1,87 @@
+package batcher
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestOutcomeRecorder(t *testing.T) {
+	boom := errors.New("boom")
+	rec := NewOutcomeRecorder(4)
+	rec.Succeed(0, "a")
+	rec.Fail(1, boom)
+	rec.Record(2, nil, boom)
+	rec.Record(2, "c", nil) // replaces the failure
+
+	if succeeded, failed := rec.Counts(); succeeded != 2 || failed != 2 {
+		t.Errorf("Expected 2 succeeded and 2 failed, got %d and %d", succeeded, failed)
+	}
+
+	results := rec.Results()
+	if results[0].Value != "a" || results[2].Value != "c" || results[0].Err != nil {
+		t.Errorf("Expected the recorded values, got %+v", results)
+	}
+	if !errors.Is(results[1].Err, boom) || !errors.Is(results[3].Err, ErrNoOutcome) {
+		t.Errorf("Expected boom and ErrNoOutcome, got %v and %v", results[1].Err, results[3].Err)
+	}
+
+	base := &LoadFeedback{CPULoad: 0.4, ErrorRate: 0.9, Custom: map[string]interface{}{"x": 1.0}}
+	fb := rec.Feedback(base)
+	if fb.ErrorRate != 0.5 || fb.CPULoad != 0.4 {
+		t.Errorf("Expected the base feedback with an error rate of 0.5, got %+v", fb)
+	}
+	if fb.Custom[SucceededItemsMetric] != 2.0 || fb.Custom[FailedItemsMetric] != 2.0 || fb.Custom["x"] != 1.0 {
+		t.Errorf("Expected the counts next to the base metrics, got %v", fb.Custom)
+	}
+	if _, ok := base.Custom[FailedItemsMetric]; ok || base.ErrorRate != 0.9 {
+		t.Errorf("Expected the base feedback untouched, got %+v", base)
+	}
+
+	rec.FailAll(boom)
+	if fb := rec.Feedback(nil); fb.ErrorRate != 1 {
+		t.Errorf("Expected an error rate of 1 after FailAll, got %g", fb.ErrorRate)
+	}
+	if fb := NewOutcomeRecorder(0).Feedback(nil); fb.ErrorRate != 0 || math.IsNaN(fb.ErrorRate) {
+		t.Errorf("Expected an error rate of 0 for an empty batch, got %g", fb.ErrorRate)
+	}
+}
+
+func TestOutcomeRecorder_DeliversResults(t *testing.T) {
+	boom := errors.New("boom")
+	b, err := New(Config{
+		InitialBatchSize: 3,
+		ContextKeys:      []any{replyKey{}},
+		HandlerFuncV2: func(ctx context.Context, batch []any, meta BatchMeta) (*LoadFeedback, error) {
+			rec := NewOutcomeRecorder(len(batch))
+			for i, item := range batch {
+				if item.(int)%2 == 0 {
+					rec.Succeed(i, item.(int)*10)
+				} else {
+					rec.Fail(i, boom)
+				}
+			}
+			return rec.Feedback(nil), DeliverResults(meta, replyKey{}, rec.Results())
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	replies := make([]chan ItemResult, 3)
+	for i := range replies {
+		replies[i] = make(chan ItemResult, 1)
+		b.Add(context.WithValue(context.Background(), replyKey{}, replies[i]), i)
+	}
+	if r := <-replies[0]; r.Value != 0 || r.Err != nil {
+		t.Errorf("Expected item 0 to succeed with 0, got %+v", r)
+	}
+	if r := <-replies[1]; !errors.Is(r.Err, boom) {
+		t.Errorf("Expected item 1 to fail with boom, got %+v", r)
+	}
+	if r := <-replies[2]; r.Value != 20 {
+		t.Errorf("Expected item 2 to succeed with 20, got %+v", r)
+	}
+}
@@ -0,0 +1,52 @@
+package batcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+)
+
+// ErrHandlerPanic is matched by errors.Is for errors caused by a handler panic
+var ErrHandlerPanic = errors.New("batcher: handler panicked")
+
+// PanicError is returned when the handler panics while processing a batch
+type PanicError struct {
+	// Value is the value passed to panic
+	Value any
+
+	// Stack is the stack trace of the panicking goroutine
+	Stack []byte
+}
+
+// Error implements error
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("batcher: handler panicked: %v", e.Value)
+}
+
+// Is reports whether target is ErrHandlerPanic
+func (e *PanicError) Is(target error) bool {
+	return target == ErrHandlerPanic
+}
+
+// callHandler invokes the handler, converting a panic into a *PanicError so a
+// misbehaving handler cannot crash the goroutine calling Add or Flush. The
+// handler finds meta in its context as well, see BatchMetaFromContext.
+func (b *Batcher) callHandler(ctx context.Context, batch []any, meta BatchMeta) (feedback *LoadFeedback, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			pe := &PanicError{Value: r, Stack: debug.Stack()}
+			b.panics.Add(1)
+			if b.cfg.OnPanic != nil {
+				b.cfg.OnPanic(pe.Value, pe.Stack)
+			}
+			feedback, err = nil, pe
+		}
+	}()
+
+	ctx = context.WithValue(ctx, batchMetaKey{}, &meta)
+	if b.cfg.HandlerFuncV2 != nil {
+		return b.cfg.HandlerFuncV2(ctx, batch, meta)
+	}
+	return b.cfg.HandlerFunc(ctx, batch)
+}
@@ -0,0 +1,68 @@
+package batcher
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestBatcher_HandlerPanic(t *testing.T) {
+	var dlq []any
+	var dlqErr error
+	var stack []byte
+	dropped := 0
+
+	b, err := New(Config{
+		InitialBatchSize: 2,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			panic("boom")
+		},
+		DeadLetterFunc: func(ctx context.Context, items []any, err error) {
+			dlq = append(dlq, items...)
+			dlqErr = err
+		},
+		OnPanic: func(value any, s []byte) {
+			stack = s
+		},
+		OnItemDropped: func(item any, reason DropReason) {
+			if reason != DropReasonPanic {
+				t.Errorf("Expected DropReasonPanic, got %v", reason)
+			}
+			dropped++
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	ctx := context.Background()
+	b.Add(ctx, 1)
+	err = b.Add(ctx, 2)
+
+	if !errors.Is(err, ErrHandlerPanic) {
+		t.Fatalf("Expected ErrHandlerPanic, got %v", err)
+	}
+	var pe *PanicError
+	if !errors.As(err, &pe) || pe.Value != "boom" {
+		t.Errorf("Expected *PanicError with value boom, got %v", err)
+	}
+	if !strings.Contains(string(stack), "panic_test.go") {
+		t.Error("Expected OnPanic to receive the stack trace")
+	}
+	if len(dlq) != 2 || !errors.Is(dlqErr, ErrHandlerPanic) {
+		t.Errorf("Expected panicking batch in dead letter, got %v (%v)", dlq, dlqErr)
+	}
+	if dropped != 2 {
+		t.Errorf("Expected OnItemDropped for both items, got %d", dropped)
+	}
+	if panics := b.GetStats().Panics; panics != 1 {
+		t.Errorf("Expected 1 panic recorded, got %d", panics)
+	}
+
+	// The batcher keeps working after a panic
+	if stats := b.GetStats(); stats.InFlightItems != 0 {
+		t.Errorf("Expected no in-flight items after panic, got %d", stats.InFlightItems)
+	}
+}
@@ -0,0 +1,75 @@
+package batcher
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// pauseFor stops automatic flushing until d from now, extending any pause
+// already in effect. Items keep accumulating and are flushed on resume.
+func (b *Batcher) pauseFor(d time.Duration) {
+	until := time.Now().Add(d)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if until.UnixNano() <= b.pausedUntil.Load() {
+		return
+	}
+	b.pausedUntil.Store(until.UnixNano())
+	b.pauses.Add(1)
+	if len(b.batch) > 0 {
+		b.scheduleFlushLocked(until)
+	}
+}
+
+// paused reports whether flushing is paused and until when
+func (b *Batcher) paused() (time.Time, bool) {
+	until := time.Unix(0, b.pausedUntil.Load())
+	return until, time.Now().Before(until)
+}
+
+// waitForResume blocks until any pause has ended or ctx is done
+func (b *Batcher) waitForResume(ctx context.Context) error {
+	for {
+		until, paused := b.paused()
+		if !paused {
+			return nil
+		}
+		t := time.NewTimer(time.Until(until))
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// backendPause returns how long the feedback asks the batcher to back off
+func (b *Batcher) backendPause(fb *LoadFeedback) time.Duration {
+	if d := max(fb.RetryAfter, fb.ThrottleFor); d > 0 {
+		return d
+	}
+	if fb.Unhealthy {
+		return b.cfg.UnhealthyBackoff
+	}
+	return 0
+}
+
+// abandonBuffered dead-letters the items left in the buffer by a Close
+// whose ctx was done before the backend pause ended
+func (b *Batcher) abandonBuffered(ctx context.Context, err error) {
+	b.mu.Lock()
+	abandoned := b.batch
+	b.batch = nil
+	b.setPendingLocked()
+	b.earliestDeadline = time.Time{}
+	b.stopTimerLocked()
+	b.releaseBytesLocked(abandoned)
+	b.mu.Unlock()
+
+	b.releaseTenants(abandoned)
+	b.deadLetter(context.WithoutCancel(ctx), abandoned, DropReasonClose, errors.Join(ErrClosed, err))
+}
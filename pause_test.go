@@ -0,0 +1,232 @@
+package batcher
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBatcher_RetryAfterPausesFlushing(t *testing.T) {
+	var mu sync.Mutex
+	var flushedAt []time.Time
+	b, err := New(Config{
+		InitialBatchSize: 2,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			flushedAt = append(flushedAt, time.Now())
+			if len(flushedAt) == 1 {
+				return &LoadFeedback{RetryAfter: 100 * time.Millisecond}, nil
+			}
+			return &LoadFeedback{}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	ctx := context.Background()
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		if err := b.Add(ctx, i); err != nil {
+			t.Fatalf("Add() error: %v", err)
+		}
+	}
+
+	stats := b.GetStats()
+	if stats.Pauses != 1 || stats.PausedUntil.IsZero() {
+		t.Errorf("Expected one active pause, got %+v", stats)
+	}
+	if stats.PendingItems != 4 {
+		t.Errorf("Expected items to accumulate while paused, got %d pending", stats.PendingItems)
+	}
+
+	// Flushing resumes on its own once the pause ends
+	time.Sleep(200 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(flushedAt) != 2 {
+		t.Fatalf("Expected 2 flushes, got %d", len(flushedAt))
+	}
+	if d := flushedAt[1].Sub(start); d < 100*time.Millisecond {
+		t.Errorf("Expected the second flush after the pause, got it after %v", d)
+	}
+	if !b.GetStats().PausedUntil.IsZero() {
+		t.Error("Expected the pause to have ended")
+	}
+}
+
+func TestBatcher_FlushWaitsForResume(t *testing.T) {
+	b, err := New(Config{
+		InitialBatchSize: 10,
+		UnhealthyBackoff: time.Hour,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			return &LoadFeedback{Unhealthy: true}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	ctx := context.Background()
+	b.Add(ctx, 1)
+	if err := b.Flush(ctx); err != nil {
+		t.Fatalf("Flush() error: %v", err)
+	}
+
+	b.Add(ctx, 2)
+	timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err := b.Flush(timeout); err != context.DeadlineExceeded {
+		t.Errorf("Expected Flush to wait out the pause until ctx is done, got %v", err)
+	}
+	if n := b.GetStats().PendingItems; n != 1 {
+		t.Errorf("Expected the item to stay buffered, got %d pending", n)
+	}
+	// Close gives up on the pause together with its context
+	closeCtx, cancelClose := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancelClose()
+	if err := b.Close(closeCtx); err != context.DeadlineExceeded {
+		t.Errorf("Expected Close to stop waiting with ctx, got %v", err)
+	}
+}
+
+func TestBatcher_CloseDeadLettersWhenPaused(t *testing.T) {
+	var mu sync.Mutex
+	var dropped []any
+	var dlqErr error
+	b, err := New(Config{
+		InitialBatchSize: 10,
+		UnhealthyBackoff: time.Hour,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			return &LoadFeedback{Unhealthy: true}, nil
+		},
+		DeadLetterFunc: func(ctx context.Context, items []any, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			dlqErr = err
+		},
+		OnItemDropped: func(item any, reason DropReason) {
+			mu.Lock()
+			defer mu.Unlock()
+			if reason == DropReasonClose {
+				dropped = append(dropped, item)
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	ctx := context.Background()
+	b.Add(ctx, 1)
+	b.Flush(ctx)
+	b.Add(ctx, 2)
+
+	closeCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err := b.Close(closeCtx); err != context.DeadlineExceeded {
+		t.Errorf("Expected Close to stop waiting with ctx, got %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(dropped) != 1 || dropped[0] != 2 {
+		t.Errorf("Expected item 2 dropped on close, got %v", dropped)
+	}
+	if !errors.Is(dlqErr, ErrClosed) || !errors.Is(dlqErr, context.DeadlineExceeded) {
+		t.Errorf("Expected ErrClosed and the ctx error for dead-lettered items, got %v", dlqErr)
+	}
+	if n := b.GetStats().PendingItems; n != 0 {
+		t.Errorf("Expected nothing left buffered after Close, got %d", n)
+	}
+	if got := DropReasonClose.String(); got != "close" {
+		t.Errorf("Expected close, got %s", got)
+	}
+}
+
+func TestBatcher_FlushNowIgnoresPause(t *testing.T) {
+	var mu sync.Mutex
+	flushes := 0
+	b, err := New(Config{
+		InitialBatchSize: 2,
+		Timeout:          time.Hour,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			flushes++
+			return &LoadFeedback{RetryAfter: time.Hour}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		b.Add(ctx, i)
+	}
+
+	// Flush waits out the pause; FlushNow does not
+	short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	defer b.Close(short) // expired, so Close does not wait out the pause either
+	if err := b.Flush(short); err == nil {
+		t.Error("Expected Flush to wait for the pause")
+	}
+	if err := b.FlushNow(ctx); err != nil {
+		t.Fatalf("FlushNow() failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if flushes != 2 || b.GetStats().PendingItems != 0 {
+		t.Errorf("Expected the paused batch flushed, got %d flushes and %d pending", flushes, b.GetStats().PendingItems)
+	}
+}
+
+func TestBatcher_ThrottleFor(t *testing.T) {
+	var mu sync.Mutex
+	var flushedAt []time.Time
+	b, err := New(Config{
+		InitialBatchSize:  2,
+		LoadCheckInterval: time.Hour,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			flushedAt = append(flushedAt, time.Now())
+			if len(flushedAt) == 1 {
+				// Idle by every other metric, but throttled
+				return &LoadFeedback{CPULoad: 0.1, ThrottleFor: 100 * time.Millisecond}, nil
+			}
+			return &LoadFeedback{CPULoad: 0.1}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	ctx := context.Background()
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		b.Add(ctx, i)
+	}
+	if stats := b.GetStats(); stats.Pauses != 1 || stats.PendingItems != 2 {
+		t.Errorf("Expected the throttle to pause flushing with items buffered, got %+v", stats)
+	}
+	if score := b.GetStats().AverageLoadScore; score != 1 {
+		t.Errorf("Expected the throttled batch to count as full overload, got %v", score)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	if len(flushedAt) != 2 || flushedAt[1].Sub(start) < 100*time.Millisecond {
+		t.Errorf("Expected the second flush after the throttle, got %v", flushedAt)
+	}
+}
@@ -0,0 +1,44 @@
+package batcher
+
+import "time"
+
+// PeekPending returns up to limit buffered items, oldest first, without
+// removing them, for inspecting a stuck pipeline. The slice is a copy;
+// the items themselves are shared with the batcher and must not be
+// modified. Items already handed to the handler are not included.
+func (b *Batcher) PeekPending(limit int) []any {
+	if limit <= 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	n := min(limit, len(b.batch))
+	items := make([]any, n)
+	for i := range items {
+		items[i] = b.batch[i].item
+	}
+	return items
+}
+
+// --- Internal methods ---
+
+// setPendingLocked updates the lock-free mirrors of the buffer
+func (b *Batcher) setPendingLocked() {
+	b.pending.Store(int64(len(b.batch)))
+	if len(b.batch) == 0 {
+		b.oldestPending.Store(0)
+	} else {
+		b.oldestPending.Store(b.batch[0].added.UnixNano())
+	}
+}
+
+// oldestPendingAge returns how long the oldest buffered item has waited
+func (b *Batcher) oldestPendingAge(now time.Time) time.Duration {
+	added := b.oldestPending.Load()
+	if added == 0 {
+		return 0
+	}
+	return max(now.Sub(time.Unix(0, added)), 0)
+}
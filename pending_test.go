@@ -0,0 +1,62 @@
+package batcher
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPeekPending(t *testing.T) {
+	b, err := New(Config{
+		InitialBatchSize: 10,
+		Timeout:          time.Hour,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			return nil, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	if items := b.PeekPending(5); len(items) != 0 {
+		t.Errorf("Expected no items from an empty buffer, got %v", items)
+	}
+	if age := b.GetStats().OldestPendingAge; age != 0 {
+		t.Errorf("Expected zero age for an empty buffer, got %v", age)
+	}
+
+	for i := 0; i < 4; i++ {
+		b.Add(context.Background(), i)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	items := b.PeekPending(3)
+	if len(items) != 3 || items[0] != 0 || items[2] != 2 {
+		t.Errorf("Expected the 3 oldest items, got %v", items)
+	}
+	if items := b.PeekPending(100); len(items) != 4 {
+		t.Errorf("Expected all 4 items, got %v", items)
+	}
+	if items := b.PeekPending(0); items != nil {
+		t.Errorf("Expected nil for limit 0, got %v", items)
+	}
+
+	// The snapshot is a copy: peeking neither drains nor aliases the buffer
+	items[0] = "changed"
+	if stats := b.GetStats(); stats.PendingItems != 4 {
+		t.Errorf("Expected 4 pending items after peeking, got %d", stats.PendingItems)
+	}
+	if got := b.PeekPending(1); got[0] != 0 {
+		t.Errorf("Expected the buffer to be unaffected by the copy, got %v", got)
+	}
+
+	if age := b.GetStats().OldestPendingAge; age < 10*time.Millisecond || age > time.Minute {
+		t.Errorf("Expected the oldest item to have waited at least 10ms, got %v", age)
+	}
+
+	b.Flush(context.Background())
+	if age := b.GetStats().OldestPendingAge; age != 0 {
+		t.Errorf("Expected zero age after a flush, got %v", age)
+	}
+}
@@ -0,0 +1,73 @@
+package batcher
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrPendingBytes is returned by Add when the item does not fit under
+// Config.MaxPendingBytes
+var ErrPendingBytes = errors.New("batcher: pending bytes limit reached")
+
+// --- Internal methods ---
+
+// reserveBytesLocked charges the size of p to the pending bytes, waiting
+// for room with BlockOnPendingBytes. Waiting releases mu, so the caller
+// must not rely on state read before.
+func (b *Batcher) reserveBytesLocked(ctx context.Context, p pendingItem) error {
+	if b.cfg.SizerFunc == nil {
+		return nil
+	}
+	for {
+		used := b.pendingBytes.Load()
+		if b.cfg.MaxPendingBytes <= 0 || used == 0 || used+p.size <= b.cfg.MaxPendingBytes {
+			b.pendingBytes.Add(p.size)
+			return nil
+		}
+		if !b.cfg.BlockOnPendingBytes {
+			b.pendingBytesRejected.Add(1)
+			return ErrPendingBytes
+		}
+
+		if b.bytesFreed == nil {
+			b.bytesFreed = make(chan struct{})
+		}
+		freed := b.bytesFreed
+		b.mu.Unlock()
+		select {
+		case <-freed:
+		case <-ctx.Done():
+			b.mu.Lock()
+			return ctx.Err()
+		}
+		b.mu.Lock()
+		if b.closed {
+			return ErrClosed
+		}
+	}
+}
+
+// releaseBytesLocked returns the size of items that left the batcher,
+// handled or dropped, and wakes the Adds waiting for room
+func (b *Batcher) releaseBytesLocked(items []pendingItem) {
+	if b.cfg.SizerFunc == nil {
+		return
+	}
+	var n int64
+	for _, p := range items {
+		n += p.size
+	}
+	if n == 0 {
+		return
+	}
+	b.pendingBytes.Add(-n)
+	b.wakeBytesWaitersLocked()
+}
+
+// wakeBytesWaitersLocked wakes the Adds waiting for room, to check again
+func (b *Batcher) wakeBytesWaitersLocked() {
+	if b.bytesFreed != nil {
+		close(b.bytesFreed)
+		b.bytesFreed = nil
+	}
+}
@@ -0,0 +1,158 @@
+package batcher
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func byteSizer(item any) int {
+	return len(item.([]byte))
+}
+
+func TestBatcher_MaxPendingBytes(t *testing.T) {
+	release := make(chan struct{})
+	b, err := New(Config{
+		InitialBatchSize: 100,
+		SizerFunc:        byteSizer,
+		MaxPendingBytes:  100,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			<-release
+			return &LoadFeedback{}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	ctx := context.Background()
+	defer b.Close(ctx)
+
+	for i := 0; i < 2; i++ {
+		if err := b.Add(ctx, make([]byte, 40)); err != nil {
+			t.Fatalf("Add() failed: %v", err)
+		}
+	}
+	if err := b.Add(ctx, make([]byte, 40)); !errors.Is(err, ErrPendingBytes) {
+		t.Errorf("Expected ErrPendingBytes, got %v", err)
+	}
+	if err := b.Add(ctx, make([]byte, 20)); err != nil {
+		t.Errorf("Expected an item that fits to be added, got %v", err)
+	}
+
+	stats := b.GetStats()
+	if stats.PendingBytes != 100 {
+		t.Errorf("Expected 100 pending bytes, got %d", stats.PendingBytes)
+	}
+	if stats.PendingBytesRejected != 1 {
+		t.Errorf("Expected 1 rejected Add, got %d", stats.PendingBytesRejected)
+	}
+
+	// In-flight items still count until handled
+	done := make(chan error)
+	go func() { done <- b.Flush(ctx) }()
+	time.Sleep(20 * time.Millisecond)
+	if err := b.Add(ctx, make([]byte, 1)); !errors.Is(err, ErrPendingBytes) {
+		t.Errorf("Expected ErrPendingBytes while in flight, got %v", err)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+
+	if got := b.GetStats().PendingBytes; got != 0 {
+		t.Errorf("Expected 0 pending bytes after the flush, got %d", got)
+	}
+	if err := b.Add(ctx, make([]byte, 500)); err != nil {
+		t.Errorf("Expected an oversized item to be admitted when nothing is pending, got %v", err)
+	}
+}
+
+func TestBatcher_BlockOnPendingBytes(t *testing.T) {
+	b, err := New(Config{
+		InitialBatchSize:    100,
+		Timeout:             20 * time.Millisecond,
+		SizerFunc:           byteSizer,
+		MaxPendingBytes:     100,
+		BlockOnPendingBytes: true,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			return &LoadFeedback{}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	ctx := context.Background()
+	defer b.Close(ctx)
+
+	if err := b.Add(ctx, make([]byte, 80)); err != nil {
+		t.Fatalf("Add() failed: %v", err)
+	}
+
+	// The timeout flush makes room for the blocked Add
+	start := time.Now()
+	if err := b.Add(ctx, make([]byte, 80)); err != nil {
+		t.Fatalf("Expected the blocked Add to succeed, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 10*time.Millisecond {
+		t.Errorf("Expected Add to wait for the flush, returned after %v", elapsed)
+	}
+
+	// A blocked Add gives up with its context
+	tctx, cancel := context.WithTimeout(ctx, 5*time.Millisecond)
+	defer cancel()
+	if err := b.Add(tctx, make([]byte, 80)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	if got := b.GetStats().PendingBytes; got != 80 {
+		t.Errorf("Expected 80 pending bytes, got %d", got)
+	}
+}
+
+func TestBatcher_PendingBytesShed(t *testing.T) {
+	b, err := New(Config{
+		InitialBatchSize:  100,
+		SizerFunc:         byteSizer,
+		DropPolicy:        DropOldest,
+		ShedHighWatermark: 2,
+		ShedLoadThreshold: 0.5,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			return &LoadFeedback{}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	ctx := context.Background()
+	defer b.Close(ctx)
+
+	b.mu.Lock()
+	b.recordFeedback(LoadFeedback{CPULoad: 1.0, ErrorRate: 1.0}, 10, 1)
+	b.mu.Unlock()
+
+	for i := 0; i < 5; i++ {
+		b.Add(ctx, make([]byte, 10))
+	}
+
+	// Shed items no longer count
+	stats := b.GetStats()
+	if stats.PendingItems >= 5 {
+		t.Fatalf("Expected items to be shed, %d pending", stats.PendingItems)
+	}
+	if want := int64(stats.PendingItems * 10); stats.PendingBytes != want {
+		t.Errorf("Expected %d pending bytes for %d items, got %d", want, stats.PendingItems, stats.PendingBytes)
+	}
+}
+
+func TestConfig_ValidateMaxPendingBytes(t *testing.T) {
+	cfg := Config{
+		InitialBatchSize: 10,
+		MaxPendingBytes:  100,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			return nil, nil
+		},
+	}
+	if _, err := New(cfg); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig without SizerFunc, got %v", err)
+	}
+}
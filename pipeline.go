@@ -0,0 +1,144 @@
+package batcher
+
+import (
+	"context"
+	"time"
+)
+
+// ScaleEvent describes a flusher added or exiting, see
+// Config.MaxConcurrency
+type ScaleEvent struct {
+	// Previous and Current are the numbers of flushers before and after
+	Previous int
+	Current  int
+
+	// Backlog is the number of items waiting for a flusher when one was
+	// added, including the full batch that found them all busy; zero
+	// when one exits
+	Backlog int
+
+	// At is when the flusher was added or exited
+	At time.Time
+}
+
+// --- Internal methods ---
+
+// handOffLocked passes a full batch to a flusher goroutine of a Pipelined
+// batcher. It must be called with the lock held, which it releases, and
+// blocks while every flusher is still handling a previous batch, so at
+// most MaxConcurrency batches are handled while the next one fills. If
+// ctx is done first, the hand-off completes in the background and
+// ctx.Err() is returned; the batch is still handled. If the oldest item
+// of the batch reaches MaxItemAge first, the caller handles it itself.
+func (b *Batcher) handOffLocked(ctx context.Context, f *flight) error {
+	b.handoffs.Add(1)
+	backlog := len(f.items) + len(b.batch)
+	b.mu.Unlock()
+
+	select {
+	case b.pipeline <- f:
+		b.handoffs.Done()
+		return nil
+	default:
+		b.scaleUp(backlog)
+	}
+
+	expired, stop := b.handOffExpiry(f)
+	defer stop()
+	select {
+	case b.pipeline <- f:
+		b.handoffs.Done()
+		return nil
+	case <-expired:
+		// The flushers fell behind MaxItemAge
+		b.handoffs.Done()
+		f.reason = FlushReasonAge
+		return b.processBatch(ctx, f)
+	case <-ctx.Done():
+		go func() {
+			b.pipeline <- f
+			b.handoffs.Done()
+		}()
+		return ctx.Err()
+	}
+}
+
+// flushLoop handles the batches handed off by Add until Close
+func (b *Batcher) flushLoop(ctx context.Context) {
+	defer close(b.flusherDone)
+
+	for f := range b.pipeline {
+		_ = b.processBatch(ctx, f)
+	}
+}
+
+// scaleUp adds a flusher for a full batch that found all of them busy,
+// if MaxConcurrency allows and the batch size cannot grow any further
+func (b *Batcher) scaleUp(backlog int) {
+	if b.cfg.MaxConcurrency <= 1 || b.batchSize.Load() < b.maxBatchSize.Load() {
+		return
+	}
+
+	b.mu.Lock()
+	if b.closed || b.flushers >= b.cfg.MaxConcurrency {
+		b.mu.Unlock()
+		return
+	}
+	b.flushers++
+	event := ScaleEvent{Previous: b.flushers - 1, Current: b.flushers, Backlog: backlog, At: time.Now()}
+	b.flusherCount.Store(int64(b.flushers))
+	b.extraFlushers.Add(1)
+	b.mu.Unlock()
+
+	go b.withLabels(b.bgCtx, roleFlusher, b.extraFlushLoop)
+	if b.cfg.OnScale != nil {
+		b.cfg.OnScale(event)
+	}
+}
+
+// extraFlushLoop handles the batches handed off by Add like flushLoop,
+// until Close or until it went FlusherIdleTimeout without one
+func (b *Batcher) extraFlushLoop(ctx context.Context) {
+	defer b.extraFlushers.Done()
+
+	idle := time.NewTimer(b.cfg.FlusherIdleTimeout)
+	defer idle.Stop()
+	for {
+		select {
+		case f, ok := <-b.pipeline:
+			if !ok {
+				return
+			}
+			_ = b.processBatch(ctx, f)
+			if !idle.Stop() {
+				<-idle.C
+			}
+			idle.Reset(b.cfg.FlusherIdleTimeout)
+		case <-idle.C:
+			b.scaleDown()
+			return
+		}
+	}
+}
+
+// scaleDown accounts for an idle flusher exiting
+func (b *Batcher) scaleDown() {
+	b.mu.Lock()
+	b.flushers--
+	event := ScaleEvent{Previous: b.flushers + 1, Current: b.flushers, At: time.Now()}
+	b.flusherCount.Store(int64(b.flushers))
+	b.mu.Unlock()
+
+	if b.cfg.OnScale != nil {
+		b.cfg.OnScale(event)
+	}
+}
+
+// stopPipeline waits for pending hand-offs and for the flushers to handle
+// them. The batcher must already be closed, so no new hand-off starts.
+func (b *Batcher) stopPipeline() {
+	b.handoffs.Wait()
+	close(b.pipeline)
+	<-b.flusherDone
+	b.extraFlushers.Wait()
+}
@@ -0,0 +1,204 @@
+package batcher
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBatcher_Pipelined(t *testing.T) {
+	const handlerTime = 50 * time.Millisecond
+
+	var mu sync.Mutex
+	var batches [][]any
+	var concurrent, maxConcurrent int
+	b, err := New(Config{
+		InitialBatchSize:  10,
+		LoadCheckInterval: time.Hour,
+		Pipelined:         true,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			mu.Lock()
+			concurrent++
+			maxConcurrent = max(maxConcurrent, concurrent)
+			mu.Unlock()
+
+			time.Sleep(handlerTime)
+
+			mu.Lock()
+			concurrent--
+			batches = append(batches, batch)
+			mu.Unlock()
+			return nil, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	// The first batch is handed off; the second fills while it is handled
+	start := time.Now()
+	for i := 0; i < 19; i++ {
+		if err := b.Add(context.Background(), i); err != nil {
+			t.Fatalf("Add() failed: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed >= handlerTime {
+		t.Errorf("Expected Add not to wait for the handler, took %v", elapsed)
+	}
+
+	// The next full batch waits for the flusher
+	b.Add(context.Background(), 19)
+	if elapsed := time.Since(start); elapsed < handlerTime/2 {
+		t.Errorf("Expected Add to block while the flusher is busy, took %v", elapsed)
+	}
+
+	b.Add(context.Background(), 20)
+	if err := b.Close(context.Background()); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	total := 0
+	for _, batch := range batches {
+		total += len(batch)
+	}
+	if total != 21 {
+		t.Errorf("Expected 21 items handled by Close, got %d in %d batches", total, len(batches))
+	}
+	if maxConcurrent > 2 {
+		t.Errorf("Expected at most the flusher and Close's flush at once, got %d", maxConcurrent)
+	}
+}
+
+func TestBatcher_PipelinedCancellation(t *testing.T) {
+	var mu sync.Mutex
+	handled, cancelled := 0, 0
+	release := make(chan struct{})
+	b, err := New(Config{
+		InitialBatchSize:  1,
+		LoadCheckInterval: time.Hour,
+		Pipelined:         true,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			select {
+			case <-release:
+			case <-ctx.Done():
+				mu.Lock()
+				cancelled++
+				mu.Unlock()
+			}
+			mu.Lock()
+			handled += len(batch)
+			mu.Unlock()
+			return nil, ctx.Err()
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer close(release)
+
+	// The flusher is stuck on the first batch, so the second hand-off
+	// gives up with the caller's context
+	b.Add(context.Background(), 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := b.Add(ctx, 2); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected DeadlineExceeded from Add, got %v", err)
+	}
+
+	// Giving up on Close cancels the handler calls in the background
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	b.Close(ctx)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if handled != 2 {
+		t.Errorf("Expected both items handled, got %d", handled)
+	}
+	if cancelled != 2 {
+		t.Errorf("Expected both handler calls cancelled, got %d", cancelled)
+	}
+}
+
+func TestBatcher_PipelinedScaling(t *testing.T) {
+	tests := []struct {
+		name         string
+		maxBatchSize int
+		wantPeak     int
+	}{
+		{"at max batch size", 10, 3},
+		{"below max batch size", 100, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var events []ScaleEvent
+			var concurrent, peak int
+			b, err := New(Config{
+				InitialBatchSize:   10,
+				MaxBatchSize:       tt.maxBatchSize,
+				LoadCheckInterval:  time.Hour,
+				Pipelined:          true,
+				MaxConcurrency:     3,
+				FlusherIdleTimeout: 30 * time.Millisecond,
+				HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+					mu.Lock()
+					concurrent++
+					peak = max(peak, concurrent)
+					mu.Unlock()
+
+					time.Sleep(20 * time.Millisecond)
+
+					mu.Lock()
+					concurrent--
+					mu.Unlock()
+					return &LoadFeedback{}, nil
+				},
+				OnScale: func(e ScaleEvent) {
+					mu.Lock()
+					defer mu.Unlock()
+					events = append(events, e)
+				},
+			})
+			if err != nil {
+				t.Fatalf("New() failed: %v", err)
+			}
+			defer b.Close(context.Background())
+
+			for i := 0; i < 100; i++ {
+				if err := b.Add(context.Background(), i); err != nil {
+					t.Fatalf("Add() failed: %v", err)
+				}
+			}
+			mu.Lock()
+			if peak != tt.wantPeak {
+				t.Errorf("Expected %d batches handled at once, got %d", tt.wantPeak, peak)
+			}
+			mu.Unlock()
+			if tt.wantPeak == 1 {
+				return
+			}
+
+			// The added flushers exit once idle
+			time.Sleep(150 * time.Millisecond)
+			if n := b.GetStats().Flushers; n != 1 {
+				t.Errorf("Expected 1 flusher after idling, got %d", n)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if len(events) != 4 {
+				t.Fatalf("Expected 2 flushers added and 2 exiting, got %+v", events)
+			}
+			if e := events[0]; e.Previous != 1 || e.Current != 2 || e.Backlog < 10 {
+				t.Errorf("Expected a scale up from 1 to 2 with a backlog, got %+v", e)
+			}
+			if e := events[3]; e.Current != 1 {
+				t.Errorf("Expected a scale down to 1, got %+v", e)
+			}
+		})
+	}
+}
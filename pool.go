@@ -0,0 +1,329 @@
+package batcher
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// PoolConfig holds the configuration for a Pool
+type PoolConfig struct {
+	// NewBatcher builds the Batcher for a key the first time an item is
+	// added for it
+	NewBatcher func(key string) (*Batcher, error)
+
+	// IdleTTL is how long a batcher may go without items before it is
+	// drained and closed (default: 5 minutes)
+	IdleTTL time.Duration
+
+	// MaxBatchers bounds the number of live batchers. Adding for a new
+	// key when the pool is full evicts the least recently used batcher.
+	// Zero means unlimited.
+	MaxBatchers int
+
+	// EvictTimeout bounds how long an evicted batcher may take to drain,
+	// e.g. while its destination asked for a pause or hangs
+	// (default: 30s)
+	EvictTimeout time.Duration
+
+	// OnEvictError, if set, is called with the key and the Close error of
+	// an evicted batcher whose drain failed or timed out
+	OnEvictError func(key string, err error)
+}
+
+// Pool lazily creates one Batcher per key, for destinations that are
+// only known at runtime such as customer webhook URLs. Batchers that
+// stay idle for IdleTTL, or that make room for a new key, are evicted:
+// closed in the background, flushing their pending items.
+type Pool struct {
+	mu      sync.Mutex
+	cfg     PoolConfig
+	entries map[string]*poolEntry
+	closed  bool
+
+	created atomic.Int64
+	evicted atomic.Int64
+
+	stop     chan struct{}
+	janitor  sync.WaitGroup
+	draining sync.WaitGroup
+}
+
+type poolEntry struct {
+	b        *Batcher
+	lastUsed time.Time
+}
+
+// NewPool creates a Pool with the given configuration
+func NewPool(cfg PoolConfig) (*Pool, error) {
+	if cfg.NewBatcher == nil || cfg.MaxBatchers < 0 {
+		return nil, ErrInvalidConfig
+	}
+	if cfg.IdleTTL <= 0 {
+		cfg.IdleTTL = 5 * time.Minute
+	}
+	if cfg.EvictTimeout <= 0 {
+		cfg.EvictTimeout = 30 * time.Second
+	}
+
+	p := &Pool{
+		cfg:     cfg,
+		entries: make(map[string]*poolEntry),
+		stop:    make(chan struct{}),
+	}
+
+	p.janitor.Add(1)
+	go p.evictIdleLoop()
+
+	return p, nil
+}
+
+// Add adds an item to the batcher of key, creating it if needed
+func (p *Pool) Add(ctx context.Context, key string, item any) error {
+	for {
+		b, err := p.get(key)
+		if err != nil {
+			return err
+		}
+
+		err = b.Add(ctx, item)
+		if !errors.Is(err, ErrClosed) {
+			return err
+		}
+
+		// The batcher was evicted while we were adding; get a new one
+		p.mu.Lock()
+		e, ok := p.entries[key]
+		stillLive := ok && e.b == b
+		closed := p.closed
+		p.mu.Unlock()
+		if stillLive || closed {
+			return err
+		}
+	}
+}
+
+// Keys returns the keys of all live batchers, sorted
+func (p *Pool) Keys() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	keys := make([]string, 0, len(p.entries))
+	for key := range p.entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Flush flushes every live batcher and returns the joined errors
+func (p *Pool) Flush(ctx context.Context) error {
+	var errs []error
+	for _, b := range p.snapshot() {
+		if err := b.Flush(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes every batcher, waits for evicted ones to finish draining
+// and returns the joined errors. If ctx is done first, it stops waiting
+// for the evicted batchers and returns ctx's error as well; they keep
+// draining until EvictTimeout.
+func (p *Pool) Close(ctx context.Context) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	p.mu.Unlock()
+
+	close(p.stop)
+	p.janitor.Wait()
+
+	var errs []error
+	for _, b := range p.snapshot() {
+		if err := b.Close(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		p.draining.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		errs = append(errs, ctx.Err())
+	}
+	return errors.Join(errs...)
+}
+
+// GetStats returns per-key statistics and the pool counters
+func (p *Pool) GetStats() PoolStats {
+	p.mu.Lock()
+	batchers := make(map[string]*Batcher, len(p.entries))
+	for key, e := range p.entries {
+		batchers[key] = e.b
+	}
+	p.mu.Unlock()
+
+	stats := PoolStats{
+		Batchers: make(map[string]Stats, len(batchers)),
+		Created:  p.created.Load(),
+		Evicted:  p.evicted.Load(),
+	}
+	for key, b := range batchers {
+		stats.Batchers[key] = b.GetStats()
+	}
+	return stats
+}
+
+// PoolStats holds pool statistics
+type PoolStats struct {
+	// Batchers holds the statistics of each live batcher keyed by key
+	Batchers map[string]Stats
+
+	// Created is the number of batchers created, and Evicted the number
+	// closed for being idle or to make room
+	Created int64
+	Evicted int64
+}
+
+// --- Internal methods ---
+
+// get returns the live batcher of key, creating it and evicting the
+// least recently used one if the pool is full. NewBatcher is called
+// outside the lock; if another Add created the batcher of key meanwhile,
+// that one is used and the new one closed.
+func (p *Pool) get(key string) (*Batcher, error) {
+	if b, err := p.lookup(key); b != nil || err != nil {
+		return b, err
+	}
+
+	b, err := p.cfg.NewBatcher(key)
+	if err != nil {
+		return nil, err
+	}
+	if b == nil {
+		return nil, ErrInvalidConfig
+	}
+
+	now := time.Now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		p.discard(b)
+		return nil, ErrClosed
+	}
+	if e, ok := p.entries[key]; ok {
+		e.lastUsed = now
+		p.discard(b)
+		return e.b, nil
+	}
+
+	if p.cfg.MaxBatchers > 0 && len(p.entries) >= p.cfg.MaxBatchers {
+		lru := ""
+		for k, e := range p.entries {
+			if lru == "" || e.lastUsed.Before(p.entries[lru].lastUsed) {
+				lru = k
+			}
+		}
+		p.evictLocked(lru)
+	}
+
+	p.entries[key] = &poolEntry{b: b, lastUsed: now}
+	p.created.Add(1)
+	return b, nil
+}
+
+// lookup returns the live batcher of key, or nil if there is none
+func (p *Pool) lookup(key string) (*Batcher, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return nil, ErrClosed
+	}
+	if e, ok := p.entries[key]; ok {
+		e.lastUsed = time.Now()
+		return e.b, nil
+	}
+	return nil, nil
+}
+
+// discard closes a batcher built for a key that was added meanwhile, or
+// after the pool closed. It holds no items to drain.
+func (p *Pool) discard(b *Batcher) {
+	p.drain(b, nil)
+}
+
+// evictLocked removes the batcher of key and drains it, reporting a
+// failed drain to OnEvictError
+func (p *Pool) evictLocked(key string) {
+	e := p.entries[key]
+	delete(p.entries, key)
+	p.evicted.Add(1)
+
+	p.drain(e.b, func(err error) {
+		if p.cfg.OnEvictError != nil {
+			p.cfg.OnEvictError(key, err)
+		}
+	})
+}
+
+// drain closes b in the background within EvictTimeout, handing its Close
+// error, if any, to report
+func (p *Pool) drain(b *Batcher, report func(error)) {
+	p.draining.Add(1)
+	go func() {
+		defer p.draining.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), p.cfg.EvictTimeout)
+		defer cancel()
+		if err := b.Close(ctx); err != nil && report != nil {
+			report(err)
+		}
+	}()
+}
+
+// evictIdleLoop evicts batchers idle for IdleTTL until the pool closes
+func (p *Pool) evictIdleLoop() {
+	defer p.janitor.Done()
+
+	ticker := time.NewTicker(p.cfg.IdleTTL / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			p.mu.Lock()
+			for key, e := range p.entries {
+				if now.Sub(e.lastUsed) >= p.cfg.IdleTTL {
+					p.evictLocked(key)
+				}
+			}
+			p.mu.Unlock()
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+func (p *Pool) snapshot() []*Batcher {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	batchers := make([]*Batcher, 0, len(p.entries))
+	for _, e := range p.entries {
+		batchers = append(batchers, e.b)
+	}
+	return batchers
+}
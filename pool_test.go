@@ -0,0 +1,243 @@
+package batcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestPool(t *testing.T, cfg PoolConfig) (*Pool, map[string][]any, *sync.Mutex) {
+	t.Helper()
+
+	var mu sync.Mutex
+	received := make(map[string][]any)
+	cfg.NewBatcher = func(key string) (*Batcher, error) {
+		return New(Config{
+			InitialBatchSize: 100,
+			HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+				mu.Lock()
+				received[key] = append(received[key], batch...)
+				mu.Unlock()
+				return nil, nil
+			},
+		})
+	}
+
+	p, err := NewPool(cfg)
+	if err != nil {
+		t.Fatalf("NewPool() failed: %v", err)
+	}
+	return p, received, &mu
+}
+
+func TestNewPool_InvalidConfig(t *testing.T) {
+	if _, err := NewPool(PoolConfig{}); err != ErrInvalidConfig {
+		t.Errorf("Expected ErrInvalidConfig, got %v", err)
+	}
+}
+
+func TestPool_CreatesPerKey(t *testing.T) {
+	p, received, mu := newTestPool(t, PoolConfig{})
+	ctx := context.Background()
+
+	for i := 0; i < 30; i++ {
+		if err := p.Add(ctx, fmt.Sprintf("key-%d", i%3), i); err != nil {
+			t.Fatalf("Add() failed: %v", err)
+		}
+	}
+	if keys := p.Keys(); fmt.Sprint(keys) != "[key-0 key-1 key-2]" {
+		t.Errorf("Expected 3 keys, got %v", keys)
+	}
+	if stats := p.GetStats(); stats.Created != 3 || stats.Batchers["key-1"].PendingItems != 10 {
+		t.Errorf("Expected 3 batchers with 10 items each, got %+v", stats)
+	}
+
+	if err := p.Close(ctx); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for key, items := range received {
+		if len(items) != 10 {
+			t.Errorf("Expected 10 items for %s, got %d", key, len(items))
+		}
+	}
+	if err := p.Add(ctx, "key-0", 1); err != ErrClosed {
+		t.Errorf("Expected ErrClosed after Close, got %v", err)
+	}
+}
+
+func TestPool_EvictsIdle(t *testing.T) {
+	p, received, mu := newTestPool(t, PoolConfig{IdleTTL: 50 * time.Millisecond})
+	defer p.Close(context.Background())
+
+	p.Add(context.Background(), "idle", 1)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(p.Keys()) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the idle batcher to be evicted")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	p.Close(context.Background())
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received["idle"]) != 1 {
+		t.Errorf("Expected the evicted batcher to be drained, got %v", received["idle"])
+	}
+	if stats := p.GetStats(); stats.Evicted != 1 {
+		t.Errorf("Expected 1 eviction, got %d", stats.Evicted)
+	}
+}
+
+func TestPool_MaxBatchers(t *testing.T) {
+	p, received, mu := newTestPool(t, PoolConfig{MaxBatchers: 2})
+	ctx := context.Background()
+
+	p.Add(ctx, "a", 1)
+	time.Sleep(time.Millisecond)
+	p.Add(ctx, "b", 2)
+	time.Sleep(time.Millisecond)
+	p.Add(ctx, "a", 3)
+	time.Sleep(time.Millisecond)
+	p.Add(ctx, "c", 4) // evicts b, the least recently used
+
+	if keys := p.Keys(); fmt.Sprint(keys) != "[a c]" {
+		t.Errorf("Expected keys [a c], got %v", keys)
+	}
+	if err := p.Close(ctx); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received["b"]) != 1 {
+		t.Errorf("Expected the evicted batcher to be drained, got %v", received["b"])
+	}
+}
+
+// newHangingPool returns a pool whose batcher of key "hang" is handled
+// until release is closed or its ctx is done
+func newHangingPool(t *testing.T, cfg PoolConfig, release chan struct{}) *Pool {
+	t.Helper()
+
+	cfg.MaxBatchers = 1
+	cfg.NewBatcher = func(key string) (*Batcher, error) {
+		return New(Config{
+			InitialBatchSize: 100,
+			HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+				if key != "hang" {
+					return nil, nil
+				}
+				select {
+				case <-release:
+					return nil, nil
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				}
+			},
+		})
+	}
+	p, err := NewPool(cfg)
+	if err != nil {
+		t.Fatalf("NewPool() failed: %v", err)
+	}
+	return p
+}
+
+func TestPool_EvictTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	evictErrs := make(chan error, 1)
+	p := newHangingPool(t, PoolConfig{
+		EvictTimeout: 20 * time.Millisecond,
+		OnEvictError: func(key string, err error) {
+			if key != "hang" {
+				t.Errorf("Expected the error of key hang, got %q", key)
+			}
+			evictErrs <- err
+		},
+	}, release)
+	ctx := context.Background()
+
+	p.Add(ctx, "hang", 1)
+	p.Add(ctx, "next", 2) // evicts hang, whose drain times out
+
+	select {
+	case err := <-evictErrs:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected the drain to time out, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected OnEvictError for the hanging drain")
+	}
+	if err := p.Close(ctx); err != nil {
+		t.Errorf("Close() failed: %v", err)
+	}
+}
+
+func TestPool_CloseContext(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	p := newHangingPool(t, PoolConfig{EvictTimeout: time.Hour}, release)
+	ctx := context.Background()
+
+	p.Add(ctx, "hang", 1)
+	p.Add(ctx, "next", 2)
+
+	// Close gives up on the evicted batcher with its ctx
+	closeCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := p.Close(closeCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected Close to give up with its ctx, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected Close to return with its ctx, took %v", elapsed)
+	}
+}
+
+func TestPool_ConcurrentCreate(t *testing.T) {
+	var built atomic.Int64
+	p, err := NewPool(PoolConfig{
+		NewBatcher: func(key string) (*Batcher, error) {
+			built.Add(1)
+			return New(Config{
+				InitialBatchSize: 100,
+				HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+					return nil, nil
+				},
+			})
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewPool() failed: %v", err)
+	}
+	ctx := context.Background()
+
+	// Racing Adds for a new key may build several batchers, but only one
+	// is kept
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			p.Add(ctx, "a", i)
+		}(i)
+	}
+	wg.Wait()
+
+	stats := p.GetStats()
+	if stats.Created != 1 || stats.Batchers["a"].PendingItems != 8 {
+		t.Errorf("Expected 1 batcher holding 8 items, got %d created and %+v", stats.Created, stats.Batchers["a"])
+	}
+	if err := p.Close(ctx); err != nil {
+		t.Errorf("Close() failed: %v", err)
+	}
+}
@@ -0,0 +1,159 @@
+package batcher
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"time"
+)
+
+// ProbeConfig configures a capability probe
+type ProbeConfig struct {
+	// ItemFunc builds the synthetic items sent in probe batches. The probe
+	// calls the real handler, so these items must be safe to process.
+	ItemFunc func(i int) any
+
+	// Sizes are the batch sizes to try. By default six sizes are spread
+	// geometrically across [MinBatchSize, MaxBatchSize].
+	Sizes []int
+
+	// Repeats is how many batches are sent per size (default: 2)
+	Repeats int
+
+	// MaxLatency is the longest acceptable handler latency. Sizes slower
+	// than this are not considered for the maximum. If zero, only errors
+	// and a load score above 0.7 disqualify a size.
+	MaxLatency time.Duration
+}
+
+// ProbeSample is the measurement of one probed batch size
+type ProbeSample struct {
+	Size      int
+	Latency   time.Duration
+	LoadScore float64
+	Err       error
+}
+
+// ProbeResult holds the probe measurements and the derived bounds
+type ProbeResult struct {
+	Samples []ProbeSample
+
+	MinBatchSize     int
+	MaxBatchSize     int
+	InitialBatchSize int
+}
+
+// ErrProbeFailed is returned when no probed batch size was acceptable
+var ErrProbeFailed = errors.New("batcher: capability probe found no acceptable batch size")
+
+// Probe sends exploratory batches of increasing size to the handler,
+// measures how latency scales and narrows the batch size bounds to what
+// the handler can sustain. The derived bounds replace MinBatchSize,
+// MaxBatchSize and the current batch size.
+//
+// The minimum is the size at which per-item cost starts to outweigh the
+// fixed per-call overhead; the maximum is the largest size that stayed
+// within MaxLatency without errors or overload; the initial size is their
+// geometric mean.
+func (b *Batcher) Probe(ctx context.Context, pc ProbeConfig) (ProbeResult, error) {
+	if pc.ItemFunc == nil {
+		return ProbeResult{}, ErrInvalidConfig
+	}
+	if pc.Repeats <= 0 {
+		pc.Repeats = 2
+	}
+
+	b.mu.Lock()
+	lo, hi := b.cfg.MinBatchSize, b.cfg.MaxBatchSize
+	b.mu.Unlock()
+
+	sizes := pc.Sizes
+	if len(sizes) == 0 {
+		sizes = probeSizes(lo, hi, 6)
+	}
+
+	var result ProbeResult
+	var fit []FeedbackSample
+	maxOK := 0
+
+	for _, size := range sizes {
+		if size < lo || size > hi {
+			continue
+		}
+		ok := true
+		for r := 0; r < pc.Repeats; r++ {
+			if err := ctx.Err(); err != nil {
+				return result, err
+			}
+
+			batch := make([]any, size)
+			for i := range batch {
+				batch[i] = pc.ItemFunc(i)
+			}
+
+			start := time.Now()
+			feedback, err := b.callHandler(ctx, batch, BatchMeta{})
+			sample := ProbeSample{Size: size, Latency: time.Since(start), Err: err}
+			if feedback != nil {
+				sample.LoadScore = b.score(feedback)
+			}
+			result.Samples = append(result.Samples, sample)
+
+			if err != nil || sample.LoadScore > 0.7 ||
+				(pc.MaxLatency > 0 && sample.Latency > pc.MaxLatency) {
+				ok = false
+				continue
+			}
+			fit = append(fit, FeedbackSample{
+				Feedback:  LoadFeedback{ProcessingTime: sample.Latency},
+				BatchSize: size,
+			})
+		}
+		if ok && size > maxOK {
+			maxOK = size
+		}
+	}
+
+	if maxOK == 0 {
+		return result, ErrProbeFailed
+	}
+
+	minSize := lo
+	if a, perItem, ok := fitServiceTime(fit); ok && perItem > 0 {
+		minSize = int(math.Ceil(a / perItem))
+	}
+	minSize = max(lo, min(minSize, maxOK))
+
+	result.MinBatchSize = minSize
+	result.MaxBatchSize = maxOK
+	result.InitialBatchSize = int(math.Round(math.Sqrt(float64(minSize) * float64(maxOK))))
+
+	b.mu.Lock()
+	b.setLimitsLocked(result.MinBatchSize, result.MaxBatchSize)
+	b.setBatchSizeLocked(result.InitialBatchSize)
+	b.mu.Unlock()
+
+	return result, nil
+}
+
+// probeSizes spreads n sizes geometrically across [lo, hi]
+func probeSizes(lo, hi, n int) []int {
+	if lo >= hi || n < 2 {
+		return []int{lo}
+	}
+	ratio := math.Pow(float64(hi)/float64(lo), 1/float64(n-1))
+
+	seen := make(map[int]bool)
+	sizes := make([]int, 0, n)
+	for i := 0; i < n; i++ {
+		size := int(math.Round(float64(lo) * math.Pow(ratio, float64(i))))
+		size = max(lo, min(size, hi))
+		if !seen[size] {
+			seen[size] = true
+			sizes = append(sizes, size)
+		}
+	}
+	sort.Ints(sizes)
+	return sizes
+}
@@ -0,0 +1,84 @@
+package batcher
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestProbeSizes(t *testing.T) {
+	sizes := probeSizes(1, 1000, 4)
+	want := []int{1, 10, 100, 1000}
+	if len(sizes) != len(want) {
+		t.Fatalf("probeSizes() = %v, want %v", sizes, want)
+	}
+	for i := range want {
+		if sizes[i] != want[i] {
+			t.Errorf("probeSizes() = %v, want %v", sizes, want)
+		}
+	}
+}
+
+func TestBatcher_Probe(t *testing.T) {
+	// Handler with 2ms overhead, 0.1ms per item; overloaded above 400 items
+	handler := func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+		time.Sleep(2*time.Millisecond + time.Duration(len(batch))*100*time.Microsecond)
+		cpu := 0.2
+		if len(batch) > 400 {
+			cpu = 1.0
+		}
+		return &LoadFeedback{CPULoad: cpu, ErrorRate: cpu - 0.2}, nil
+	}
+
+	b, err := New(Config{
+		InitialBatchSize: 10,
+		MinBatchSize:     1,
+		MaxBatchSize:     1000,
+		HandlerFunc:      handler,
+		Probe: &ProbeConfig{
+			ItemFunc: func(i int) any { return i },
+			Sizes:    []int{5, 20, 100, 400, 1000},
+			Repeats:  1,
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	b.mu.Lock()
+	minSize, maxSize := b.cfg.MinBatchSize, b.cfg.MaxBatchSize
+	b.mu.Unlock()
+
+	if maxSize != 400 {
+		t.Errorf("Expected max batch size 400, got %d", maxSize)
+	}
+	if minSize < 5 || minSize > 100 {
+		t.Errorf("Expected min batch size near the overhead break-even (~20), got %d", minSize)
+	}
+	current := b.GetCurrentBatchSize()
+	if current < minSize || current > maxSize {
+		t.Errorf("Initial size %d outside [%d, %d]", current, minSize, maxSize)
+	}
+}
+
+func TestBatcher_ProbeFails(t *testing.T) {
+	b, err := New(Config{
+		InitialBatchSize: 10,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			return &LoadFeedback{CPULoad: 1.0, ErrorRate: 1.0}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	_, err = b.Probe(context.Background(), ProbeConfig{
+		ItemFunc: func(i int) any { return i },
+		Sizes:    []int{1, 10},
+	})
+	if err != ErrProbeFailed {
+		t.Errorf("Expected ErrProbeFailed, got %v", err)
+	}
+}
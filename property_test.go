@@ -0,0 +1,170 @@
+package batcher
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"reflect"
+	"sync"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+// opKind is one step of a generated workload
+type opKind int
+
+const (
+	opAdd opKind = iota
+	opFlush
+	opFlushAndWait
+	opClose
+)
+
+// workload is a random configuration plus per-goroutine op sequences
+type workload struct {
+	BatchSize  int
+	Timeout    time.Duration
+	DropPolicy DropPolicy
+	Watermark  int
+	Load       float64
+	Producers  [][]opKind
+}
+
+// Generate implements quick.Generator
+func (workload) Generate(r *rand.Rand, size int) reflect.Value {
+	w := workload{
+		BatchSize:  1 + r.Intn(20),
+		DropPolicy: DropPolicy(r.Intn(4)),
+		Watermark:  2 + r.Intn(30),
+		Load:       r.Float64(),
+	}
+	if r.Intn(2) == 0 {
+		w.Timeout = time.Duration(r.Intn(3)) * time.Millisecond
+	}
+	w.Producers = make([][]opKind, 1+r.Intn(4))
+	for i := range w.Producers {
+		ops := make([]opKind, r.Intn(size*4+1))
+		for j := range ops {
+			switch n := r.Intn(100); {
+			case n < 85:
+				ops[j] = opAdd
+			case n < 93:
+				ops[j] = opFlush
+			case n < 99:
+				ops[j] = opFlushAndWait
+			default:
+				ops[j] = opClose
+			}
+		}
+		w.Producers[i] = ops
+	}
+	return reflect.ValueOf(w)
+}
+
+// deliveryLog records where every item ended up
+type deliveryLog struct {
+	mu       sync.Mutex
+	handled  map[int]int
+	dropped  map[int]int
+	accepted map[int]bool
+}
+
+func (l *deliveryLog) record(m map[int]int, items []any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, item := range items {
+		m[item.(int)]++
+	}
+}
+
+// runWorkload runs w and reports whether every accepted item was either
+// handled or dead-lettered exactly once, and no rejected item was seen
+func runWorkload(t *testing.T, w workload) bool {
+	log := &deliveryLog{
+		handled:  make(map[int]int),
+		dropped:  make(map[int]int),
+		accepted: make(map[int]bool),
+	}
+
+	b, err := New(Config{
+		InitialBatchSize:  w.BatchSize,
+		Timeout:           w.Timeout,
+		DropPolicy:        w.DropPolicy,
+		ShedHighWatermark: w.Watermark,
+		ShedLoadThreshold: 0.01,
+		PriorityFunc:      func(item any) int { return item.(int) % 7 },
+		LoadCheckInterval: time.Millisecond,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			log.record(log.handled, batch)
+			return &LoadFeedback{CPULoad: w.Load, QueueDepth: int(w.Load * 100)}, nil
+		},
+		DeadLetterFunc: func(ctx context.Context, items []any, err error) {
+			log.record(log.dropped, items)
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for p, ops := range w.Producers {
+		wg.Add(1)
+		go func(p int, ops []opKind) {
+			defer wg.Done()
+			for i, op := range ops {
+				switch op {
+				case opAdd:
+					item := p*1_000_000 + i
+					err := b.Add(ctx, item)
+					if err == nil || errors.Is(err, ErrDropped) {
+						log.mu.Lock()
+						log.accepted[item] = true
+						log.mu.Unlock()
+					}
+				case opFlush:
+					_ = b.Flush(ctx)
+				case opFlushAndWait:
+					_ = b.FlushAndWait(ctx)
+				case opClose:
+					_ = b.Close(ctx)
+				}
+			}
+		}(p, ops)
+	}
+	wg.Wait()
+
+	_ = b.Close(ctx)
+	_ = b.FlushAndWait(ctx)
+
+	log.mu.Lock()
+	defer log.mu.Unlock()
+
+	for item := range log.accepted {
+		if n := log.handled[item] + log.dropped[item]; n != 1 {
+			t.Logf("Item %d delivered %d times (handled %d, dropped %d)",
+				item, n, log.handled[item], log.dropped[item])
+			return false
+		}
+	}
+	for _, m := range []map[int]int{log.handled, log.dropped} {
+		for item := range m {
+			if !log.accepted[item] {
+				t.Logf("Item %d delivered but Add reported it rejected", item)
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func TestProperty_NoItemLoss(t *testing.T) {
+	cfg := &quick.Config{MaxCount: 200}
+	if testing.Short() {
+		cfg.MaxCount = 30
+	}
+	if err := quick.Check(func(w workload) bool { return runWorkload(t, w) }, cfg); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,115 @@
+package batcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Source yields the items a Puller batches. Next blocks until an item is
+// available or ctx is done, and returns io.EOF once the source is
+// exhausted.
+type Source interface {
+	Next(ctx context.Context) (any, error)
+}
+
+// SourceFunc adapts a function to a Source
+type SourceFunc func(ctx context.Context) (any, error)
+
+// Next implements Source
+func (f SourceFunc) Next(ctx context.Context) (any, error) {
+	return f(ctx)
+}
+
+// PullerConfig holds the configuration for a Puller
+type PullerConfig struct {
+	// Config is the batcher configuration
+	Config Config
+
+	// Source is pulled for items
+	Source Source
+}
+
+// Puller inverts the flow of a Batcher: instead of buffering items pushed
+// with Add, it pulls them from a Source, such as a queue consumer or a
+// cursor, and pumps them to the handler. Items are pulled only as fast as
+// the handler takes them, in batches of the size the load feedback
+// settles on: the puller stops while a full batch is handled, or with
+// Config.Pipelined while the next one is full as well, and while the
+// backend asked for a pause. Partial batches are flushed by Timeout while
+// the source has nothing to give.
+type Puller struct {
+	b   *Batcher
+	src Source
+}
+
+// NewPuller creates a Puller with the given configuration. Source must be
+// set.
+func NewPuller(cfg PullerConfig) (*Puller, error) {
+	if cfg.Source == nil {
+		return nil, ErrInvalidConfig
+	}
+	b, err := New(cfg.Config)
+	if err != nil {
+		return nil, err
+	}
+	return &Puller{b: b, src: cfg.Source}, nil
+}
+
+// Run pulls items from the source and batches them until the source is
+// exhausted, when it flushes the last batch and returns its error, until
+// ctx is done, or until the source fails, whose error it returns. Failed
+// batches do not stop it; they reach Stats and the dead-letter handling
+// as usual. Neither do items Add rejects, e.g. with ErrInvalidItem,
+// ErrPendingBytes, ErrMemoryPressure or ErrQuotaExceeded: they are
+// dead-lettered with DropReasonRejected and the error. Run must not be
+// called concurrently, and the Puller should be closed after it returns.
+func (p *Puller) Run(ctx context.Context) error {
+	for {
+		if err := p.b.waitForResume(ctx); err != nil {
+			return err
+		}
+
+		item, err := p.src.Next(ctx)
+		if errors.Is(err, io.EOF) {
+			return p.b.Flush(ctx)
+		}
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			return fmt.Errorf("batcher: source: %w", err)
+		}
+
+		err = p.b.Add(ctx, item)
+		switch {
+		case errors.Is(err, ErrClosed):
+			return err
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case rejected(err):
+			p.b.deadLetter(ctx, []pendingItem{{item: item}}, DropReasonRejected, err)
+		}
+	}
+}
+
+// Close closes the batcher, flushing the items still buffered
+func (p *Puller) Close(ctx context.Context) error {
+	return p.b.Close(ctx)
+}
+
+// Batcher returns the underlying Batcher, e.g. for its statistics
+func (p *Puller) Batcher() *Batcher {
+	return p.b
+}
+
+// --- Internal methods ---
+
+// rejected reports whether Add failed without taking the item. Shed items
+// are dead-lettered by Add itself, and handler errors of a flush the item
+// triggered leave it to the batch's failure handling.
+func rejected(err error) bool {
+	return errors.Is(err, ErrInvalidItem) || errors.Is(err, ErrPendingBytes) ||
+		errors.Is(err, ErrMemoryPressure) || errors.Is(err, ErrQuotaExceeded)
+}
@@ -0,0 +1,267 @@
+package batcher
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// sliceSource yields the ints 0 to n-1, then io.EOF
+func sliceSource(n int, pulled *atomic.Int64) SourceFunc {
+	return func(ctx context.Context) (any, error) {
+		i := pulled.Add(1) - 1
+		if i >= int64(n) {
+			return nil, io.EOF
+		}
+		return int(i), nil
+	}
+}
+
+func TestPuller_DrainsSource(t *testing.T) {
+	var mu sync.Mutex
+	seen := make(map[int]int)
+	var pulled atomic.Int64
+
+	p, err := NewPuller(PullerConfig{
+		Config: Config{
+			InitialBatchSize: 10,
+			HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				for _, item := range batch {
+					seen[item.(int)]++
+				}
+				return &LoadFeedback{}, nil
+			},
+		},
+		Source: sliceSource(95, &pulled),
+	})
+	if err != nil {
+		t.Fatalf("NewPuller() failed: %v", err)
+	}
+	defer p.Close(context.Background())
+
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(seen) != 95 {
+		t.Errorf("Expected 95 items handled, got %d", len(seen))
+	}
+	for item, n := range seen {
+		if n != 1 {
+			t.Errorf("Expected item %d handled once, got %d", item, n)
+		}
+	}
+	if batches := p.Batcher().GetStats().Batches; batches != 10 {
+		t.Errorf("Expected 10 batches, got %d", batches)
+	}
+}
+
+func TestPuller_StopsWhilePaused(t *testing.T) {
+	var pulled atomic.Int64
+	var pauseEnds atomic.Int64
+	var early atomic.Int64
+
+	src := sliceSource(20, &pulled)
+	p, err := NewPuller(PullerConfig{
+		Config: Config{
+			InitialBatchSize: 10,
+			HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+				if pauseEnds.Load() == 0 {
+					pauseEnds.Store(time.Now().Add(50 * time.Millisecond).UnixNano())
+					return &LoadFeedback{RetryAfter: 50 * time.Millisecond}, nil
+				}
+				return &LoadFeedback{}, nil
+			},
+		},
+		Source: SourceFunc(func(ctx context.Context) (any, error) {
+			if end := pauseEnds.Load(); end != 0 && time.Now().UnixNano() < end {
+				early.Add(1)
+			}
+			return src(ctx)
+		}),
+	})
+	if err != nil {
+		t.Fatalf("NewPuller() failed: %v", err)
+	}
+	defer p.Close(context.Background())
+
+	start := time.Now()
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("Expected Run to wait out the pause, took %v", elapsed)
+	}
+	if n := early.Load(); n != 0 {
+		t.Errorf("Expected no pulls during the pause, got %d", n)
+	}
+}
+
+func TestPuller_SourceError(t *testing.T) {
+	boom := errors.New("boom")
+	var handled atomic.Int64
+	calls := 0
+
+	p, err := NewPuller(PullerConfig{
+		Config: Config{
+			InitialBatchSize: 10,
+			HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+				handled.Add(int64(len(batch)))
+				return &LoadFeedback{}, nil
+			},
+		},
+		Source: SourceFunc(func(ctx context.Context) (any, error) {
+			if calls++; calls > 3 {
+				return nil, boom
+			}
+			return calls, nil
+		}),
+	})
+	if err != nil {
+		t.Fatalf("NewPuller() failed: %v", err)
+	}
+
+	if err := p.Run(context.Background()); !errors.Is(err, boom) {
+		t.Errorf("Expected the source error, got %v", err)
+	}
+	if err := p.Close(context.Background()); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+	if n := handled.Load(); n != 3 {
+		t.Errorf("Expected the 3 pulled items handled on Close, got %d", n)
+	}
+}
+
+func TestPuller_DeadLettersRejected(t *testing.T) {
+	var handled, pulled atomic.Int64
+	var mu sync.Mutex
+	var dead []any
+	var reasons []DropReason
+
+	p, err := NewPuller(PullerConfig{
+		Config: Config{
+			InitialBatchSize: 10,
+			ValidateFunc: func(item any) error {
+				if item.(int)%2 == 1 {
+					return errors.New("odd")
+				}
+				return nil
+			},
+			HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+				handled.Add(int64(len(batch)))
+				return &LoadFeedback{}, nil
+			},
+			DeadLetterFunc: func(ctx context.Context, items []any, err error) {
+				if !errors.Is(err, ErrInvalidItem) {
+					t.Errorf("Expected ErrInvalidItem, got %v", err)
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				dead = append(dead, items...)
+			},
+			OnItemDropped: func(item any, reason DropReason) {
+				mu.Lock()
+				defer mu.Unlock()
+				reasons = append(reasons, reason)
+			},
+		},
+		Source: sliceSource(10, &pulled),
+	})
+	if err != nil {
+		t.Fatalf("NewPuller() failed: %v", err)
+	}
+	defer p.Close(context.Background())
+
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	// The rejected items were already taken from the source: they are
+	// dead-lettered rather than lost
+	mu.Lock()
+	defer mu.Unlock()
+	if len(dead) != 5 || dead[0] != 1 || dead[4] != 9 {
+		t.Errorf("Expected the 5 odd items dead-lettered, got %v", dead)
+	}
+	for _, r := range reasons {
+		if r != DropReasonRejected {
+			t.Errorf("Expected DropReasonRejected, got %v", r)
+		}
+	}
+	if n := handled.Load(); n != 5 {
+		t.Errorf("Expected the 5 even items handled, got %d", n)
+	}
+	if got := DropReasonRejected.String(); got != "rejected" {
+		t.Errorf("Expected \"rejected\", got %q", got)
+	}
+}
+
+func TestPuller_Timeout(t *testing.T) {
+	flushed := make(chan int, 1)
+	items := make(chan int, 3)
+	items <- 1
+	items <- 2
+	items <- 3
+
+	p, err := NewPuller(PullerConfig{
+		Config: Config{
+			InitialBatchSize: 10,
+			Timeout:          10 * time.Millisecond,
+			HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+				flushed <- len(batch)
+				return &LoadFeedback{}, nil
+			},
+		},
+		Source: SourceFunc(func(ctx context.Context) (any, error) {
+			select {
+			case item := <-items:
+				return item, nil
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}),
+	})
+	if err != nil {
+		t.Fatalf("NewPuller() failed: %v", err)
+	}
+	defer p.Close(context.Background())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- p.Run(ctx) }()
+
+	// The partial batch is flushed while the source blocks
+	select {
+	case n := <-flushed:
+		if n != 3 {
+			t.Errorf("Expected a batch of 3, got %d", n)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the partial batch to be flushed by Timeout")
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestNewPuller_NoSource(t *testing.T) {
+	_, err := NewPuller(PullerConfig{Config: Config{
+		InitialBatchSize: 10,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			return nil, nil
+		},
+	}})
+	if !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig, got %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package batcher
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ErrUnknownStrategy is returned by NewStrategy for a name no strategy is
+// registered under
+var ErrUnknownStrategy = errors.New("batcher: unknown strategy")
+
+// StrategyFactory creates a new, independent AdjustmentStrategy
+type StrategyFactory func() AdjustmentStrategy
+
+var (
+	strategiesMu sync.RWMutex
+	strategies   = map[string]StrategyFactory{
+		"threshold": func() AdjustmentStrategy { return &ThresholdStrategy{} },
+		"queueing":  func() AdjustmentStrategy { return &QueueingStrategy{} },
+		"pid":       func() AdjustmentStrategy { return &PIDStrategy{} },
+		"aimd":      func() AdjustmentStrategy { return &AIMDStrategy{} },
+	}
+)
+
+// RegisterStrategy makes a strategy available under name, for
+// Config.Strategy and NewStrategy, typically from an init function. The
+// built-in strategies are registered as "threshold", "queueing", "pid" and
+// "aimd", with their default parameters. It panics if factory is nil or
+// name is already registered, as database/sql.Register does.
+func RegisterStrategy(name string, factory StrategyFactory) {
+	strategiesMu.Lock()
+	defer strategiesMu.Unlock()
+
+	if factory == nil {
+		panic("batcher: RegisterStrategy factory is nil")
+	}
+	if _, dup := strategies[name]; dup {
+		panic("batcher: RegisterStrategy called twice for strategy " + name)
+	}
+	strategies[name] = factory
+}
+
+// NewStrategy creates the strategy registered under name, or returns an
+// error wrapping ErrUnknownStrategy
+func NewStrategy(name string) (AdjustmentStrategy, error) {
+	factory, ok := lookupStrategy(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownStrategy, name)
+	}
+	return factory(), nil
+}
+
+// Strategies returns the names of the registered strategies, sorted
+func Strategies() []string {
+	strategiesMu.RLock()
+	defer strategiesMu.RUnlock()
+
+	names := make([]string, 0, len(strategies))
+	for name := range strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// --- Internal methods ---
+
+func lookupStrategy(name string) (StrategyFactory, bool) {
+	strategiesMu.RLock()
+	defer strategiesMu.RUnlock()
+	factory, ok := strategies[name]
+	return factory, ok
+}
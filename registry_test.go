@@ -0,0 +1,87 @@
+package batcher
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// fixedStrategy always asks for the same batch size
+type fixedStrategy struct{ size int }
+
+func (s *fixedStrategy) NextBatchSize(in AdjustmentInput) int {
+	return s.size
+}
+
+func TestRegisterStrategy(t *testing.T) {
+	// Registration is global, so it must survive -count > 1
+	if !slices.Contains(Strategies(), "test-fixed") {
+		RegisterStrategy("test-fixed", func() AdjustmentStrategy { return &fixedStrategy{size: 42} })
+	}
+	if !slices.Contains(Strategies(), "test-fixed") {
+		t.Errorf("Expected test-fixed among %v", Strategies())
+	}
+
+	b, err := New(Config{
+		InitialBatchSize: 10,
+		MaxBatchSize:     100,
+		Strategy:         "test-fixed",
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			return &LoadFeedback{}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	b.Add(context.Background(), 1)
+	b.Flush(context.Background())
+	if size := b.AdjustNow(); size != 42 {
+		t.Errorf("Expected the registered strategy to set size 42, got %d", size)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected registering a name twice to panic")
+		}
+	}()
+	RegisterStrategy("test-fixed", func() AdjustmentStrategy { return &fixedStrategy{} })
+}
+
+func TestNewStrategy(t *testing.T) {
+	for _, name := range []string{"threshold", "queueing", "pid", "aimd"} {
+		s, err := NewStrategy(name)
+		if err != nil || s == nil {
+			t.Errorf("Expected built-in strategy %q, got %v", name, err)
+		}
+	}
+
+	a, _ := NewStrategy("aimd")
+	b, _ := NewStrategy("aimd")
+	if a == b {
+		t.Error("Expected every call to create a new strategy")
+	}
+
+	if _, err := NewStrategy("nope"); !errors.Is(err, ErrUnknownStrategy) {
+		t.Errorf("Expected ErrUnknownStrategy, got %v", err)
+	}
+}
+
+func TestConfig_UnknownStrategy(t *testing.T) {
+	cfg := Config{
+		InitialBatchSize: 10,
+		Strategy:         "nope",
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			return nil, nil
+		},
+	}
+	if _, err := New(cfg); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig, got %v", err)
+	}
+	var cerr *ConfigError
+	if !errors.As(cfg.Validate(), &cerr) || cerr.Field != "Strategy" {
+		t.Errorf("Expected a ConfigError for Strategy, got %v", cfg.Validate())
+	}
+}
@@ -0,0 +1,139 @@
+package batcher
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrRetryable marks handler errors after which the batch may be handled
+// again, e.g. fmt.Errorf("%w: %v", batcher.ErrRetryable, err). See
+// Config.RequeuePolicy.
+var ErrRetryable = errors.New("batcher: retryable")
+
+// RequeuePolicy selects what happens to the items of a batch whose
+// handler failed with ErrRetryable
+type RequeuePolicy int
+
+const (
+	// RequeueNone hands the error to the caller and forgets the items
+	RequeueNone RequeuePolicy = iota
+
+	// RequeueFront puts the items back at the front of the buffer in
+	// their original order, ahead of the items added since, so they are
+	// flushed first by the next batch. Items stay in the one buffer
+	// rather than a separate retry queue, so they keep their place and
+	// count towards the batch size and load shedding like any other.
+	RequeueFront
+)
+
+// String returns the string representation of RequeuePolicy
+func (p RequeuePolicy) String() string {
+	switch p {
+	case RequeueNone:
+		return "none"
+	case RequeueFront:
+		return "front"
+	default:
+		return "unknown"
+	}
+}
+
+// RequeueError is the error items are dead-lettered with when the batcher
+// gives up requeuing them, after MaxRequeues attempts, MaxRequeueElapsed,
+// or on Close. It wraps the error of the last attempt and says how long
+// the items were tried, for triage in DeadLetterFunc:
+//
+//	var re *batcher.RequeueError
+//	if errors.As(err, &re) {
+//		log.Printf("gave up after %d attempts in %v", re.Attempts, re.Elapsed)
+//	}
+type RequeueError struct {
+	// Attempts is the number of times the items were handled. Items of
+	// a batch retried together share it; otherwise it is that of the
+	// item tried most often.
+	Attempts int
+
+	// Elapsed is the time since the first attempt of the item tried the
+	// longest
+	Elapsed time.Duration
+
+	// Err is the error of the last attempt
+	Err error
+}
+
+// Error implements error
+func (e *RequeueError) Error() string {
+	return fmt.Sprintf("batcher: gave up after %d attempts in %v: %v", e.Attempts, e.Elapsed, e.Err)
+}
+
+// Unwrap returns the error of the last attempt
+func (e *RequeueError) Unwrap() error {
+	return e.Err
+}
+
+// --- Internal methods ---
+
+// requeueError describes the items given up on after err
+func requeueError(items []pendingItem, err error) error {
+	if len(items) == 0 {
+		return err
+	}
+	re := &RequeueError{Err: err}
+	for _, p := range items {
+		re.Attempts = max(re.Attempts, p.requeues+1)
+		re.Elapsed = max(re.Elapsed, time.Since(p.firstTry))
+	}
+	return re
+}
+
+// requeueLocked puts the items of a failed batch, whose attempt started
+// at start, back at the front of the buffer and returns those it gave up
+// on: items requeued MaxRequeues times already or first tried
+// MaxRequeueElapsed ago, or all of them once the batcher is closed
+func (b *Batcher) requeueLocked(items []pendingItem, start time.Time) (exhausted []pendingItem) {
+	for i := range items {
+		if items[i].firstTry.IsZero() {
+			items[i].firstTry = start
+		}
+	}
+	if b.closed {
+		return items
+	}
+
+	requeued := make([]pendingItem, 0, len(items)+len(b.batch))
+	var earliest time.Time
+	for _, p := range items {
+		if p.requeues >= b.cfg.MaxRequeues || b.requeueExpired(p) {
+			exhausted = append(exhausted, p)
+			continue
+		}
+		p.requeues++
+		requeued = append(requeued, p)
+		if !p.deadline.IsZero() && (earliest.IsZero() || p.deadline.Before(earliest)) {
+			earliest = p.deadline
+		}
+	}
+	if len(requeued) == 0 {
+		return exhausted
+	}
+
+	wasEmpty := len(b.batch) == 0
+	b.batch = append(requeued, b.batch...)
+	b.setPendingLocked()
+	b.requeued.Add(int64(len(requeued)))
+
+	if wasEmpty && b.timedFlushes() && b.timer == nil {
+		b.startTimerLocked()
+	}
+	if !earliest.IsZero() && (b.earliestDeadline.IsZero() || earliest.Before(b.earliestDeadline)) {
+		b.earliestDeadline = earliest
+		b.scheduleFlushLocked(earliest.Add(-b.cfg.DeadlineMargin))
+	}
+	return exhausted
+}
+
+// requeueExpired reports whether p was first tried MaxRequeueElapsed ago
+func (b *Batcher) requeueExpired(p pendingItem) bool {
+	return b.cfg.MaxRequeueElapsed > 0 && time.Since(p.firstTry) >= b.cfg.MaxRequeueElapsed
+}
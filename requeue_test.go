@@ -0,0 +1,182 @@
+package batcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+var errTransient = fmt.Errorf("%w: connection reset", ErrRetryable)
+
+// newRequeueTestBatcher returns a batcher whose handler fails with the
+// error fail returns for the call, and the batches it succeeded on
+func newRequeueTestBatcher(t *testing.T, cfg Config, fail func(call int) error) (*Batcher, func() [][]any) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var handled [][]any
+	calls := 0
+	cfg.Timeout = time.Hour
+	cfg.HandlerFunc = func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if err := fail(calls); err != nil {
+			return nil, err
+		}
+		handled = append(handled, append([]any(nil), batch...))
+		return &LoadFeedback{CPULoad: 0.5}, nil
+	}
+	b, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	return b, func() [][]any {
+		mu.Lock()
+		defer mu.Unlock()
+		return handled
+	}
+}
+
+func TestRequeue_FrontPreservesOrder(t *testing.T) {
+	b, handled := newRequeueTestBatcher(t, Config{InitialBatchSize: 3, RequeuePolicy: RequeueFront},
+		func(call int) error {
+			if call == 1 {
+				return errTransient
+			}
+			return nil
+		})
+	defer b.Close(context.Background())
+
+	ctx := context.Background()
+	for i := 1; i <= 3; i++ {
+		err := b.Add(ctx, i)
+		if i == 3 && !errors.Is(err, ErrRetryable) {
+			t.Errorf("Expected the retryable error from the flushing Add, got %v", err)
+		}
+	}
+	if got := b.PeekPending(10); fmt.Sprint(got) != "[1 2 3]" {
+		t.Errorf("Expected the failed batch back in the buffer, got %v", got)
+	}
+
+	b.Add(ctx, 4)
+	if got := handled(); len(got) != 1 || fmt.Sprint(got[0]) != "[1 2 3 4]" {
+		t.Errorf("Expected the requeued items first in the next batch, got %v", got)
+	}
+	if stats := b.GetStats(); stats.RequeuedItems != 3 || stats.PendingItems != 0 {
+		t.Errorf("Expected 3 requeued and no pending items, got %d and %d", stats.RequeuedItems, stats.PendingItems)
+	}
+}
+
+func TestRequeue_GivesUpAfterMaxRequeues(t *testing.T) {
+	var dropped []any
+	var reasons []DropReason
+	b, _ := newRequeueTestBatcher(t, Config{
+		InitialBatchSize: 1,
+		RequeuePolicy:    RequeueFront,
+		MaxRequeues:      2,
+		OnItemDropped: func(item any, reason DropReason) {
+			dropped = append(dropped, item)
+			reasons = append(reasons, reason)
+		},
+	}, func(int) error { return errTransient })
+	defer b.Close(context.Background())
+
+	ctx := context.Background()
+	b.Add(ctx, "a")
+	b.Flush(ctx)
+	if len(dropped) != 0 || b.GetStats().PendingItems != 1 {
+		t.Fatalf("Expected the item requeued twice, got dropped %v", dropped)
+	}
+	b.Flush(ctx)
+	if len(dropped) != 1 || dropped[0] != "a" || reasons[0] != DropReasonRequeue {
+		t.Errorf("Expected the item dropped for requeue after 3 failures, got %v %v", dropped, reasons)
+	}
+	if stats := b.GetStats(); stats.RequeuedItems != 2 || stats.PendingItems != 0 {
+		t.Errorf("Expected 2 requeues and an empty buffer, got %d and %d", stats.RequeuedItems, stats.PendingItems)
+	}
+}
+
+func TestRequeue_OnlyRetryableErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy RequeuePolicy
+		err    error
+	}{
+		{"permanent error", RequeueFront, errors.New("invalid row")},
+		{"policy none", RequeueNone, errTransient},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, _ := newRequeueTestBatcher(t, Config{InitialBatchSize: 2, RequeuePolicy: tt.policy},
+				func(int) error { return tt.err })
+			defer b.Close(context.Background())
+
+			b.Add(context.Background(), 1)
+			b.Add(context.Background(), 2)
+			if stats := b.GetStats(); stats.RequeuedItems != 0 || stats.PendingItems != 0 {
+				t.Errorf("Expected nothing requeued, got %d requeued and %d pending", stats.RequeuedItems, stats.PendingItems)
+			}
+		})
+	}
+}
+
+func TestRequeue_DeadLettersOnClose(t *testing.T) {
+	var dlq []any
+	b, _ := newRequeueTestBatcher(t, Config{
+		InitialBatchSize: 10,
+		RequeuePolicy:    RequeueFront,
+		DeadLetterFunc: func(ctx context.Context, items []any, err error) {
+			if !errors.Is(err, ErrRetryable) {
+				t.Errorf("Expected the handler error in dead letter, got %v", err)
+			}
+			dlq = append(dlq, items...)
+		},
+	}, func(int) error { return errTransient })
+
+	b.Add(context.Background(), 1)
+	b.Add(context.Background(), 2)
+	b.Close(context.Background())
+	if fmt.Sprint(dlq) != "[1 2]" {
+		t.Errorf("Expected the items dead-lettered on close, got %v", dlq)
+	}
+}
+
+func TestRequeue_GivesUpAfterMaxRequeueElapsed(t *testing.T) {
+	var dlErr error
+	b, _ := newRequeueTestBatcher(t, Config{
+		InitialBatchSize:  10,
+		RequeuePolicy:     RequeueFront,
+		MaxRequeues:       100,
+		MaxRequeueElapsed: 30 * time.Millisecond,
+		DeadLetterFunc: func(ctx context.Context, items []any, err error) {
+			dlErr = err
+		},
+	}, func(int) error { return errTransient })
+	defer b.Close(context.Background())
+
+	ctx := context.Background()
+	b.Add(ctx, "a")
+	b.Flush(ctx)
+	b.Flush(ctx)
+	if dlErr != nil || b.GetStats().PendingItems != 1 {
+		t.Fatalf("Expected the item requeued within the budget, got %v", dlErr)
+	}
+
+	time.Sleep(40 * time.Millisecond)
+	b.Flush(ctx)
+	var re *RequeueError
+	if !errors.As(dlErr, &re) {
+		t.Fatalf("Expected a RequeueError in dead letter, got %v", dlErr)
+	}
+	if re.Attempts != 3 || re.Elapsed < 30*time.Millisecond || !errors.Is(dlErr, ErrRetryable) {
+		t.Errorf("Expected 3 attempts over at least 30ms wrapping the handler error, got %d in %v: %v",
+			re.Attempts, re.Elapsed, re.Err)
+	}
+	if stats := b.GetStats(); stats.RequeuedItems != 2 || stats.PendingItems != 0 {
+		t.Errorf("Expected 2 requeues and an empty buffer, got %d and %d", stats.RequeuedItems, stats.PendingItems)
+	}
+}
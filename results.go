@@ -0,0 +1,69 @@
+package batcher
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ItemResult is the outcome of one item of a batch, for handlers whose
+// backend answers per item, such as a bulk API
+type ItemResult struct {
+	Value any
+	Err   error
+}
+
+// ErrResultMismatch is reported to every item of a batch whose handler
+// returned a different number of results than the batch has items
+var ErrResultMismatch = errors.New("batcher: result count does not match batch")
+
+// DemuxResults hands results[i] to deliver for item i of a batch of n
+// items, results being in batch order. If there are not exactly n
+// results, none can be matched to its item: every item gets an error
+// wrapping ErrResultMismatch, which is also returned.
+func DemuxResults(n int, results []ItemResult, deliver func(i int, r ItemResult)) error {
+	if len(results) != n {
+		err := fmt.Errorf("%w: %d results for %d items", ErrResultMismatch, len(results), n)
+		for i := 0; i < n; i++ {
+			deliver(i, ItemResult{Err: err})
+		}
+		return err
+	}
+	for i, r := range results {
+		deliver(i, r)
+	}
+	return nil
+}
+
+// DeliverResults sends each item its result on the reply channel it was
+// added with. The channel, a chan ItemResult, is put in the Add context
+// under key, which must be one of Config.ContextKeys, and should be
+// buffered: items without a channel, or whose channel is full, are
+// skipped rather than block the handler. Mismatched results are handled
+// as by DemuxResults. Items the batcher drops instead of handling, such as
+// shed or quarantined ones, are sent the error they were dead-lettered
+// with, and the items of a failed batch the error of the batch, so a
+// caller waiting on the channel is never left hanging.
+func DeliverResults(meta BatchMeta, key any, results []ItemResult) error {
+	return DemuxResults(len(meta.ItemValues), results, func(i int, r ItemResult) {
+		reply, _ := meta.Value(i, key).(chan ItemResult)
+		select {
+		case reply <- r:
+		default:
+		}
+	})
+}
+
+// --- Internal methods ---
+
+// reply sends r to the reply channel the item was added with, if any,
+// without blocking
+func (p pendingItem) reply(r ItemResult) {
+	for _, v := range p.values {
+		if reply, ok := v.(chan ItemResult); ok {
+			select {
+			case reply <- r:
+			default:
+			}
+		}
+	}
+}
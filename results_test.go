@@ -0,0 +1,136 @@
+package batcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestDemuxResults(t *testing.T) {
+	results := []ItemResult{{Value: "a"}, {Err: errors.New("rejected")}, {Value: "c"}}
+
+	got := make([]ItemResult, 3)
+	if err := DemuxResults(3, results, func(i int, r ItemResult) { got[i] = r }); err != nil {
+		t.Fatalf("DemuxResults() failed: %v", err)
+	}
+	if fmt.Sprint(got) != fmt.Sprint(results) {
+		t.Errorf("Expected results %v, got %v", results, got)
+	}
+
+	// A mismatch fails every item
+	got = make([]ItemResult, 4)
+	err := DemuxResults(4, results, func(i int, r ItemResult) { got[i] = r })
+	if !errors.Is(err, ErrResultMismatch) {
+		t.Errorf("Expected ErrResultMismatch, got %v", err)
+	}
+	for i, r := range got {
+		if !errors.Is(r.Err, ErrResultMismatch) {
+			t.Errorf("Expected item %d to get ErrResultMismatch, got %v", i, r)
+		}
+	}
+}
+
+// replyKey is the context key of the reply channel in the tests
+type replyKey struct{}
+
+func TestDeliverResults(t *testing.T) {
+	b, err := New(Config{
+		InitialBatchSize: 3,
+		ContextKeys:      []any{replyKey{}},
+		HandlerFuncV2: func(ctx context.Context, batch []any, meta BatchMeta) (*LoadFeedback, error) {
+			results := make([]ItemResult, len(batch))
+			for i, item := range batch {
+				results[i] = ItemResult{Value: item.(int) * 10}
+			}
+			return nil, DeliverResults(meta, replyKey{}, results)
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	replies := make([]chan ItemResult, 3)
+	for i := range replies {
+		replies[i] = make(chan ItemResult, 1)
+		ctx := context.WithValue(context.Background(), replyKey{}, replies[i])
+		if err := b.Add(ctx, i); err != nil {
+			t.Fatalf("Add() failed: %v", err)
+		}
+	}
+
+	for i, reply := range replies {
+		select {
+		case r := <-reply:
+			if r.Value != i*10 || r.Err != nil {
+				t.Errorf("Expected result %d for item %d, got %v", i*10, i, r)
+			}
+		default:
+			t.Errorf("Expected a result for item %d", i)
+		}
+	}
+}
+
+func TestDeliverResults_Dropped(t *testing.T) {
+	b, err := New(Config{
+		InitialBatchSize: 2,
+		ContextKeys:      []any{replyKey{}},
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			panic("boom")
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	replies := make([]chan ItemResult, 2)
+	for i := range replies {
+		replies[i] = make(chan ItemResult, 1)
+		b.Add(context.WithValue(context.Background(), replyKey{}, replies[i]), i)
+	}
+
+	for i, reply := range replies {
+		select {
+		case r := <-reply:
+			if !errors.Is(r.Err, ErrHandlerPanic) {
+				t.Errorf("Expected ErrHandlerPanic for item %d, got %v", i, r)
+			}
+		default:
+			t.Errorf("Expected dropped item %d to get a result", i)
+		}
+	}
+}
+
+func TestDeliverResults_Failed(t *testing.T) {
+	boom := errors.New("boom")
+	b, err := New(Config{
+		InitialBatchSize: 2,
+		ContextKeys:      []any{replyKey{}},
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			return nil, boom
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	replies := make([]chan ItemResult, 2)
+	for i := range replies {
+		replies[i] = make(chan ItemResult, 1)
+		b.Add(context.WithValue(context.Background(), replyKey{}, replies[i]), i)
+	}
+
+	for i, reply := range replies {
+		select {
+		case r := <-reply:
+			if !errors.Is(r.Err, boom) {
+				t.Errorf("Expected the batch error for item %d, got %v", i, r)
+			}
+		default:
+			t.Errorf("Expected item %d of the failed batch to get a result", i)
+		}
+	}
+}
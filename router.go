@@ -0,0 +1,320 @@
+package batcher
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// KeyFunc extracts the routing key from an item
+type KeyFunc func(item any) string
+
+// RouterConfig holds the configuration for a sharded Router
+type RouterConfig struct {
+	// Shards is the initial set of downstream shard names
+	Shards []string
+
+	// KeyFunc extracts the key used to pick a shard for an item
+	KeyFunc KeyFunc
+
+	// NewBatcher builds the Batcher that serves the given shard.
+	// Each shard gets its own Batcher so batch sizes adapt to the
+	// load of that shard independently.
+	NewBatcher func(shard string) (*Batcher, error)
+
+	// LimitsFor optionally returns the batch size bounds of a shard,
+	// overriding those NewBatcher configured, for shards whose backends
+	// differ in capacity. A zero min or max keeps the configured bound.
+	// Each shard's current size and bounds are reported in
+	// RouterStats.Shards.
+	LimitsFor func(shard string) (min, max int)
+
+	// VirtualNodes is the number of points each shard occupies on the
+	// hash ring (default: 100). More points give a more even spread.
+	VirtualNodes int
+}
+
+var (
+	// ErrNoShards is returned when an item is routed while no shard exists
+	ErrNoShards = errors.New("batcher: no shards available")
+
+	// ErrShardExists is returned when adding a shard that is already present
+	ErrShardExists = errors.New("batcher: shard already exists")
+
+	// ErrUnknownShard is returned when removing a shard that does not exist
+	ErrUnknownShard = errors.New("batcher: unknown shard")
+)
+
+// Router owns one Batcher per downstream shard and routes items to them
+// using consistent hashing on the item key. Adding or removing a shard
+// only moves the keys that hash to that shard.
+type Router struct {
+	mu     sync.RWMutex
+	cfg    RouterConfig
+	ring   []ringPoint
+	shards map[string]*Batcher
+	closed bool
+}
+
+type ringPoint struct {
+	hash  uint32
+	shard string
+}
+
+// NewRouter creates a Router with the given configuration
+func NewRouter(cfg RouterConfig) (*Router, error) {
+	if cfg.KeyFunc == nil || cfg.NewBatcher == nil {
+		return nil, ErrInvalidConfig
+	}
+	if cfg.VirtualNodes <= 0 {
+		cfg.VirtualNodes = 100
+	}
+
+	r := &Router{
+		cfg:    cfg,
+		shards: make(map[string]*Batcher),
+	}
+
+	for _, name := range cfg.Shards {
+		if err := r.addShardLocked(name); err != nil {
+			_ = r.Close(context.Background())
+			return nil, err
+		}
+	}
+	r.rebuildRingLocked()
+
+	return r, nil
+}
+
+// Add routes the item to the shard owning its key
+func (r *Router) Add(ctx context.Context, item any) error {
+	key := r.cfg.KeyFunc(item)
+
+	for {
+		r.mu.RLock()
+		if r.closed {
+			r.mu.RUnlock()
+			return ErrClosed
+		}
+		b, name := r.lookupLocked(key)
+		r.mu.RUnlock()
+
+		if b == nil {
+			return ErrNoShards
+		}
+
+		err := b.Add(ctx, item)
+		if !errors.Is(err, ErrClosed) {
+			return err
+		}
+
+		// The shard was removed while we were adding; route again
+		r.mu.RLock()
+		stillOwned := r.shards[name] == b
+		closed := r.closed
+		r.mu.RUnlock()
+		if stillOwned || closed {
+			return err
+		}
+	}
+}
+
+// ShardFor returns the name of the shard that currently owns key
+func (r *Router) ShardFor(key string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, name := r.lookupLocked(key)
+	return name, name != ""
+}
+
+// AddShard creates a Batcher for a new shard and adds it to the ring
+func (r *Router) AddShard(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return ErrClosed
+	}
+	if err := r.addShardLocked(name); err != nil {
+		return err
+	}
+	r.rebuildRingLocked()
+	return nil
+}
+
+// RemoveShard removes a shard from the ring and closes its Batcher,
+// flushing any items still buffered for it
+func (r *Router) RemoveShard(ctx context.Context, name string) error {
+	r.mu.Lock()
+	b, ok := r.shards[name]
+	if !ok {
+		r.mu.Unlock()
+		return ErrUnknownShard
+	}
+	delete(r.shards, name)
+	r.rebuildRingLocked()
+	r.mu.Unlock()
+
+	return b.Close(ctx)
+}
+
+// Shards returns the names of all shards, sorted
+func (r *Router) Shards() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.shards))
+	for name := range r.shards {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Flush flushes every shard and returns the joined errors
+func (r *Router) Flush(ctx context.Context) error {
+	var errs []error
+	for _, b := range r.snapshot() {
+		if err := b.Flush(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes every shard and returns the joined errors
+func (r *Router) Close(ctx context.Context) error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	r.mu.Unlock()
+
+	var errs []error
+	for _, b := range r.snapshot() {
+		if err := b.Close(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// GetStats returns per-shard statistics and their aggregate
+func (r *Router) GetStats() RouterStats {
+	r.mu.RLock()
+	shards := make(map[string]*Batcher, len(r.shards))
+	for name, b := range r.shards {
+		shards[name] = b
+	}
+	r.mu.RUnlock()
+
+	stats := RouterStats{Shards: make(map[string]Stats, len(shards))}
+	loadSamples := 0
+	for name, b := range shards {
+		s := b.GetStats()
+		stats.Shards[name] = s
+		stats.TotalBatchSize += s.CurrentBatchSize
+		stats.PendingItems += s.PendingItems
+		if s.RecentFeedbackSize > 0 {
+			stats.AverageLoadScore += s.AverageLoadScore
+			loadSamples++
+		}
+	}
+	if loadSamples > 0 {
+		stats.AverageLoadScore /= float64(loadSamples)
+	}
+
+	return stats
+}
+
+// RouterStats holds aggregate statistics across all shards
+type RouterStats struct {
+	// Shards holds the statistics of each shard keyed by name
+	Shards map[string]Stats
+
+	// TotalBatchSize is the sum of the current batch sizes
+	TotalBatchSize int
+
+	// PendingItems is the number of items buffered across all shards
+	PendingItems int
+
+	// AverageLoadScore is the mean load score of shards with feedback
+	AverageLoadScore float64
+}
+
+// --- Internal methods ---
+
+func (r *Router) addShardLocked(name string) error {
+	if _, ok := r.shards[name]; ok {
+		return ErrShardExists
+	}
+	b, err := r.cfg.NewBatcher(name)
+	if err != nil {
+		return err
+	}
+	if b == nil {
+		return ErrInvalidConfig
+	}
+	if r.cfg.LimitsFor != nil {
+		if err := b.SetLimits(r.cfg.LimitsFor(name)); err != nil {
+			_ = b.Close(context.Background())
+			return err
+		}
+	}
+	r.shards[name] = b
+	return nil
+}
+
+func (r *Router) rebuildRingLocked() {
+	ring := make([]ringPoint, 0, len(r.shards)*r.cfg.VirtualNodes)
+	for name := range r.shards {
+		for i := 0; i < r.cfg.VirtualNodes; i++ {
+			ring = append(ring, ringPoint{
+				hash:  hashKey(name + "#" + strconv.Itoa(i)),
+				shard: name,
+			})
+		}
+	}
+	sort.Slice(ring, func(i, j int) bool {
+		if ring[i].hash == ring[j].hash {
+			return ring[i].shard < ring[j].shard
+		}
+		return ring[i].hash < ring[j].hash
+	})
+	r.ring = ring
+}
+
+func (r *Router) lookupLocked(key string) (*Batcher, string) {
+	if len(r.ring) == 0 {
+		return nil, ""
+	}
+	h := hashKey(key)
+	idx := sort.Search(len(r.ring), func(i int) bool { return r.ring[i].hash >= h })
+	if idx == len(r.ring) {
+		idx = 0
+	}
+	name := r.ring[idx].shard
+	return r.shards[name], name
+}
+
+func (r *Router) snapshot() []*Batcher {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	batchers := make([]*Batcher, 0, len(r.shards))
+	for _, b := range r.shards {
+		batchers = append(batchers, b)
+	}
+	return batchers
+}
+
+func hashKey(key string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return h.Sum32()
+}
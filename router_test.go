@@ -0,0 +1,193 @@
+package batcher
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func newTestRouter(t *testing.T, shards ...string) (*Router, map[string]*[]any, *sync.Mutex) {
+	t.Helper()
+
+	var mu sync.Mutex
+	received := make(map[string]*[]any)
+
+	r, err := NewRouter(RouterConfig{
+		Shards:  shards,
+		KeyFunc: func(item any) string { return fmt.Sprint(item) },
+		NewBatcher: func(shard string) (*Batcher, error) {
+			mu.Lock()
+			items := &[]any{}
+			received[shard] = items
+			mu.Unlock()
+
+			return New(Config{
+				InitialBatchSize: 5,
+				HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+					mu.Lock()
+					*items = append(*items, batch...)
+					mu.Unlock()
+					return &LoadFeedback{CPULoad: 0.3}, nil
+				},
+			})
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewRouter() failed: %v", err)
+	}
+	return r, received, &mu
+}
+
+func TestNewRouter_InvalidConfig(t *testing.T) {
+	if _, err := NewRouter(RouterConfig{}); err != ErrInvalidConfig {
+		t.Errorf("Expected ErrInvalidConfig, got %v", err)
+	}
+}
+
+func TestRouter_RoutesByKey(t *testing.T) {
+	r, received, mu := newTestRouter(t, "a", "b", "c")
+	ctx := context.Background()
+
+	for i := 0; i < 300; i++ {
+		if err := r.Add(ctx, i); err != nil {
+			t.Fatalf("Add() error: %v", err)
+		}
+	}
+	if err := r.Close(ctx); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	total := 0
+	for shard, items := range received {
+		total += len(*items)
+		for _, item := range *items {
+			owner, _ := r.ShardFor(fmt.Sprint(item))
+			if owner != shard {
+				t.Errorf("Item %v delivered to %s, want %s", item, shard, owner)
+			}
+		}
+		if len(*items) == 0 {
+			t.Errorf("Shard %s received no items", shard)
+		}
+	}
+	if total != 300 {
+		t.Errorf("Expected 300 items delivered, got %d", total)
+	}
+}
+
+func TestRouter_AddRemoveShard(t *testing.T) {
+	r, _, _ := newTestRouter(t, "a", "b")
+	defer r.Close(context.Background())
+
+	before := make(map[string]string)
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprint(i)
+		before[key], _ = r.ShardFor(key)
+	}
+
+	if err := r.AddShard("c"); err != nil {
+		t.Fatalf("AddShard() error: %v", err)
+	}
+	if err := r.AddShard("c"); err != ErrShardExists {
+		t.Errorf("Expected ErrShardExists, got %v", err)
+	}
+
+	// Only keys that moved to the new shard may change owner
+	for key, old := range before {
+		now, _ := r.ShardFor(key)
+		if now != old && now != "c" {
+			t.Errorf("Key %s moved from %s to %s", key, old, now)
+		}
+	}
+
+	if err := r.RemoveShard(context.Background(), "c"); err != nil {
+		t.Fatalf("RemoveShard() error: %v", err)
+	}
+	if err := r.RemoveShard(context.Background(), "c"); err != ErrUnknownShard {
+		t.Errorf("Expected ErrUnknownShard, got %v", err)
+	}
+
+	for key, old := range before {
+		if now, _ := r.ShardFor(key); now != old {
+			t.Errorf("Key %s owned by %s after removal, want %s", key, now, old)
+		}
+	}
+}
+
+func TestRouter_Stats(t *testing.T) {
+	r, _, _ := newTestRouter(t, "a", "b")
+	defer r.Close(context.Background())
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		r.Add(ctx, i)
+	}
+
+	stats := r.GetStats()
+	if len(stats.Shards) != 2 {
+		t.Errorf("Expected 2 shards in stats, got %d", len(stats.Shards))
+	}
+	if stats.PendingItems != 3 {
+		t.Errorf("Expected 3 pending items, got %d", stats.PendingItems)
+	}
+	if stats.TotalBatchSize != 10 {
+		t.Errorf("Expected total batch size 10, got %d", stats.TotalBatchSize)
+	}
+}
+
+func TestRouter_NoShards(t *testing.T) {
+	r, _, _ := newTestRouter(t)
+	defer r.Close(context.Background())
+
+	if err := r.Add(context.Background(), 1); err != ErrNoShards {
+		t.Errorf("Expected ErrNoShards, got %v", err)
+	}
+}
+
+func TestRouter_LimitsFor(t *testing.T) {
+	r, err := NewRouter(RouterConfig{
+		Shards:  []string{"small", "large"},
+		KeyFunc: func(item any) string { return fmt.Sprint(item) },
+		NewBatcher: func(shard string) (*Batcher, error) {
+			return New(Config{
+				InitialBatchSize: 50,
+				MinBatchSize:     10,
+				MaxBatchSize:     100,
+				HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+					return nil, nil
+				},
+			})
+		},
+		LimitsFor: func(shard string) (int, int) {
+			if shard == "small" {
+				return 1, 20
+			}
+			return 0, 500
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewRouter() failed: %v", err)
+	}
+	defer r.Close(context.Background())
+
+	stats := r.GetStats()
+	small, large := stats.Shards["small"], stats.Shards["large"]
+	if small.MinBatchSize != 1 || small.MaxBatchSize != 20 || small.CurrentBatchSize != 20 {
+		t.Errorf("Expected small shard in [1, 20] at 20, got [%d, %d] at %d",
+			small.MinBatchSize, small.MaxBatchSize, small.CurrentBatchSize)
+	}
+	if large.MinBatchSize != 10 || large.MaxBatchSize != 500 || large.CurrentBatchSize != 50 {
+		t.Errorf("Expected large shard in [10, 500] at 50, got [%d, %d] at %d",
+			large.MinBatchSize, large.MaxBatchSize, large.CurrentBatchSize)
+	}
+
+	// Invalid limits fail the shard
+	r.cfg.LimitsFor = func(string) (int, int) { return 30, 20 }
+	if err := r.AddShard("bad"); err != ErrInvalidConfig {
+		t.Errorf("Expected ErrInvalidConfig, got %v", err)
+	}
+}
@@ -0,0 +1,28 @@
+package batcher
+
+import "time"
+
+// sampleFeedback decides, without taking the batcher lock, whether the
+// feedback of a completed batch is recorded and how many feedbacks the
+// recorded sample stands for
+func (b *Batcher) sampleFeedback() (weight float64, ok bool) {
+	rate := b.cfg.FeedbackSampleRate
+	interval := b.cfg.FeedbackSampleInterval
+	if rate <= 1 && interval <= 0 {
+		return 1, true
+	}
+
+	seen := b.feedbackSeen.Add(1)
+	if rate > 1 && seen%int64(rate) != 0 {
+		return 0, false
+	}
+	if interval > 0 {
+		now := time.Now().UnixNano()
+		last := b.lastSampled.Load()
+		if now-last < int64(interval) || !b.lastSampled.CompareAndSwap(last, now) {
+			return 0, false
+		}
+	}
+
+	return float64(seen - b.sampledUpTo.Swap(seen)), true
+}
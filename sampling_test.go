@@ -0,0 +1,59 @@
+package batcher
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBatcher_FeedbackSampleRate(t *testing.T) {
+	b, err := New(Config{
+		InitialBatchSize:   1,
+		FeedbackSampleRate: 4,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			return &LoadFeedback{CPULoad: 0.5}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	for i := 0; i < 8; i++ {
+		b.Add(context.Background(), i)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.drainFeedbackLocked()
+	if len(b.recentFeedback) != 2 {
+		t.Fatalf("Expected 2 sampled feedbacks, got %d", len(b.recentFeedback))
+	}
+	for _, s := range b.recentFeedback {
+		if s.Weight != 4 {
+			t.Errorf("Expected sample weight 4, got %v", s.Weight)
+		}
+	}
+}
+
+func TestBatcher_FeedbackSampleInterval(t *testing.T) {
+	b, err := New(Config{
+		InitialBatchSize:       1,
+		FeedbackSampleInterval: time.Hour,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			return &LoadFeedback{CPULoad: 0.5}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	for i := 0; i < 5; i++ {
+		b.Add(context.Background(), i)
+	}
+
+	if n := b.GetStats().RecentFeedbackSize; n != 1 {
+		t.Errorf("Expected 1 feedback recorded within the interval, got %d", n)
+	}
+}
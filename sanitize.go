@@ -0,0 +1,62 @@
+package batcher
+
+import (
+	"math"
+	"time"
+)
+
+// maxBackendPause bounds the pause a single feedback can ask for with
+// RetryAfter or ThrottleFor, so that a broken backend cannot stall the
+// batcher, and a Close waiting on it, for hours
+const maxBackendPause = 5 * time.Minute
+
+// sanitizeFeedback repairs out-of-range values in handler feedback so that
+// broken telemetry cannot wedge the adjuster. Ratios are clamped to
+// [0, 1], negative counts and durations become zero and pauses are capped
+// at maxBackendPause. It reports whether anything was changed, and
+// whether the sample is usable at all: a NaN or infinite CPU load or error
+// rate carries no signal, so such samples are dropped rather than guessed
+// at.
+func sanitizeFeedback(fb *LoadFeedback) (changed, usable bool) {
+	if !isFinite(fb.CPULoad) || !isFinite(fb.ErrorRate) {
+		return true, false
+	}
+
+	if fb.CPULoad < 0 || fb.CPULoad > 1 {
+		fb.CPULoad = clamp01(fb.CPULoad)
+		changed = true
+	}
+	if fb.ErrorRate < 0 || fb.ErrorRate > 1 {
+		fb.ErrorRate = clamp01(fb.ErrorRate)
+		changed = true
+	}
+	if fb.QueueDepth < 0 {
+		fb.QueueDepth = 0
+		changed = true
+	}
+	if fb.DBLocks < 0 {
+		fb.DBLocks = 0
+		changed = true
+	}
+	if fb.ProcessingTime < 0 {
+		fb.ProcessingTime = 0
+		changed = true
+	}
+	if fb.RetryAfter < 0 || fb.RetryAfter > maxBackendPause {
+		fb.RetryAfter = min(max(fb.RetryAfter, 0), maxBackendPause)
+		changed = true
+	}
+	if fb.ThrottleFor < 0 || fb.ThrottleFor > maxBackendPause {
+		fb.ThrottleFor = min(max(fb.ThrottleFor, 0), maxBackendPause)
+		changed = true
+	}
+	if fb.SuggestedBatchSize < 0 {
+		fb.SuggestedBatchSize = 0
+		changed = true
+	}
+	return changed, true
+}
+
+func isFinite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
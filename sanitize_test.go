@@ -0,0 +1,118 @@
+package batcher
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSanitizeFeedback(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      LoadFeedback
+		want    LoadFeedback
+		changed bool
+		usable  bool
+	}{
+		{
+			name:   "valid",
+			in:     LoadFeedback{CPULoad: 0.5, ErrorRate: 0.1, QueueDepth: 3},
+			want:   LoadFeedback{CPULoad: 0.5, ErrorRate: 0.1, QueueDepth: 3},
+			usable: true,
+		},
+		{
+			name:    "out of range",
+			in:      LoadFeedback{CPULoad: 1.7, ErrorRate: -0.2, QueueDepth: -4, DBLocks: -1, ProcessingTime: -time.Second},
+			want:    LoadFeedback{CPULoad: 1},
+			changed: true,
+			usable:  true,
+		},
+		{
+			name:    "NaN CPU",
+			in:      LoadFeedback{CPULoad: math.NaN()},
+			changed: true,
+		},
+		{
+			name:    "infinite error rate",
+			in:      LoadFeedback{ErrorRate: math.Inf(1)},
+			changed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fb := tt.in
+			changed, usable := sanitizeFeedback(&fb)
+			if changed != tt.changed || usable != tt.usable {
+				t.Errorf("sanitizeFeedback() = (%v, %v), want (%v, %v)", changed, usable, tt.changed, tt.usable)
+			}
+			if usable && (fb.CPULoad != tt.want.CPULoad || fb.ErrorRate != tt.want.ErrorRate ||
+				fb.QueueDepth != tt.want.QueueDepth || fb.DBLocks != tt.want.DBLocks ||
+				fb.ProcessingTime != tt.want.ProcessingTime) {
+				t.Errorf("Expected %+v, got %+v", tt.want, fb)
+			}
+		})
+	}
+}
+
+func TestBatcher_SanitizesFeedback(t *testing.T) {
+	feedbacks := []*LoadFeedback{
+		{CPULoad: math.NaN()},
+		{CPULoad: 3},
+		{CPULoad: 0.5},
+	}
+	i := 0
+	b, err := New(Config{
+		InitialBatchSize: 1,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			fb := feedbacks[i]
+			i++
+			return fb, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	for j := range feedbacks {
+		b.Add(context.Background(), j)
+	}
+
+	stats := b.GetStats()
+	if stats.SanitizedFeedback != 2 {
+		t.Errorf("Expected 2 sanitized samples, got %d", stats.SanitizedFeedback)
+	}
+	if stats.RecentFeedbackSize != 2 {
+		t.Errorf("Expected the NaN sample to be dropped, got %d samples", stats.RecentFeedbackSize)
+	}
+	if math.IsNaN(stats.AverageLoadScore) {
+		t.Error("Expected a finite average load score")
+	}
+	if feedbacks[1].CPULoad != 3 {
+		t.Error("Expected the handler's feedback to be left untouched")
+	}
+}
+
+func TestSanitizeFeedback_NegativeSuggestion(t *testing.T) {
+	fb := LoadFeedback{SuggestedBatchSize: -5}
+	if changed, _ := sanitizeFeedback(&fb); !changed || fb.SuggestedBatchSize != 0 {
+		t.Errorf("Expected a negative suggestion to be dropped, got %d", fb.SuggestedBatchSize)
+	}
+}
+
+func TestSanitizeFeedback_CapsPauses(t *testing.T) {
+	fb := LoadFeedback{RetryAfter: 3 * time.Hour, ThrottleFor: 24 * time.Hour}
+	if changed, _ := sanitizeFeedback(&fb); !changed {
+		t.Error("Expected absurd pauses to count as sanitized")
+	}
+	if fb.RetryAfter != maxBackendPause || fb.ThrottleFor != maxBackendPause {
+		t.Errorf("Expected pauses capped at %v, got %v and %v", maxBackendPause, fb.RetryAfter, fb.ThrottleFor)
+	}
+
+	fb = LoadFeedback{RetryAfter: time.Minute}
+	if changed, _ := sanitizeFeedback(&fb); changed || fb.RetryAfter != time.Minute {
+		t.Errorf("Expected a reasonable pause kept, got %v", fb.RetryAfter)
+	}
+}
@@ -0,0 +1,109 @@
+package batcher
+
+import (
+	"math"
+	"time"
+)
+
+// CustomMetric makes an entry of LoadFeedback.Custom contribute to the
+// load score, e.g. {Name: "replication_lag_ms", Weight: 0.3, Critical: 5000}
+type CustomMetric struct {
+	// Name is the key of the metric in LoadFeedback.Custom
+	Name string
+
+	// Weight is the share of the load score given to this metric.
+	// The built-in score keeps the remaining share; all weights together
+	// must not exceed 1.0.
+	Weight float64
+
+	// Baseline is the value that counts as no load (normalized to 0.0)
+	Baseline float64
+
+	// Critical is the value that counts as full load (normalized to 1.0)
+	Critical float64
+
+	// Extract optionally reads the value from the custom metrics, for
+	// values that are not plain numbers. It reports false if absent.
+	Extract func(custom map[string]interface{}) (float64, bool)
+}
+
+// normalize maps the metric value onto [0, 1] between Baseline and Critical
+func (m CustomMetric) normalize(v float64) float64 {
+	span := m.Critical - m.Baseline
+	if span == 0 {
+		if v >= m.Critical {
+			return 1
+		}
+		return 0
+	}
+	return clamp01((v - m.Baseline) / span)
+}
+
+// value extracts the metric value from the feedback
+func (m CustomMetric) value(custom map[string]interface{}) (float64, bool) {
+	if m.Extract != nil {
+		return m.Extract(custom)
+	}
+	return toFloat(custom[m.Name])
+}
+
+// ScoreWith calculates the load score blending in the given custom metrics.
+// Each metric takes its Weight share of the score; the built-in LoadScore
+// takes the rest. Metrics missing from Custom count as no load.
+func (lf *LoadFeedback) ScoreWith(metrics []CustomMetric) float64 {
+	if len(metrics) == 0 {
+		return lf.LoadScore()
+	}
+
+	totalWeight := 0.0
+	custom := 0.0
+	for _, m := range metrics {
+		totalWeight += m.Weight
+		if v, ok := m.value(lf.Custom); ok {
+			custom += m.Weight * m.normalize(v)
+		}
+	}
+	totalWeight = math.Min(totalWeight, 1)
+
+	return math.Min((1-totalWeight)*lf.LoadScore()+custom, 1.0)
+}
+
+// score returns the load score of feedback under this batcher's
+// configuration
+func (b *Batcher) score(lf *LoadFeedback) float64 {
+	return scoreFeedback(lf, b.cfg.CustomMetrics)
+}
+
+// scoreFeedback returns the load score of feedback blending in metrics; a
+// throttled batch counts as full overload, whatever the other metrics say
+func scoreFeedback(lf *LoadFeedback, metrics []CustomMetric) float64 {
+	if lf.ThrottleFor > 0 {
+		return 1
+	}
+	return lf.ScoreWith(metrics)
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case time.Duration:
+		return float64(n.Milliseconds()), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,88 @@
+package batcher
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestLoadFeedback_ScoreWith(t *testing.T) {
+	lag := CustomMetric{Name: "replication_lag_ms", Weight: 0.5, Critical: 5000}
+
+	tests := []struct {
+		name   string
+		custom map[string]interface{}
+		want   float64
+	}{
+		{"missing metric", nil, 0.3},
+		{"half critical", map[string]interface{}{"replication_lag_ms": 2500}, 0.55},
+		{"beyond critical", map[string]interface{}{"replication_lag_ms": 9000.0}, 0.8},
+		{"duration value", map[string]interface{}{"replication_lag_ms": 5 * time.Second}, 0.8},
+		{"non-numeric", map[string]interface{}{"replication_lag_ms": "high"}, 0.3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lf := LoadFeedback{CPULoad: 1.0, Custom: tt.custom}
+			if got := lf.ScoreWith([]CustomMetric{lag}); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("ScoreWith() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadFeedback_ScoreWithExtract(t *testing.T) {
+	m := CustomMetric{
+		Weight:   1,
+		Baseline: 10,
+		Critical: 20,
+		Extract: func(custom map[string]interface{}) (float64, bool) {
+			v, ok := custom["pool"].([]int)
+			if !ok {
+				return 0, false
+			}
+			return float64(len(v)), true
+		},
+	}
+
+	lf := LoadFeedback{CPULoad: 1, Custom: map[string]interface{}{"pool": make([]int, 15)}}
+	if got := lf.ScoreWith([]CustomMetric{m}); got != 0.5 {
+		t.Errorf("ScoreWith() = %v, want 0.5", got)
+	}
+}
+
+func TestNew_InvalidCustomMetrics(t *testing.T) {
+	_, err := New(Config{
+		InitialBatchSize: 10,
+		CustomMetrics: []CustomMetric{
+			{Name: "a", Weight: 0.6, Critical: 1},
+			{Name: "b", Weight: 0.6, Critical: 1},
+		},
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) { return nil, nil },
+	})
+	if !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig for weights above 1.0, got %v", err)
+	}
+}
+
+func TestBatcher_CustomMetricsInStats(t *testing.T) {
+	b, err := New(Config{
+		InitialBatchSize: 1,
+		CustomMetrics:    []CustomMetric{{Name: "lag", Weight: 1, Critical: 100}},
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			return &LoadFeedback{Custom: map[string]interface{}{"lag": 100}}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	b.Add(context.Background(), 1)
+
+	if score := b.GetStats().AverageLoadScore; score != 1.0 {
+		t.Errorf("Expected custom metric to drive load score to 1.0, got %v", score)
+	}
+}
@@ -0,0 +1,83 @@
+package batcher
+
+import "time"
+
+// ShadowDecision is one decision of Config.ShadowStrategy, next to the one
+// the live strategy made in the same adjustment cycle
+type ShadowDecision struct {
+	// Live is the batch size the live strategy decided on, and Shadow the
+	// one the shadow strategy would have, from its own previous decision
+	Live   int
+	Shadow int
+
+	// LoadScore is the load score both decisions were based on
+	LoadScore float64
+
+	// At is when the decisions were made
+	At time.Time
+}
+
+// ShadowStats holds the evaluation of a shadow strategy
+type ShadowStats struct {
+	// BatchSize is the latest hypothetical batch size of the shadow
+	// strategy
+	BatchSize int
+
+	// Decisions is the number of adjustment cycles it decided in
+	Decisions int64
+
+	// MeanDivergence is the mean absolute difference between its batch
+	// sizes and the live ones, over its decisions
+	MeanDivergence float64
+
+	// Estimates holds its internal estimates, if it implements
+	// EstimateReporter
+	Estimates map[string]float64
+}
+
+// --- Internal methods ---
+
+// shadowLocked runs the shadow strategy on the input of the live one, from
+// its own hypothetical batch size, and records its decision next to live
+func (b *Batcher) shadowLocked(in AdjustmentInput, live int, now time.Time) {
+	in.CurrentBatchSize = b.shadowSize
+	size := b.decideLocked(b.cfg.ShadowStrategy, in)
+	b.shadowSize = size
+
+	b.shadowBatchSize.Store(int64(size))
+	b.shadowDecisions.Add(1)
+	b.shadowDivergence.Add(int64(max(size-live, live-size)))
+	if b.cfg.OnShadowDecision != nil {
+		b.shadowDecision = &ShadowDecision{Live: live, Shadow: size, LoadScore: in.LoadScore, At: now}
+	}
+}
+
+// notifyShadow hands the latest shadow decision, if any, to
+// OnShadowDecision
+func (b *Batcher) notifyShadow() {
+	if b.cfg.OnShadowDecision == nil {
+		return
+	}
+	b.mu.Lock()
+	d := b.shadowDecision
+	b.shadowDecision = nil
+	b.mu.Unlock()
+
+	if d != nil {
+		b.cfg.OnShadowDecision(*d)
+	}
+}
+
+func (b *Batcher) shadowStats() *ShadowStats {
+	stats := &ShadowStats{
+		BatchSize: int(b.shadowBatchSize.Load()),
+		Decisions: b.shadowDecisions.Load(),
+	}
+	if r, ok := b.cfg.ShadowStrategy.(EstimateReporter); ok {
+		stats.Estimates = r.Estimates()
+	}
+	if stats.Decisions > 0 {
+		stats.MeanDivergence = float64(b.shadowDivergence.Load()) / float64(stats.Decisions)
+	}
+	return stats
+}
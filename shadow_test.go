@@ -0,0 +1,106 @@
+package batcher
+
+import (
+	"context"
+	"testing"
+)
+
+func TestBatcher_ShadowStrategy(t *testing.T) {
+	var decisions []ShadowDecision
+	b, err := New(Config{
+		InitialBatchSize:   20,
+		MaxBatchSize:       100,
+		AdjustmentStrategy: &fixedStrategy{size: 30},
+		ShadowStrategy:     &fixedStrategy{size: 50},
+		OnShadowDecision: func(d ShadowDecision) {
+			decisions = append(decisions, d)
+		},
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			return &LoadFeedback{}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	if s := b.GetStats().Shadow; s == nil || s.BatchSize != 20 || s.Decisions != 0 {
+		t.Fatalf("Expected the shadow to start at the initial size, got %+v", s)
+	}
+
+	for i := 0; i < 2; i++ {
+		b.mu.Lock()
+		b.recordFeedback(LoadFeedback{CPULoad: 0.5}, 20, 1)
+		b.mu.Unlock()
+		b.AdjustNow()
+	}
+
+	// The shadow decides without controlling anything
+	if size := b.GetCurrentBatchSize(); size != 30 {
+		t.Errorf("Expected the live strategy to set size 30, got %d", size)
+	}
+	s := b.GetStats().Shadow
+	if s.BatchSize != 50 || s.Decisions != 2 || s.MeanDivergence != 20 {
+		t.Errorf("Expected shadow size 50 after 2 decisions diverging by 20, got %+v", s)
+	}
+
+	if len(decisions) != 2 {
+		t.Fatalf("Expected 2 shadow decisions, got %d", len(decisions))
+	}
+	if d := decisions[1]; d.Live != 30 || d.Shadow != 50 || d.At.IsZero() {
+		t.Errorf("Expected live 30 and shadow 50, got %+v", d)
+	}
+}
+
+func TestBatcher_ShadowFollowsItsOwnSize(t *testing.T) {
+	b, err := New(Config{
+		InitialBatchSize:   20,
+		MaxBatchSize:       100,
+		AdjustmentStrategy: &fixedStrategy{size: 20},
+		ShadowStrategy:     &ThresholdStrategy{},
+		AdjustmentFactor:   0.5,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			return &LoadFeedback{}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	// An idle backend makes the threshold strategy grow from its own
+	// previous decision every cycle, while the live size stays put
+	prev := 20
+	for i := 0; i < 3; i++ {
+		b.mu.Lock()
+		b.recordFeedback(LoadFeedback{}, 20, 1)
+		b.mu.Unlock()
+		b.AdjustNow()
+
+		size := b.GetStats().Shadow.BatchSize
+		if size <= prev {
+			t.Errorf("Expected the shadow to grow past %d in cycle %d, got %d", prev, i, size)
+		}
+		prev = size
+	}
+	if size := b.GetCurrentBatchSize(); size != 20 {
+		t.Errorf("Expected the live size to stay 20, got %d", size)
+	}
+}
+
+func TestBatcher_NoShadowStats(t *testing.T) {
+	b, err := New(Config{
+		InitialBatchSize: 20,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			return &LoadFeedback{}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	if s := b.GetStats().Shadow; s != nil {
+		t.Errorf("Expected no shadow statistics without a shadow strategy, got %+v", s)
+	}
+}
@@ -0,0 +1,174 @@
+package batcher
+
+import (
+	"context"
+	"errors"
+)
+
+// DropPolicy selects which item is shed when the batcher is overloaded
+type DropPolicy int
+
+const (
+	// DropNone disables load shedding
+	DropNone DropPolicy = iota
+
+	// DropNewest rejects the incoming item
+	DropNewest
+
+	// DropOldest evicts the oldest buffered item
+	DropOldest
+
+	// DropLowestPriority evicts the buffered or incoming item with the
+	// lowest priority according to Config.PriorityFunc
+	DropLowestPriority
+)
+
+// String returns the string representation of DropPolicy
+func (p DropPolicy) String() string {
+	switch p {
+	case DropNone:
+		return "none"
+	case DropNewest:
+		return "newest"
+	case DropOldest:
+		return "oldest"
+	case DropLowestPriority:
+		return "lowest-priority"
+	default:
+		return "unknown"
+	}
+}
+
+// ErrDropped is returned by Add when the incoming item was shed
+var ErrDropped = errors.New("batcher: item dropped due to overload")
+
+// DeadLetterFunc receives items the batcher gave up on, with the reason
+type DeadLetterFunc func(ctx context.Context, items []any, err error)
+
+// DropReason tells why the batcher gave up on an item
+type DropReason int
+
+const (
+	// DropReasonShed means the item was shed under sustained overload,
+	// either rejected by Add or evicted from the buffer
+	DropReasonShed DropReason = iota
+
+	// DropReasonPanic means the handler panicked on the item's batch
+	DropReasonPanic
+
+	// DropReasonRequeue means the item's batch failed with ErrRetryable
+	// but the item could not be requeued: it was requeued MaxRequeues
+	// times already, or the batcher was closed
+	DropReasonRequeue
+
+	// DropReasonPoison means the item failed a batch on its own after
+	// Config.BisectFailures isolated it, and was quarantined
+	DropReasonPoison
+
+	// DropReasonClose means the item was still buffered when Close gave
+	// up waiting out a pause the backend asked for
+	DropReasonClose
+
+	// DropReasonRejected means Add rejected an item a Puller pulled, e.g.
+	// as invalid or over a quota or limit, with no caller to return the
+	// error to
+	DropReasonRejected
+)
+
+// String returns the string representation of DropReason
+func (r DropReason) String() string {
+	switch r {
+	case DropReasonShed:
+		return "shed"
+	case DropReasonPanic:
+		return "panic"
+	case DropReasonRequeue:
+		return "requeue"
+	case DropReasonPoison:
+		return "poison"
+	case DropReasonClose:
+		return "close"
+	case DropReasonRejected:
+		return "rejected"
+	default:
+		return "unknown"
+	}
+}
+
+// shedLocked applies the drop policy before the incoming item is buffered.
+// It returns the evicted items and whether the incoming item itself was shed.
+func (b *Batcher) shedLocked(incoming pendingItem) (dropped []pendingItem, rejectIncoming bool) {
+	if b.cfg.DropPolicy == DropNone || b.cfg.ShedHighWatermark <= 0 || !b.adaptive.Load() {
+		return nil, false
+	}
+
+	pending := len(b.batch) + b.inFlightItems
+	if b.shedding.Load() {
+		// Hysteresis: keep shedding until we drop back to the low watermark
+		if pending <= b.cfg.ShedLowWatermark {
+			b.shedding.Store(false)
+		}
+	} else if pending >= b.cfg.ShedHighWatermark {
+		b.drainFeedbackLocked()
+		b.shedding.Store(b.aggregateLoadLocked() >= b.cfg.ShedLoadThreshold)
+	}
+	if !b.shedding.Load() {
+		return nil, false
+	}
+
+	b.shedItems.Add(1)
+
+	switch b.cfg.DropPolicy {
+	case DropOldest:
+		if len(b.batch) == 0 {
+			return []pendingItem{incoming}, true
+		}
+		oldest := b.batch[0]
+		b.batch = b.batch[1:]
+		b.releaseTenants([]pendingItem{oldest})
+		return []pendingItem{oldest}, false
+
+	case DropLowestPriority:
+		if b.cfg.PriorityFunc == nil || len(b.batch) == 0 {
+			return []pendingItem{incoming}, true
+		}
+		lowest := -1
+		lowestPrio := b.cfg.PriorityFunc(incoming.item)
+		for i, p := range b.batch {
+			if prio := b.cfg.PriorityFunc(p.item); prio < lowestPrio {
+				lowest, lowestPrio = i, prio
+			}
+		}
+		if lowest < 0 {
+			return []pendingItem{incoming}, true
+		}
+		victim := b.batch[lowest]
+		b.batch = append(b.batch[:lowest], b.batch[lowest+1:]...)
+		b.releaseTenants([]pendingItem{victim})
+		return []pendingItem{victim}, false
+
+	default:
+		return []pendingItem{incoming}, true
+	}
+}
+
+// deadLetter hands items the batcher gave up on to DeadLetterFunc and
+// OnItemDropped, and sends err to their reply channels, if any
+func (b *Batcher) deadLetter(ctx context.Context, pending []pendingItem, reason DropReason, err error) {
+	if len(pending) == 0 {
+		return
+	}
+	items := make([]any, len(pending))
+	for i, p := range pending {
+		items[i] = p.item
+		p.reply(ItemResult{Err: err})
+	}
+	if b.cfg.DeadLetterFunc != nil {
+		b.cfg.DeadLetterFunc(ctx, items, err)
+	}
+	if b.cfg.OnItemDropped != nil {
+		for _, item := range items {
+			b.cfg.OnItemDropped(item, reason)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package batcher
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+func newShedTestBatcher(t *testing.T, policy DropPolicy, dlq *[]any, mu *sync.Mutex) *Batcher {
+	t.Helper()
+
+	b, err := New(Config{
+		InitialBatchSize:  100,
+		DropPolicy:        policy,
+		ShedHighWatermark: 10,
+		ShedLowWatermark:  4,
+		ShedLoadThreshold: 0.5,
+		PriorityFunc:      func(item any) int { return item.(int) },
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			return &LoadFeedback{CPULoad: 1.0}, nil
+		},
+		DeadLetterFunc: func(ctx context.Context, items []any, err error) {
+			if err != ErrDropped {
+				t.Errorf("Expected ErrDropped in dead letter, got %v", err)
+			}
+			mu.Lock()
+			*dlq = append(*dlq, items...)
+			mu.Unlock()
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	// Simulate sustained overload: high load feedback and busy handlers
+	b.mu.Lock()
+	b.recordFeedback(LoadFeedback{CPULoad: 1.0, ErrorRate: 1.0}, 10, 1)
+	b.inFlightItems = 8
+	b.mu.Unlock()
+
+	return b
+}
+
+func TestShed_DropNewest(t *testing.T) {
+	var mu sync.Mutex
+	var dlq []any
+	b := newShedTestBatcher(t, DropNewest, &dlq, &mu)
+	defer b.Close(context.Background())
+
+	ctx := context.Background()
+	b.Add(ctx, 1)
+	b.Add(ctx, 2)
+	if err := b.Add(ctx, 3); err != ErrDropped {
+		t.Fatalf("Expected ErrDropped, got %v", err)
+	}
+
+	stats := b.GetStats()
+	if !stats.Shedding || stats.ShedItems != 1 {
+		t.Errorf("Expected shedding with 1 shed item, got %+v", stats)
+	}
+	if len(dlq) != 1 || dlq[0] != 3 {
+		t.Errorf("Expected item 3 in dead letter, got %v", dlq)
+	}
+
+	// Recovery: shedding stops once below the low watermark
+	b.mu.Lock()
+	b.inFlightItems = 0
+	b.mu.Unlock()
+
+	if err := b.Add(ctx, 4); err != nil {
+		t.Errorf("Expected Add to succeed after recovery, got %v", err)
+	}
+	if b.GetStats().Shedding {
+		t.Error("Expected shedding to stop after recovery")
+	}
+}
+
+func TestShed_DropOldest(t *testing.T) {
+	var mu sync.Mutex
+	var dlq []any
+	b := newShedTestBatcher(t, DropOldest, &dlq, &mu)
+	defer b.Close(context.Background())
+
+	ctx := context.Background()
+	b.Add(ctx, 1)
+	b.Add(ctx, 2)
+	if err := b.Add(ctx, 3); err != nil {
+		t.Fatalf("Expected incoming item to be accepted, got %v", err)
+	}
+
+	if len(dlq) != 1 || dlq[0] != 1 {
+		t.Errorf("Expected oldest item 1 in dead letter, got %v", dlq)
+	}
+	if pending := b.GetStats().PendingItems; pending != 2 {
+		t.Errorf("Expected 2 pending items, got %d", pending)
+	}
+}
+
+func TestShed_DropLowestPriority(t *testing.T) {
+	var mu sync.Mutex
+	var dlq []any
+	b := newShedTestBatcher(t, DropLowestPriority, &dlq, &mu)
+	defer b.Close(context.Background())
+
+	ctx := context.Background()
+	b.Add(ctx, 5)
+	b.Add(ctx, 1)
+	b.Add(ctx, 7)
+	if err := b.Add(ctx, 0); err != ErrDropped {
+		t.Errorf("Expected lowest-priority incoming item to be dropped, got %v", err)
+	}
+
+	if len(dlq) != 2 || dlq[0] != 1 || dlq[1] != 0 {
+		t.Errorf("Expected [1 0] in dead letter, got %v", dlq)
+	}
+}
+
+func TestShed_NotOverloaded(t *testing.T) {
+	b, err := New(Config{
+		InitialBatchSize:  100,
+		DropPolicy:        DropNewest,
+		ShedHighWatermark: 2,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			return &LoadFeedback{}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	// Above the watermark but without overload feedback nothing is shed
+	for i := 0; i < 5; i++ {
+		if err := b.Add(context.Background(), i); err != nil {
+			t.Errorf("Add() error: %v", err)
+		}
+	}
+}
+
+func TestDropPolicy_String(t *testing.T) {
+	tests := []struct {
+		policy DropPolicy
+		want   string
+	}{
+		{DropNone, "none"},
+		{DropNewest, "newest"},
+		{DropOldest, "oldest"},
+		{DropLowestPriority, "lowest-priority"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.policy.String(); got != tt.want {
+			t.Errorf("String() = %v, want %v", got, tt.want)
+		}
+	}
+}
+
+func TestShed_OnItemDropped(t *testing.T) {
+	type drop struct {
+		item   any
+		reason DropReason
+	}
+	var drops []drop
+
+	b, err := New(Config{
+		InitialBatchSize:  100,
+		DropPolicy:        DropOldest,
+		ShedHighWatermark: 10,
+		ShedLowWatermark:  4,
+		ShedLoadThreshold: 0.5,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			return nil, nil
+		},
+		OnItemDropped: func(item any, reason DropReason) {
+			drops = append(drops, drop{item, reason})
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	b.mu.Lock()
+	b.recordFeedback(LoadFeedback{CPULoad: 1.0, ErrorRate: 1.0}, 10, 1)
+	b.inFlightItems = 8
+	b.mu.Unlock()
+
+	ctx := context.Background()
+	b.Add(ctx, 1)
+	b.Add(ctx, 2)
+	b.Add(ctx, 3)
+
+	if len(drops) != 1 || drops[0].item != 1 || drops[0].reason != DropReasonShed {
+		t.Errorf("Expected item 1 dropped as shed, got %v", drops)
+	}
+	if got := DropReasonShed.String(); got != "shed" {
+		t.Errorf("Expected shed, got %s", got)
+	}
+}
@@ -0,0 +1,286 @@
+package batcher
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+// The simulation tests drive a batcher through load profiles one
+// adjustment cycle at a time on a simulated clock, so controllers can be
+// held to properties such as "under sustained overload the batch size
+// reaches MinBatchSize within K cycles" without sleeping or flakiness.
+
+// simInterval is the simulated time between adjustment cycles
+const simInterval = time.Second
+
+// simClock is a manually advanced clock for Batcher.now
+type simClock struct {
+	t time.Time
+}
+
+func (c *simClock) now() time.Time { return c.t }
+
+// simController is a controller under test
+type simController struct {
+	name string
+	new  func() AdjustmentStrategy
+}
+
+// simControllers are the controllers every generic scenario runs against
+var simControllers = []simController{
+	{"threshold", func() AdjustmentStrategy { return &ThresholdStrategy{} }},
+	{"aimd", func() AdjustmentStrategy { return &AIMDStrategy{} }},
+	{"pid", func() AdjustmentStrategy { return &PIDStrategy{} }},
+}
+
+// simScenario is a load profile and what the batch sizes must satisfy
+type simScenario struct {
+	name string
+
+	// initial, min and max are the batch size bounds
+	initial, min, max int
+
+	// controllers restricts the scenario to the named controllers; it
+	// runs against all of them if empty
+	controllers []string
+
+	// cycles is the number of adjustment cycles simulated
+	cycles int
+
+	// load returns the load score the backend reports during a cycle,
+	// through a custom metric that makes up the whole score
+	load func(cycle int) float64
+
+	// check inspects the batch size after every cycle, sizes[i] being
+	// the size after cycle i
+	check func(t *testing.T, sizes []int)
+}
+
+// simulate runs the scenario against a controller and returns the batch
+// size after every cycle
+func simulate(t *testing.T, sc simScenario, strategy AdjustmentStrategy) []int {
+	t.Helper()
+
+	load := 0.0
+	b, err := New(Config{
+		InitialBatchSize:   sc.initial,
+		MinBatchSize:       sc.min,
+		MaxBatchSize:       sc.max,
+		AdjustmentFactor:   0.2,
+		LoadCheckInterval:  time.Hour, // cycles are driven by the test
+		AdjustmentStrategy: strategy,
+		CustomMetrics:      []CustomMetric{{Name: "load", Weight: 1, Critical: 1}},
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			return &LoadFeedback{Custom: map[string]any{"load": load}}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	clock := &simClock{t: time.Now()}
+	b.mu.Lock()
+	b.now = clock.now
+	b.lastAdjust = clock.t
+	b.mu.Unlock()
+
+	ctx := context.Background()
+	sizes := make([]int, 0, sc.cycles)
+	for cycle := 0; cycle < sc.cycles; cycle++ {
+		load = sc.load(cycle)
+
+		// A few full batches per cycle; Add flushes them synchronously
+		size := b.GetCurrentBatchSize()
+		for i := 0; i < 3*size; i++ {
+			if err := b.Add(ctx, i); err != nil {
+				t.Fatalf("Add() failed: %v", err)
+			}
+		}
+
+		clock.t = clock.t.Add(simInterval)
+		b.adjustBatchSize()
+		sizes = append(sizes, b.GetCurrentBatchSize())
+	}
+	return sizes
+}
+
+// ramp returns a load profile rising linearly from from to to over n
+// cycles and holding to afterwards
+func ramp(from, to float64, n int) func(int) float64 {
+	return func(cycle int) float64 {
+		if cycle >= n {
+			return to
+		}
+		return from + (to-from)*float64(cycle)/float64(n)
+	}
+}
+
+// steps returns a load profile holding each level for n cycles, then the
+// last level
+func steps(n int, levels ...float64) func(int) float64 {
+	return func(cycle int) float64 {
+		return levels[min(cycle/n, len(levels)-1)]
+	}
+}
+
+// reachesWithin fails unless the size is want after one of the cycles
+// [from, from+k)
+func reachesWithin(want, from, k int) func(t *testing.T, sizes []int) {
+	return func(t *testing.T, sizes []int) {
+		t.Helper()
+		for _, s := range sizes[from:min(from+k, len(sizes))] {
+			if s == want {
+				return
+			}
+		}
+		t.Errorf("Expected size %d within %d cycles of cycle %d, got %v", want, k, from, sizes)
+	}
+}
+
+// holds fails unless the size is want after every cycle [from, to)
+func holds(want, from, to int) func(t *testing.T, sizes []int) {
+	return func(t *testing.T, sizes []int) {
+		t.Helper()
+		for _, s := range sizes[from:min(to, len(sizes))] {
+			if s != want {
+				t.Errorf("Expected size %d over cycles [%d, %d), got %v", want, from, to, sizes)
+				return
+			}
+		}
+	}
+}
+
+// nonDecreasing fails if the size shrinks over cycles [from, to)
+func nonDecreasing(from, to int) func(t *testing.T, sizes []int) {
+	return func(t *testing.T, sizes []int) {
+		t.Helper()
+		window := sizes[from:min(to, len(sizes))]
+		for i := 1; i < len(window); i++ {
+			if window[i] < window[i-1] {
+				t.Errorf("Expected no shrinking over cycles [%d, %d), got %v", from, to, sizes)
+				return
+			}
+		}
+	}
+}
+
+// grows fails unless the size is larger after cycle to-1 than after
+// cycle from
+func grows(from, to int) func(t *testing.T, sizes []int) {
+	return func(t *testing.T, sizes []int) {
+		t.Helper()
+		last := min(to, len(sizes)) - 1
+		if sizes[last] <= sizes[from] {
+			t.Errorf("Expected growth over cycles [%d, %d), got %v", from, to, sizes)
+		}
+	}
+}
+
+// all combines checks
+func all(checks ...func(*testing.T, []int)) func(*testing.T, []int) {
+	return func(t *testing.T, sizes []int) {
+		t.Helper()
+		for _, check := range checks {
+			check(t, sizes)
+		}
+	}
+}
+
+func TestSimulation_Controllers(t *testing.T) {
+	scenarios := []simScenario{
+		// Properties every controller must have
+		{
+			name:    "load ramps to 0.9 over 10 cycles",
+			initial: 50, min: 5, max: 200,
+			cycles: 30,
+			load:   ramp(0.2, 0.9, 10),
+			check:  all(reachesWithin(5, 0, 25), holds(5, 25, 30)),
+		},
+		{
+			name:    "sudden outage",
+			initial: 100, min: 5, max: 100,
+			cycles: 30,
+			load:   steps(5, 0.1, 1.0),
+			check:  all(holds(100, 0, 5), reachesWithin(5, 5, 20), holds(5, 25, 30)),
+		},
+		{
+			name:    "idle backend",
+			initial: 10, min: 5, max: 100,
+			cycles: 30,
+			load:   steps(1, 0.05),
+			check:  all(nonDecreasing(0, 30), grows(0, 30)),
+		},
+		{
+			name:    "recovery after overload",
+			initial: 50, min: 5, max: 100,
+			cycles: 40,
+			load:   steps(20, 0.95, 0.05),
+			check:  all(reachesWithin(5, 0, 20), nonDecreasing(20, 40), grows(20, 40)),
+		},
+
+		// Properties of particular controllers
+		{
+			name:        "idle backend reaches the maximum",
+			controllers: []string{"threshold", "pid"},
+			initial:     10, min: 5, max: 100,
+			cycles: 30,
+			load:   steps(1, 0.05),
+			check:  all(reachesWithin(100, 0, 20), holds(100, 20, 30)),
+		},
+		{
+			name:        "full recovery after overload",
+			controllers: []string{"threshold", "pid"},
+			initial:     50, min: 5, max: 100,
+			cycles: 60,
+			load:   steps(20, 0.95, 0.05),
+			check:  all(reachesWithin(100, 20, 25), holds(100, 45, 60)),
+		},
+		{
+			name:        "steady load inside the band holds the size",
+			controllers: []string{"threshold"},
+			initial:     40, min: 5, max: 100,
+			cycles: 20,
+			load:   steps(1, 0.4),
+			check:  holds(40, 0, 20),
+		},
+		{
+			name:        "overload halves the size every cycle",
+			controllers: []string{"aimd"},
+			initial:     80, min: 5, max: 100,
+			cycles: 5,
+			load:   steps(1, 0.9),
+			check: func(t *testing.T, sizes []int) {
+				if want := []int{40, 20, 10, 5, 5}; fmt.Sprint(sizes) != fmt.Sprint(want) {
+					t.Errorf("Expected sizes %v, got %v", want, sizes)
+				}
+			},
+		},
+		{
+			name:        "calm backend grows one item per cycle",
+			controllers: []string{"aimd"},
+			initial:     10, min: 5, max: 100,
+			cycles: 20,
+			load:   steps(1, 0.3),
+			check: func(t *testing.T, sizes []int) {
+				if sizes[19] != 30 {
+					t.Errorf("Expected size 30 after 20 cycles, got %v", sizes)
+				}
+			},
+		},
+	}
+
+	for _, sc := range scenarios {
+		for _, c := range simControllers {
+			if len(sc.controllers) > 0 && !slices.Contains(sc.controllers, c.name) {
+				continue
+			}
+			t.Run(fmt.Sprintf("%s/%s", sc.name, c.name), func(t *testing.T) {
+				sc.check(t, simulate(t, sc, c.new()))
+			})
+		}
+	}
+}
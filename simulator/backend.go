@@ -28,6 +28,29 @@ type Backend struct {
 	totalProcessed int64
 	totalBatches   int64
 	totalErrors    int64
+
+	// Randomness and load traces
+	seed      int64
+	rng       *rand.Rand
+	start     time.Time // first batch; traces are timed from it
+	recording *Trace
+	replay    *Trace
+
+	// Injected spike, overriding the pattern until then
+	spikeUntil time.Time
+
+	// Recent item latencies, for the reported percentiles
+	latencies latencies
+
+	// Finite resources, if set with SetResources
+	resources *resources
+
+	// Shape of PatternDiurnal
+	diurnal DiurnalConfig
+
+	// Error bursts after overload, see SetRecovery
+	recovery     RecoveryConfig
+	overloadedAt time.Time
 }
 
 // LoadPattern defines how backend load varies over time
@@ -45,6 +68,10 @@ const (
 	
 	// PatternGradual gradually increases load over time
 	PatternGradual
+
+	// PatternDiurnal follows a day/night and weekday/weekend curve,
+	// compressed in time, see DiurnalConfig
+	PatternDiurnal
 )
 
 // String returns the string representation of LoadPattern
@@ -58,6 +85,8 @@ func (lp LoadPattern) String() string {
 		return "spikes"
 	case PatternGradual:
 		return "gradual"
+	case PatternDiurnal:
+		return "diurnal"
 	default:
 		return "unknown"
 	}
@@ -65,6 +94,7 @@ func (lp LoadPattern) String() string {
 
 // NewBackend creates a new backend simulator
 func NewBackend(pattern LoadPattern) *Backend {
+	seed := time.Now().UnixNano()
 	return &Backend{
 		cpuLoad:       0.3,
 		queueDepth:    0,
@@ -72,6 +102,60 @@ func NewBackend(pattern LoadPattern) *Backend {
 		errorRate:     0.01,
 		maxQueueDepth: 200,
 		loadPattern:   pattern,
+		seed:          seed,
+		rng:           rand.New(rand.NewSource(seed)),
+	}
+}
+
+// NewSeededBackend creates a backend simulator whose processing-time
+// jitter, error draws and random spikes come from seed, for reproducible
+// runs
+func NewSeededBackend(pattern LoadPattern, seed int64) *Backend {
+	b := NewBackend(pattern)
+	b.seed = seed
+	b.rng = rand.New(rand.NewSource(seed))
+	return b
+}
+
+// NewReplayBackend creates a backend whose load follows a recorded trace
+// instead of a pattern, timed from its first batch
+func NewReplayBackend(trace *Trace) (*Backend, error) {
+	if trace == nil || len(trace.Points) == 0 {
+		return nil, ErrEmptyTrace
+	}
+	b := NewBackend(PatternConstant)
+	b.seed = trace.Seed
+	b.rng = rand.New(rand.NewSource(trace.Seed))
+	b.replay = trace.clone()
+	return b, nil
+}
+
+// StartRecording starts capturing the load the backend applies, for
+// replay with NewReplayBackend
+func (b *Backend) StartRecording() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.recording = &Trace{Pattern: b.loadPattern.String(), Seed: b.seed}
+}
+
+// Trace returns the load recorded since StartRecording, or nil if the
+// backend is not recording
+func (b *Backend) Trace() *Trace {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.recording == nil {
+		return nil
+	}
+	return b.recording.clone()
+}
+
+// InjectSpike drives the backend into overload for d, whatever its
+// pattern or trace; a recording captures the spike like any other load
+func (b *Backend) InjectSpike(d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if until := time.Now().Add(d); until.After(b.spikeUntil) {
+		b.spikeUntil = until
 	}
 }
 
@@ -80,9 +164,27 @@ func (b *Backend) ProcessBatch(ctx context.Context, batch []any) (*batcher.LoadF
 	startTime := time.Now()
 	
 	b.mu.Lock()
+	if b.start.IsZero() {
+		b.start = startTime
+	}
 	
 	// Add to queue
 	batchSize := len(batch)
+	if r := b.resources; r != nil {
+		if err := r.acquire(); err != nil {
+			b.totalBatches++
+			b.totalErrors += int64(batchSize)
+			feedback := &batcher.LoadFeedback{
+				CPULoad:    b.cpuLoad,
+				QueueDepth: b.queueDepth,
+				ErrorRate:  1,
+				Custom:     map[string]interface{}{"batch_size": batchSize},
+			}
+			r.metrics(feedback.Custom, b.queueDepth)
+			b.mu.Unlock()
+			return feedback, err
+		}
+	}
 	b.queueDepth += batchSize
 	
 	// Update load based on pattern
@@ -90,6 +192,9 @@ func (b *Backend) ProcessBatch(ctx context.Context, batch []any) (*batcher.LoadF
 	
 	// Simulate processing time based on queue depth and CPU load
 	processingTime := b.calculateProcessingTime(batchSize)
+	if r := b.resources; r != nil {
+		processingTime = time.Duration(float64(processingTime) * r.slowdown(b.queueDepth))
+	}
 	
 	b.mu.Unlock()
 	
@@ -111,7 +216,7 @@ func (b *Backend) ProcessBatch(ctx context.Context, batch []any) (*batcher.LoadF
 	// Simulate errors based on load
 	errors := 0
 	for i := 0; i < batchSize; i++ {
-		if rand.Float64() < b.errorRate {
+		if b.rng.Float64() < b.errorRate {
 			errors++
 			b.totalErrors++
 		} else {
@@ -123,24 +228,65 @@ func (b *Backend) ProcessBatch(ctx context.Context, batch []any) (*batcher.LoadF
 	if batchSize > 0 {
 		currentErrorRate = float64(errors) / float64(batchSize)
 	}
+
+	elapsed := time.Since(startTime)
+	b.latencies.record(b, elapsed, batchSize)
+	p95, p99 := b.latencies.percentiles()
 	
 	// Create feedback
 	feedback := &batcher.LoadFeedback{
 		CPULoad:        b.cpuLoad,
 		QueueDepth:     b.queueDepth,
-		ProcessingTime: time.Since(startTime),
+		ProcessingTime: elapsed,
 		ErrorRate:      currentErrorRate,
 		DBLocks:        b.dbLocks,
 		Custom: map[string]interface{}{
-			"batch_size": batchSize,
+			"batch_size":     batchSize,
+			MetricLatencyP95: p95,
+			MetricLatencyP99: p99,
 		},
 	}
+	if r := b.resources; r != nil {
+		r.metrics(feedback.Custom, b.queueDepth)
+		r.release(b)
+	}
 	
 	return feedback, nil
 }
 
-// updateLoad updates backend load based on the pattern
+// updateLoad updates backend load based on the pattern or the replayed
+// trace, recording it if asked to
 func (b *Backend) updateLoad() {
+	if b.replay != nil {
+		p := b.replay.At(time.Since(b.start))
+		b.cpuLoad, b.errorRate = p.CPULoad, p.ErrorRate
+	} else {
+		b.applyPattern()
+	}
+	now := time.Now()
+	if now.Before(b.spikeUntil) {
+		b.cpuLoad, b.errorRate = 0.95, 0.1
+	}
+	b.applyRecovery(now)
+
+	if b.recording != nil {
+		b.recording.record(TracePoint{
+			Elapsed:   time.Since(b.start),
+			CPULoad:   b.cpuLoad,
+			ErrorRate: b.errorRate,
+		})
+	}
+
+	// Adjust DB locks based on queue depth
+	if b.queueDepth > 100 {
+		b.dbLocks = 20 + b.rng.Intn(30)
+	} else {
+		b.dbLocks = b.rng.Intn(10)
+	}
+}
+
+// applyPattern sets the load of the configured pattern
+func (b *Backend) applyPattern() {
 	switch b.loadPattern {
 	case PatternConstant:
 		// Keep load constant
@@ -158,14 +304,14 @@ func (b *Backend) updateLoad() {
 		
 	case PatternSpikes:
 		// Random spikes
-		if rand.Float64() < 0.1 { // 10% chance of spike
-			b.cpuLoad = 0.9 + rand.Float64()*0.1
+		if b.rng.Float64() < 0.1 { // 10% chance of spike
+			b.cpuLoad = 0.9 + b.rng.Float64()*0.1
 			b.errorRate = 0.1
-			b.dbLocks = 30 + rand.Intn(40)
+			b.dbLocks = 30 + b.rng.Intn(40)
 		} else {
-			b.cpuLoad = 0.2 + rand.Float64()*0.3
+			b.cpuLoad = 0.2 + b.rng.Float64()*0.3
 			b.errorRate = 0.01
-			b.dbLocks = rand.Intn(10)
+			b.dbLocks = b.rng.Intn(10)
 		}
 		
 	case PatternGradual:
@@ -173,13 +319,11 @@ func (b *Backend) updateLoad() {
 		increase := float64(b.totalBatches) * 0.001
 		b.cpuLoad = Math.Min(0.2+increase, 0.95)
 		b.errorRate = Math.Min(0.01+increase*0.05, 0.2)
-	}
-	
-	// Adjust DB locks based on queue depth
-	if b.queueDepth > 100 {
-		b.dbLocks = 20 + rand.Intn(30)
-	} else {
-		b.dbLocks = rand.Intn(10)
+
+	case PatternDiurnal:
+		// Errors creep up as the load nears saturation
+		b.cpuLoad = b.diurnal.load(time.Since(b.start))
+		b.errorRate = 0.01 + 0.1*max(b.cpuLoad-0.8, 0)
 	}
 }
 
@@ -203,7 +347,7 @@ func (b *Backend) calculateProcessingTime(batchSize int) time.Duration {
 	totalTime := float64(baseTime) * float64(batchSize) * loadMultiplier * queueMultiplier
 	
 	// Add some randomness
-	jitter := 0.8 + rand.Float64()*0.4 // 80% to 120%
+	jitter := 0.8 + b.rng.Float64()*0.4 // 80% to 120%
 	totalTime *= jitter
 	
 	return time.Duration(totalTime)
@@ -214,7 +358,10 @@ func (b *Backend) GetStats() BackendStats {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 	
-	return BackendStats{
+	p95, p99 := b.latencies.percentiles()
+	stats := BackendStats{
+		LatencyP95:     p95,
+		LatencyP99:     p99,
 		CPULoad:        b.cpuLoad,
 		QueueDepth:     b.queueDepth,
 		DBLocks:        b.dbLocks,
@@ -223,6 +370,11 @@ func (b *Backend) GetStats() BackendStats {
 		TotalBatches:   b.totalBatches,
 		TotalErrors:    b.totalErrors,
 	}
+	if r := b.resources; r != nil {
+		stats.PoolUtilization = r.poolUtilization()
+		stats.MemoryUsage = r.memoryUsage(b.queueDepth)
+	}
+	return stats
 }
 
 // BackendStats holds backend statistics
@@ -234,6 +386,16 @@ type BackendStats struct {
 	TotalProcessed int64
 	TotalBatches   int64
 	TotalErrors    int64
+
+	// LatencyP95 and LatencyP99 are percentiles of the recent item
+	// latencies
+	LatencyP95 time.Duration
+	LatencyP99 time.Duration
+
+	// PoolUtilization and MemoryUsage are the fractions of the
+	// connection pool and memory in use; zero without SetResources
+	PoolUtilization float64
+	MemoryUsage     float64
 }
 
 // String formats backend stats as a string
@@ -2,8 +2,11 @@ package simulator
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
+
+	"github.com/amirafroozeh1/Load-Aware-Batcher"
 )
 
 func TestNewBackend(t *testing.T) {
@@ -345,6 +348,184 @@ func TestMath_Min(t *testing.T) {
 }
 
 // Helper function
+
+func TestBackend_InjectSpike(t *testing.T) {
+	b := NewBackend(PatternConstant)
+	batch := []any{1}
+
+	fb, _ := b.ProcessBatch(context.Background(), batch)
+	if fb.CPULoad != 0.5 {
+		t.Fatalf("Expected constant load 0.5, got %v", fb.CPULoad)
+	}
+
+	b.InjectSpike(50 * time.Millisecond)
+	fb, _ = b.ProcessBatch(context.Background(), batch)
+	if fb.CPULoad != 0.95 {
+		t.Errorf("Expected spike load 0.95, got %v", fb.CPULoad)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	fb, _ = b.ProcessBatch(context.Background(), batch)
+	if fb.CPULoad != 0.5 {
+		t.Errorf("Expected load back to 0.5 after the spike, got %v", fb.CPULoad)
+	}
+}
+
+func TestBackend_LatencyPercentiles(t *testing.T) {
+	b := NewBackend(PatternConstant)
+	batch := make([]any, 5)
+
+	var fb *batcher.LoadFeedback
+	for i := 0; i < 20; i++ {
+		fb, _ = b.ProcessBatch(context.Background(), batch)
+	}
+	p95, ok95 := fb.Custom[MetricLatencyP95].(time.Duration)
+	p99, ok99 := fb.Custom[MetricLatencyP99].(time.Duration)
+	if !ok95 || !ok99 {
+		t.Fatalf("Expected latency percentiles in the feedback, got %v", fb.Custom)
+	}
+	if p95 <= 0 || p99 < p95 {
+		t.Errorf("Expected 0 < p95 <= p99, got %v and %v", p95, p99)
+	}
+	if stats := b.GetStats(); stats.LatencyP99 != p99 {
+		t.Errorf("Expected stats p99 %v, got %v", p99, stats.LatencyP99)
+	}
+}
+
+func TestBackend_LatencyTarget(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs the simulator for a second")
+	}
+
+	// Items cost about 2ms at constant load, so a 10ms p99 target calls
+	// for batches of a handful of items
+	backend := NewBackend(PatternConstant)
+	b, err := batcher.New(batcher.Config{
+		InitialBatchSize:  40,
+		MaxBatchSize:      200,
+		LoadCheckInterval: 50 * time.Millisecond,
+		HandlerFunc:       backend.ProcessBatch,
+		CustomMetrics: []batcher.CustomMetric{
+			{Name: MetricLatencyP99, Weight: 0.9, Critical: 10},
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for i := 0; ctx.Err() == nil; i++ {
+		b.Add(ctx, i)
+	}
+
+	if size := b.GetCurrentBatchSize(); size >= 20 {
+		t.Errorf("Expected the p99 target to shrink the batch size, got %d", size)
+	}
+}
+
+func TestBackend_ConnectionPoolLeak(t *testing.T) {
+	b := NewBackend(PatternConstant)
+	b.SetResources(ResourceConfig{PoolSize: 2, LeakRate: 1})
+	batch := []any{1}
+
+	fb, err := b.ProcessBatch(context.Background(), batch)
+	if err != nil {
+		t.Fatalf("Expected a free connection, got %v", err)
+	}
+	if u := fb.Custom[MetricPoolUtilization]; u != 0.5 {
+		t.Errorf("Expected pool utilization 0.5, got %v", u)
+	}
+
+	b.ProcessBatch(context.Background(), batch)
+	fb, err = b.ProcessBatch(context.Background(), batch)
+	if !errors.Is(err, ErrPoolExhausted) {
+		t.Fatalf("Expected ErrPoolExhausted once both connections leaked, got %v", err)
+	}
+	if u := fb.Custom[MetricPoolUtilization]; u != 1.0 {
+		t.Errorf("Expected pool utilization 1, got %v", u)
+	}
+
+	b.ReleaseLeaks()
+	if _, err := b.ProcessBatch(context.Background(), batch); err != nil {
+		t.Errorf("Expected the pool back after ReleaseLeaks, got %v", err)
+	}
+}
+
+func TestBackend_MemoryLeak(t *testing.T) {
+	b := NewBackend(PatternConstant)
+	b.SetResources(ResourceConfig{MemoryLimit: 100 << 10, MemoryLeak: 30 << 10})
+	batch := []any{1}
+
+	var usage []float64
+	for i := 0; i < 4; i++ {
+		fb, _ := b.ProcessBatch(context.Background(), batch)
+		usage = append(usage, fb.Custom[MetricMemoryUsage].(float64))
+	}
+	for i := 1; i < len(usage); i++ {
+		if usage[i] <= usage[i-1] && usage[i] < 1 {
+			t.Errorf("Expected memory use to grow with the leak, got %v", usage)
+		}
+	}
+	if s := b.GetStats(); s.MemoryUsage != 1 {
+		t.Errorf("Expected memory exhausted after 4 leaks of 30%%, got %v", s.MemoryUsage)
+	}
+
+	b.ReleaseLeaks()
+	if s := b.GetStats(); s.MemoryUsage != 0 {
+		t.Errorf("Expected memory back after ReleaseLeaks, got %v", s.MemoryUsage)
+	}
+}
+
+func TestDiurnalConfig_Load(t *testing.T) {
+	cfg := DiurnalConfig{Day: 24 * time.Second, PeakHour: 14}
+	hour := func(day int, h float64) time.Duration {
+		return time.Duration(day)*cfg.Day + time.Duration(h*float64(time.Second))
+	}
+
+	peak, night := cfg.load(hour(0, 14)), cfg.load(hour(0, 2))
+	if peak < 0.84 || night > 0.16 {
+		t.Errorf("Expected a weekday peak of 0.85 and trough of 0.15, got %v and %v", peak, night)
+	}
+	if morning := cfg.load(hour(1, 9)); morning <= night || morning >= peak {
+		t.Errorf("Expected the morning between trough and peak, got %v", morning)
+	}
+
+	// Saturday is the sixth day of the week
+	if weekend := cfg.load(hour(5, 14)); weekend > 0.51 || weekend < 0.49 {
+		t.Errorf("Expected the weekend peak halved above the trough, got %v", weekend)
+	}
+	if monday := cfg.load(hour(7, 14)); monday != peak {
+		t.Errorf("Expected the next week to repeat, got %v", monday)
+	}
+}
+
+func TestBackend_RecoveryErrorBurst(t *testing.T) {
+	b := NewBackend(PatternConstant)
+	b.SetRecovery(RecoveryConfig{Period: 200 * time.Millisecond})
+	batch := []any{1}
+
+	b.InjectSpike(20 * time.Millisecond)
+	b.ProcessBatch(context.Background(), batch)
+	if rate := b.GetStats().ErrorRate; rate != 0.3 {
+		t.Errorf("Expected the burst error rate 0.3 during overload, got %v", rate)
+	}
+
+	// The spike is over but the backend still fails more than usual
+	time.Sleep(50 * time.Millisecond)
+	b.ProcessBatch(context.Background(), batch)
+	if rate := b.GetStats().ErrorRate; rate <= 0.05 || rate >= 0.3 {
+		t.Errorf("Expected a decaying error rate while recovering, got %v", rate)
+	}
+
+	time.Sleep(250 * time.Millisecond)
+	b.ProcessBatch(context.Background(), batch)
+	if rate := b.GetStats().ErrorRate; rate != 0.01 {
+		t.Errorf("Expected the usual error rate after recovery, got %v", rate)
+	}
+}
+
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && findSubstring(s, substr)
 }
@@ -0,0 +1,75 @@
+package simulator
+
+import (
+	"math"
+	"time"
+)
+
+// DiurnalConfig shapes the load of PatternDiurnal: a 24-hour curve
+// peaking at PeakHour and bottoming out twelve hours later, with lighter
+// weekends, played back with every simulated day compressed into Day
+type DiurnalConfig struct {
+	// Day is how long a simulated day lasts (default: 1 minute)
+	Day time.Duration
+
+	// PeakHour is the hour of the day, 0 to 24, at which load peaks
+	// (default: 14)
+	PeakHour float64
+
+	// Base is the CPU load halfway between night and peak (default: 0.5)
+	Base float64
+
+	// Amplitude is how far the CPU load swings above and below Base
+	// (default: 0.35)
+	Amplitude float64
+
+	// WeekendFactor scales the load above the night-time trough on the
+	// last two days of every simulated week; 1 makes weekends like
+	// weekdays (default: 0.5)
+	WeekendFactor float64
+}
+
+// SetDiurnal configures PatternDiurnal, which NewBackend otherwise runs
+// with the DiurnalConfig defaults
+func (b *Backend) SetDiurnal(cfg DiurnalConfig) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.diurnal = cfg
+}
+
+// --- Internal methods ---
+
+// withDefaults returns the configuration with defaults applied
+func (c DiurnalConfig) withDefaults() DiurnalConfig {
+	if c.Day <= 0 {
+		c.Day = time.Minute
+	}
+	if c.PeakHour <= 0 || c.PeakHour > 24 {
+		c.PeakHour = 14
+	}
+	if c.Base <= 0 {
+		c.Base = 0.5
+	}
+	if c.Amplitude <= 0 {
+		c.Amplitude = 0.35
+	}
+	if c.WeekendFactor <= 0 {
+		c.WeekendFactor = 0.5
+	}
+	return c
+}
+
+// load returns the CPU load elapsed into the simulation, which starts at
+// midnight on the first day of a week
+func (c DiurnalConfig) load(elapsed time.Duration) float64 {
+	c = c.withDefaults()
+	days := float64(elapsed) / float64(c.Day)
+	hour := 24 * (days - math.Floor(days))
+
+	trough := c.Base - c.Amplitude
+	load := c.Base + c.Amplitude*math.Cos(2*math.Pi*(hour-c.PeakHour)/24)
+	if weekday := int(days) % 7; weekday >= 5 {
+		load = trough + (load-trough)*c.WeekendFactor
+	}
+	return math.Max(0, math.Min(1, load))
+}
@@ -0,0 +1,59 @@
+package simulator
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	// MetricLatencyP95 and MetricLatencyP99 are the keys of
+	// LoadFeedback.Custom under which the backend reports the 95th and
+	// 99th percentile of its recent item latencies, as time.Duration. A
+	// CustomMetric on either, with Critical at the latency target in
+	// milliseconds, makes the batcher size batches to meet it.
+	MetricLatencyP95 = "latency_p95"
+	MetricLatencyP99 = "latency_p99"
+
+	// latencyWindow is the number of recent item latencies the
+	// percentiles are taken over
+	latencyWindow = 1024
+)
+
+// latencies is a ring of recent item latencies
+type latencies struct {
+	ring []time.Duration
+	next int
+}
+
+// record adds the latencies of a batch that took d: the items of a batch
+// finish around d, spread out with a tail that grows with the CPU load,
+// as queueing and contention hit some requests much harder than others
+func (l *latencies) record(b *Backend, d time.Duration, n int) {
+	spread := 0.1 + 0.6*b.cpuLoad
+	for i := 0; i < n; i++ {
+		item := time.Duration(float64(d) * math.Exp(b.rng.NormFloat64()*spread))
+		if len(l.ring) < latencyWindow {
+			l.ring = append(l.ring, item)
+			continue
+		}
+		l.ring[l.next] = item
+		l.next = (l.next + 1) % latencyWindow
+	}
+}
+
+// percentiles returns the p95 and p99 of the recorded latencies using
+// nearest-rank
+func (l *latencies) percentiles() (p95, p99 time.Duration) {
+	if len(l.ring) == 0 {
+		return 0, 0
+	}
+	sorted := make([]time.Duration, len(l.ring))
+	copy(sorted, l.ring)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := func(p float64) time.Duration {
+		i := int(math.Ceil(p*float64(len(sorted)))) - 1
+		return sorted[max(i, 0)]
+	}
+	return rank(0.95), rank(0.99)
+}
@@ -0,0 +1,59 @@
+package simulator
+
+import "time"
+
+// RecoveryConfig models a backend that keeps failing for a while after an
+// overload, as caches run cold and retries pile on: once the CPU load
+// reaches Threshold, the error rate jumps to at least ErrorRate and decays
+// back over Period after the load drops again
+type RecoveryConfig struct {
+	// Threshold is the CPU load from which the backend counts as
+	// overloaded (default: 0.9)
+	Threshold float64
+
+	// Period is how long the error rate takes to recover after the
+	// overload ends
+	Period time.Duration
+
+	// ErrorRate is the error rate right after the overload (default: 0.3)
+	ErrorRate float64
+}
+
+// SetRecovery makes overloads leave the backend failing for a recovery
+// period. A zero Period turns the model off.
+func (b *Backend) SetRecovery(cfg RecoveryConfig) {
+	if cfg.Threshold <= 0 {
+		cfg.Threshold = 0.9
+	}
+	if cfg.ErrorRate <= 0 {
+		cfg.ErrorRate = 0.3
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.recovery = cfg
+	b.overloadedAt = time.Time{}
+}
+
+// --- Internal methods ---
+
+// applyRecovery raises the error rate while the backend recovers from its
+// last overload
+func (b *Backend) applyRecovery(now time.Time) {
+	r := b.recovery
+	if r.Period <= 0 {
+		return
+	}
+	if b.cpuLoad >= r.Threshold {
+		b.overloadedAt = now
+	}
+	if b.overloadedAt.IsZero() {
+		return
+	}
+	since := now.Sub(b.overloadedAt)
+	if since >= r.Period {
+		return
+	}
+	burst := r.ErrorRate * (1 - float64(since)/float64(r.Period))
+	b.errorRate = max(b.errorRate, burst)
+}
@@ -0,0 +1,123 @@
+package simulator
+
+import "errors"
+
+const (
+	// MetricPoolUtilization is the key of LoadFeedback.Custom under which
+	// the backend reports the fraction of its connection pool in use,
+	// leaked connections included, from 0 to 1
+	MetricPoolUtilization = "pool_utilization"
+
+	// MetricMemoryUsage is the key of LoadFeedback.Custom under which the
+	// backend reports its memory use as a fraction of MemoryLimit, from 0
+	// to 1
+	MetricMemoryUsage = "memory_usage"
+)
+
+// ErrPoolExhausted is returned by a backend with resources when no
+// connection is free for a batch
+var ErrPoolExhausted = errors.New("simulator: connection pool exhausted")
+
+// ResourceConfig gives a Backend finite resources, whose exhaustion shows
+// in the Custom metrics of its feedback before it shows in errors
+type ResourceConfig struct {
+	// PoolSize is the number of connections. Each batch holds one while
+	// it is processed; a batch finding none free fails with
+	// ErrPoolExhausted (default: 10)
+	PoolSize int
+
+	// LeakRate is the fraction of batches that never return their
+	// connection, until ReleaseLeaks
+	LeakRate float64
+
+	// MemoryLimit is the memory of the backend in bytes (default: 64 MiB)
+	MemoryLimit int64
+
+	// ItemMemory is the memory an item takes while queued (default: 1 KiB)
+	ItemMemory int64
+
+	// MemoryLeak is the memory each batch leaks, until ReleaseLeaks.
+	// Memory use above 80% of MemoryLimit slows processing down, up to
+	// threefold when it is exhausted, as garbage collection would.
+	MemoryLeak int64
+}
+
+// SetResources gives the backend finite resources, reported as
+// MetricPoolUtilization and MetricMemoryUsage from the next batch on
+func (b *Backend) SetResources(cfg ResourceConfig) {
+	if cfg.PoolSize <= 0 {
+		cfg.PoolSize = 10
+	}
+	if cfg.MemoryLimit <= 0 {
+		cfg.MemoryLimit = 64 << 20
+	}
+	if cfg.ItemMemory <= 0 {
+		cfg.ItemMemory = 1 << 10
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.resources = &resources{ResourceConfig: cfg}
+}
+
+// ReleaseLeaks returns leaked connections and memory, as restarting the
+// backend would
+func (b *Backend) ReleaseLeaks() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if r := b.resources; r != nil {
+		r.leakedConns = 0
+		r.leakedMemory = 0
+	}
+}
+
+// resources is the state of the finite resources of a backend
+type resources struct {
+	ResourceConfig
+
+	connsInUse   int
+	leakedConns  int
+	leakedMemory int64
+}
+
+// acquire takes a connection for a batch
+func (r *resources) acquire() error {
+	if r.connsInUse+r.leakedConns >= r.PoolSize {
+		return ErrPoolExhausted
+	}
+	r.connsInUse++
+	return nil
+}
+
+// release returns the connection of a batch, unless it leaks, along with
+// some memory
+func (r *resources) release(b *Backend) {
+	r.connsInUse--
+	if b.rng.Float64() < r.LeakRate {
+		r.leakedConns++
+	}
+	r.leakedMemory += r.MemoryLeak
+}
+
+// poolUtilization is the fraction of the pool in use
+func (r *resources) poolUtilization() float64 {
+	return min(float64(r.connsInUse+r.leakedConns)/float64(r.PoolSize), 1)
+}
+
+// memoryUsage is the fraction of the memory in use with queueDepth items
+// queued
+func (r *resources) memoryUsage(queueDepth int) float64 {
+	used := r.leakedMemory + int64(queueDepth)*r.ItemMemory
+	return min(float64(used)/float64(r.MemoryLimit), 1)
+}
+
+// slowdown is the factor memory pressure stretches processing time by
+func (r *resources) slowdown(queueDepth int) float64 {
+	return 1 + 2*max(r.memoryUsage(queueDepth)-0.8, 0)/0.2
+}
+
+// metrics adds the resource metrics to the custom metrics of feedback
+func (r *resources) metrics(custom map[string]interface{}, queueDepth int) {
+	custom[MetricPoolUtilization] = r.poolUtilization()
+	custom[MetricMemoryUsage] = r.memoryUsage(queueDepth)
+}
@@ -0,0 +1,334 @@
+package simulator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/amirafroozeh1/Load-Aware-Batcher"
+)
+
+// Disturbance shapes the simulated backend load during a soak run. It
+// returns the load it contributes at the given elapsed time and whether
+// the backend is down.
+type Disturbance func(elapsed time.Duration) (load float64, outage bool)
+
+// SineLoad oscillates load around base with the given amplitude and period
+func SineLoad(base, amplitude float64, period time.Duration) Disturbance {
+	return func(elapsed time.Duration) (float64, bool) {
+		return base + amplitude*math.Sin(2*math.Pi*elapsed.Seconds()/period.Seconds()), false
+	}
+}
+
+// Spikes adds height to the load with the given probability per batch
+func Spikes(probability, height float64, seed int64) Disturbance {
+	var mu sync.Mutex
+	rng := rand.New(rand.NewSource(seed))
+	return func(time.Duration) (float64, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		if rng.Float64() < probability {
+			return height, false
+		}
+		return 0, false
+	}
+}
+
+// Outages takes the backend down for length at the end of every interval
+func Outages(every, length time.Duration) Disturbance {
+	return func(elapsed time.Duration) (float64, bool) {
+		return 0, elapsed%every >= every-length
+	}
+}
+
+// ErrOutage is returned by the soak backend while an outage is active
+var ErrOutage = errors.New("simulator: backend outage")
+
+// SoakConfig holds the configuration for a SoakRunner
+type SoakConfig struct {
+	// Batcher is the configuration of the batcher under test. HandlerFunc
+	// and HandlerFuncV2 are replaced by the simulated backend.
+	Batcher batcher.Config
+
+	// Duration is how long producers keep adding items (default: 1m)
+	Duration time.Duration
+
+	// Rate is the number of items added per second (default: 1000)
+	Rate int
+
+	// Producers is the number of concurrent producers (default: 4)
+	Producers int
+
+	// Disturbances are summed into the backend load, clamped to [0, 1]
+	Disturbances []Disturbance
+
+	// ItemCost is the processing time per item at zero load; it triples
+	// at full load (default: 50µs)
+	ItemCost time.Duration
+
+	// SampleInterval is how often invariants are checked (default: 100ms)
+	SampleInterval time.Duration
+
+	// MaxHeapBytes fails the run if the heap grows beyond it (default: no limit)
+	MaxHeapBytes uint64
+}
+
+// SoakSample is one periodic observation of a soak run
+type SoakSample struct {
+	Elapsed   time.Duration
+	BatchSize int
+	Load      float64
+	Pending   int
+	HeapBytes uint64
+}
+
+// SoakReport summarizes a soak run
+type SoakReport struct {
+	Duration time.Duration
+
+	ItemsAdded     int64
+	ItemsDelivered int64
+	ItemsFailed    int64
+	ItemsDropped   int64
+	ItemsRejected  int64
+	Batches        int64
+
+	MinBatchSize  int
+	MaxBatchSize  int
+	PeakHeapBytes uint64
+
+	// Samples holds the periodic observations in order
+	Samples []SoakSample
+
+	// Violations lists every invariant that did not hold
+	Violations []string
+}
+
+// Lost returns the number of added items that were neither delivered,
+// failed nor dropped
+func (r *SoakReport) Lost() int64 {
+	return r.ItemsAdded - r.ItemsDelivered - r.ItemsFailed - r.ItemsDropped
+}
+
+// Passed reports whether every invariant held
+func (r *SoakReport) Passed() bool {
+	return len(r.Violations) == 0
+}
+
+// String formats the report for logs and demos
+func (r *SoakReport) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Soak run: %v, %d batches\n", r.Duration.Round(time.Millisecond), r.Batches)
+	fmt.Fprintf(&sb, "Items: %d added | %d delivered | %d failed | %d dropped | %d rejected | %d lost\n",
+		r.ItemsAdded, r.ItemsDelivered, r.ItemsFailed, r.ItemsDropped, r.ItemsRejected, r.Lost())
+	fmt.Fprintf(&sb, "Batch size: %d..%d | Peak heap: %.1f MiB\n",
+		r.MinBatchSize, r.MaxBatchSize, float64(r.PeakHeapBytes)/(1<<20))
+	if r.Passed() {
+		sb.WriteString("Result: PASS\n")
+	} else {
+		sb.WriteString("Result: FAIL\n")
+		for _, v := range r.Violations {
+			fmt.Fprintf(&sb, "  - %s\n", v)
+		}
+	}
+	return sb.String()
+}
+
+// SoakRunner drives a batcher for a long time under composed load
+// patterns and checks that it keeps its invariants: no item is lost,
+// memory stays bounded and the batch size stays within its limits
+type SoakRunner struct {
+	cfg   SoakConfig
+	start time.Time
+
+	load      atomic.Uint64 // math.Float64bits of the last backend load
+	added     atomic.Int64
+	delivered atomic.Int64
+	failed    atomic.Int64
+	dropped   atomic.Int64
+	rejected  atomic.Int64
+	batches   atomic.Int64
+}
+
+// NewSoakRunner creates a SoakRunner with the given configuration
+func NewSoakRunner(cfg SoakConfig) *SoakRunner {
+	if cfg.Duration <= 0 {
+		cfg.Duration = time.Minute
+	}
+	if cfg.Rate <= 0 {
+		cfg.Rate = 1000
+	}
+	if cfg.Producers <= 0 {
+		cfg.Producers = 4
+	}
+	if cfg.ItemCost <= 0 {
+		cfg.ItemCost = 50 * time.Microsecond
+	}
+	if cfg.SampleInterval <= 0 {
+		cfg.SampleInterval = 100 * time.Millisecond
+	}
+	return &SoakRunner{cfg: cfg}
+}
+
+// Run drives the batcher until the configured duration elapses or ctx is
+// done, drains it and returns the report. An error is returned only if
+// the batcher could not be created.
+func (r *SoakRunner) Run(ctx context.Context) (*SoakReport, error) {
+	bcfg := r.cfg.Batcher
+	bcfg.HandlerFuncV2 = nil
+	bcfg.HandlerFunc = r.handle
+	userDeadLetter := bcfg.DeadLetterFunc
+	bcfg.DeadLetterFunc = func(ctx context.Context, items []any, err error) {
+		if errors.Is(err, batcher.ErrDropped) {
+			r.dropped.Add(int64(len(items)))
+		}
+		if userDeadLetter != nil {
+			userDeadLetter(ctx, items, err)
+		}
+	}
+
+	b, err := batcher.New(bcfg)
+	if err != nil {
+		return nil, err
+	}
+
+	r.start = time.Now()
+	runCtx, cancel := context.WithTimeout(ctx, r.cfg.Duration)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	interval := time.Duration(float64(time.Second) * float64(r.cfg.Producers) / float64(r.cfg.Rate))
+	for i := 0; i < r.cfg.Producers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.produce(runCtx, b, interval)
+		}()
+	}
+
+	report := &SoakReport{MinBatchSize: math.MaxInt}
+	ticker := time.NewTicker(r.cfg.SampleInterval)
+	defer ticker.Stop()
+
+sampling:
+	for {
+		select {
+		case <-ticker.C:
+			r.sample(b, report)
+		case <-runCtx.Done():
+			break sampling
+		}
+	}
+
+	wg.Wait()
+	if err := b.Close(context.Background()); err != nil && !errors.Is(err, ErrOutage) {
+		report.Violations = append(report.Violations, fmt.Sprintf("close: %v", err))
+	}
+	_ = b.FlushAndWait(context.Background())
+	r.sample(b, report)
+
+	report.Duration = time.Since(r.start)
+	report.ItemsAdded = r.added.Load()
+	report.ItemsDelivered = r.delivered.Load()
+	report.ItemsFailed = r.failed.Load()
+	report.ItemsDropped = r.dropped.Load()
+	report.ItemsRejected = r.rejected.Load()
+	report.Batches = r.batches.Load()
+
+	if lost := report.Lost(); lost != 0 {
+		report.Violations = append(report.Violations, fmt.Sprintf("%d items lost", lost))
+	}
+	return report, nil
+}
+
+// --- Internal methods ---
+
+func (r *SoakRunner) produce(ctx context.Context, b *batcher.Batcher, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			err := b.Add(ctx, struct{}{})
+			switch {
+			case err == nil, errors.Is(err, batcher.ErrDropped), errors.Is(err, ErrOutage):
+				// Accepted; dropped items are counted by the dead-letter hook
+				// and failed items by the handler
+				r.added.Add(1)
+			default:
+				r.rejected.Add(1)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (r *SoakRunner) handle(ctx context.Context, batch []any) (*batcher.LoadFeedback, error) {
+	r.batches.Add(1)
+
+	elapsed := time.Since(r.start)
+	load, outage := 0.0, false
+	for _, d := range r.cfg.Disturbances {
+		l, o := d(elapsed)
+		load += l
+		outage = outage || o
+	}
+	load = math.Max(0, math.Min(1, load))
+	r.load.Store(math.Float64bits(load))
+
+	if outage {
+		r.failed.Add(int64(len(batch)))
+		return &batcher.LoadFeedback{CPULoad: 1, ErrorRate: 1}, ErrOutage
+	}
+
+	cost := time.Duration(float64(r.cfg.ItemCost) * float64(len(batch)) * (1 + 2*load))
+	time.Sleep(cost)
+	r.delivered.Add(int64(len(batch)))
+
+	return &batcher.LoadFeedback{
+		CPULoad:        load,
+		ProcessingTime: cost,
+	}, nil
+}
+
+func (r *SoakRunner) sample(b *batcher.Batcher, report *SoakReport) {
+	stats := b.GetStats()
+
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	s := SoakSample{
+		Elapsed:   time.Since(r.start),
+		BatchSize: stats.CurrentBatchSize,
+		Load:      math.Float64frombits(r.load.Load()),
+		Pending:   stats.PendingItems,
+		HeapBytes: ms.HeapAlloc,
+	}
+	report.Samples = append(report.Samples, s)
+	report.MinBatchSize = min(report.MinBatchSize, s.BatchSize)
+	report.MaxBatchSize = max(report.MaxBatchSize, s.BatchSize)
+	report.PeakHeapBytes = max(report.PeakHeapBytes, s.HeapBytes)
+
+	cfg := r.cfg.Batcher
+	lo, hi := max(cfg.MinBatchSize, 1), cfg.MaxBatchSize
+	if hi <= 0 {
+		hi = 1000
+	}
+	if s.BatchSize < lo || s.BatchSize > hi {
+		report.Violations = append(report.Violations,
+			fmt.Sprintf("at %v: batch size %d outside [%d, %d]", s.Elapsed, s.BatchSize, lo, hi))
+	}
+	if r.cfg.MaxHeapBytes > 0 && s.HeapBytes > r.cfg.MaxHeapBytes {
+		report.Violations = append(report.Violations,
+			fmt.Sprintf("at %v: heap %d bytes exceeds %d", s.Elapsed, s.HeapBytes, r.cfg.MaxHeapBytes))
+	}
+}
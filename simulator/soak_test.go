@@ -0,0 +1,95 @@
+package simulator
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amirafroozeh1/Load-Aware-Batcher"
+)
+
+func TestSoakRunner_ComposedChaos(t *testing.T) {
+	duration := 2 * time.Second
+	if testing.Short() {
+		duration = 500 * time.Millisecond
+	}
+
+	runner := NewSoakRunner(SoakConfig{
+		Batcher: batcher.Config{
+			InitialBatchSize:  20,
+			MinBatchSize:      5,
+			MaxBatchSize:      200,
+			Timeout:           20 * time.Millisecond,
+			LoadCheckInterval: 50 * time.Millisecond,
+		},
+		Duration: duration,
+		Rate:     2000,
+		Disturbances: []Disturbance{
+			SineLoad(0.4, 0.3, time.Second),
+			Spikes(0.1, 0.5, 1),
+			Outages(400*time.Millisecond, 50*time.Millisecond),
+		},
+		SampleInterval: 20 * time.Millisecond,
+		MaxHeapBytes:   1 << 30,
+	})
+
+	report, err := runner.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	t.Log("\n" + report.String())
+
+	if !report.Passed() {
+		t.Errorf("Expected all invariants to hold, got %v", report.Violations)
+	}
+	if report.ItemsAdded == 0 || report.Batches == 0 {
+		t.Errorf("Expected items to flow, got %+v", report)
+	}
+	if report.ItemsFailed == 0 {
+		t.Error("Expected outages to fail some items")
+	}
+	if len(report.Samples) == 0 {
+		t.Error("Expected periodic samples")
+	}
+}
+
+func TestSoakRunner_DetectsViolations(t *testing.T) {
+	runner := NewSoakRunner(SoakConfig{
+		Batcher:        batcher.Config{InitialBatchSize: 10},
+		Duration:       100 * time.Millisecond,
+		SampleInterval: 10 * time.Millisecond,
+		MaxHeapBytes:   1, // any heap exceeds this
+	})
+
+	report, err := runner.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if report.Passed() {
+		t.Error("Expected a heap violation")
+	}
+	if !strings.Contains(report.String(), "FAIL") {
+		t.Errorf("Expected report to show FAIL, got %s", report)
+	}
+}
+
+func TestDisturbances(t *testing.T) {
+	sine := SineLoad(0.5, 0.2, 4*time.Second)
+	if l, _ := sine(time.Second); l < 0.69 || l > 0.71 {
+		t.Errorf("Expected sine peak 0.7 at a quarter period, got %v", l)
+	}
+
+	outage := Outages(time.Second, 100*time.Millisecond)
+	if _, down := outage(500 * time.Millisecond); down {
+		t.Error("Expected backend up mid-interval")
+	}
+	if _, down := outage(950 * time.Millisecond); !down {
+		t.Error("Expected backend down at the end of the interval")
+	}
+
+	spikes := Spikes(1, 0.3, 1)
+	if l, _ := spikes(0); l != 0.3 {
+		t.Errorf("Expected spike of 0.3, got %v", l)
+	}
+}
@@ -0,0 +1,96 @@
+package simulator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// ErrEmptyTrace is returned when replaying a trace without points
+var ErrEmptyTrace = errors.New("simulator: empty trace")
+
+// TracePoint is the load the backend applied from Elapsed on, measured
+// from its first batch
+type TracePoint struct {
+	Elapsed   time.Duration `json:"elapsed"`
+	CPULoad   float64       `json:"cpuLoad"`
+	ErrorRate float64       `json:"errorRate"`
+}
+
+// Trace is a recorded load signal. Replaying it drives a Backend through
+// the same load over time whatever batcher configuration is in front of
+// it, so configurations can be compared on identical input.
+type Trace struct {
+	// Pattern is the pattern the trace was recorded with
+	Pattern string `json:"pattern"`
+
+	// Seed seeds the processing-time jitter and error draws of the
+	// replaying backend
+	Seed int64 `json:"seed"`
+
+	// Points are ordered by Elapsed; consecutive points differ in load
+	Points []TracePoint `json:"points"`
+}
+
+// ReadTrace decodes a trace written by Trace.WriteJSON
+func ReadTrace(r io.Reader) (*Trace, error) {
+	var t Trace
+	if err := json.NewDecoder(r).Decode(&t); err != nil {
+		return nil, fmt.Errorf("simulator: read trace: %w", err)
+	}
+	if len(t.Points) == 0 {
+		return nil, ErrEmptyTrace
+	}
+	if !sort.SliceIsSorted(t.Points, func(i, j int) bool { return t.Points[i].Elapsed < t.Points[j].Elapsed }) {
+		return nil, fmt.Errorf("simulator: read trace: points out of order")
+	}
+	return &t, nil
+}
+
+// WriteJSON encodes the trace
+func (t *Trace) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(t)
+}
+
+// Duration returns the elapsed time of the last point
+func (t *Trace) Duration() time.Duration {
+	if len(t.Points) == 0 {
+		return 0
+	}
+	return t.Points[len(t.Points)-1].Elapsed
+}
+
+// At returns the point in effect at elapsed: the last one at or before
+// it, or the first one before the trace starts. The last point holds
+// after the trace ends.
+func (t *Trace) At(elapsed time.Duration) TracePoint {
+	i := sort.Search(len(t.Points), func(i int) bool { return t.Points[i].Elapsed > elapsed })
+	if i == 0 {
+		return t.Points[0]
+	}
+	return t.Points[i-1]
+}
+
+// --- Internal methods ---
+
+// record appends a point unless the load did not change
+func (t *Trace) record(p TracePoint) {
+	if n := len(t.Points); n > 0 {
+		last := t.Points[n-1]
+		if last.CPULoad == p.CPULoad && last.ErrorRate == p.ErrorRate {
+			return
+		}
+	}
+	t.Points = append(t.Points, p)
+}
+
+func (t *Trace) clone() *Trace {
+	c := *t
+	c.Points = append([]TracePoint(nil), t.Points...)
+	return &c
+}
@@ -0,0 +1,107 @@
+package simulator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTrace_At(t *testing.T) {
+	trace := &Trace{Points: []TracePoint{
+		{Elapsed: 10 * time.Millisecond, CPULoad: 0.2},
+		{Elapsed: 20 * time.Millisecond, CPULoad: 0.5},
+		{Elapsed: 30 * time.Millisecond, CPULoad: 0.9},
+	}}
+
+	tests := []struct {
+		elapsed time.Duration
+		want    float64
+	}{
+		{0, 0.2},
+		{10 * time.Millisecond, 0.2},
+		{25 * time.Millisecond, 0.5},
+		{30 * time.Millisecond, 0.9},
+		{time.Hour, 0.9},
+	}
+	for _, tt := range tests {
+		if got := trace.At(tt.elapsed).CPULoad; got != tt.want {
+			t.Errorf("At(%v): expected %v, got %v", tt.elapsed, tt.want, got)
+		}
+	}
+	if d := trace.Duration(); d != 30*time.Millisecond {
+		t.Errorf("Expected duration 30ms, got %v", d)
+	}
+}
+
+func TestTrace_RoundTrip(t *testing.T) {
+	trace := &Trace{Pattern: "spikes", Seed: 7, Points: []TracePoint{
+		{Elapsed: 0, CPULoad: 0.3, ErrorRate: 0.01},
+		{Elapsed: time.Second, CPULoad: 0.95, ErrorRate: 0.1},
+	}}
+
+	var buf bytes.Buffer
+	if err := trace.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON() failed: %v", err)
+	}
+	got, err := ReadTrace(&buf)
+	if err != nil {
+		t.Fatalf("ReadTrace() failed: %v", err)
+	}
+	if got.Pattern != "spikes" || got.Seed != 7 || len(got.Points) != 2 || got.Points[1] != trace.Points[1] {
+		t.Errorf("Expected %+v, got %+v", trace, got)
+	}
+
+	if _, err := ReadTrace(strings.NewReader(`{"points": []}`)); !errors.Is(err, ErrEmptyTrace) {
+		t.Errorf("Expected ErrEmptyTrace, got %v", err)
+	}
+	if _, err := ReadTrace(strings.NewReader(`{"points": [{"elapsed": 2}, {"elapsed": 1}]}`)); err == nil {
+		t.Error("Expected an error for points out of order")
+	}
+}
+
+func TestBackend_RecordAndReplay(t *testing.T) {
+	ctx := context.Background()
+	batch := []any{1}
+
+	recorder := NewBackend(PatternSpikes)
+	if recorder.Trace() != nil {
+		t.Error("Expected no trace before StartRecording")
+	}
+	recorder.StartRecording()
+	for i := 0; i < 20; i++ {
+		recorder.ProcessBatch(ctx, batch)
+	}
+	trace := recorder.Trace()
+	if trace == nil || len(trace.Points) == 0 || trace.Pattern != "spikes" {
+		t.Fatalf("Expected a recorded spikes trace, got %+v", trace)
+	}
+	for i := 1; i < len(trace.Points); i++ {
+		if trace.Points[i].Elapsed < trace.Points[i-1].Elapsed {
+			t.Fatalf("Points out of order at %d", i)
+		}
+	}
+
+	if _, err := NewReplayBackend(&Trace{}); !errors.Is(err, ErrEmptyTrace) {
+		t.Errorf("Expected ErrEmptyTrace, got %v", err)
+	}
+
+	replay, err := NewReplayBackend(&Trace{Points: []TracePoint{
+		{Elapsed: 0, CPULoad: 0.2, ErrorRate: 0},
+		{Elapsed: 50 * time.Millisecond, CPULoad: 0.9, ErrorRate: 0},
+	}})
+	if err != nil {
+		t.Fatalf("NewReplayBackend() failed: %v", err)
+	}
+	fb, _ := replay.ProcessBatch(ctx, batch)
+	if fb.CPULoad != 0.2 {
+		t.Errorf("Expected replayed load 0.2, got %v", fb.CPULoad)
+	}
+	time.Sleep(60 * time.Millisecond)
+	fb, _ = replay.ProcessBatch(ctx, batch)
+	if fb.CPULoad != 0.9 {
+		t.Errorf("Expected replayed load 0.9, got %v", fb.CPULoad)
+	}
+}
@@ -0,0 +1,109 @@
+package batcher
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// sojournBase is the upper bound of the first histogram bucket
+	sojournBase = 10 * time.Microsecond
+
+	// sojournBucketsPerDoubling sets the resolution: bucket bounds grow
+	// by 2^(1/4), so a percentile is overstated by at most 19%
+	sojournBucketsPerDoubling = 4
+
+	// sojournBuckets covers sojourn times up to about 168s; longer ones
+	// fall into the last bucket
+	sojournBuckets = 24 * sojournBucketsPerDoubling
+
+	// sojournWindow is how long an observation counts towards the
+	// percentiles: they cover the current and the previous window
+	sojournWindow = time.Minute
+)
+
+// sojournHistogram records how long items take from Add to handler
+// completion in log-scaled buckets, over a sliding window of one to two
+// sojournWindows
+type sojournHistogram struct {
+	mu       sync.Mutex
+	current  [sojournBuckets]int64
+	previous [sojournBuckets]int64
+	rotated  time.Time
+}
+
+// record adds the sojourn of every item of a completed batch and returns
+// the longest one, that of the oldest item
+func (h *sojournHistogram) record(now time.Time, items []pendingItem) time.Duration {
+	var longest time.Duration
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.rotateLocked(now)
+	for _, p := range items {
+		d := now.Sub(p.added)
+		h.current[sojournBucket(d)]++
+		longest = max(longest, d)
+	}
+	return longest
+}
+
+// percentile returns the upper bound of the bucket holding quantile q of
+// the recent observations, zero if there are none
+func (h *sojournHistogram) percentile(now time.Time, q float64) time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.rotateLocked(now)
+	var total int64
+	for i := range h.current {
+		total += h.current[i] + h.previous[i]
+	}
+	if total == 0 {
+		return 0
+	}
+
+	rank := int64(math.Ceil(q * float64(total)))
+	var seen int64
+	for i := range h.current {
+		seen += h.current[i] + h.previous[i]
+		if seen >= rank {
+			return sojournBound(i)
+		}
+	}
+	return sojournBound(sojournBuckets - 1)
+}
+
+// rotateLocked starts a new window once the current one is over,
+// discarding the previous one
+func (h *sojournHistogram) rotateLocked(now time.Time) {
+	if h.rotated.IsZero() {
+		h.rotated = now
+	}
+	switch elapsed := now.Sub(h.rotated); {
+	case elapsed >= 2*sojournWindow:
+		h.previous = [sojournBuckets]int64{}
+		h.current = [sojournBuckets]int64{}
+		h.rotated = now
+	case elapsed >= sojournWindow:
+		h.previous = h.current
+		h.current = [sojournBuckets]int64{}
+		h.rotated = now
+	}
+}
+
+// sojournBucket returns the bucket counting d
+func sojournBucket(d time.Duration) int {
+	if d <= sojournBase {
+		return 0
+	}
+	i := int(math.Ceil(sojournBucketsPerDoubling * math.Log2(float64(d)/float64(sojournBase))))
+	return min(i, sojournBuckets-1)
+}
+
+// sojournBound returns the upper bound of bucket i
+func sojournBound(i int) time.Duration {
+	return time.Duration(float64(sojournBase) * math.Exp2(float64(i)/sojournBucketsPerDoubling))
+}
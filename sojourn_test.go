@@ -0,0 +1,118 @@
+package batcher
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSojournHistogram_Percentiles(t *testing.T) {
+	var h sojournHistogram
+	now := time.Now()
+
+	if got := h.percentile(now, 0.5); got != 0 {
+		t.Errorf("Expected 0 without observations, got %v", got)
+	}
+
+	// 90 items of 1ms and 10 of 100ms
+	items := make([]pendingItem, 0, 100)
+	for i := 0; i < 90; i++ {
+		items = append(items, pendingItem{added: now.Add(-time.Millisecond)})
+	}
+	for i := 0; i < 10; i++ {
+		items = append(items, pendingItem{added: now.Add(-100 * time.Millisecond)})
+	}
+	if longest := h.record(now, items); longest != 100*time.Millisecond {
+		t.Errorf("Expected the longest sojourn to be 100ms, got %v", longest)
+	}
+
+	tests := []struct {
+		q    float64
+		want time.Duration
+	}{
+		{0.5, time.Millisecond},
+		{0.9, time.Millisecond},
+		{0.95, 100 * time.Millisecond},
+		{0.99, 100 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		got := h.percentile(now, tt.q)
+		if got < tt.want || float64(got) > float64(tt.want)*1.19 {
+			t.Errorf("Expected p%v within 19%% above %v, got %v", tt.q*100, tt.want, got)
+		}
+	}
+}
+
+func TestSojournHistogram_Window(t *testing.T) {
+	var h sojournHistogram
+	now := time.Now()
+	h.record(now, []pendingItem{{added: now.Add(-time.Second)}})
+
+	if got := h.percentile(now.Add(sojournWindow), 0.5); got < time.Second {
+		t.Errorf("Expected the previous window to count, got %v", got)
+	}
+	if got := h.percentile(now.Add(2*sojournWindow), 0.5); got != 0 {
+		t.Errorf("Expected observations to expire after two windows, got %v", got)
+	}
+}
+
+func TestSojournBucket_Bounds(t *testing.T) {
+	for _, d := range []time.Duration{0, sojournBase, time.Millisecond, 1234 * time.Microsecond, time.Second} {
+		i := sojournBucket(d)
+		if d > sojournBound(i) || (i > 0 && d <= sojournBound(i-1)) {
+			t.Errorf("Expected %v in (%v, %v]", d, sojournBound(i-1), sojournBound(i))
+		}
+	}
+	if i := sojournBucket(time.Hour); i != sojournBuckets-1 {
+		t.Errorf("Expected long sojourns in the last bucket, got %d", i)
+	}
+}
+
+func TestBatcher_SojournStats(t *testing.T) {
+	b, err := New(Config{
+		InitialBatchSize: 2,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			time.Sleep(20 * time.Millisecond)
+			return nil, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	ctx := context.Background()
+	b.Add(ctx, 1)
+	time.Sleep(30 * time.Millisecond)
+	b.Add(ctx, 2)
+
+	stats := b.GetStats()
+	if stats.SojournP50 < 20*time.Millisecond {
+		t.Errorf("Expected p50 to include the handler time, got %v", stats.SojournP50)
+	}
+	if stats.SojournP99 < 50*time.Millisecond {
+		t.Errorf("Expected p99 to include the buffering time, got %v", stats.SojournP99)
+	}
+}
+
+func TestSynthesizeSample_SojournLatency(t *testing.T) {
+	b, err := New(Config{
+		InitialBatchSize: 1,
+		LatencyTarget:    100 * time.Millisecond,
+		SojournLatency:   true,
+		PlainHandlerFunc: func(ctx context.Context, batch []any) error { return nil },
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	// A fast handler still scores high when items waited long to be flushed
+	s := b.synthesizeSample(10, 10*time.Millisecond, 200*time.Millisecond, nil)
+	if s.Score != 0.8 {
+		t.Errorf("Expected the sojourn to be scored, got %v", s.Score)
+	}
+	if s.Feedback.ProcessingTime != 10*time.Millisecond {
+		t.Errorf("Expected ProcessingTime to remain the handler time, got %v", s.Feedback.ProcessingTime)
+	}
+}
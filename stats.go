@@ -0,0 +1,57 @@
+package batcher
+
+import "time"
+
+// loadSnapshot holds the window-derived statistics, recomputed whenever
+// the feedback window or the memory pressure changes so that GetStats can
+// read them without the main lock
+type loadSnapshot struct {
+	average        float64
+	aggregated     float64
+	samples        int
+	memoryPressure float64
+}
+
+// refreshLoadLocked publishes a new loadSnapshot
+func (b *Batcher) refreshLoadLocked() {
+	snap := &loadSnapshot{
+		average:        b.averageLoadLocked(),
+		aggregated:     b.aggregateLoadLocked(),
+		samples:        len(b.recentFeedback),
+		memoryPressure: b.memoryPressure,
+	}
+	b.load.Store(snap)
+}
+
+// setBatchSizeLocked updates the batch size and its lock-free mirror
+func (b *Batcher) setBatchSizeLocked(n int) {
+	b.currentBatchSize = n
+	b.batchSize.Store(int64(n))
+}
+
+// handlerTimeSmoothing is the weight of the latest batch in the average
+// handler time
+const handlerTimeSmoothing = 0.2
+
+// recordHandlerTimeLocked folds a handler time into the average and its
+// lock-free mirror
+func (b *Batcher) recordHandlerTimeLocked(d time.Duration) {
+	if b.avgHandlerTime == 0 {
+		b.avgHandlerTime = float64(d)
+	} else {
+		b.avgHandlerTime += handlerTimeSmoothing * (float64(d) - b.avgHandlerTime)
+	}
+	b.avgHandlerTimeNs.Store(int64(b.avgHandlerTime))
+}
+
+// staleWindowLocked reports whether a batch is still being handled and
+// no feedback arrived since the previous adjustment, as happens when
+// handler calls take longer than LoadCheckInterval. Adjusting then would
+// act on the same window again.
+func (b *Batcher) staleWindowLocked() bool {
+	if len(b.flights) == 0 {
+		return false
+	}
+	n := len(b.recentFeedback)
+	return n == 0 || b.recentFeedback[n-1].RecordedAt.Before(b.lastAdjust)
+}
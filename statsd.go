@@ -0,0 +1,198 @@
+package batcher
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StatsDConfig holds the configuration for a StatsDEmitter
+type StatsDConfig struct {
+	// Addr is the host:port of the StatsD or DogStatsD agent
+	Addr string
+
+	// Namespace is prepended to every metric name, separated by a dot
+	Namespace string
+
+	// Tags are attached to every metric in DogStatsD format, on top of
+	// the batcher name as the "batcher" tag and the batcher Labels
+	Tags map[string]string
+
+	// Interval is how often metrics are pushed (default: 10s)
+	Interval time.Duration
+
+	// MaxPacketSize bounds the size of one UDP datagram (default: 1432)
+	MaxPacketSize int
+}
+
+// StatsDEmitter periodically pushes batcher statistics to a StatsD or
+// DogStatsD agent over UDP. Gauges report the current state, counters
+// the change since the previous push and the timing the average handler
+// latency over that period. The sojourn percentiles of Stats are sent as
+// gauges in milliseconds.
+type StatsDEmitter struct {
+	b    *Batcher
+	cfg  StatsDConfig
+	conn net.Conn
+	tags string
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+	last Stats
+}
+
+// NewStatsDEmitter starts pushing the statistics of b to cfg.Addr
+func NewStatsDEmitter(b *Batcher, cfg StatsDConfig) (*StatsDEmitter, error) {
+	if b == nil || cfg.Addr == "" {
+		return nil, ErrInvalidConfig
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 10 * time.Second
+	}
+	if cfg.MaxPacketSize <= 0 {
+		cfg.MaxPacketSize = 1432
+	}
+
+	conn, err := net.Dial("udp", cfg.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("batcher: statsd: %w", err)
+	}
+
+	tags := make(map[string]string, len(cfg.Tags)+len(b.cfg.Labels)+1)
+	if b.cfg.Name != "" {
+		tags["batcher"] = b.cfg.Name
+	}
+	for k, v := range b.cfg.Labels {
+		tags[k] = v
+	}
+	for k, v := range cfg.Tags {
+		tags[k] = v
+	}
+
+	e := &StatsDEmitter{
+		b:    b,
+		cfg:  cfg,
+		conn: conn,
+		tags: formatTags(tags),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+		last: b.GetStats(),
+	}
+	go e.loop()
+	return e, nil
+}
+
+// Close pushes a final set of metrics and stops the emitter
+func (e *StatsDEmitter) Close() error {
+	var err error
+	e.once.Do(func() {
+		close(e.stop)
+		<-e.done
+		err = errors.Join(e.push(), e.conn.Close())
+	})
+	return err
+}
+
+// --- Internal methods ---
+
+func (e *StatsDEmitter) loop() {
+	defer close(e.done)
+
+	ticker := time.NewTicker(e.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			_ = e.push()
+		case <-e.stop:
+			return
+		}
+	}
+}
+
+// push sends one round of metrics. UDP is fire-and-forget, so the only
+// errors reported are local write failures.
+func (e *StatsDEmitter) push() error {
+	s := e.b.GetStats()
+	prev := e.last
+	e.last = s
+	mismatch := 0.0
+	if s.IntervalMismatch {
+		mismatch = 1
+	}
+
+	lines := []string{
+		e.line("batch_size", float64(s.CurrentBatchSize), "g"),
+		e.line("pending_items", float64(s.PendingItems), "g"),
+		e.line("in_flight_items", float64(s.InFlightItems), "g"),
+		e.line("load_score", s.AggregatedLoadScore, "g"),
+		e.line("batches", float64(s.Batches-prev.Batches), "c"),
+		e.line("errors", float64(s.HandlerErrors-prev.HandlerErrors), "c"),
+		e.line("shed_items", float64(s.ShedItems-prev.ShedItems), "c"),
+		e.line("panics", float64(s.Panics-prev.Panics), "c"),
+		e.line("skipped_adjustments", float64(s.SkippedAdjustments-prev.SkippedAdjustments), "c"),
+		e.line("interval_mismatch", mismatch, "g"),
+	}
+	if n := s.Batches - prev.Batches; n > 0 {
+		avg := (s.HandlerTime - prev.HandlerTime) / time.Duration(n)
+		lines = append(lines, e.line("flush_latency", float64(avg.Milliseconds()), "ms"))
+	}
+	if s.SojournP50 > 0 {
+		lines = append(lines,
+			e.line("sojourn.p50", float64(s.SojournP50.Milliseconds()), "g"),
+			e.line("sojourn.p95", float64(s.SojournP95.Milliseconds()), "g"),
+			e.line("sojourn.p99", float64(s.SojournP99.Milliseconds()), "g"),
+		)
+	}
+
+	var errs []error
+	var packet strings.Builder
+	for _, l := range lines {
+		if packet.Len() > 0 && packet.Len()+1+len(l) > e.cfg.MaxPacketSize {
+			if _, err := e.conn.Write([]byte(packet.String())); err != nil {
+				errs = append(errs, err)
+			}
+			packet.Reset()
+		}
+		if packet.Len() > 0 {
+			packet.WriteByte('\n')
+		}
+		packet.WriteString(l)
+	}
+	if packet.Len() > 0 {
+		if _, err := e.conn.Write([]byte(packet.String())); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (e *StatsDEmitter) line(name string, value float64, kind string) string {
+	if e.cfg.Namespace != "" {
+		name = e.cfg.Namespace + "." + name
+	}
+	return fmt.Sprintf("%s:%g|%s%s", name, value, kind, e.tags)
+}
+
+// formatTags renders tags in DogStatsD format, sorted for stable output
+func formatTags(tags map[string]string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	pairs := make([]string, 0, len(tags))
+	for k, v := range tags {
+		if v == "" {
+			pairs = append(pairs, k)
+		} else {
+			pairs = append(pairs, k+":"+v)
+		}
+	}
+	sort.Strings(pairs)
+	return "|#" + strings.Join(pairs, ",")
+}
@@ -0,0 +1,157 @@
+package batcher
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStatsDEmitter(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("UDP unavailable: %v", err)
+	}
+	defer conn.Close()
+
+	b, err := New(Config{
+		InitialBatchSize: 2,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			return &LoadFeedback{CPULoad: 0.5}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	e, err := NewStatsDEmitter(b, StatsDConfig{
+		Addr:      conn.LocalAddr().String(),
+		Namespace: "orders",
+		Tags:      map[string]string{"env": "test", "region": "eu"},
+		Interval:  time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("NewStatsDEmitter() failed: %v", err)
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		b.Add(ctx, i)
+	}
+	if err := e.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+
+	buf := make([]byte, 2048)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("No metrics received: %v", err)
+	}
+	packet := string(buf[:n])
+
+	for _, want := range []string{
+		"orders.batch_size:2|g|#env:test,region:eu",
+		"orders.pending_items:1|g|#env:test,region:eu",
+		"orders.batches:2|c|#env:test,region:eu",
+		"orders.errors:0|c|#env:test,region:eu",
+		"orders.flush_latency:",
+		"orders.sojourn.p99:",
+	} {
+		if !strings.Contains(packet, want) {
+			t.Errorf("Expected %q in packet:\n%s", want, packet)
+		}
+	}
+}
+
+func TestStatsDEmitter_SplitsPackets(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("UDP unavailable: %v", err)
+	}
+	defer conn.Close()
+
+	b, err := New(Config{
+		InitialBatchSize: 2,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			return nil, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	e, err := NewStatsDEmitter(b, StatsDConfig{
+		Addr:          conn.LocalAddr().String(),
+		Interval:      time.Hour,
+		MaxPacketSize: 40,
+	})
+	if err != nil {
+		t.Fatalf("NewStatsDEmitter() failed: %v", err)
+	}
+	e.Close()
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, 2048)
+	packets := 0
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			break
+		}
+		if n > 40 && strings.Contains(string(buf[:n]), "\n") {
+			t.Errorf("Packet of %d bytes exceeds the limit: %q", n, buf[:n])
+		}
+		packets++
+		conn.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	}
+	if packets < 2 {
+		t.Errorf("Expected metrics split over several packets, got %d", packets)
+	}
+}
+
+func TestNewStatsDEmitter_InvalidConfig(t *testing.T) {
+	if _, err := NewStatsDEmitter(nil, StatsDConfig{Addr: "127.0.0.1:8125"}); err != ErrInvalidConfig {
+		t.Errorf("Expected ErrInvalidConfig, got %v", err)
+	}
+}
+
+func TestStatsDEmitter_NameAndLabelTags(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("UDP unavailable: %v", err)
+	}
+	defer conn.Close()
+
+	b, err := New(Config{
+		Name:             "orders",
+		Labels:           map[string]string{"tenant": "acme"},
+		InitialBatchSize: 2,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			return nil, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	e, err := NewStatsDEmitter(b, StatsDConfig{Addr: conn.LocalAddr().String(), Interval: time.Hour})
+	if err != nil {
+		t.Fatalf("NewStatsDEmitter() failed: %v", err)
+	}
+	e.Close()
+
+	buf := make([]byte, 2048)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("No metrics received: %v", err)
+	}
+	if !strings.Contains(string(buf[:n]), "batch_size:2|g|#batcher:orders,tenant:acme") {
+		t.Errorf("Expected the batcher name and labels as tags, got:\n%s", buf[:n])
+	}
+}
@@ -0,0 +1,479 @@
+package batcher
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// FeedbackSample is a recorded LoadFeedback together with the batch it described
+type FeedbackSample struct {
+	// Feedback is the feedback returned by the handler
+	Feedback LoadFeedback
+
+	// BatchSize is the number of items in the batch
+	BatchSize int
+
+	// RecordedAt is when the feedback was recorded
+	RecordedAt time.Time
+
+	// Weight is the number of handler feedbacks this sample stands for.
+	// It is above 1 when feedback sampling skipped some.
+	Weight float64
+
+	// Score is the load score of the sample, computed when it was
+	// recorded. Synthesized samples carry a score without feedback.
+	Score float64
+
+	// Sojourn is the time the oldest item of the batch took from Add to
+	// handler completion, buffering included
+	Sojourn time.Duration
+}
+
+// AdjustmentInput is the state handed to an AdjustmentStrategy on every
+// adjustment cycle
+type AdjustmentInput struct {
+	// CurrentBatchSize is the batch size in effect
+	CurrentBatchSize int
+
+	// MinBatchSize and MaxBatchSize are the configured bounds. The
+	// batcher clamps the returned size to them.
+	MinBatchSize int
+	MaxBatchSize int
+
+	// AdjustmentFactor is the configured adjustment factor
+	AdjustmentFactor float64
+
+	// GrowFactor and ShrinkFactor are the configured factors for growing
+	// and shrinking. Zero means AdjustmentFactor.
+	GrowFactor   float64
+	ShrinkFactor float64
+
+	// LoadScore is the aggregated load score of the feedback window
+	LoadScore float64
+
+	// Samples is the recent feedback window, oldest first.
+	// It must be treated as read-only and not retained.
+	Samples []FeedbackSample
+
+	// ItemsAdded is the number of items accepted since the previous cycle
+	ItemsAdded int
+
+	// Elapsed is the time since the previous cycle
+	Elapsed time.Duration
+
+	// CycleStart is when the previous cycle ran. Samples recorded after it
+	// are new in this cycle; the older ones were seen by a previous one.
+	CycleStart time.Time
+}
+
+// AdjustmentStrategy decides the next batch size from recent feedback.
+// NextBatchSize is called by the adjuster goroutine and by AdjustNow on
+// the caller's goroutine, always under the batcher lock, so calls never
+// overlap.
+type AdjustmentStrategy interface {
+	NextBatchSize(in AdjustmentInput) int
+}
+
+// EstimateReporter is implemented by strategies that expose their internal
+// estimates for observability. They are surfaced in Stats.StrategyEstimates.
+// Estimates may be called concurrently with NextBatchSize.
+type EstimateReporter interface {
+	Estimates() map[string]float64
+}
+
+// ThresholdStrategy steps the batch size by GrowFactor or ShrinkFactor when
+// the load score leaves the [Low, High] band. This is the default strategy.
+type ThresholdStrategy struct {
+	// Low is the load score below which the batch size grows (default: 0.25)
+	Low float64
+
+	// High is the load score above which the batch size shrinks (default: 0.55)
+	High float64
+}
+
+// NextBatchSize implements AdjustmentStrategy
+func (s *ThresholdStrategy) NextBatchSize(in AdjustmentInput) int {
+	low, high := s.Low, s.High
+	if low <= 0 {
+		low = 0.25
+	}
+	if high <= 0 {
+		high = 0.55
+	}
+
+	// Adjust batch size based on load
+	// Low load (< Low) -> increase batch size
+	// Medium load (Low - High) -> keep current size
+	// High load (> High) -> decrease batch size
+
+	newSize := float64(in.CurrentBatchSize)
+	grow, shrink := in.GrowFactor, in.ShrinkFactor
+	if grow == 0 {
+		grow = in.AdjustmentFactor
+	}
+	if shrink == 0 {
+		shrink = in.AdjustmentFactor
+	}
+
+	if in.LoadScore < low {
+		// Backend is idle, increase batch size
+		newSize += math.Floor(math.Max(float64(in.CurrentBatchSize)*grow, 1))
+	} else if in.LoadScore > high {
+		// Backend is overloaded, decrease batch size
+		newSize -= math.Floor(math.Max(float64(in.CurrentBatchSize)*shrink, 1))
+	}
+
+	return boundedSize(newSize, in)
+}
+
+// boundedSize converts a size computed in floating point to an int within
+// [MinBatchSize, MaxBatchSize], so that huge or NaN intermediates cannot
+// overflow the conversion and wrap around
+func boundedSize(size float64, in AdjustmentInput) int {
+	if math.IsNaN(size) {
+		return in.CurrentBatchSize
+	}
+	if size <= float64(in.MinBatchSize) {
+		return in.MinBatchSize
+	}
+	if in.MaxBatchSize > 0 && size >= float64(in.MaxBatchSize) {
+		return in.MaxBatchSize
+	}
+	if size >= math.MaxInt {
+		return math.MaxInt
+	}
+	return int(size)
+}
+
+// QueueingStrategy sizes batches analytically from a queueing model of the
+// backend instead of stepping on thresholds.
+//
+// The handler time of a batch of n items is modelled as T(n) = a + b*n,
+// where a is the fixed per-call overhead and b the per-item cost, fitted by
+// least squares over the feedback window. With an item arrival rate λ the
+// backend utilization is ρ(n) = λ*T(n)/n = λ*(a/n + b), so the smallest
+// batch size that keeps the backend at TargetUtilization is
+// n = λ*a / (TargetUtilization - λ*b).
+type QueueingStrategy struct {
+	// TargetUtilization is the fraction of time the backend should spend
+	// handling batches (default: 0.7)
+	TargetUtilization float64
+
+	// Smoothing is the EWMA weight given to the newest arrival rate
+	// measurement (default: 0.5)
+	Smoothing float64
+
+	mu          sync.Mutex
+	arrivalRate float64
+	serviceRate float64
+	utilization float64
+	queueLength float64
+	target      float64
+	overhead    time.Duration
+	perItem     time.Duration
+}
+
+// NextBatchSize implements AdjustmentStrategy
+func (s *QueueingStrategy) NextBatchSize(in AdjustmentInput) int {
+	target := s.TargetUtilization
+	if target <= 0 || target >= 1 {
+		target = 0.7
+	}
+	alpha := s.Smoothing
+	if alpha <= 0 || alpha > 1 {
+		alpha = 0.5
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.target = target
+
+	// Arrival rate (items/second), smoothed
+	if in.Elapsed > 0 {
+		rate := float64(in.ItemsAdded) / in.Elapsed.Seconds()
+		if s.arrivalRate == 0 {
+			s.arrivalRate = rate
+		} else {
+			s.arrivalRate = alpha*rate + (1-alpha)*s.arrivalRate
+		}
+	}
+
+	a, b, ok := fitServiceTime(in.Samples)
+	if !ok {
+		return in.CurrentBatchSize
+	}
+	s.overhead = time.Duration(a * float64(time.Second))
+	s.perItem = time.Duration(b * float64(time.Second))
+
+	n := float64(in.CurrentBatchSize)
+	serviceTime := a + b*n
+	if serviceTime > 0 {
+		s.serviceRate = n / serviceTime
+	}
+	lambda := s.arrivalRate
+	if s.serviceRate > 0 {
+		s.utilization = lambda / s.serviceRate
+	}
+	// Little's Law: items in service = arrival rate * time in service
+	s.queueLength = lambda * serviceTime
+
+	if lambda <= 0 {
+		return in.CurrentBatchSize
+	}
+
+	headroom := target - lambda*b
+	if headroom <= 0 {
+		// Even infinitely large batches cannot keep up; amortize as much
+		// of the overhead as allowed
+		return in.MaxBatchSize
+	}
+
+	return boundedSize(math.Ceil(lambda*a/headroom), in)
+}
+
+// Estimates implements EstimateReporter
+func (s *QueueingStrategy) Estimates() map[string]float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return map[string]float64{
+		"arrival_rate":       s.arrivalRate,
+		"service_rate":       s.serviceRate,
+		"utilization":        s.utilization,
+		"queue_length":       s.queueLength,
+		"overhead_seconds":   s.overhead.Seconds(),
+		"per_item_seconds":   s.perItem.Seconds(),
+		"target_utilization": s.target,
+	}
+}
+
+// fitServiceTime fits ProcessingTime = a + b*BatchSize (in seconds) over the
+// samples. When every sample has the same size the cost is attributed
+// entirely to per-call overhead.
+func fitServiceTime(samples []FeedbackSample) (a, b float64, ok bool) {
+	var n, sumX, sumY, sumXX, sumXY float64
+	for _, s := range samples {
+		if s.BatchSize <= 0 || s.Feedback.ProcessingTime <= 0 {
+			continue
+		}
+		x := float64(s.BatchSize)
+		y := s.Feedback.ProcessingTime.Seconds()
+		n++
+		sumX += x
+		sumY += y
+		sumXX += x * x
+		sumXY += x * y
+	}
+	if n == 0 {
+		return 0, 0, false
+	}
+
+	meanX, meanY := sumX/n, sumY/n
+	variance := sumXX/n - meanX*meanX
+	if variance <= 1e-9 {
+		return meanY, 0, true
+	}
+
+	b = (sumXY/n - meanX*meanY) / variance
+	a = meanY - b*meanX
+	if b < 0 {
+		b = 0
+		a = meanY
+	}
+	if a < 0 {
+		a = 0
+		b = meanY / meanX
+	}
+	return a, b, true
+}
+
+// PIDStrategy drives the load score towards Setpoint with a PID controller,
+// giving smoother convergence than threshold stepping. The controller output
+// is the relative change applied to the current batch size, so an output of
+// 0.1 grows the batch by 10%.
+type PIDStrategy struct {
+	// Kp, Ki and Kd are the proportional, integral and derivative gains
+	// (defaults when all are zero: 0.5, 0.1, 0.05)
+	Kp, Ki, Kd float64
+
+	// Setpoint is the target load score (default: 0.4)
+	Setpoint float64
+
+	// OutputLimit bounds the relative change per cycle (default: 0.5)
+	OutputLimit float64
+
+	// IntegralLimit bounds the accumulated integral term (default: 1.0)
+	IntegralLimit float64
+
+	mu         sync.Mutex
+	integral   float64
+	prevError  float64
+	derivative float64
+	lastError  float64
+	lastOutput float64
+	started    bool
+}
+
+// NextBatchSize implements AdjustmentStrategy
+func (s *PIDStrategy) NextBatchSize(in AdjustmentInput) int {
+	kp, ki, kd := s.Kp, s.Ki, s.Kd
+	if kp == 0 && ki == 0 && kd == 0 {
+		kp, ki, kd = 0.5, 0.1, 0.05
+	}
+	setpoint := s.Setpoint
+	if setpoint <= 0 {
+		setpoint = 0.4
+	}
+	outLimit := s.OutputLimit
+	if outLimit <= 0 {
+		outLimit = 0.5
+	}
+	intLimit := s.IntegralLimit
+	if intLimit <= 0 {
+		intLimit = 1.0
+	}
+
+	dt := in.Elapsed.Seconds()
+	if dt <= 0 {
+		dt = 1
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Positive error means spare capacity, so the batch should grow
+	e := setpoint - in.LoadScore
+
+	s.derivative = 0
+	if s.started {
+		s.derivative = (e - s.prevError) / dt
+	}
+	s.prevError = e
+	s.started = true
+
+	integral := s.integral + e*dt
+	integral = math.Max(-intLimit, math.Min(integral, intLimit))
+
+	out := kp*e + ki*integral + kd*s.derivative
+	clamped := math.Max(-outLimit, math.Min(out, outLimit))
+
+	// Anti-windup: stop integrating while the output or the batch size
+	// is saturated in the direction the error pushes
+	atMax := in.MaxBatchSize > 0 && in.CurrentBatchSize >= in.MaxBatchSize
+	atMin := in.CurrentBatchSize <= in.MinBatchSize
+	saturated := (clamped != out) ||
+		(e > 0 && atMax) ||
+		(e < 0 && atMin)
+	if !saturated {
+		s.integral = integral
+	}
+
+	s.lastError = e
+	s.lastOutput = clamped
+
+	delta := float64(in.CurrentBatchSize) * clamped
+	if delta > 0 && delta < 1 {
+		delta = 1
+	} else if delta < 0 && delta > -1 {
+		delta = -1
+	}
+	if e == 0 {
+		delta = 0
+	}
+
+	return boundedSize(float64(in.CurrentBatchSize)+math.Round(delta), in)
+}
+
+// Estimates implements EstimateReporter
+func (s *PIDStrategy) Estimates() map[string]float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return map[string]float64{
+		"error":      s.lastError,
+		"integral":   s.integral,
+		"derivative": s.derivative,
+		"output":     s.lastOutput,
+	}
+}
+
+// AIMDStrategy grows the batch size additively while the backend is calm and
+// shrinks it multiplicatively on overload or errors, the way TCP congestion
+// control does. This reacts to sudden outages much faster than symmetric
+// steps while still probing for capacity gently.
+type AIMDStrategy struct {
+	// AdditiveStep is the number of items added per calm cycle (default: 1)
+	AdditiveStep int
+
+	// DecreaseFactor multiplies the batch size on overload (default: 0.5)
+	DecreaseFactor float64
+
+	// OverloadThreshold is the load score above which the batch shrinks
+	// (default: 0.7)
+	OverloadThreshold float64
+
+	// CalmThreshold is the load score below which the batch grows
+	// (default: 0.5)
+	CalmThreshold float64
+
+	// ErrorThreshold is the error rate of any batch since the previous
+	// cycle above which the batch shrinks (default: 0.05)
+	ErrorThreshold float64
+}
+
+// NextBatchSize implements AdjustmentStrategy
+func (s *AIMDStrategy) NextBatchSize(in AdjustmentInput) int {
+	step := s.AdditiveStep
+	if step <= 0 {
+		step = 1
+	}
+	factor := s.DecreaseFactor
+	if factor <= 0 || factor >= 1 {
+		factor = 0.5
+	}
+	overload := s.OverloadThreshold
+	if overload <= 0 {
+		overload = 0.7
+	}
+	calm := s.CalmThreshold
+	if calm <= 0 {
+		calm = 0.5
+	}
+	errThreshold := s.ErrorThreshold
+	if errThreshold <= 0 {
+		errThreshold = 0.05
+	}
+
+	if in.LoadScore > overload || recentErrorRate(in) > errThreshold {
+		return int(float64(in.CurrentBatchSize) * factor)
+	}
+	if in.LoadScore < calm {
+		return boundedSize(float64(in.CurrentBatchSize)+float64(step), in)
+	}
+	return in.CurrentBatchSize
+}
+
+// recentErrorRate returns the highest error rate among the samples recorded
+// since the previous adjustment cycle, so that a batch that failed is acted
+// on once rather than in every cycle until newer feedback arrives. Without
+// CycleStart, as in an input built by hand, the cycle is taken to span
+// Elapsed up to the newest sample.
+func recentErrorRate(in AdjustmentInput) float64 {
+	if len(in.Samples) == 0 {
+		return 0
+	}
+	since := in.CycleStart
+	if since.IsZero() && in.Elapsed > 0 {
+		since = in.Samples[len(in.Samples)-1].RecordedAt.Add(-in.Elapsed)
+	}
+	worst := 0.0
+	for _, s := range in.Samples {
+		if !since.IsZero() && !s.RecordedAt.After(since) {
+			continue
+		}
+		worst = math.Max(worst, s.Feedback.ErrorRate)
+	}
+	return worst
+}
@@ -0,0 +1,327 @@
+package batcher
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestThresholdStrategy(t *testing.T) {
+	s := &ThresholdStrategy{}
+	tests := []struct {
+		name string
+		load float64
+		want int
+	}{
+		{"low load grows", 0.1, 24},
+		{"medium load holds", 0.4, 20},
+		{"high load shrinks", 0.8, 16},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := s.NextBatchSize(AdjustmentInput{
+				CurrentBatchSize: 20,
+				AdjustmentFactor: 0.2,
+				LoadScore:        tt.load,
+			})
+			if got != tt.want {
+				t.Errorf("NextBatchSize() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestThresholdStrategy_Asymmetric(t *testing.T) {
+	s := &ThresholdStrategy{}
+	in := AdjustmentInput{
+		CurrentBatchSize: 100,
+		AdjustmentFactor: 0.2,
+		GrowFactor:       0.1,
+		ShrinkFactor:     0.5,
+	}
+
+	in.LoadScore = 0.1
+	if got := s.NextBatchSize(in); got != 110 {
+		t.Errorf("Expected growth by GrowFactor to 110, got %d", got)
+	}
+	in.LoadScore = 0.8
+	if got := s.NextBatchSize(in); got != 50 {
+		t.Errorf("Expected shrinking by ShrinkFactor to 50, got %d", got)
+	}
+
+	// Unset factors fall back to AdjustmentFactor
+	in.ShrinkFactor = 0
+	if got := s.NextBatchSize(in); got != 80 {
+		t.Errorf("Expected shrinking by AdjustmentFactor to 80, got %d", got)
+	}
+}
+
+func TestFitServiceTime(t *testing.T) {
+	samples := []FeedbackSample{
+		{BatchSize: 10, Feedback: LoadFeedback{ProcessingTime: 20 * time.Millisecond}},
+		{BatchSize: 20, Feedback: LoadFeedback{ProcessingTime: 30 * time.Millisecond}},
+		{BatchSize: 30, Feedback: LoadFeedback{ProcessingTime: 40 * time.Millisecond}},
+	}
+
+	a, b, ok := fitServiceTime(samples)
+	if !ok {
+		t.Fatal("fitServiceTime() returned !ok")
+	}
+	if a < 0.0099 || a > 0.0101 {
+		t.Errorf("Overhead = %v, want ~0.01", a)
+	}
+	if b < 0.00099 || b > 0.00101 {
+		t.Errorf("Per-item cost = %v, want ~0.001", b)
+	}
+
+	if _, _, ok := fitServiceTime(nil); ok {
+		t.Error("fitServiceTime(nil) should return !ok")
+	}
+}
+
+func TestQueueingStrategy(t *testing.T) {
+	s := &QueueingStrategy{TargetUtilization: 0.7}
+	samples := []FeedbackSample{
+		{BatchSize: 10, Feedback: LoadFeedback{ProcessingTime: 20 * time.Millisecond}},
+		{BatchSize: 20, Feedback: LoadFeedback{ProcessingTime: 30 * time.Millisecond}},
+	}
+
+	// λ = 100 items/s, a = 10ms, b = 1ms -> n = 100*0.01 / (0.7 - 0.1) ≈ 1.67
+	got := s.NextBatchSize(AdjustmentInput{
+		CurrentBatchSize: 20,
+		MinBatchSize:     1,
+		MaxBatchSize:     100,
+		Samples:          samples,
+		ItemsAdded:       100,
+		Elapsed:          time.Second,
+	})
+	if got != 2 {
+		t.Errorf("NextBatchSize() = %d, want 2", got)
+	}
+
+	est := s.Estimates()
+	if est["arrival_rate"] != 100 {
+		t.Errorf("arrival_rate = %v, want 100", est["arrival_rate"])
+	}
+	if est["service_rate"] <= 0 || est["utilization"] <= 0 {
+		t.Errorf("Expected positive service rate and utilization, got %v", est)
+	}
+
+	// Arrivals faster than per-item capacity allows -> max size
+	got = s.NextBatchSize(AdjustmentInput{
+		CurrentBatchSize: 20,
+		MaxBatchSize:     100,
+		Samples:          samples,
+		ItemsAdded:       10000,
+		Elapsed:          time.Second,
+	})
+	if got != 100 {
+		t.Errorf("NextBatchSize() under overload = %d, want 100", got)
+	}
+}
+
+func TestBatcher_StrategyEstimatesInStats(t *testing.T) {
+	b, err := New(Config{
+		InitialBatchSize:   5,
+		MaxBatchSize:       50,
+		LoadCheckInterval:  50 * time.Millisecond,
+		AdjustmentStrategy: &QueueingStrategy{},
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			return &LoadFeedback{ProcessingTime: time.Duration(len(batch)) * time.Millisecond}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	ctx := context.Background()
+	for i := 0; i < 50; i++ {
+		b.Add(ctx, i)
+	}
+	time.Sleep(120 * time.Millisecond)
+
+	stats := b.GetStats()
+	if stats.StrategyEstimates == nil {
+		t.Fatal("Expected strategy estimates in stats")
+	}
+	if _, ok := stats.StrategyEstimates["arrival_rate"]; !ok {
+		t.Errorf("arrival_rate missing from estimates: %v", stats.StrategyEstimates)
+	}
+}
+
+func TestPIDStrategy_Direction(t *testing.T) {
+	in := AdjustmentInput{
+		CurrentBatchSize: 50,
+		MinBatchSize:     1,
+		MaxBatchSize:     100,
+		Elapsed:          time.Second,
+	}
+
+	low := &PIDStrategy{}
+	in.LoadScore = 0.1
+	if got := low.NextBatchSize(in); got <= 50 {
+		t.Errorf("Expected growth under low load, got %d", got)
+	}
+
+	high := &PIDStrategy{}
+	in.LoadScore = 0.9
+	if got := high.NextBatchSize(in); got >= 50 {
+		t.Errorf("Expected shrink under high load, got %d", got)
+	}
+}
+
+func TestPIDStrategy_OutputClamp(t *testing.T) {
+	s := &PIDStrategy{Kp: 100, OutputLimit: 0.25}
+	got := s.NextBatchSize(AdjustmentInput{
+		CurrentBatchSize: 40,
+		MinBatchSize:     1,
+		MaxBatchSize:     1000,
+		LoadScore:        0.0,
+		Elapsed:          time.Second,
+	})
+	if got != 50 {
+		t.Errorf("NextBatchSize() = %d, want 50 (clamped to +25%%)", got)
+	}
+}
+
+func TestPIDStrategy_AntiWindup(t *testing.T) {
+	s := &PIDStrategy{Kp: 0.1, Ki: 1}
+	in := AdjustmentInput{
+		CurrentBatchSize: 100,
+		MinBatchSize:     1,
+		MaxBatchSize:     100,
+		LoadScore:        0.0,
+		Elapsed:          time.Second,
+	}
+
+	// Pinned at max under low load: the integral must not accumulate
+	for i := 0; i < 20; i++ {
+		s.NextBatchSize(in)
+	}
+	if integral := s.Estimates()["integral"]; integral != 0 {
+		t.Errorf("Integral wound up to %v while saturated at max", integral)
+	}
+}
+
+func TestPIDStrategy_Converges(t *testing.T) {
+	s := &PIDStrategy{Setpoint: 0.5}
+	size := 10
+
+	// Load proportional to batch size: setpoint is reached at 50 items
+	for i := 0; i < 60; i++ {
+		size = s.NextBatchSize(AdjustmentInput{
+			CurrentBatchSize: size,
+			MinBatchSize:     1,
+			MaxBatchSize:     200,
+			LoadScore:        float64(size) / 100,
+			Elapsed:          time.Second,
+		})
+		size = max(1, min(size, 200))
+	}
+
+	if size < 45 || size > 55 {
+		t.Errorf("Expected size to converge near 50, got %d", size)
+	}
+}
+
+func TestAIMDStrategy(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name    string
+		load    float64
+		samples []FeedbackSample
+		want    int
+	}{
+		{"calm grows additively", 0.2, nil, 41},
+		{"medium holds", 0.6, nil, 40},
+		{"overload halves", 0.9, nil, 20},
+		{
+			name: "errors halve",
+			load: 0.2,
+			samples: []FeedbackSample{
+				{RecordedAt: now, Feedback: LoadFeedback{ErrorRate: 0.5}},
+			},
+			want: 20,
+		},
+		{
+			name: "old errors ignored",
+			load: 0.2,
+			samples: []FeedbackSample{
+				{RecordedAt: now.Add(-time.Minute), Feedback: LoadFeedback{ErrorRate: 0.5}},
+				{RecordedAt: now},
+			},
+			want: 41,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &AIMDStrategy{}
+			got := s.NextBatchSize(AdjustmentInput{
+				CurrentBatchSize: 40,
+				LoadScore:        tt.load,
+				Samples:          tt.samples,
+				Elapsed:          time.Second,
+			})
+			if got != tt.want {
+				t.Errorf("NextBatchSize() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAIMDStrategy_ErrorsCountOnce(t *testing.T) {
+	start := time.Now()
+	samples := []FeedbackSample{
+		{RecordedAt: start.Add(-time.Second)},
+		{RecordedAt: start.Add(500 * time.Millisecond), Feedback: LoadFeedback{ErrorRate: 0.5}},
+	}
+	s := &AIMDStrategy{}
+
+	// The failed batch is new in the first cycle
+	size := s.NextBatchSize(AdjustmentInput{
+		CurrentBatchSize: 40,
+		LoadScore:        0.2,
+		Samples:          samples,
+		Elapsed:          time.Second,
+		CycleStart:       start,
+	})
+	if size != 20 {
+		t.Fatalf("Expected the error to halve the batch size, got %d", size)
+	}
+
+	// No feedback arrived since: the next cycle sees the same window
+	size = s.NextBatchSize(AdjustmentInput{
+		CurrentBatchSize: size,
+		LoadScore:        0.2,
+		Samples:          samples,
+		Elapsed:          time.Second,
+		CycleStart:       start.Add(time.Second),
+	})
+	if size != 21 {
+		t.Errorf("Expected the error counted once and the batch to grow, got %d", size)
+	}
+}
+
+func TestStrategies_NoOverflowAtHugeSizes(t *testing.T) {
+	in := AdjustmentInput{
+		CurrentBatchSize: math.MaxInt - 10,
+		MinBatchSize:     1,
+		MaxBatchSize:     math.MaxInt,
+		AdjustmentFactor: 1e300,
+		LoadScore:        0,
+	}
+
+	strategies := map[string]AdjustmentStrategy{
+		"threshold": &ThresholdStrategy{},
+		"aimd":      &AIMDStrategy{AdditiveStep: math.MaxInt},
+	}
+	for name, s := range strategies {
+		if got := s.NextBatchSize(in); got < in.CurrentBatchSize {
+			t.Errorf("%s: size wrapped around to %d while growing", name, got)
+		}
+	}
+}
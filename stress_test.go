@@ -0,0 +1,211 @@
+//go:build stress
+
+package batcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// The stress tests run millions of Adds against random interleavings of
+// the other operations and check, from the handler side, that every
+// accepted item is delivered exactly once and every batch respects the
+// size bounds. They are slow and only built with the stress tag:
+//
+//	go test -race -tags stress -run Stress -timeout 30m .
+
+const (
+	stressItems     = 2_000_000
+	stressProducers = 16
+
+	// stressMaxBatch bounds every batch size limit the test sets
+	stressMaxBatch = 200
+)
+
+// stressLog records where every item ended up, indexed by item
+type stressLog struct {
+	// maxBatch bounds the size of handled batches, if positive
+	maxBatch int
+
+	handled  []atomic.Int32
+	dropped  []atomic.Int32
+	accepted []atomic.Bool
+
+	mu         sync.Mutex
+	violations []string
+}
+
+func newStressLog(n int) *stressLog {
+	return &stressLog{
+		handled:  make([]atomic.Int32, n),
+		dropped:  make([]atomic.Int32, n),
+		accepted: make([]atomic.Bool, n),
+	}
+}
+
+func (l *stressLog) violate(format string, args ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.violations) < 20 {
+		l.violations = append(l.violations, fmt.Sprintf(format, args...))
+	}
+}
+
+// handle is the handler of the batcher under test and checks the
+// invariants of each batch as it arrives
+func (l *stressLog) handle(ctx context.Context, batch []any) (*LoadFeedback, error) {
+	if len(batch) == 0 {
+		l.violate("Expected a non-empty batch")
+	}
+	if l.maxBatch > 0 && len(batch) > l.maxBatch {
+		l.violate("Expected batch size at most %d, got %d", l.maxBatch, len(batch))
+	}
+	for _, item := range batch {
+		if n := l.handled[item.(int)].Add(1); n > 1 {
+			l.violate("Expected item %d handled once, handled %d times", item, n)
+		}
+	}
+	// Vary the reported load so the batch size keeps adapting
+	return &LoadFeedback{CPULoad: float64(len(batch)%10) / 10}, nil
+}
+
+func (l *stressLog) deadLetter(ctx context.Context, items []any, err error) {
+	for _, item := range items {
+		l.dropped[item.(int)].Add(1)
+	}
+}
+
+// check verifies that every accepted item was handled or dead-lettered
+// exactly once and no rejected item was delivered
+func (l *stressLog) check(t *testing.T) {
+	t.Helper()
+	for _, v := range l.violations {
+		t.Error(v)
+	}
+	missing := 0
+	for item := range l.accepted {
+		n := l.handled[item].Load() + l.dropped[item].Load()
+		switch {
+		case l.accepted[item].Load() && n != 1:
+			if missing++; missing <= 20 {
+				t.Errorf("Expected item %d delivered once, got %d (handled %d, dropped %d)",
+					item, n, l.handled[item].Load(), l.dropped[item].Load())
+			}
+		case !l.accepted[item].Load() && n != 0:
+			t.Errorf("Expected rejected item %d not delivered, delivered %d times", item, n)
+		}
+	}
+	if missing > 20 {
+		t.Errorf("... and %d more items not delivered exactly once", missing-20)
+	}
+}
+
+func TestStress_ExactlyOnce(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		cfg   Config
+		pause bool
+	}{
+		{"plain", Config{}, false},
+		{"pipelined", Config{Pipelined: true}, false},
+		{"timeout", Config{Timeout: time.Millisecond}, false},
+		{"shedding", Config{
+			DropPolicy:        DropOldest,
+			ShedHighWatermark: 500,
+			ShedLoadThreshold: 0.5,
+		}, false},
+		{"paused", Config{}, true},
+		{"paused-pipelined", Config{Pipelined: true}, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			runStress(t, tc.cfg, tc.pause)
+		})
+	}
+}
+
+// runStress adds stressItems items from stressProducers goroutines while
+// a chaos goroutine flushes, resizes and finally closes the batcher at
+// random, and with pause set pauses flushing too. Items added during a
+// pause are flushed as one batch on resume, so batch sizes are only
+// checked without pauses.
+func runStress(t *testing.T, cfg Config, pause bool) {
+	log := newStressLog(stressItems)
+	if !pause {
+		log.maxBatch = stressMaxBatch
+	}
+	cfg.InitialBatchSize = 50
+	cfg.MinBatchSize = 1
+	cfg.MaxBatchSize = stressMaxBatch
+	cfg.LoadCheckInterval = time.Millisecond
+	cfg.HandlerFunc = log.handle
+	cfg.DeadLetterFunc = log.deadLetter
+
+	b, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	ctx := context.Background()
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for p := 0; p < stressProducers; p++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := next.Add(1) - 1; i < stressItems; i = next.Add(1) - 1 {
+				err := b.Add(ctx, int(i))
+				if err == nil || errors.Is(err, ErrDropped) {
+					log.accepted[i].Store(true)
+				} else if !errors.Is(err, ErrClosed) {
+					t.Errorf("Add(%d) failed: %v", i, err)
+				}
+			}
+		}()
+	}
+
+	// Close once most items are in, so that late Adds race with it
+	closeAt := int64(stressItems * (80 + rand.Intn(20)) / 100)
+	chaos := make(chan struct{})
+	go func() {
+		defer close(chaos)
+		r := rand.New(rand.NewSource(time.Now().UnixNano()))
+		for next.Load() < closeAt {
+			switch r.Intn(7) {
+			case 0:
+				_ = b.Flush(ctx)
+			case 1:
+				_ = b.FlushNow(ctx)
+			case 2:
+				_ = b.FlushAndWait(ctx)
+			case 3:
+				_ = b.Barrier(ctx)
+			case 4:
+				if !pause {
+					continue
+				}
+				b.pauseFor(time.Duration(r.Intn(500)) * time.Microsecond)
+			case 5:
+				min := 1 + r.Intn(stressMaxBatch)
+				_ = b.SetLimits(min, min+r.Intn(stressMaxBatch-min+1))
+			case 6:
+				b.AdjustNow()
+			}
+			time.Sleep(time.Duration(r.Intn(200)) * time.Microsecond)
+		}
+		if err := b.Close(ctx); err != nil {
+			t.Errorf("Close() failed: %v", err)
+		}
+	}()
+
+	wg.Wait()
+	<-chaos
+	_ = b.FlushAndWait(ctx)
+
+	log.check(t)
+}
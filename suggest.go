@@ -0,0 +1,61 @@
+package batcher
+
+import "math"
+
+// SuggestionPolicy selects how LoadFeedback.SuggestedBatchSize is used
+type SuggestionPolicy int
+
+const (
+	// SuggestionBlend averages the suggested size with the size computed
+	// by the strategy, weighted by Config.SuggestionWeight
+	SuggestionBlend SuggestionPolicy = iota
+
+	// SuggestionOverride uses the suggested size instead of the size
+	// computed by the strategy
+	SuggestionOverride
+
+	// SuggestionIgnore disregards suggested sizes
+	SuggestionIgnore
+)
+
+// String returns the string representation of SuggestionPolicy
+func (p SuggestionPolicy) String() string {
+	switch p {
+	case SuggestionBlend:
+		return "blend"
+	case SuggestionOverride:
+		return "override"
+	case SuggestionIgnore:
+		return "ignore"
+	default:
+		return "unknown"
+	}
+}
+
+// --- Internal methods ---
+
+// applySuggestionLocked combines the size computed by the strategy with
+// the latest size suggested by the backend in the feedback window, if any.
+// The result is clamped by the caller.
+func (b *Batcher) applySuggestionLocked(size int) int {
+	if b.cfg.SuggestionPolicy == SuggestionIgnore {
+		return size
+	}
+
+	suggested := 0
+	for i := len(b.recentFeedback) - 1; i >= 0; i-- {
+		if s := b.recentFeedback[i].Feedback.SuggestedBatchSize; s > 0 {
+			suggested = s
+			break
+		}
+	}
+	if suggested == 0 {
+		return size
+	}
+
+	if b.cfg.SuggestionPolicy == SuggestionOverride {
+		return suggested
+	}
+	w := b.cfg.SuggestionWeight
+	return int(math.Round(w*float64(suggested) + (1-w)*float64(size)))
+}
@@ -0,0 +1,63 @@
+package batcher
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// holdStrategy keeps the current batch size
+type holdStrategy struct{}
+
+func (holdStrategy) NextBatchSize(in AdjustmentInput) int { return in.CurrentBatchSize }
+
+func TestSuggestedBatchSize(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    SuggestionPolicy
+		weight    float64
+		suggested []int // per feedback sample, oldest first
+		want      int
+	}{
+		{"blend", SuggestionBlend, 0, []int{100}, 70},
+		{"weighted blend", SuggestionBlend, 0.25, []int{100}, 55},
+		{"override", SuggestionOverride, 0, []int{100}, 100},
+		{"ignore", SuggestionIgnore, 0, []int{100}, 40},
+		{"latest suggestion wins", SuggestionOverride, 0, []int{100, 80, 0}, 80},
+		{"no suggestion", SuggestionOverride, 0, []int{0}, 40},
+		{"clamped to max", SuggestionOverride, 0, []int{5000}, 200},
+		{"clamped to min", SuggestionOverride, 0, []int{1}, 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := New(Config{
+				InitialBatchSize:   40,
+				MinBatchSize:       10,
+				MaxBatchSize:       200,
+				LoadCheckInterval:  time.Hour,
+				AdjustmentStrategy: holdStrategy{},
+				SuggestionPolicy:   tt.policy,
+				SuggestionWeight:   tt.weight,
+				HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+					return nil, nil
+				},
+			})
+			if err != nil {
+				t.Fatalf("New() failed: %v", err)
+			}
+			defer b.Close(context.Background())
+
+			b.mu.Lock()
+			for _, s := range tt.suggested {
+				b.recordFeedback(LoadFeedback{CPULoad: 0.4, SuggestedBatchSize: s}, 40, 1)
+			}
+			b.mu.Unlock()
+			b.adjustBatchSize()
+
+			if got := b.GetCurrentBatchSize(); got != tt.want {
+				t.Errorf("Expected batch size %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package batcher
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// PlainHandlerFunc processes a batch without reporting backend load. The
+// batcher derives load from how long the call takes and whether it fails.
+type PlainHandlerFunc func(ctx context.Context, batch []any) error
+
+// errorSmoothing is the weight of the latest outcome in the error rate
+const errorSmoothing = 0.2
+
+// outcomeTracker keeps a moving average of handler failures
+type outcomeTracker struct {
+	mu   sync.Mutex
+	rate float64
+}
+
+func (o *outcomeTracker) record(failed bool) float64 {
+	outcome := 0.0
+	if failed {
+		outcome = 1
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.rate += errorSmoothing * (outcome - o.rate)
+	return o.rate
+}
+
+// synthesizeSample builds a feedback sample from the measured handler
+// latency and outcome. Latency is scored against Config.LatencyTarget
+// if set, so that the target maps to Config.NeutralLoadScore, and
+// otherwise against the fastest per-item latency observed. With
+// Config.SojournLatency the target applies to the sojourn time of the
+// batch instead. A rising failure rate raises the score on its own.
+func (b *Batcher) synthesizeSample(batchSize int, elapsed, sojourn time.Duration, err error) FeedbackSample {
+	latency := 0.0
+	if target := b.cfg.LatencyTarget; target > 0 {
+		measured := elapsed
+		if b.cfg.SojournLatency {
+			measured = sojourn
+		}
+		latency = clamp01(b.cfg.NeutralLoadScore * float64(measured) / float64(target))
+	} else {
+		latency = b.latency.score(elapsed, batchSize)
+	}
+
+	failed := 0.0
+	if err != nil {
+		failed = 1
+	}
+	errorRate := b.outcomes.record(err != nil)
+
+	return FeedbackSample{
+		Feedback: LoadFeedback{
+			ProcessingTime: elapsed,
+			ErrorRate:      failed,
+		},
+		BatchSize: batchSize,
+		Score:     math.Max(latency, errorRate),
+	}
+}
@@ -0,0 +1,85 @@
+package batcher
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBatcher_PlainHandlerFunc(t *testing.T) {
+	fail := false
+	b, err := New(Config{
+		InitialBatchSize: 1,
+		MaxBatchSize:     100,
+		PlainHandlerFunc: func(ctx context.Context, batch []any) error {
+			if fail {
+				return errors.New("backend unavailable")
+			}
+			return nil
+		},
+		LatencyTarget:     time.Second,
+		LoadCheckInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	ctx := context.Background()
+	for i := 0; i < 10; i++ {
+		b.Add(ctx, i)
+	}
+	stats := b.GetStats()
+	if stats.RecentFeedbackSize != 10 {
+		t.Fatalf("Expected synthesized feedback for every batch, got %d", stats.RecentFeedbackSize)
+	}
+	if stats.AggregatedLoadScore > 0.1 {
+		t.Errorf("Expected a fast, healthy handler to score low, got %v", stats.AggregatedLoadScore)
+	}
+
+	b.adjustBatchSize()
+	grown := b.GetCurrentBatchSize()
+	if grown <= 1 {
+		t.Errorf("Expected the batch size to grow, got %d", grown)
+	}
+
+	fail = true
+	for i := 0; i < 10*grown; i++ {
+		b.Add(ctx, i)
+	}
+	if score := b.GetStats().AggregatedLoadScore; score < 0.55 {
+		t.Errorf("Expected failures to raise the score, got %v", score)
+	}
+	b.adjustBatchSize()
+	if size := b.GetCurrentBatchSize(); size >= grown {
+		t.Errorf("Expected the batch size to shrink below %d, got %d", grown, size)
+	}
+}
+
+func TestSynthesizeSample_LatencyTarget(t *testing.T) {
+	b, err := New(Config{
+		InitialBatchSize: 1,
+		LatencyTarget:    100 * time.Millisecond,
+		PlainHandlerFunc: func(ctx context.Context, batch []any) error { return nil },
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	tests := []struct {
+		elapsed time.Duration
+		want    float64
+	}{
+		{50 * time.Millisecond, 0.2},
+		{100 * time.Millisecond, 0.4},
+		{time.Second, 1},
+	}
+	for _, tt := range tests {
+		s := b.synthesizeSample(10, tt.elapsed, tt.elapsed, nil)
+		if diff := s.Score - tt.want; diff > 1e-9 || diff < -1e-9 {
+			t.Errorf("Score at %v = %v, want %v", tt.elapsed, s.Score, tt.want)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package batcher
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// TenantQuota limits what one tenant may put into a shared batcher
+type TenantQuota struct {
+	// ItemsPerSecond is the sustained rate at which the tenant may add
+	// items. Zero means unlimited.
+	ItemsPerSecond float64
+
+	// Burst is the number of items the tenant may add at once on top of
+	// the rate (default: ItemsPerSecond, at least 1)
+	Burst int
+
+	// MaxPending caps the buffered plus in-flight items of the tenant.
+	// Zero means unlimited.
+	MaxPending int
+}
+
+// ErrQuotaExceeded is returned by Add when the item's tenant is over its
+// TenantQuota
+var ErrQuotaExceeded = errors.New("batcher: tenant quota exceeded")
+
+// TenantStats holds the statistics of one tenant
+type TenantStats struct {
+	// Pending is the number of buffered plus in-flight items
+	Pending int
+
+	// Added is the number of items accepted by Add, and Rejected the
+	// number refused with ErrQuotaExceeded
+	Added    int64
+	Rejected int64
+}
+
+// tenantState is the quota accounting of one tenant
+type tenantState struct {
+	quota   TenantQuota
+	tokens  float64
+	refill  time.Time
+	pending int
+
+	added    int64
+	rejected int64
+}
+
+// --- Internal methods ---
+
+// quotaFor returns the quota of a tenant: its entry in TenantQuotas, or
+// the default TenantQuota
+func (b *Batcher) quotaFor(tenant string) TenantQuota {
+	q, ok := b.cfg.TenantQuotas[tenant]
+	if !ok {
+		q = b.cfg.TenantQuota
+	}
+	if q.Burst <= 0 {
+		q.Burst = max(1, int(math.Ceil(q.ItemsPerSecond)))
+	}
+	return q
+}
+
+// admitTenant charges one item to the tenant, or returns
+// ErrQuotaExceeded if the tenant is over its rate or pending cap
+func (b *Batcher) admitTenant(tenant string, now time.Time) error {
+	b.tenantMu.Lock()
+	defer b.tenantMu.Unlock()
+
+	t, ok := b.tenants[tenant]
+	if !ok {
+		q := b.quotaFor(tenant)
+		t = &tenantState{quota: q, tokens: float64(q.Burst), refill: now}
+		b.tenants[tenant] = t
+	}
+
+	if q := t.quota; q.ItemsPerSecond > 0 {
+		t.tokens = math.Min(float64(q.Burst), t.tokens+now.Sub(t.refill).Seconds()*q.ItemsPerSecond)
+		t.refill = now
+	}
+	if (t.quota.ItemsPerSecond > 0 && t.tokens < 1) ||
+		(t.quota.MaxPending > 0 && t.pending >= t.quota.MaxPending) {
+		t.rejected++
+		return ErrQuotaExceeded
+	}
+
+	if t.quota.ItemsPerSecond > 0 {
+		t.tokens--
+	}
+	t.pending++
+	t.added++
+	return nil
+}
+
+// releaseTenants returns items that left the batcher, handled or
+// dropped, to the pending caps of their tenants
+func (b *Batcher) releaseTenants(items []pendingItem) {
+	if b.cfg.TenantFunc == nil || len(items) == 0 {
+		return
+	}
+
+	b.tenantMu.Lock()
+	defer b.tenantMu.Unlock()
+
+	for _, p := range items {
+		if t, ok := b.tenants[p.tenant]; ok {
+			t.pending--
+		}
+	}
+}
+
+// unadmitTenant undoes admitTenant for an item Add refused for another
+// reason. The rate is not refunded.
+func (b *Batcher) unadmitTenant(tenant string) {
+	if b.cfg.TenantFunc == nil {
+		return
+	}
+
+	b.tenantMu.Lock()
+	defer b.tenantMu.Unlock()
+
+	if t, ok := b.tenants[tenant]; ok {
+		t.pending--
+		t.added--
+	}
+}
+
+// tenantStats returns a copy of the per-tenant statistics
+func (b *Batcher) tenantStats() map[string]TenantStats {
+	b.tenantMu.Lock()
+	defer b.tenantMu.Unlock()
+
+	stats := make(map[string]TenantStats, len(b.tenants))
+	for name, t := range b.tenants {
+		stats[name] = TenantStats{Pending: t.pending, Added: t.added, Rejected: t.rejected}
+	}
+	return stats
+}
@@ -0,0 +1,132 @@
+package batcher
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTenantTestBatcher(t *testing.T, cfg Config) *Batcher {
+	t.Helper()
+
+	cfg.InitialBatchSize = 100
+	cfg.Timeout = time.Hour
+	cfg.TenantFunc = func(ctx context.Context, item any) string {
+		tenant, _ := ctx.Value(tenantKey{}).(string)
+		return tenant
+	}
+	cfg.HandlerFunc = func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+		return nil, nil
+	}
+	b, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	t.Cleanup(func() { b.Close(context.Background()) })
+	return b
+}
+
+func tenantCtx(tenant string) context.Context {
+	return context.WithValue(context.Background(), tenantKey{}, tenant)
+}
+
+func TestTenant_MaxPending(t *testing.T) {
+	b := newTenantTestBatcher(t, Config{TenantQuota: TenantQuota{MaxPending: 3}})
+
+	for i := 0; i < 3; i++ {
+		if err := b.Add(tenantCtx("acme"), i); err != nil {
+			t.Fatalf("Add() failed: %v", err)
+		}
+	}
+	if err := b.Add(tenantCtx("acme"), 3); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Expected ErrQuotaExceeded over the pending cap, got %v", err)
+	}
+	if err := b.Add(tenantCtx("globex"), 4); err != nil {
+		t.Errorf("Expected another tenant to be unaffected, got %v", err)
+	}
+
+	stats := b.GetStats().Tenants
+	if acme := stats["acme"]; acme.Pending != 3 || acme.Added != 3 || acme.Rejected != 1 {
+		t.Errorf("Unexpected acme stats: %+v", acme)
+	}
+	if globex := stats["globex"]; globex.Pending != 1 || globex.Added != 1 {
+		t.Errorf("Unexpected globex stats: %+v", globex)
+	}
+
+	// Handled items free the cap again
+	b.Flush(context.Background())
+	if err := b.Add(tenantCtx("acme"), 5); err != nil {
+		t.Errorf("Expected the cap to be released by the flush, got %v", err)
+	}
+	if acme := b.GetStats().Tenants["acme"]; acme.Pending != 1 {
+		t.Errorf("Expected 1 pending acme item, got %d", acme.Pending)
+	}
+}
+
+func TestTenant_Rate(t *testing.T) {
+	b := newTenantTestBatcher(t, Config{
+		TenantQuotas: map[string]TenantQuota{"acme": {ItemsPerSecond: 10, Burst: 2}},
+	})
+
+	for i := 0; i < 2; i++ {
+		if err := b.Add(tenantCtx("acme"), i); err != nil {
+			t.Fatalf("Add() failed within the burst: %v", err)
+		}
+	}
+	if err := b.Add(tenantCtx("acme"), 2); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Expected ErrQuotaExceeded beyond the burst, got %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		if err := b.Add(tenantCtx("globex"), i); err != nil {
+			t.Fatalf("Expected tenants without a quota to be unlimited, got %v", err)
+		}
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	if err := b.Add(tenantCtx("acme"), 3); err != nil {
+		t.Errorf("Expected the rate to refill, got %v", err)
+	}
+}
+
+func TestTenant_ReleasedWhenShed(t *testing.T) {
+	b := newTenantTestBatcher(t, Config{
+		DropPolicy:        DropOldest,
+		ShedHighWatermark: 2,
+		ShedLoadThreshold: 0.5,
+		TenantQuota:       TenantQuota{MaxPending: 10},
+	})
+	b.mu.Lock()
+	b.recordFeedback(LoadFeedback{CPULoad: 1.0, ErrorRate: 1.0}, 10, 1)
+	b.mu.Unlock()
+
+	for i := 0; i < 5; i++ {
+		b.Add(tenantCtx("acme"), i)
+	}
+	stats := b.GetStats()
+	if stats.ShedItems == 0 {
+		t.Fatal("Expected items to be shed")
+	}
+	if acme := stats.Tenants["acme"]; acme.Pending != stats.PendingItems {
+		t.Errorf("Expected shed items to leave the tenant count, got %d pending for %d buffered",
+			acme.Pending, stats.PendingItems)
+	}
+}
+
+func TestTenant_NoStatsWithoutTenantFunc(t *testing.T) {
+	b, err := New(Config{
+		InitialBatchSize: 10,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			return nil, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	b.Add(tenantCtx("acme"), 1)
+	if tenants := b.GetStats().Tenants; tenants != nil {
+		t.Errorf("Expected no tenant stats, got %v", tenants)
+	}
+}
@@ -0,0 +1,144 @@
+package batcher
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+// TwoLevelConfig holds the configuration for a TwoLevel
+type TwoLevelConfig struct {
+	// Inner configures the batcher cutting micro-batches, typically with
+	// a short Timeout that meets the latency budget. Its handler is
+	// provided by the TwoLevel; HandlerFunc, HandlerFuncV2 and
+	// PlainHandlerFunc are ignored. So are RequeuePolicy and
+	// BisectFailures: a micro-batch that fails partway has some items in
+	// the outer batcher already, and handling it again would deliver
+	// those twice. Configure retries on Outer.
+	Inner Config
+
+	// Outer configures the batcher aggregating micro-batches into the
+	// mega-batches its handler receives. Its batch size counts items,
+	// not micro-batches. A PlainHandlerFunc returns no feedback to
+	// forward, so with one only the outer level adapts to the backend;
+	// the inner level keeps its InitialBatchSize.
+	Outer Config
+}
+
+// TwoLevel batches in two stages: a fast inner batcher flushes small
+// micro-batches within the latency budget into an outer batcher, which
+// aggregates them into the large mega-batches a bulk API wants. Feedback
+// returned by the outer handler sizes both levels, so a loaded backend
+// slows down the micro-batches as well.
+type TwoLevel struct {
+	inner *Batcher
+	outer *Batcher
+
+	// feedback is the latest outer feedback not yet reported to inner
+	feedback atomic.Pointer[LoadFeedback]
+}
+
+// NewTwoLevel creates a TwoLevel with the given configuration. The outer
+// handler must be set.
+func NewTwoLevel(cfg TwoLevelConfig) (*TwoLevel, error) {
+	t := &TwoLevel{}
+
+	ocfg := cfg.Outer
+	switch {
+	case ocfg.HandlerFuncV2 != nil:
+		handler := ocfg.HandlerFuncV2
+		ocfg.HandlerFuncV2 = func(ctx context.Context, batch []any, meta BatchMeta) (*LoadFeedback, error) {
+			feedback, err := handler(ctx, batch, meta)
+			t.forward(feedback)
+			return feedback, err
+		}
+	case ocfg.HandlerFunc != nil:
+		handler := ocfg.HandlerFunc
+		ocfg.HandlerFunc = func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			feedback, err := handler(ctx, batch)
+			t.forward(feedback)
+			return feedback, err
+		}
+	case ocfg.PlainHandlerFunc == nil:
+		return nil, ErrInvalidConfig
+	}
+
+	outer, err := New(ocfg)
+	if err != nil {
+		return nil, err
+	}
+
+	icfg := cfg.Inner
+	icfg.HandlerFuncV2 = nil
+	icfg.PlainHandlerFunc = nil
+	icfg.HandlerFunc = t.handleMicroBatch
+	icfg.RequeuePolicy = RequeueNone
+	icfg.BisectFailures = false
+	inner, err := New(icfg)
+	if err != nil {
+		outer.Close(context.Background())
+		return nil, err
+	}
+
+	t.inner, t.outer = inner, outer
+	return t, nil
+}
+
+// Add adds one item to the current micro-batch
+func (t *TwoLevel) Add(ctx context.Context, item any) error {
+	return t.inner.Add(ctx, item)
+}
+
+// Flush hands the current micro-batch to the outer batcher and flushes
+// the mega-batch
+func (t *TwoLevel) Flush(ctx context.Context) error {
+	return errors.Join(t.inner.Flush(ctx), t.outer.Flush(ctx))
+}
+
+// Close closes the inner batcher, then the outer one, so items pending
+// at either level reach the outer handler
+func (t *TwoLevel) Close(ctx context.Context) error {
+	return errors.Join(t.inner.Close(ctx), t.outer.Close(ctx))
+}
+
+// GetStats returns the statistics of both levels
+func (t *TwoLevel) GetStats() TwoLevelStats {
+	return TwoLevelStats{Inner: t.inner.GetStats(), Outer: t.outer.GetStats()}
+}
+
+// TwoLevelStats holds two-level batching statistics
+type TwoLevelStats struct {
+	// Inner holds the statistics of the micro-batcher
+	Inner Stats
+
+	// Outer holds the statistics of the mega-batcher
+	Outer Stats
+}
+
+// --- Internal methods ---
+
+// handleMicroBatch moves a micro-batch into the outer batcher and reports
+// the outer feedback received since the previous micro-batch, if any.
+// Adding may flush a mega-batch, which holds the inner batcher back while
+// the backend is busy. Its errors fail the micro-batch, which the inner
+// batcher does not retry, as the items added before are in the outer one.
+func (t *TwoLevel) handleMicroBatch(ctx context.Context, batch []any) (*LoadFeedback, error) {
+	var errs []error
+	for _, item := range batch {
+		if err := t.outer.Add(ctx, item); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return t.feedback.Swap(nil), errors.Join(errs...)
+}
+
+// forward keeps outer feedback for the next micro-batch. Each feedback is
+// reported to the inner batcher once, so a pause it asks for is not
+// repeated.
+func (t *TwoLevel) forward(feedback *LoadFeedback) {
+	if feedback == nil {
+		return
+	}
+	fb := *feedback
+	t.feedback.Store(&fb)
+}
@@ -0,0 +1,147 @@
+package batcher
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTwoLevel_AggregatesMicroBatches(t *testing.T) {
+	var mu sync.Mutex
+	var sizes []int
+
+	tl, err := NewTwoLevel(TwoLevelConfig{
+		Inner: Config{InitialBatchSize: 10, Timeout: time.Hour},
+		Outer: Config{
+			InitialBatchSize: 100,
+			MaxBatchSize:     100,
+			Timeout:          time.Hour,
+			HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+				mu.Lock()
+				sizes = append(sizes, len(batch))
+				mu.Unlock()
+				return &LoadFeedback{CPULoad: 0.5}, nil
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewTwoLevel() failed: %v", err)
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 255; i++ {
+		if err := tl.Add(ctx, i); err != nil {
+			t.Fatalf("Add() failed: %v", err)
+		}
+	}
+	if err := tl.Close(ctx); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(sizes) != 3 || sizes[0] != 100 || sizes[1] != 100 || sizes[2] != 55 {
+		t.Errorf("Expected mega-batches of 100, 100 and 55 items, got %v", sizes)
+	}
+
+	stats := tl.GetStats()
+	if stats.Inner.Batches != 26 || stats.Outer.Batches != 3 {
+		t.Errorf("Expected 26 micro-batches and 3 mega-batches, got %d and %d",
+			stats.Inner.Batches, stats.Outer.Batches)
+	}
+}
+
+func TestTwoLevel_FeedbackReachesBothLevels(t *testing.T) {
+	tl, err := NewTwoLevel(TwoLevelConfig{
+		Inner: Config{InitialBatchSize: 10, MinBatchSize: 1, Timeout: time.Hour, LoadCheckInterval: time.Hour},
+		Outer: Config{
+			InitialBatchSize:  20,
+			MinBatchSize:      1,
+			Timeout:           time.Hour,
+			LoadCheckInterval: time.Hour,
+			HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+				return &LoadFeedback{CPULoad: 1.0}, nil
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewTwoLevel() failed: %v", err)
+	}
+	defer tl.Close(context.Background())
+
+	// The second micro-batch fills the mega-batch, the third one reports
+	// its feedback to the inner batcher
+	ctx := context.Background()
+	for i := 0; i < 30; i++ {
+		tl.Add(ctx, i)
+	}
+
+	tl.inner.adjustBatchSize()
+	tl.outer.adjustBatchSize()
+	if got := tl.outer.GetCurrentBatchSize(); got >= 20 {
+		t.Errorf("Expected the outer batch size to shrink under load, got %d", got)
+	}
+	if got := tl.inner.GetCurrentBatchSize(); got >= 10 {
+		t.Errorf("Expected the inner batch size to shrink under load, got %d", got)
+	}
+	if got := tl.GetStats().Inner.RecentFeedbackSize; got != 1 {
+		t.Errorf("Expected the outer feedback to be reported to the inner batcher once, got %d samples", got)
+	}
+}
+
+func TestTwoLevel_PartialMicroBatchNotRetried(t *testing.T) {
+	var mu sync.Mutex
+	seen := make(map[any]int)
+	calls := 0
+
+	tl, err := NewTwoLevel(TwoLevelConfig{
+		Inner: Config{
+			InitialBatchSize: 3,
+			Timeout:          time.Hour,
+			RequeuePolicy:    RequeueFront,
+			BisectFailures:   true,
+		},
+		Outer: Config{
+			InitialBatchSize: 2,
+			MaxBatchSize:     2,
+			Timeout:          time.Hour,
+			HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				if calls++; calls == 1 {
+					return nil, errTransient
+				}
+				for _, item := range batch {
+					seen[item]++
+				}
+				return nil, nil
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewTwoLevel() failed: %v", err)
+	}
+
+	// The first mega-batch fails while the micro-batch [0 1 2] is moved,
+	// after which item 2 is in the outer batcher already
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		tl.Add(ctx, i)
+	}
+	if err := tl.Close(ctx); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if seen[2] != 1 {
+		t.Errorf("Expected item 2 delivered once, got %d", seen[2])
+	}
+}
+
+func TestTwoLevel_RequiresOuterHandler(t *testing.T) {
+	if _, err := NewTwoLevel(TwoLevelConfig{}); err != ErrInvalidConfig {
+		t.Errorf("Expected ErrInvalidConfig without an outer handler, got %v", err)
+	}
+}
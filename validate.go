@@ -0,0 +1,115 @@
+package batcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ConfigError describes a problem with one Config field. It wraps
+// ErrInvalidConfig.
+type ConfigError struct {
+	// Field is the Config field, e.g. "MinBatchSize" or
+	// "CustomMetrics[2].Weight"
+	Field string
+
+	// Reason says what is wrong with it
+	Reason string
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("batcher: invalid configuration: %s: %s", e.Field, e.Reason)
+}
+
+func (e *ConfigError) Unwrap() error {
+	return ErrInvalidConfig
+}
+
+// Validate returns every problem New would reject the configuration for,
+// each a *ConfigError, joined with errors.Join; nil if there are none.
+// Defaults are taken into account: a zero MaxBatchSize stands for 1000.
+// New rejects the configuration with this error, and Validate checks a
+// configuration without creating a batcher, e.g. before rolling it out.
+func (c Config) Validate() error {
+	var errs []error
+	invalid := func(field, format string, args ...any) {
+		errs = append(errs, &ConfigError{Field: field, Reason: fmt.Sprintf(format, args...)})
+	}
+
+	if c.InitialBatchSize <= 0 {
+		invalid("InitialBatchSize", "must be positive, got %d", c.InitialBatchSize)
+	}
+	minSize, maxSize := c.MinBatchSize, c.MaxBatchSize
+	if minSize <= 0 {
+		minSize = 1
+	}
+	if maxSize <= 0 {
+		maxSize = 1000
+	}
+	if minSize > maxSize {
+		invalid("MinBatchSize", "%d exceeds MaxBatchSize %d", minSize, maxSize)
+	}
+	if c.HandlerFunc == nil && c.HandlerFuncV2 == nil && c.PlainHandlerFunc == nil && !c.DryRun {
+		invalid("HandlerFunc", "one of HandlerFunc, HandlerFuncV2 and PlainHandlerFunc must be set")
+	}
+
+	total := 0.0
+	for i, m := range c.CustomMetrics {
+		if m.Weight < 0 {
+			invalid(fmt.Sprintf("CustomMetrics[%d].Weight", i), "must not be negative, got %g", m.Weight)
+		}
+		if m.Name == "" && m.Extract == nil {
+			invalid(fmt.Sprintf("CustomMetrics[%d].Name", i), "one of Name and Extract must be set")
+		}
+		total += m.Weight
+	}
+	if total > 1 {
+		invalid("CustomMetrics", "weights add up to %g, more than 1", total)
+	}
+
+	for _, f := range []struct {
+		name  string
+		value float64
+	}{
+		{"AdjustmentFactor", c.AdjustmentFactor},
+		{"GrowFactor", c.GrowFactor},
+		{"ShrinkFactor", c.ShrinkFactor},
+		{"ErrorSpikeThreshold", c.ErrorSpikeThreshold},
+	} {
+		if f.value < 0 || math.IsNaN(f.value) || math.IsInf(f.value, 0) {
+			invalid(f.name, "must be a non-negative number, got %g", f.value)
+		}
+	}
+	if f := c.ErrorSpikeFactor; f < 0 || f >= 1 || math.IsNaN(f) {
+		invalid("ErrorSpikeFactor", "must be in [0, 1), got %g", f)
+	}
+	if c.DegradeAfter < 0 {
+		invalid("DegradeAfter", "must not be negative, got %d", c.DegradeAfter)
+	}
+	if c.AdjustmentStrategy == nil && c.Strategy != "" {
+		if _, ok := lookupStrategy(c.Strategy); !ok {
+			invalid("Strategy", "no strategy registered as %q", c.Strategy)
+		}
+	}
+	if c.MaxConcurrency < 0 {
+		invalid("MaxConcurrency", "must not be negative, got %d", c.MaxConcurrency)
+	}
+	if c.MaxRequeueElapsed < 0 {
+		invalid("MaxRequeueElapsed", "must not be negative, got %v", c.MaxRequeueElapsed)
+	}
+	if c.MaxPendingBytes < 0 {
+		invalid("MaxPendingBytes", "must not be negative, got %d", c.MaxPendingBytes)
+	} else if c.MaxPendingBytes > 0 && c.SizerFunc == nil {
+		invalid("SizerFunc", "must be set with MaxPendingBytes")
+	}
+
+	return errors.Join(errs...)
+}
+
+// --- Internal methods ---
+
+// dryRunHandler stands in for the handler in DryRun mode
+func dryRunHandler(ctx context.Context, batch []any) (*LoadFeedback, error) {
+	return nil, nil
+}
@@ -0,0 +1,105 @@
+package batcher
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	handler := func(ctx context.Context, batch []any) (*LoadFeedback, error) { return nil, nil }
+
+	tests := []struct {
+		name   string
+		cfg    Config
+		fields []string
+	}{
+		{"valid", Config{InitialBatchSize: 10, HandlerFunc: handler}, nil},
+		{"dry run needs no handler", Config{InitialBatchSize: 10, DryRun: true}, nil},
+		{"default max", Config{InitialBatchSize: 10, MinBatchSize: 500, HandlerFunc: handler}, nil},
+		{"min above default max", Config{InitialBatchSize: 10, MinBatchSize: 1500, HandlerFunc: handler},
+			[]string{"MinBatchSize"}},
+		{
+			"every problem",
+			Config{
+				AdjustmentFactor: math.NaN(),
+				CustomMetrics:    []CustomMetric{{Name: "a", Weight: 0.8}, {Weight: -0.1}, {Name: "c", Weight: 0.5}},
+			},
+			[]string{"InitialBatchSize", "HandlerFunc", "CustomMetrics[1].Weight", "CustomMetrics[1].Name",
+				"CustomMetrics", "AdjustmentFactor"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if len(tt.fields) == 0 {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidConfig) {
+				t.Fatalf("Expected ErrInvalidConfig, got %v", err)
+			}
+
+			var got []string
+			for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+				var ce *ConfigError
+				if !errors.As(e, &ce) {
+					t.Fatalf("Expected a *ConfigError, got %T", e)
+				}
+				got = append(got, ce.Field)
+			}
+			if len(got) != len(tt.fields) {
+				t.Fatalf("Expected problems with %v, got %v", tt.fields, got)
+			}
+			for i := range got {
+				if got[i] != tt.fields[i] {
+					t.Errorf("Expected problems with %v, got %v", tt.fields, got)
+					break
+				}
+			}
+
+			if _, err := New(tt.cfg); !errors.Is(err, ErrInvalidConfig) || err.Error() != tt.cfg.Validate().Error() {
+				t.Errorf("Expected New to reject the configuration with the Validate error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestBatcher_DryRun(t *testing.T) {
+	called := false
+	b, err := New(Config{
+		InitialBatchSize:  10,
+		MaxBatchSize:      100,
+		LoadCheckInterval: time.Hour,
+		DryRun:            true,
+		HandlerFunc: func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+			called = true
+			return nil, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer b.Close(context.Background())
+
+	for i := 0; i < 50; i++ {
+		if err := b.Add(context.Background(), i); err != nil {
+			t.Fatalf("Add() failed: %v", err)
+		}
+	}
+	b.adjustBatchSize()
+
+	if called {
+		t.Error("Expected the handler not to be called in dry-run mode")
+	}
+	stats := b.GetStats()
+	if stats.Batches != 5 || stats.RecentFeedbackSize == 0 {
+		t.Errorf("Expected 5 simulated batches with synthesized feedback, got %d and %d samples",
+			stats.Batches, stats.RecentFeedbackSize)
+	}
+}
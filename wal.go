@@ -0,0 +1,206 @@
+package batcher
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// FileWAL is a WAL in an append-only file of JSON records, one per line,
+// synced to disk on every Append and Commit. Items are stored as JSON, so
+// they must be encodable with encoding/json. Committed batches are
+// dropped from the file when it is opened.
+type FileWAL struct {
+	path   string
+	decode func(data []byte) (any, error)
+
+	mu      sync.Mutex
+	f       *os.File
+	pending []WALEntry
+	index   map[string]int // position of a pending ID in pending
+}
+
+// walRecord is a line of a FileWAL
+type walRecord struct {
+	ID        string            `json:"id"`
+	Items     []json.RawMessage `json:"items,omitempty"`
+	Committed bool              `json:"committed,omitempty"`
+}
+
+// OpenFileWAL opens or creates the WAL at path. decode turns an item
+// read back from the file into the value the sink expects, e.g. by
+// unmarshaling it into the item type; if nil, items are unmarshaled into
+// an any, so an object becomes a map[string]any. A last line cut short
+// by a crash is ignored.
+func OpenFileWAL(path string, decode func(data []byte) (any, error)) (*FileWAL, error) {
+	if decode == nil {
+		decode = func(data []byte) (any, error) {
+			var v any
+			err := json.Unmarshal(data, &v)
+			return v, err
+		}
+	}
+	w := &FileWAL{path: path, decode: decode, index: make(map[string]int)}
+	records, err := w.load()
+	if err != nil {
+		return nil, fmt.Errorf("batcher: open wal: %w", err)
+	}
+	if err := w.compact(records); err != nil {
+		return nil, fmt.Errorf("batcher: open wal: %w", err)
+	}
+	return w, nil
+}
+
+// Append implements WAL
+func (w *FileWAL) Append(ctx context.Context, id string, items []any) error {
+	rec := walRecord{ID: id, Items: make([]json.RawMessage, len(items))}
+	for i, item := range items {
+		data, err := json.Marshal(item)
+		if err != nil {
+			return fmt.Errorf("encode item %d: %w", i, err)
+		}
+		rec.Items[i] = data
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.writeLocked(rec); err != nil {
+		return err
+	}
+	w.index[id] = len(w.pending)
+	w.pending = append(w.pending, WALEntry{ID: id, Items: items})
+	return nil
+}
+
+// Commit implements WAL
+func (w *FileWAL) Commit(ctx context.Context, id string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.writeLocked(walRecord{ID: id, Committed: true}); err != nil {
+		return err
+	}
+	w.removeLocked(id)
+	return nil
+}
+
+// Pending implements WAL
+func (w *FileWAL) Pending(ctx context.Context) ([]WALEntry, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return append([]WALEntry(nil), w.pending...), nil
+}
+
+// Close closes the file
+func (w *FileWAL) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.f.Close()
+}
+
+// --- Internal methods ---
+
+// load reads the pending batches from the file, if it exists, and
+// returns their records
+func (w *FileWAL) load() ([]walRecord, error) {
+	f, err := os.Open(w.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	records := make(map[string]walRecord)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<30)
+	for scanner.Scan() {
+		var rec walRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			// A torn write of the last record
+			break
+		}
+		if rec.Committed {
+			w.removeLocked(rec.ID)
+			delete(records, rec.ID)
+			continue
+		}
+		entry := WALEntry{ID: rec.ID, Items: make([]any, len(rec.Items))}
+		for i, data := range rec.Items {
+			if entry.Items[i], err = w.decode(data); err != nil {
+				return nil, fmt.Errorf("batch %s: decode item %d: %w", rec.ID, i, err)
+			}
+		}
+		w.index[rec.ID] = len(w.pending)
+		w.pending = append(w.pending, entry)
+		records[rec.ID] = rec
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	out := make([]walRecord, len(w.pending))
+	for i, entry := range w.pending {
+		out[i] = records[entry.ID]
+	}
+	return out, nil
+}
+
+// compact rewrites the file with the given records only, replacing it
+// atomically, and keeps it open for appending
+func (w *FileWAL) compact(records []walRecord) error {
+	tmp := w.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	w.f = f
+	for _, rec := range records {
+		if err := w.writeLocked(rec); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := os.Rename(tmp, w.path); err != nil {
+		f.Close()
+		return err
+	}
+	return nil
+}
+
+// writeLocked appends rec to the file and syncs it
+func (w *FileWAL) writeLocked(rec walRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if _, err := w.f.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return w.f.Sync()
+}
+
+// removeLocked drops the batch id from the pending ones
+func (w *FileWAL) removeLocked(id string) {
+	i, ok := w.index[id]
+	if !ok {
+		return
+	}
+	w.pending = append(w.pending[:i], w.pending[i+1:]...)
+	delete(w.index, id)
+	for j := i; j < len(w.pending); j++ {
+		w.index[w.pending[j].ID] = j
+	}
+}
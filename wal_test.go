@@ -0,0 +1,69 @@
+package batcher
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileWAL_ReopenAndCompact(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "wal")
+
+	type event struct {
+		Name string `json:"name"`
+	}
+	decode := func(data []byte) (any, error) {
+		var e event
+		err := json.Unmarshal(data, &e)
+		return e, err
+	}
+
+	w, err := OpenFileWAL(path, decode)
+	if err != nil {
+		t.Fatalf("OpenFileWAL() failed: %v", err)
+	}
+	w.Append(ctx, "1", []any{event{"a"}})
+	w.Append(ctx, "2", []any{event{"b"}, event{"c"}})
+	w.Append(ctx, "3", []any{event{"d"}})
+	w.Commit(ctx, "2")
+	w.Close()
+
+	// A record torn by a crash
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	f.WriteString(`{"id":"4","items":[{"na`)
+	f.Close()
+
+	w, err = OpenFileWAL(path, decode)
+	if err != nil {
+		t.Fatalf("OpenFileWAL() failed: %v", err)
+	}
+	defer w.Close()
+
+	pending, _ := w.Pending(ctx)
+	if len(pending) != 2 || pending[0].ID != "1" || pending[1].ID != "3" {
+		t.Fatalf("Expected batches 1 and 3 pending, got %v", pending)
+	}
+	if e, ok := pending[1].Items[0].(event); !ok || e.Name != "d" {
+		t.Errorf("Expected the decoded item, got %#v", pending[1].Items[0])
+	}
+
+	data, _ := os.ReadFile(path)
+	if lines := strings.Count(string(data), "\n"); lines != 2 {
+		t.Errorf("Expected the file compacted to 2 records, got %d", lines)
+	}
+
+	// Appends go to the compacted file
+	w.Append(ctx, "5", []any{event{"e"}})
+	w.Commit(ctx, "1")
+	w.Close()
+	w, _ = OpenFileWAL(path, decode)
+	defer w.Close()
+	pending, _ = w.Pending(ctx)
+	if len(pending) != 2 || pending[0].ID != "3" || pending[1].ID != "5" {
+		t.Errorf("Expected batches 3 and 5 pending, got %v", pending)
+	}
+}
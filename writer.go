@@ -0,0 +1,148 @@
+package batcher
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+)
+
+// WriterConfig holds the configuration for a WriterBatcher
+type WriterConfig struct {
+	// Config is the batcher configuration. Its handlers are ignored;
+	// HandlerFunc below handles the batches.
+	Config Config
+
+	// HandlerFunc is called with each flushed batch of records. The
+	// records are owned by the handler.
+	HandlerFunc func(ctx context.Context, records [][]byte) (*LoadFeedback, error)
+
+	// Delimiter separates records in the written bytes and is not part
+	// of them (default: '\n')
+	Delimiter byte
+
+	// MaxRecordSize bounds the size of a record; a longer one is cut
+	// into records of this size (default: 64 KiB)
+	MaxRecordSize int
+}
+
+// WriterBatcher is an io.WriteCloser that splits the bytes written to it
+// into records, one per line by default, and batches them, so that a
+// load-aware batcher can be the sink of a log.Logger, a slog handler or
+// any other writer-based log shipper
+type WriterBatcher struct {
+	b   *Batcher
+	cfg WriterConfig
+
+	// mu guards partial, the start of a record not yet terminated
+	mu      sync.Mutex
+	partial []byte
+}
+
+// NewWriterBatcher creates a WriterBatcher with the given configuration.
+// HandlerFunc must be set.
+func NewWriterBatcher(cfg WriterConfig) (*WriterBatcher, error) {
+	if cfg.HandlerFunc == nil {
+		return nil, ErrInvalidConfig
+	}
+	if cfg.Delimiter == 0 {
+		cfg.Delimiter = '\n'
+	}
+	if cfg.MaxRecordSize <= 0 {
+		cfg.MaxRecordSize = 64 << 10
+	}
+
+	handler := cfg.HandlerFunc
+	bcfg := cfg.Config
+	bcfg.HandlerFuncV2 = nil
+	bcfg.PlainHandlerFunc = nil
+	bcfg.HandlerFunc = func(ctx context.Context, batch []any) (*LoadFeedback, error) {
+		records := make([][]byte, len(batch))
+		for i, item := range batch {
+			records[i] = item.([]byte)
+		}
+		return handler(ctx, records)
+	}
+
+	b, err := New(bcfg)
+	if err != nil {
+		return nil, err
+	}
+	return &WriterBatcher{b: b, cfg: cfg}, nil
+}
+
+// Write adds every complete record in p to the batcher and keeps a
+// trailing partial record until the rest of it is written. Empty
+// records are skipped. Records are copied, so p may be reused. An error
+// of a batch flushed by Write is returned after all of p is consumed.
+func (w *WriterBatcher) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var errs []error
+	n := 0
+	for n < len(p) {
+		rest := p[n:]
+		i := bytes.IndexByte(rest, w.cfg.Delimiter)
+		if i < 0 {
+			w.partial = append(w.partial, rest...)
+			n = len(p)
+			break
+		}
+		w.partial = append(w.partial, rest[:i]...)
+		n += i + 1
+
+		if err := w.addPartialLocked(); err != nil {
+			if errors.Is(err, ErrClosed) {
+				return n - i - 1, err
+			}
+			errs = append(errs, err)
+		}
+	}
+
+	// Cut records that outgrow MaxRecordSize without a delimiter
+	for len(w.partial) >= w.cfg.MaxRecordSize {
+		record := w.partial[:w.cfg.MaxRecordSize:w.cfg.MaxRecordSize]
+		w.partial = append([]byte(nil), w.partial[w.cfg.MaxRecordSize:]...)
+		if err := w.b.Add(context.Background(), record); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return n, errors.Join(errs...)
+}
+
+// Flush adds a partial record, if any, and flushes the current batch
+func (w *WriterBatcher) Flush(ctx context.Context) error {
+	w.mu.Lock()
+	err := w.addPartialLocked()
+	w.mu.Unlock()
+
+	return errors.Join(err, w.b.Flush(ctx))
+}
+
+// Close adds a partial record, if any, and closes the batcher, flushing
+// the records still buffered
+func (w *WriterBatcher) Close() error {
+	w.mu.Lock()
+	err := w.addPartialLocked()
+	w.mu.Unlock()
+
+	return errors.Join(err, w.b.Close(context.Background()))
+}
+
+// Batcher returns the underlying Batcher, e.g. for its statistics
+func (w *WriterBatcher) Batcher() *Batcher {
+	return w.b
+}
+
+// --- Internal methods ---
+
+// addPartialLocked adds the buffered record, if any, to the batcher
+func (w *WriterBatcher) addPartialLocked() error {
+	if len(w.partial) == 0 {
+		return nil
+	}
+	record := w.partial
+	w.partial = nil
+	return w.b.Add(context.Background(), record)
+}
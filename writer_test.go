@@ -0,0 +1,81 @@
+package batcher
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func newTestWriter(t *testing.T, cfg WriterConfig) (*WriterBatcher, func() []string) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var records []string
+	cfg.HandlerFunc = func(ctx context.Context, batch [][]byte) (*LoadFeedback, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, r := range batch {
+			records = append(records, string(r))
+		}
+		return nil, nil
+	}
+
+	w, err := NewWriterBatcher(cfg)
+	if err != nil {
+		t.Fatalf("NewWriterBatcher() failed: %v", err)
+	}
+	return w, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), records...)
+	}
+}
+
+func TestWriterBatcher_Logger(t *testing.T) {
+	w, records := newTestWriter(t, WriterConfig{Config: Config{InitialBatchSize: 2}})
+	logger := log.New(w, "", 0)
+
+	for i := 0; i < 5; i++ {
+		logger.Printf("line %d", i)
+	}
+	if got := records(); len(got) != 4 {
+		t.Errorf("Expected 2 full batches of 2 records, got %v", got)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+	want := "[line 0 line 1 line 2 line 3 line 4]"
+	if got := fmt.Sprint(records()); got != want {
+		t.Errorf("Expected records %s, got %s", want, got)
+	}
+}
+
+func TestWriterBatcher_SplitWrites(t *testing.T) {
+	w, records := newTestWriter(t, WriterConfig{
+		Config:        Config{InitialBatchSize: 100},
+		Delimiter:     ';',
+		MaxRecordSize: 8,
+	})
+
+	buf := []byte("ab")
+	w.Write(buf)
+	copy(buf, "xx") // the writer must not keep p
+	w.Write([]byte("c;;de"))
+	w.Write([]byte(";" + strings.Repeat("z", 10)))
+	if err := w.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+
+	want := "[abc de zzzzzzzz zz]"
+	if got := fmt.Sprint(records()); got != want {
+		t.Errorf("Expected records %s, got %s", want, got)
+	}
+
+	w.Close()
+	if _, err := w.Write([]byte("late;")); err != ErrClosed {
+		t.Errorf("Expected ErrClosed after Close, got %v", err)
+	}
+}